@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/mediocregopher/mediocre-go-lib/mcmp"
+	"github.com/mediocregopher/mediocre-go-lib/mctx"
+	"github.com/mediocregopher/mediocre-go-lib/mlog"
+	"github.com/pquerna/otp/totp"
+)
+
+// errUnauthorized is returned by an AuthProvider's Verify method when r
+// simply doesn't carry valid credentials, as opposed to some other error
+// occurring while checking them.
+var errUnauthorized = errors.New("unauthorized")
+
+// AuthProvider implements a single method of authenticating incoming
+// requests, e.g. TOTP+BasicAuth, or an OAuth2/OIDC redirect flow.
+//
+// A single AuthProvider is selected, by name, for the whole totp-proxy
+// instance (see main); authHandler uses it for every request which doesn't
+// already carry a valid session cookie.
+type AuthProvider interface {
+	// Challenge writes whatever response is needed to begin (or continue)
+	// this provider's authentication flow, e.g. a 401 with a
+	// WWW-Authenticate header, or a redirect to an identity provider.
+	Challenge(w http.ResponseWriter, r *http.Request)
+
+	// Verify checks if r is itself a valid authentication (e.g. a BasicAuth
+	// header passing a TOTP check, or an OAuth2/OIDC callback carrying a
+	// valid code), and if so returns the identity (e.g. username) it
+	// resolves to.
+	//
+	// Verify returns errUnauthorized if r simply doesn't carry valid
+	// credentials for this provider.
+	Verify(r *http.Request) (string, error)
+}
+
+// totpProvider is an AuthProvider implementing the original totp-proxy
+// behavior: a BasicAuth prompt whose password is checked as a TOTP code
+// against a per-user secret.
+type totpProvider struct {
+	cmp         *mcmp.Component
+	userSecrets map[string]string
+}
+
+// Challenge implements the AuthProvider interface.
+func (p *totpProvider) Challenge(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("WWW-Authenticate", "Basic")
+	w.WriteHeader(http.StatusUnauthorized)
+}
+
+// Verify implements the AuthProvider interface.
+func (p *totpProvider) Verify(r *http.Request) (string, error) {
+	user, pass, ok := r.BasicAuth()
+	if !ok || pass == "" {
+		return "", errUnauthorized
+	}
+
+	mlog.From(p.cmp).Debug("authenticating with user",
+		mctx.Annotate(r.Context(), "user", user))
+
+	userSecret, ok := p.userSecrets[user]
+	if !ok || !totp.Validate(pass, userSecret) {
+		return "", errUnauthorized
+	}
+	return user, nil
+}
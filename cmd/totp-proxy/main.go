@@ -1,33 +1,65 @@
 package main
 
 /*
-	totp-proxy is a reverse proxy which implements basic time-based one-time
-	password (totp) authentication for any website.
-
-	It takes in a JSON object which maps usernames to totp secrets (generated at
-	a site like https://freeotp.github.io/qrcode.html), as well as a url to
-	proxy requests to. Users are prompted with a basic-auth prompt, and if they
-	succeed their totp challenge a cookie is set and requests are proxied to the
-	destination.
+	totp-proxy is a reverse proxy which implements authentication for any
+	website, via a pluggable AuthProvider.
+
+	The original (and default) AuthProvider is basic time-based one-time
+	password (totp) authentication: it takes in a JSON object which maps
+	usernames to totp secrets (generated at a site like
+	https://freeotp.github.io/qrcode.html), and users are prompted with a
+	basic-auth prompt which is checked as a TOTP code.
+
+	Alternatively an OIDC (Keycloak, Google, or any other discovery-compliant
+	issuer) or plain OAuth2 (GitHub, Bitbucket) AuthProvider may be selected
+	via the auth-provider param, for deployments wanting real SSO rather than
+	a single shared set of TOTP secrets.
+
+	Whichever AuthProvider is selected, a successful authentication is
+	tracked server-side as a Session in a SessionStore (selected via the
+	session-backend param), and the cookie handed to the client is just that
+	Session's opaque ID. A cookie whose session has neared expiry is
+	transparently renewed with a sliding window; GET /logout revokes it
+	outright, and GET /sessions lists every session currently open for the
+	requesting user. This allows a single session (or every session
+	belonging to a user) to be invalidated without rotating any secret and
+	taking down every other session in the process.
 */
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/url"
 	"time"
 
 	"github.com/mediocregopher/mediocre-go-lib/m"
 	"github.com/mediocregopher/mediocre-go-lib/mcfg"
-	"github.com/mediocregopher/mediocre-go-lib/mcrypto"
 	"github.com/mediocregopher/mediocre-go-lib/mctx"
 	"github.com/mediocregopher/mediocre-go-lib/merr"
 	"github.com/mediocregopher/mediocre-go-lib/mhttp"
 	"github.com/mediocregopher/mediocre-go-lib/mlog"
-	"github.com/mediocregopher/mediocre-go-lib/mrand"
 	"github.com/mediocregopher/mediocre-go-lib/mrun"
 	"github.com/mediocregopher/mediocre-go-lib/mtime"
-	"github.com/pquerna/otp/totp"
+	"github.com/mediocregopher/radix/v3"
+)
+
+// authProviderName values select which AuthProvider implementation is used
+// by the auth-provider param.
+const (
+	authProviderTOTP            = "totp"
+	authProviderOIDC            = "oidc"
+	authProviderOAuth2GitHub    = "oauth2-github"
+	authProviderOAuth2Bitbucket = "oauth2-bitbucket"
+)
+
+// sessionBackendName values select which SessionStore implementation is used
+// by the session-backend param.
+const (
+	sessionBackendMem   = "mem"
+	sessionBackendFile  = "file"
+	sessionBackendBolt  = "bolt"
+	sessionBackendRedis = "redis"
 )
 
 func main() {
@@ -38,21 +70,109 @@ func main() {
 	cookieTimeout := mcfg.Duration(cmp, "cookie-timeout",
 		mcfg.ParamDefault(mtime.Duration{1 * time.Hour}),
 		mcfg.ParamUsage("Timeout for cookies"))
+	cookieRenewWindow := mcfg.Duration(cmp, "cookie-renew-window",
+		mcfg.ParamDefault(mtime.Duration{10 * time.Minute}),
+		mcfg.ParamUsage("How close to expiring a cookie's session needs to be before it's transparently renewed for another cookie-timeout."))
+
+	authProviderName := mcfg.String(cmp, "auth-provider",
+		mcfg.ParamDefault(authProviderTOTP),
+		mcfg.ParamUsage("Which AuthProvider to authenticate connections with. One of: "+
+			authProviderTOTP+", "+authProviderOIDC+", "+
+			authProviderOAuth2GitHub+", "+authProviderOAuth2Bitbucket))
 
 	var userSecrets map[string]string
 	mcfg.JSON(cmp, "users", &userSecrets,
-		mcfg.ParamRequired(),
-		mcfg.ParamUsage("JSON object which maps usernames to their TOTP secret strings"))
+		mcfg.ParamUsage("JSON object which maps usernames to their TOTP secret strings. Required if auth-provider is "+authProviderTOTP+"."))
+
+	oidcCmp := cmp.Child("oidc")
+	oidcIssuer := mcfg.String(oidcCmp, "issuer",
+		mcfg.ParamUsage("URL of the OIDC issuer to authenticate against (e.g. a Keycloak realm or https://accounts.google.com), used to discover its authorization/token endpoints."))
+	oidcClientID := mcfg.String(oidcCmp, "client-id", mcfg.ParamUsage("OAuth2 client ID registered with the OIDC issuer."))
+	oidcClientSecret := mcfg.String(oidcCmp, "client-secret", mcfg.ParamUsage("OAuth2 client secret registered with the OIDC issuer."))
+	oidcRedirectURL := mcfg.String(oidcCmp, "redirect-url", mcfg.ParamUsage("Callback URL registered with the OIDC issuer, normally this instance's own address plus /oauth2/callback."))
+	oidcUsernameClaim := mcfg.String(oidcCmp, "username-claim",
+		mcfg.ParamDefault("email"),
+		mcfg.ParamUsage("ID token claim whose value is used as the proxied username."))
+	var oidcScopes []string
+	mcfg.JSON(oidcCmp, "scopes", &oidcScopes,
+		mcfg.ParamDefault([]string{"openid", "profile", "email"}),
+		mcfg.ParamUsage("JSON array of OAuth2 scopes to request from the OIDC issuer."))
+
+	githubCmp := cmp.Child("oauth2-github")
+	githubClientID := mcfg.String(githubCmp, "client-id", mcfg.ParamUsage("OAuth2 client ID registered with GitHub."))
+	githubClientSecret := mcfg.String(githubCmp, "client-secret", mcfg.ParamUsage("OAuth2 client secret registered with GitHub."))
+	githubRedirectURL := mcfg.String(githubCmp, "redirect-url", mcfg.ParamUsage("Callback URL registered with GitHub, normally this instance's own address plus /oauth2/callback."))
+
+	bitbucketCmp := cmp.Child("oauth2-bitbucket")
+	bitbucketClientID := mcfg.String(bitbucketCmp, "client-id", mcfg.ParamUsage("OAuth2 client ID registered with Bitbucket."))
+	bitbucketClientSecret := mcfg.String(bitbucketCmp, "client-secret", mcfg.ParamUsage("OAuth2 client secret registered with Bitbucket."))
+	bitbucketRedirectURL := mcfg.String(bitbucketCmp, "redirect-url", mcfg.ParamUsage("Callback URL registered with Bitbucket, normally this instance's own address plus /oauth2/callback."))
 
-	var secret mcrypto.Secret
-	secretStr := mcfg.String(cmp, "secret",
-		mcfg.ParamUsage("String used to sign authentication tokens. If one isn't given a new one will be generated on each startup, invalidating all previous tokens."))
+	sessionBackendName := mcfg.String(cmp, "session-backend",
+		mcfg.ParamDefault(sessionBackendMem),
+		mcfg.ParamUsage("Which SessionStore to track authenticated sessions with. One of: "+
+			sessionBackendMem+", "+sessionBackendFile+", "+sessionBackendBolt+", "+sessionBackendRedis))
+
+	sessionCmp := cmp.Child("session")
+	sessionFilePath := mcfg.String(sessionCmp, "file-path",
+		mcfg.ParamDefault("totp-proxy-sessions.json"),
+		mcfg.ParamUsage("Path of the JSON file sessions are persisted to, when session-backend is "+sessionBackendFile+"."))
+	sessionBoltPath := mcfg.String(sessionCmp, "bolt-path",
+		mcfg.ParamDefault("totp-proxy-sessions.db"),
+		mcfg.ParamUsage("Path of the bolt file sessions are persisted to, when session-backend is "+sessionBackendBolt+"."))
+	sessionRedisAddr := mcfg.String(sessionCmp, "redis-addr",
+		mcfg.ParamDefault("127.0.0.1:6379"),
+		mcfg.ParamUsage("Address redis is listening on, when session-backend is "+sessionBackendRedis+"."))
+
+	var authProvider AuthProvider
+	mrun.InitHook(cmp, func(context.Context) error {
+		switch *authProviderName {
+		case authProviderTOTP:
+			authProvider = &totpProvider{cmp: cmp, userSecrets: userSecrets}
+		case authProviderOIDC:
+			var err error
+			authProvider, err = newOIDCProvider(oidcCmp,
+				*oidcIssuer, *oidcClientID, *oidcClientSecret, *oidcRedirectURL,
+				*oidcUsernameClaim, oidcScopes)
+			if err != nil {
+				return merr.Wrap(oidcCmp.Context(), err)
+			}
+		case authProviderOAuth2GitHub:
+			authProvider = newGitHubProvider(githubCmp, *githubClientID, *githubClientSecret, *githubRedirectURL)
+		case authProviderOAuth2Bitbucket:
+			authProvider = newBitbucketProvider(bitbucketCmp, *bitbucketClientID, *bitbucketClientSecret, *bitbucketRedirectURL)
+		default:
+			return merr.New(cmp.Context(), "unknown auth-provider: "+*authProviderName)
+		}
+		return nil
+	})
+
+	var sessions SessionStore
 	mrun.InitHook(cmp, func(context.Context) error {
-		if *secretStr == "" {
-			*secretStr = mrand.Hex(32)
+		switch *sessionBackendName {
+		case sessionBackendMem:
+			sessions = newMemSessionStore()
+		case sessionBackendFile:
+			var err error
+			sessions, err = newFileSessionStore(*sessionFilePath)
+			if err != nil {
+				return merr.Wrap(sessionCmp.Context(), err)
+			}
+		case sessionBackendBolt:
+			var err error
+			sessions, err = newBoltSessionStore(*sessionBoltPath)
+			if err != nil {
+				return merr.Wrap(sessionCmp.Context(), err)
+			}
+		case sessionBackendRedis:
+			client, err := radix.NewPool("tcp", *sessionRedisAddr, 4, nil)
+			if err != nil {
+				return merr.Wrap(sessionCmp.Context(), err)
+			}
+			sessions = newRedisSessionStore(client)
+		default:
+			return merr.New(sessionCmp.Context(), "unknown session-backend: "+*sessionBackendName)
 		}
-		mlog.From(cmp).Info("generating secret")
-		secret = mcrypto.NewSecret([]byte(*secretStr))
 		return nil
 	})
 
@@ -63,60 +183,126 @@ func main() {
 	mrun.InitHook(cmp, func(context.Context) error {
 		u, err := url.Parse(*proxyURL)
 		if err != nil {
-			return merr.Wrap(err, cmp.Context())
+			return merr.Wrap(cmp.Context(), err)
 		}
 		proxyHandler.Handler = mhttp.ReverseProxy(u)
 		return nil
 	})
 
+	// setCookie writes sess's ID to the client as the session cookie, valid
+	// for as long as remains of cookie-timeout.
+	setCookie := func(w http.ResponseWriter, sess *Session) {
+		http.SetCookie(w, &http.Cookie{
+			Name:   *cookieName,
+			Value:  sess.ID,
+			MaxAge: int(time.Until(sess.ExpiresAt).Seconds()),
+		})
+	}
+
+	// clearCookie instructs the client to delete the session cookie.
+	clearCookie := func(w http.ResponseWriter) {
+		http.SetCookie(w, &http.Cookie{
+			Name:   *cookieName,
+			Value:  "",
+			MaxAge: -1,
+		})
+	}
+
+	// sessionFromRequest returns the Session referenced by r's cookie, or
+	// errSessionNotFound if there isn't a valid one.
+	sessionFromRequest := func(r *http.Request) (*Session, error) {
+		cookie, err := r.Cookie(*cookieName)
+		if err != nil || cookie.Value == "" {
+			return nil, errSessionNotFound
+		}
+		return sessions.Get(cookie.Value)
+	}
+
 	authHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// TODO mlog.FromHTTP?
 		ctx := r.Context()
 
 		unauthorized := func() {
 			mlog.From(cmp).Debug("connection is unauthorized")
-			w.Header().Add("WWW-Authenticate", "Basic")
-			w.WriteHeader(http.StatusUnauthorized)
+			authProvider.Challenge(w, r)
 		}
 
-		authorized := func() {
-			mlog.From(cmp).Debug("connection is authorized, rewriting cookies")
-			sig := mcrypto.SignString(secret, "")
-			http.SetCookie(w, &http.Cookie{
-				Name:   *cookieName,
-				Value:  sig.String(),
-				MaxAge: int((*cookieTimeout).Seconds()),
-			})
+		authorizeAs := func(username string) {
+			mlog.From(cmp).Debug("connection is authorized, starting session",
+				mctx.Annotate(ctx, "user", username))
+			sess, err := sessions.Create(username, (*cookieTimeout).Duration)
+			if err != nil {
+				mlog.From(cmp).Warn("error creating session", ctx, err)
+				unauthorized()
+				return
+			}
+			setCookie(w, sess)
 			proxyHandler.ServeHTTP(w, r)
 		}
 
-		if cookie, _ := r.Cookie(*cookieName); cookie != nil {
-			mlog.From(cmp).Debug("authenticating with cookie",
-				mctx.Annotate(ctx, "cookie", cookie.String()))
-			var sig mcrypto.Signature
-			if err := sig.UnmarshalText([]byte(cookie.Value)); err == nil {
-				err := mcrypto.VerifyString(secret, sig, "")
-				if err == nil && time.Since(sig.Time()) < (*cookieTimeout).Duration {
-					authorized()
-					return
+		if sess, err := sessionFromRequest(r); err == nil {
+			mlog.From(cmp).Debug("authenticating with session cookie",
+				mctx.Annotate(ctx, "user", sess.Username))
+
+			if time.Until(sess.ExpiresAt) < (*cookieRenewWindow).Duration {
+				renewed, err := sessions.Refresh(sess.ID, (*cookieTimeout).Duration)
+				if err != nil {
+					mlog.From(cmp).Warn("error renewing session", ctx, err)
+				} else {
+					sess = renewed
 				}
 			}
+
+			setCookie(w, sess)
+			proxyHandler.ServeHTTP(w, r)
+			return
+		} else if !merr.Equal(err, errSessionNotFound) {
+			mlog.From(cmp).Warn("error looking up session", ctx, err)
 		}
 
-		if user, pass, ok := r.BasicAuth(); ok && pass != "" {
-			mlog.From(cmp).Debug("authenticating with user",
-				mctx.Annotate(ctx, "user", user))
-			if userSecret, ok := userSecrets[user]; ok {
-				if totp.Validate(pass, userSecret) {
-					authorized()
-					return
-				}
-			}
+		if user, err := authProvider.Verify(r); err == nil {
+			authorizeAs(user)
+			return
+		} else if !merr.Equal(err, errUnauthorized) {
+			mlog.From(cmp).Warn("error verifying authentication", ctx, err)
 		}
 
 		unauthorized()
 	})
 
-	mhttp.InstListeningServer(cmp, authHandler)
+	logoutHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if sess, err := sessionFromRequest(r); err == nil {
+			if err := sessions.Revoke(sess.ID); err != nil {
+				mlog.From(cmp).Warn("error revoking session", r.Context(), err)
+			}
+		}
+		clearCookie(w)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	sessionsHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sess, err := sessionFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		userSessions, err := sessions.List(sess.Username)
+		if err != nil {
+			mlog.From(cmp).Warn("error listing sessions", r.Context(), err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(userSessions)
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/logout", logoutHandler)
+	mux.Handle("/sessions", sessionsHandler)
+	mux.Handle("/", authHandler)
+
+	mhttp.InstListeningServer(cmp, mux)
 	m.Exec(cmp)
 }
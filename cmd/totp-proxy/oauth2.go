@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/mediocregopher/mediocre-go-lib/mcmp"
+	"github.com/mediocregopher/mediocre-go-lib/mcrypto"
+	"github.com/mediocregopher/mediocre-go-lib/merr"
+	"github.com/mediocregopher/mediocre-go-lib/mrand"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/bitbucket"
+	"golang.org/x/oauth2/github"
+)
+
+// oauth2StatePayload is signed (with a short validity window, see
+// oauth2StateTTL) and round-tripped through the state query param of a plain
+// OAuth2 flow, to confirm that a callback request corresponds to a Challenge
+// this instance actually issued.
+const oauth2StatePayload = "totp-proxy-oauth2-state"
+
+// oauth2StateTTL bounds how long a Challenge's state/nonce tokens remain
+// acceptable to Verify, limiting the window for replay of a captured
+// callback URL.
+const oauth2StateTTL = 10 * time.Minute
+
+// oauth2Provider is an AuthProvider implementing a plain OAuth2
+// authorization-code flow against a fixed identity provider (as opposed to
+// OIDC, which layers an ID token and discovery on top of this same flow).
+// The identity it resolves to is read out of a userInfoURL response, using
+// usernameFromUserInfo.
+type oauth2Provider struct {
+	cmp         *mcmp.Component
+	conf        *oauth2.Config
+	state       mcrypto.Secret
+	userInfoURL string
+
+	// usernameFromUserInfo extracts the username from the JSON body returned
+	// by a GET of userInfoURL, authenticated with the obtained access token.
+	usernameFromUserInfo func([]byte) (string, error)
+}
+
+// newGitHubProvider returns an oauth2Provider configured against GitHub's
+// OAuth2 endpoint, resolving identities via the authenticated user's GitHub
+// login name.
+func newGitHubProvider(cmp *mcmp.Component, clientID, clientSecret, redirectURL string) *oauth2Provider {
+	return &oauth2Provider{
+		cmp: cmp,
+		conf: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       []string{"read:user"},
+			Endpoint:     github.Endpoint,
+		},
+		state:       mcrypto.NewSecret([]byte(mrand.Hex(32))),
+		userInfoURL: "https://api.github.com/user",
+		usernameFromUserInfo: func(b []byte) (string, error) {
+			var userInfo struct {
+				Login string `json:"login"`
+			}
+			if err := json.Unmarshal(b, &userInfo); err != nil {
+				return "", merr.Wrap(context.Background(), err)
+			}
+			return userInfo.Login, nil
+		},
+	}
+}
+
+// newBitbucketProvider returns an oauth2Provider configured against
+// Bitbucket's OAuth2 endpoint, resolving identities via the authenticated
+// user's Bitbucket username.
+func newBitbucketProvider(cmp *mcmp.Component, clientID, clientSecret, redirectURL string) *oauth2Provider {
+	return &oauth2Provider{
+		cmp: cmp,
+		conf: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       []string{"account"},
+			Endpoint:     bitbucket.Endpoint,
+		},
+		state:       mcrypto.NewSecret([]byte(mrand.Hex(32))),
+		userInfoURL: "https://api.bitbucket.org/2.0/user",
+		usernameFromUserInfo: func(b []byte) (string, error) {
+			var userInfo struct {
+				Username string `json:"username"`
+			}
+			if err := json.Unmarshal(b, &userInfo); err != nil {
+				return "", merr.Wrap(context.Background(), err)
+			}
+			return userInfo.Username, nil
+		},
+	}
+}
+
+// Challenge implements the AuthProvider interface.
+func (p *oauth2Provider) Challenge(w http.ResponseWriter, r *http.Request) {
+	state := mcrypto.SignString(p.state, oauth2StatePayload).String()
+	http.Redirect(w, r, p.conf.AuthCodeURL(state), http.StatusFound)
+}
+
+// verifyState checks a callback request's state param against secret/payload,
+// and returns errUnauthorized if it wasn't issued by this instance within
+// oauth2StateTTL.
+func verifyState(secret mcrypto.Secret, payload, state string) error {
+	var sig mcrypto.Signature
+	if err := sig.UnmarshalText([]byte(state)); err != nil {
+		return errUnauthorized
+	} else if err := mcrypto.VerifyString(secret, sig, payload); err != nil {
+		return errUnauthorized
+	} else if time.Since(sig.Time()) > oauth2StateTTL {
+		return errUnauthorized
+	}
+	return nil
+}
+
+// Verify implements the AuthProvider interface.
+func (p *oauth2Provider) Verify(r *http.Request) (string, error) {
+	q := r.URL.Query()
+	if errStr := q.Get("error"); errStr != "" {
+		return "", merr.New(r.Context(), errStr)
+	}
+
+	if err := verifyState(p.state, oauth2StatePayload, q.Get("state")); err != nil {
+		return "", err
+	}
+
+	code := q.Get("code")
+	if code == "" {
+		return "", errUnauthorized
+	}
+
+	tok, err := p.conf.Exchange(r.Context(), code)
+	if err != nil {
+		return "", merr.Wrap(r.Context(), err)
+	}
+
+	resp, err := p.conf.Client(r.Context(), tok).Get(p.userInfoURL)
+	if err != nil {
+		return "", merr.Wrap(r.Context(), err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", merr.Wrap(r.Context(), err)
+	}
+
+	username, err := p.usernameFromUserInfo(body)
+	if err != nil {
+		return "", merr.Wrap(r.Context(), err)
+	} else if username == "" {
+		return "", merr.New(r.Context(), "user info response didn't include a username")
+	}
+	return username, nil
+}
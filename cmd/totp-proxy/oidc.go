@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/mediocregopher/mediocre-go-lib/mcmp"
+	"github.com/mediocregopher/mediocre-go-lib/mcrypto"
+	"github.com/mediocregopher/mediocre-go-lib/merr"
+	"github.com/mediocregopher/mediocre-go-lib/mrand"
+	"golang.org/x/oauth2"
+)
+
+// oidcNoncePayload is the payload signed into the "nonce" param of an OIDC
+// authorization request; Verify checks that the id_token's nonce claim is a
+// valid, recent signature of it, proving the ID token was minted in response
+// to a Challenge this instance actually issued.
+const oidcNoncePayload = "totp-proxy-oidc-nonce"
+
+// oidcDiscoveryDoc holds the subset of an OIDC provider's discovery document
+// (as served from {issuer}/.well-known/openid-configuration) this package
+// needs.
+type oidcDiscoveryDoc struct {
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+}
+
+// discoverOIDC fetches and parses the given issuer's OIDC discovery
+// document.
+func discoverOIDC(issuer string) (oidcDiscoveryDoc, error) {
+	var doc oidcDiscoveryDoc
+
+	url := strings.TrimRight(issuer, "/") + "/.well-known/openid-configuration"
+	resp, err := http.Get(url)
+	if err != nil {
+		return doc, merr.Wrap(context.Background(), err)
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return doc, merr.Wrap(context.Background(), err)
+	}
+	return doc, nil
+}
+
+// oidcProvider is an AuthProvider implementing the OpenID Connect
+// authorization-code flow against any issuer which supports OIDC discovery
+// (e.g. Keycloak, Google, or any other compliant identity provider).
+//
+// The identity it resolves to is read out of usernameClaim on the returned
+// ID token. The ID token's signature isn't independently reverified, since
+// it's obtained directly from the issuer's token endpoint over TLS, the same
+// trust boundary the access token itself relies on.
+type oidcProvider struct {
+	cmp           *mcmp.Component
+	conf          *oauth2.Config
+	nonceSecret   mcrypto.Secret
+	usernameClaim string
+}
+
+// newOIDCProvider discovers issuer's authorization/token endpoints and
+// returns an oidcProvider configured to use them.
+func newOIDCProvider(
+	cmp *mcmp.Component,
+	issuer, clientID, clientSecret, redirectURL, usernameClaim string,
+	scopes []string,
+) (*oidcProvider, error) {
+	doc, err := discoverOIDC(issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &oidcProvider{
+		cmp: cmp,
+		conf: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       scopes,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  doc.AuthorizationEndpoint,
+				TokenURL: doc.TokenEndpoint,
+			},
+		},
+		nonceSecret:   mcrypto.NewSecret([]byte(mrand.Hex(32))),
+		usernameClaim: usernameClaim,
+	}, nil
+}
+
+// Challenge implements the AuthProvider interface.
+func (p *oidcProvider) Challenge(w http.ResponseWriter, r *http.Request) {
+	state := mcrypto.SignString(p.nonceSecret, oauth2StatePayload).String()
+	nonce := mcrypto.SignString(p.nonceSecret, oidcNoncePayload).String()
+	url := p.conf.AuthCodeURL(state, oauth2.SetAuthURLParam("nonce", nonce))
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+// idTokenClaims decodes (without reverifying the signature of, see
+// oidcProvider's doc comment) the claims of the given JWT-shaped ID token.
+func idTokenClaims(idToken string) (map[string]interface{}, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, merr.New(context.Background(), "malformed id_token")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, merr.Wrap(context.Background(), err)
+	}
+
+	claims := map[string]interface{}{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, merr.Wrap(context.Background(), err)
+	}
+	return claims, nil
+}
+
+// Verify implements the AuthProvider interface.
+func (p *oidcProvider) Verify(r *http.Request) (string, error) {
+	q := r.URL.Query()
+	if errStr := q.Get("error"); errStr != "" {
+		return "", merr.New(r.Context(), errStr)
+	}
+
+	if err := verifyState(p.nonceSecret, oauth2StatePayload, q.Get("state")); err != nil {
+		return "", err
+	}
+
+	code := q.Get("code")
+	if code == "" {
+		return "", errUnauthorized
+	}
+
+	tok, err := p.conf.Exchange(r.Context(), code)
+	if err != nil {
+		return "", merr.Wrap(r.Context(), err)
+	}
+
+	rawIDToken, ok := tok.Extra("id_token").(string)
+	if !ok {
+		return "", merr.New(r.Context(), "token response didn't include an id_token")
+	}
+
+	claims, err := idTokenClaims(rawIDToken)
+	if err != nil {
+		return "", merr.Wrap(r.Context(), err)
+	}
+
+	nonce, _ := claims["nonce"].(string)
+	if err := verifyState(p.nonceSecret, oidcNoncePayload, nonce); err != nil {
+		return "", err
+	}
+
+	username, _ := claims[p.usernameClaim].(string)
+	if username == "" {
+		return "", merr.New(r.Context(), "id_token missing "+p.usernameClaim+" claim")
+	}
+	return username, nil
+}
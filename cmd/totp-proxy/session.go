@@ -0,0 +1,74 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// errSessionNotFound is returned by a SessionStore's Get, Refresh, or Revoke
+// when no session exists for the given ID.
+var errSessionNotFound = errors.New("session not found")
+
+// Session represents one authenticated user's session, as tracked by a
+// SessionStore. Its ID is what's actually stored in the client's cookie; the
+// rest is only ever held server-side.
+type Session struct {
+	ID        string
+	Username  string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+
+	// RefreshToken, if set, is an upstream (e.g. OIDC) refresh token which
+	// can be used to mint a new access/ID token on this user's behalf
+	// without prompting them to re-authenticate, once ExpiresAt is reached.
+	RefreshToken string
+}
+
+// Expired returns true if ExpiresAt has already passed.
+func (s *Session) Expired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
+
+// SessionStore persists Sessions across requests (and, depending on the
+// backend, across restarts), so that a session can be looked up, renewed
+// with a sliding window, or revoked outright, rather than relying solely on
+// a self-contained signed cookie that can only be invalidated all-at-once by
+// rotating the signing secret.
+type SessionStore interface {
+	// Create mints and persists a new Session for the given username, with
+	// ExpiresAt set to ttl from now.
+	Create(username string, ttl time.Duration) (*Session, error)
+
+	// Get returns the Session with the given ID, or errSessionNotFound if
+	// none exists (including if one existed but has expired).
+	Get(id string) (*Session, error)
+
+	// Refresh slides the session's ExpiresAt to ttl from now, persists the
+	// change, and returns the updated Session. It returns errSessionNotFound
+	// under the same conditions as Get.
+	Refresh(id string, ttl time.Duration) (*Session, error)
+
+	// Revoke deletes the session with the given ID. It's a no-op, not an
+	// error, if no such session exists.
+	Revoke(id string) error
+
+	// RevokeUser deletes every session belonging to username.
+	RevokeUser(username string) error
+
+	// List returns every non-expired session belonging to username.
+	List(username string) ([]*Session, error)
+}
+
+// newSessionID returns a new, random session ID suitable for use as an
+// opaque cookie value. SessionStore implementations look sessions up by this
+// ID; nothing about a session is ever trusted from the cookie itself, so the
+// ID must not be guessable, hence crypto/rand rather than math/rand.
+func newSessionID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/mediocregopher/mediocre-go-lib/merr"
+	bolt "go.etcd.io/bbolt"
+)
+
+// sessionsBucket is the sole bolt bucket used by boltSessionStore, keyed by
+// session ID with JSON-encoded Session values.
+var sessionsBucket = []byte("sessions")
+
+// boltSessionStore is a disk-backed SessionStore using a single embedded
+// bolt file, providing single-process durability across restarts without
+// requiring any external service (c.f. mqueue's boltQueue, which makes the
+// same tradeoff for queues).
+type boltSessionStore struct {
+	db *bolt.DB
+}
+
+func newBoltSessionStore(path string) (*boltSessionStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, merr.Wrap(context.Background(), err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(sessionsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, merr.Wrap(context.Background(), err)
+	}
+
+	return &boltSessionStore{db: db}, nil
+}
+
+func (s *boltSessionStore) put(sess *Session) error {
+	b, err := json.Marshal(sess)
+	if err != nil {
+		return merr.Wrap(context.Background(), err)
+	}
+
+	return merr.Wrap(context.Background(), s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionsBucket).Put([]byte(sess.ID), b)
+	}))
+}
+
+// Create implements the SessionStore interface.
+func (s *boltSessionStore) Create(username string, ttl time.Duration) (*Session, error) {
+	now := time.Now()
+	sess := &Session{
+		ID:        newSessionID(),
+		Username:  username,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(ttl),
+	}
+	return sess, s.put(sess)
+}
+
+// Get implements the SessionStore interface.
+func (s *boltSessionStore) Get(id string) (*Session, error) {
+	var sess Session
+	found := false
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(sessionsBucket).Get([]byte(id))
+		if b == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(b, &sess)
+	})
+	if err != nil {
+		return nil, merr.Wrap(context.Background(), err)
+	} else if !found || sess.Expired() {
+		return nil, errSessionNotFound
+	}
+	return &sess, nil
+}
+
+// Refresh implements the SessionStore interface.
+func (s *boltSessionStore) Refresh(id string, ttl time.Duration) (*Session, error) {
+	sess, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	sess.ExpiresAt = time.Now().Add(ttl)
+	return sess, s.put(sess)
+}
+
+// Revoke implements the SessionStore interface.
+func (s *boltSessionStore) Revoke(id string) error {
+	return merr.Wrap(context.Background(), s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionsBucket).Delete([]byte(id))
+	}))
+}
+
+// RevokeUser implements the SessionStore interface.
+func (s *boltSessionStore) RevokeUser(username string) error {
+	return merr.Wrap(context.Background(), s.db.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket(sessionsBucket)
+
+		var toDelete [][]byte
+		c := bkt.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var sess Session
+			if err := json.Unmarshal(v, &sess); err != nil {
+				return err
+			}
+			if sess.Username == username {
+				toDelete = append(toDelete, append([]byte{}, k...))
+			}
+		}
+
+		for _, k := range toDelete {
+			if err := bkt.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+// List implements the SessionStore interface.
+func (s *boltSessionStore) List(username string) ([]*Session, error) {
+	var out []*Session
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(sessionsBucket).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var sess Session
+			if err := json.Unmarshal(v, &sess); err != nil {
+				return err
+			}
+			if sess.Username == username && !sess.Expired() {
+				cp := sess
+				out = append(out, &cp)
+			}
+		}
+		return nil
+	})
+	return out, merr.Wrap(context.Background(), err)
+}
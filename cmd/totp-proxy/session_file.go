@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mediocregopher/mediocre-go-lib/merr"
+)
+
+// fileSessionStore is a SessionStore which keeps all Sessions in memory (via
+// an embedded memSessionStore), but persists them as a single JSON document
+// to a file after every mutation, so that sessions survive a restart of this
+// process without requiring an external service.
+type fileSessionStore struct {
+	path string
+	mu   sync.Mutex
+	mem  *memSessionStore
+}
+
+func newFileSessionStore(path string) (*fileSessionStore, error) {
+	s := &fileSessionStore{path: path, mem: newMemSessionStore()}
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, merr.Wrap(context.Background(), err)
+	}
+
+	if err := json.Unmarshal(b, &s.mem.sessions); err != nil {
+		return nil, merr.Wrap(context.Background(), err)
+	}
+	return s, nil
+}
+
+// persist writes the current set of sessions out to s.path. Callers must
+// hold s.mu.
+func (s *fileSessionStore) persist() error {
+	s.mem.mu.Lock()
+	b, err := json.Marshal(s.mem.sessions)
+	s.mem.mu.Unlock()
+	if err != nil {
+		return merr.Wrap(context.Background(), err)
+	}
+	return merr.Wrap(context.Background(), os.WriteFile(s.path, b, 0600))
+}
+
+// Create implements the SessionStore interface.
+func (s *fileSessionStore) Create(username string, ttl time.Duration) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, err := s.mem.Create(username, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return sess, s.persist()
+}
+
+// Get implements the SessionStore interface.
+func (s *fileSessionStore) Get(id string) (*Session, error) {
+	return s.mem.Get(id)
+}
+
+// Refresh implements the SessionStore interface.
+func (s *fileSessionStore) Refresh(id string, ttl time.Duration) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, err := s.mem.Refresh(id, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return sess, s.persist()
+}
+
+// Revoke implements the SessionStore interface.
+func (s *fileSessionStore) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.mem.Revoke(id); err != nil {
+		return err
+	}
+	return s.persist()
+}
+
+// RevokeUser implements the SessionStore interface.
+func (s *fileSessionStore) RevokeUser(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.mem.RevokeUser(username); err != nil {
+		return err
+	}
+	return s.persist()
+}
+
+// List implements the SessionStore interface.
+func (s *fileSessionStore) List(username string) ([]*Session, error) {
+	return s.mem.List(username)
+}
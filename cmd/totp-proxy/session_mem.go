@@ -0,0 +1,101 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// memSessionStore is a SessionStore which keeps all Sessions in memory.
+// It's the simplest backend, but sessions don't survive a restart of this
+// process and aren't shared between instances.
+type memSessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+func newMemSessionStore() *memSessionStore {
+	return &memSessionStore{sessions: map[string]*Session{}}
+}
+
+// Create implements the SessionStore interface.
+func (s *memSessionStore) Create(username string, ttl time.Duration) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	sess := &Session{
+		ID:        newSessionID(),
+		Username:  username,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(ttl),
+	}
+	s.sessions[sess.ID] = sess
+
+	cp := *sess
+	return &cp, nil
+}
+
+// Get implements the SessionStore interface.
+func (s *memSessionStore) Get(id string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[id]
+	if !ok || sess.Expired() {
+		return nil, errSessionNotFound
+	}
+
+	cp := *sess
+	return &cp, nil
+}
+
+// Refresh implements the SessionStore interface.
+func (s *memSessionStore) Refresh(id string, ttl time.Duration) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[id]
+	if !ok || sess.Expired() {
+		return nil, errSessionNotFound
+	}
+	sess.ExpiresAt = time.Now().Add(ttl)
+
+	cp := *sess
+	return &cp, nil
+}
+
+// Revoke implements the SessionStore interface.
+func (s *memSessionStore) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+// RevokeUser implements the SessionStore interface.
+func (s *memSessionStore) RevokeUser(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, sess := range s.sessions {
+		if sess.Username == username {
+			delete(s.sessions, id)
+		}
+	}
+	return nil
+}
+
+// List implements the SessionStore interface.
+func (s *memSessionStore) List(username string) ([]*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []*Session
+	for _, sess := range s.sessions {
+		if sess.Username == username && !sess.Expired() {
+			cp := *sess
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/mediocregopher/mediocre-go-lib/merr"
+	"github.com/mediocregopher/radix/v3"
+)
+
+// redisSessionStore is a SessionStore backed by Redis, allowing sessions to
+// be shared across multiple totp-proxy instances. Each session is stored as
+// a hash at sessionKey(id), with a TTL matching its ExpiresAt so expired
+// sessions are reaped by Redis itself; its ID is also tracked in a per-user
+// set at userSessionsKey(username), so RevokeUser and List don't require
+// scanning every key.
+type redisSessionStore struct {
+	client radix.Client
+}
+
+func newRedisSessionStore(client radix.Client) *redisSessionStore {
+	return &redisSessionStore{client: client}
+}
+
+func sessionKey(id string) string            { return "totp-proxy:session:" + id }
+func userSessionsKey(username string) string { return "totp-proxy:user-sessions:" + username }
+
+func (s *redisSessionStore) put(sess *Session) error {
+	ttl := time.Until(sess.ExpiresAt)
+	if ttl <= 0 {
+		return s.Revoke(sess.ID)
+	}
+
+	key := sessionKey(sess.ID)
+	err := s.client.Do(radix.FlatCmd(nil, "HSET", key,
+		"username", sess.Username,
+		"issuedAt", sess.IssuedAt.Unix(),
+		"expiresAt", sess.ExpiresAt.Unix(),
+		"refreshToken", sess.RefreshToken,
+	))
+	if err != nil {
+		return merr.Wrap(context.Background(), err)
+	}
+
+	if err := s.client.Do(radix.FlatCmd(nil, "EXPIRE", key, int(ttl.Seconds()))); err != nil {
+		return merr.Wrap(context.Background(), err)
+	}
+	return merr.Wrap(context.Background(), s.client.Do(radix.FlatCmd(nil, "SADD", userSessionsKey(sess.Username), sess.ID)))
+}
+
+func sessionFromFields(id string, fields map[string]string) (*Session, error) {
+	issuedAt, err := strconv.ParseInt(fields["issuedAt"], 10, 64)
+	if err != nil {
+		return nil, merr.Wrap(context.Background(), err)
+	}
+	expiresAt, err := strconv.ParseInt(fields["expiresAt"], 10, 64)
+	if err != nil {
+		return nil, merr.Wrap(context.Background(), err)
+	}
+
+	return &Session{
+		ID:           id,
+		Username:     fields["username"],
+		IssuedAt:     time.Unix(issuedAt, 0),
+		ExpiresAt:    time.Unix(expiresAt, 0),
+		RefreshToken: fields["refreshToken"],
+	}, nil
+}
+
+// Create implements the SessionStore interface.
+func (s *redisSessionStore) Create(username string, ttl time.Duration) (*Session, error) {
+	now := time.Now()
+	sess := &Session{
+		ID:        newSessionID(),
+		Username:  username,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(ttl),
+	}
+	return sess, s.put(sess)
+}
+
+// Get implements the SessionStore interface.
+func (s *redisSessionStore) Get(id string) (*Session, error) {
+	var fields map[string]string
+	if err := s.client.Do(radix.Cmd(&fields, "HGETALL", sessionKey(id))); err != nil {
+		return nil, merr.Wrap(context.Background(), err)
+	}
+	if len(fields) == 0 {
+		return nil, errSessionNotFound
+	}
+	return sessionFromFields(id, fields)
+}
+
+// Refresh implements the SessionStore interface.
+func (s *redisSessionStore) Refresh(id string, ttl time.Duration) (*Session, error) {
+	sess, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	sess.ExpiresAt = time.Now().Add(ttl)
+	return sess, s.put(sess)
+}
+
+// Revoke implements the SessionStore interface.
+func (s *redisSessionStore) Revoke(id string) error {
+	sess, err := s.Get(id)
+	if merr.Equal(err, errSessionNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err := s.client.Do(radix.FlatCmd(nil, "DEL", sessionKey(id))); err != nil {
+		return merr.Wrap(context.Background(), err)
+	}
+	return merr.Wrap(context.Background(), s.client.Do(radix.FlatCmd(nil, "SREM", userSessionsKey(sess.Username), id)))
+}
+
+// RevokeUser implements the SessionStore interface.
+func (s *redisSessionStore) RevokeUser(username string) error {
+	sessions, err := s.List(username)
+	if err != nil {
+		return err
+	}
+
+	for _, sess := range sessions {
+		if err := s.Revoke(sess.ID); err != nil {
+			return err
+		}
+	}
+	return merr.Wrap(context.Background(), s.client.Do(radix.FlatCmd(nil, "DEL", userSessionsKey(username))))
+}
+
+// List implements the SessionStore interface.
+func (s *redisSessionStore) List(username string) ([]*Session, error) {
+	var ids []string
+	if err := s.client.Do(radix.Cmd(&ids, "SMEMBERS", userSessionsKey(username))); err != nil {
+		return nil, merr.Wrap(context.Background(), err)
+	}
+
+	var out []*Session
+	for _, id := range ids {
+		sess, err := s.Get(id)
+		if merr.Equal(err, errSessionNotFound) {
+			// Redis already expired the hash key itself; just clean up the
+			// now-dangling membership lazily.
+			s.client.Do(radix.FlatCmd(nil, "SREM", userSessionsKey(username), id))
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		out = append(out, sess)
+	}
+	return out, nil
+}
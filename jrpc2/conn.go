@@ -0,0 +1,285 @@
+package jrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mediocregopher/mediocre-go-lib/jstream"
+	"github.com/mediocregopher/mediocre-go-lib/mrun"
+)
+
+// Handler serves inbound Requests read by a Conn's Run method. The returned
+// value is marshaled as the Response's result if err is nil; otherwise err
+// is converted into the Response's error object (see errToJRPC2).
+//
+// req.HasID is false if req is actually a notification; a Handler may
+// ignore this distinction entirely, since Run only writes a Response back
+// when HasID is true.
+type Handler func(ctx context.Context, req *Request) (interface{}, error)
+
+// Conn is a JSON-RPC 2.0 connection multiplexed over a single jstream
+// StreamReader/StreamWriter pair. See the jrpc2 package doc for how it
+// relates to jstream and mrpc/jsonrpc2.
+//
+// A Conn may be used to make Calls, to serve inbound Requests via Run, or
+// both at once (e.g. for peer-to-peer usage); both directions share the
+// same underlying jstream pair.
+type Conn struct {
+	r *jstream.StreamReader
+	w *jstream.StreamWriter
+
+	writeL sync.Mutex
+
+	nextID int64 // accessed atomically
+
+	l       sync.Mutex
+	pending map[int64]chan *Response     // outstanding Calls, by ID
+	cancel  map[int64]context.CancelFunc // in-flight Requests being Run, by ID
+}
+
+// NewConn returns a Conn which reads/writes JSON-RPC 2.0 messages, one per
+// jstream JSONValue Element, over r/w.
+func NewConn(r *jstream.StreamReader, w *jstream.StreamWriter) *Conn {
+	return &Conn{
+		r:       r,
+		w:       w,
+		pending: map[int64]chan *Response{},
+		cancel:  map[int64]context.CancelFunc{},
+	}
+}
+
+// writeMsg marshals msg and writes it as a single JSONValue Element. It may
+// be called concurrently by any number of goroutines (Run's dispatched
+// Handler goroutines, and Call), and serializes their writes itself.
+func (c *Conn) writeMsg(msg wireMsg) error {
+	msg.JSONRPC = "2.0"
+	c.writeL.Lock()
+	defer c.writeL.Unlock()
+	return c.w.EncodeValue(msg)
+}
+
+// Notify writes n to the peer. No response is expected, and none is waited
+// for.
+func (c *Conn) Notify(n Notification) error {
+	b, err := json.Marshal(n.Params)
+	if err != nil {
+		return fmt.Errorf("jrpc2: marshaling params: %w", err)
+	}
+	return c.writeMsg(wireMsg{Method: n.Method, Params: b})
+}
+
+// Call writes a request for method, with params marshaled as its params,
+// and blocks until a matching Response is read by Run (which must be
+// running concurrently, e.g. in another goroutine, for this to ever
+// return), or ctx is canceled.
+//
+// If ctx is canceled before a Response arrives, Call abandons the call
+// locally (a late Response, if one ever arrives, is simply dropped),
+// notifies the peer via a "$/cancelRequest" notification so it may, on a
+// best-effort basis, stop whatever work it was doing (see Run), and
+// returns ctx.Err().
+func (c *Conn) Call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("jrpc2: marshaling params: %w", err)
+	}
+
+	id := atomic.AddInt64(&c.nextID, 1)
+	ch := make(chan *Response, 1)
+
+	c.l.Lock()
+	c.pending[id] = ch
+	c.l.Unlock()
+
+	if err := c.writeMsg(wireMsg{Method: method, Params: b, ID: &id}); err != nil {
+		c.abandon(id)
+		return nil, fmt.Errorf("jrpc2: writing request: %w", err)
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Err != nil {
+			return nil, resp.Err
+		}
+		return resp.Result, nil
+	case <-ctx.Done():
+		c.abandon(id)
+		c.Notify(Notification{Method: methodCancelRequest, Params: cancelParams{ID: id}})
+		return nil, ctx.Err()
+	}
+}
+
+// abandon removes id's pending Call entry, if any, so that a Response which
+// arrives for it later (if ever) is silently dropped by Run instead of
+// being delivered to a Call which has already returned.
+func (c *Conn) abandon(id int64) {
+	c.l.Lock()
+	delete(c.pending, id)
+	c.l.Unlock()
+}
+
+// errToJRPC2 converts a Handler's returned error into the Error object a
+// Response is written with. If err is already an *Error it's used as-is;
+// otherwise it's wrapped as CodeInternalError.
+func errToJRPC2(err error) *Error {
+	var jErr *Error
+	if errors.As(err, &jErr) {
+		return jErr
+	}
+	return &Error{Code: CodeInternalError, Message: err.Error()}
+}
+
+// dispatch calls h with req, writing the resulting Response back (if
+// req.HasID) once h returns, and cleans up req's cancellation entry.
+func (c *Conn) dispatch(ctx context.Context, h Handler, req *Request) error {
+	if req.HasID {
+		defer func() {
+			c.l.Lock()
+			delete(c.cancel, req.ID)
+			c.l.Unlock()
+		}()
+	}
+
+	result, err := h(ctx, req)
+	if !req.HasID {
+		// notifications get no response, regardless of h's return value
+		return nil
+	}
+
+	msg := wireMsg{ID: &req.ID}
+	if err != nil {
+		msg.Error = errToJRPC2(err)
+	} else if msg.Result, err = json.Marshal(result); err != nil {
+		msg.Result = nil
+		msg.Error = errToJRPC2(fmt.Errorf("jrpc2: marshaling result: %w", err))
+	}
+	return c.writeMsg(msg)
+}
+
+// handleCancel processes an inbound "$/cancelRequest" notification by
+// canceling the Context of the in-flight Request (dispatched by Run) with
+// the given ID, if any is still running.
+func (c *Conn) handleCancel(params json.RawMessage) {
+	var p cancelParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return
+	}
+	c.l.Lock()
+	cancel, ok := c.cancel[p.ID]
+	c.l.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+// Run reads messages off of Conn's jstream.StreamReader in a loop,
+// resolving Responses to their matching Call, and dispatching Requests and
+// Notifications to h, each in its own goroutine tracked via
+// mrun.WithThreads, until the StreamReader is exhausted (a read returning
+// io.EOF, jstream.ErrStreamEnded or jstream.ErrCanceled, in which case Run
+// waits for every dispatched goroutine to return and then returns any error
+// they returned, or nil if there were none) or some other error is hit
+// (which is returned immediately, without waiting on any still-running
+// goroutines).
+//
+// A Request being handled by h may be canceled by the peer sending a
+// "$/cancelRequest" notification (see Call); Run cancels that Request's
+// ctx in response, so h can react promptly instead of running to
+// completion unnecessarily.
+func (c *Conn) Run(ctx context.Context, h Handler) error {
+	dispatchCtx := ctx
+
+	for {
+		el := c.r.Next()
+		if el.Err == io.EOF || el.Err == jstream.ErrStreamEnded || el.Err == jstream.ErrCanceled {
+			break
+		} else if el.Err != nil {
+			return el.Err
+		}
+
+		var msg wireMsg
+		if err := el.Value(&msg); err != nil {
+			continue
+		}
+
+		switch {
+		case msg.Method == methodCancelRequest:
+			c.handleCancel(msg.Params)
+
+		case msg.Method != "":
+			req := &Request{Method: msg.Method, params: msg.Params}
+			if msg.ID != nil {
+				req.ID = *msg.ID
+				req.HasID = true
+			}
+			if msg.Blob {
+				bel := c.r.Next()
+				if bel.Err != nil {
+					return bel.Err
+				}
+				blob, err := bel.Bytes()
+				if err != nil {
+					return err
+				}
+				req.blob = blob
+			}
+
+			reqCtx := ctx
+			if req.HasID {
+				var cancel context.CancelFunc
+				reqCtx, cancel = context.WithCancel(ctx)
+				c.l.Lock()
+				c.cancel[req.ID] = cancel
+				c.l.Unlock()
+			}
+
+			if req.blob == nil {
+				dispatchCtx = mrun.WithThreads(dispatchCtx, 1, func() error {
+					return c.dispatch(reqCtx, h, req)
+				})
+				continue
+			}
+
+			// A Request carrying a Byte Blob must have that Blob fully
+			// drained before c.r may be used again, per jstream's
+			// StreamReader contract; h is still dispatched via
+			// mrun.WithThreads, as with any other Request, but Run waits
+			// for that one goroutine (and the drain below, in case h never
+			// read the Blob itself, e.g. via RequestWithBlob) before
+			// reading further, rather than leaving it in flight like the
+			// rest.
+			blob := req.blob
+			blobCtx := mrun.WithThreads(context.Background(), 1, func() error {
+				err := c.dispatch(reqCtx, h, req)
+				if _, dErr := io.Copy(io.Discard, blob); dErr != nil && dErr != jstream.ErrCanceled && err == nil {
+					err = dErr
+				}
+				return err
+			})
+			if err := mrun.Wait(blobCtx, nil); err != nil {
+				return err
+			}
+
+		default: // a response
+			if msg.ID == nil {
+				continue
+			}
+			c.l.Lock()
+			ch, ok := c.pending[*msg.ID]
+			if ok {
+				delete(c.pending, *msg.ID)
+			}
+			c.l.Unlock()
+			if ok {
+				ch <- &Response{ID: *msg.ID, Result: msg.Result, Err: msg.Error}
+			}
+		}
+	}
+
+	return mrun.Wait(dispatchCtx, nil)
+}
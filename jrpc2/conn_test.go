@@ -0,0 +1,185 @@
+package jrpc2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	. "testing"
+
+	"github.com/mediocregopher/mediocre-go-lib/jstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func echoHandler() Handler {
+	return func(ctx context.Context, req *Request) (interface{}, error) {
+		switch req.Method {
+		case "echo":
+			var s string
+			if err := req.Params(&s); err != nil {
+				return nil, err
+			}
+			return s, nil
+		case "fail":
+			return nil, errors.New("oh no")
+		case "failCode":
+			return nil, &Error{Code: CodeInvalidParams, Message: "bad params"}
+		default:
+			return nil, &Error{Code: CodeMethodNotFound, Message: "unknown method: " + req.Method}
+		}
+	}
+}
+
+// newTestConnPair returns a Conn connected, over a net.Pipe, to a peer Conn
+// which is already Run'ing h in the background. The returned Conn is also
+// Run, with a Handler that always fails, since these tests only ever use it
+// to make Calls, never to serve them; Run must still be going for Responses
+// to its Calls to ever be read and delivered.
+func newTestConnPair(t *T, h Handler) *Conn {
+	srvNetConn, cliNetConn := net.Pipe()
+
+	srv := NewConn(jstream.NewStreamReader(srvNetConn), jstream.NewStreamWriter(srvNetConn))
+	go srv.Run(context.Background(), h)
+
+	cli := NewConn(jstream.NewStreamReader(cliNetConn), jstream.NewStreamWriter(cliNetConn))
+	go cli.Run(context.Background(), func(context.Context, *Request) (interface{}, error) {
+		return nil, errors.New("test client Conn doesn't serve calls")
+	})
+	return cli
+}
+
+func TestConnCall(t *T) {
+	cli := newTestConnPair(t, echoHandler())
+
+	t.Run("success", func(t *T) {
+		res, err := cli.Call(context.Background(), "echo", "hello")
+		require.NoError(t, err)
+		var s string
+		require.NoError(t, jsonUnmarshal(res, &s))
+		assert.Equal(t, "hello", s)
+	})
+
+	t.Run("error", func(t *T) {
+		_, err := cli.Call(context.Background(), "fail", nil)
+		var jErr *Error
+		require.True(t, errors.As(err, &jErr))
+		assert.Equal(t, int64(CodeInternalError), jErr.Code)
+	})
+
+	t.Run("errorCode", func(t *T) {
+		_, err := cli.Call(context.Background(), "failCode", nil)
+		var jErr *Error
+		require.True(t, errors.As(err, &jErr))
+		assert.Equal(t, int64(CodeInvalidParams), jErr.Code)
+	})
+
+	t.Run("methodNotFound", func(t *T) {
+		_, err := cli.Call(context.Background(), "nope", nil)
+		var jErr *Error
+		require.True(t, errors.As(err, &jErr))
+		assert.Equal(t, int64(CodeMethodNotFound), jErr.Code)
+	})
+}
+
+func TestConnConcurrentCalls(t *T) {
+	cli := newTestConnPair(t, echoHandler())
+
+	const n = 20
+	errCh := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			res, err := cli.Call(context.Background(), "echo", "hello")
+			if err == nil {
+				var s string
+				if err = jsonUnmarshal(res, &s); err == nil && s != "hello" {
+					err = errors.New("unexpected result: " + s)
+				}
+			}
+			errCh <- err
+		}()
+	}
+	for i := 0; i < n; i++ {
+		assert.NoError(t, <-errCh)
+	}
+}
+
+func TestConnCallCanceled(t *T) {
+	startedCh := make(chan struct{})
+	canceledCh := make(chan struct{})
+	h := Handler(func(ctx context.Context, req *Request) (interface{}, error) {
+		close(startedCh)
+		<-ctx.Done()
+		close(canceledCh)
+		return nil, ctx.Err()
+	})
+
+	cli := newTestConnPair(t, h)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	doneCh := make(chan error, 1)
+	go func() {
+		_, err := cli.Call(ctx, "slow", nil)
+		doneCh <- err
+	}()
+
+	<-startedCh
+	cancel()
+	assert.Equal(t, context.Canceled, <-doneCh)
+
+	// the peer's Handler should have had its ctx canceled too, in response
+	// to the "$/cancelRequest" notification Call sends.
+	<-canceledCh
+}
+
+func TestConnNotify(t *T) {
+	gotCh := make(chan string, 1)
+	h := Handler(func(ctx context.Context, req *Request) (interface{}, error) {
+		assert.False(t, req.HasID)
+		var s string
+		req.Params(&s)
+		gotCh <- s
+		return nil, nil
+	})
+
+	cli := newTestConnPair(t, h)
+	require.NoError(t, cli.Notify(Notification{Method: "echo", Params: "hello"}))
+	assert.Equal(t, "hello", <-gotCh)
+}
+
+func TestConnRequestWithBlob(t *T) {
+	blobCh := make(chan []byte, 1)
+	h := Handler(func(ctx context.Context, req *Request) (interface{}, error) {
+		rwb, ok := req.WithBlob()
+		if !ok {
+			return nil, errors.New("expected a blob")
+		}
+		b, err := io.ReadAll(rwb.Blob)
+		if err != nil {
+			return nil, err
+		}
+		blobCh <- b
+		return nil, nil
+	})
+
+	srvNetConn, cliNetConn := net.Pipe()
+	srv := NewConn(jstream.NewStreamReader(srvNetConn), jstream.NewStreamWriter(srvNetConn))
+	go srv.Run(context.Background(), h)
+
+	cliW := jstream.NewStreamWriter(cliNetConn)
+	go func() {
+		cliW.EncodeValue(wireMsg{JSONRPC: "2.0", Method: "upload", Blob: true})
+		cliW.EncodeBytes(0, bytes.NewReader([]byte("payload")))
+	}()
+
+	assert.Equal(t, []byte("payload"), <-blobCh)
+}
+
+func jsonUnmarshal(b []byte, i interface{}) error {
+	if len(b) == 0 {
+		return nil
+	}
+	return json.Unmarshal(b, i)
+}
@@ -0,0 +1,83 @@
+// Package jrpc2 implements a JSON-RPC 2.0 (https://www.jsonrpc.org/specification)
+// client/server abstraction layered directly on top of
+// jstream.StreamReader/StreamWriter, taking design cues from
+// golang.org/x/tools/internal/jsonrpc2.
+//
+// Unlike mrpc/jsonrpc2, which encodes straight onto an io.ReadWriteCloser
+// using encoding/json, Conn carries each JSON-RPC message as a single
+// jstream JSONValue Element, so that the same jstream.StreamReader/
+// StreamWriter pair can also carry a Byte Blob Element as a request's
+// payload (see RequestWithBlob) without needing a side-channel of its own.
+//
+// A Conn multiplexes an arbitrary number of outstanding Calls and inbound
+// Requests over a single jstream pair: outstanding Calls are tracked by
+// their monotonically-increasing ID in a mutex-protected map and resolved
+// by Run as their Responses are read, while inbound Requests are dispatched
+// to a Handler concurrently, each in its own goroutine tracked via
+// mrun.WithThreads.
+package jrpc2
+
+import "encoding/json"
+
+// The standard JSON-RPC 2.0 error codes. See Error.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Error is the error object delivered in a Response when a Call fails, or
+// returned from a Handler whose error should carry a specific code/data
+// rather than being reported as CodeInternalError.
+type Error struct {
+	Code    int64           `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Message is implemented by Request, Notification, and Response: the three
+// kinds of message read and written by a Conn.
+type Message interface {
+	isMessage()
+}
+
+func (*Request) isMessage()      {}
+func (*Notification) isMessage() {}
+func (*Response) isMessage()     {}
+
+// wireMsg is the single JSON shape every Message is marshaled to/from as a
+// jstream JSONValue Element. Exactly one of (Method) or (Result, Err) is
+// set, per whether the message is a request/notification or a response;
+// ID is set on everything but a Notification.
+type wireMsg struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      *int64          `json:"id,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+
+	// Blob indicates that this wire message is immediately followed, as the
+	// next jstream Element, by a Byte Blob which is logically part of this
+	// message's payload. Only ever set on a Request/Notification; see
+	// RequestWithBlob.
+	Blob bool `json:"blob,omitempty"`
+}
+
+// cancelParams is the params of a "$/cancelRequest" Notification, sent by a
+// Conn to its peer when a Call's ctx is canceled locally. See Conn.Call.
+type cancelParams struct {
+	ID int64 `json:"id"`
+}
+
+// methodCancelRequest is the well-known method name used to propagate local
+// Call cancellation to the peer. It carries no response of its own, since
+// it's sent as a Notification.
+const methodCancelRequest = "$/cancelRequest"
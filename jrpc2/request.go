@@ -0,0 +1,72 @@
+package jrpc2
+
+import (
+	"encoding/json"
+
+	"github.com/mediocregopher/mediocre-go-lib/jstream"
+)
+
+// Request is an inbound JSON-RPC 2.0 request or notification, passed to a
+// Handler by Run. A notification is represented as a Request with HasID
+// false; Run never writes a Response for one, since the spec gives
+// notifications none, but otherwise dispatches it to the Handler
+// identically to a Request.
+type Request struct {
+	ID     int64
+	HasID  bool
+	Method string
+
+	params json.RawMessage
+	blob   jstream.BytesReader // set if the wire message had Blob:true
+}
+
+// Params unmarshals the Request's params into i.
+func (req *Request) Params(i interface{}) error {
+	if len(req.params) == 0 {
+		return nil
+	}
+	return json.Unmarshal(req.params, i)
+}
+
+// WithBlob returns req as a RequestWithBlob, and true, if req's wire
+// message declared a Byte Blob payload (see RequestWithBlob); otherwise it
+// returns nil, false.
+//
+// A Handler expecting a streamed payload should call this rather than
+// assuming one is always present, since a misbehaving peer may send a
+// plain Request instead.
+func (req *Request) WithBlob() (*RequestWithBlob, bool) {
+	if req.blob == nil {
+		return nil, false
+	}
+	return &RequestWithBlob{Request: req, Blob: req.blob}, true
+}
+
+// RequestWithBlob is a Request whose wire message was immediately followed
+// by a jstream Byte Blob Element, carrying some additional payload too
+// large, or too naturally streaming, to buffer into Params up front (e.g. a
+// file upload). Blob must be fully read, till io.EOF or jstream.ErrCanceled,
+// before Run's underlying jstream.StreamReader may be used again; see
+// jstream.Element.Bytes.
+type RequestWithBlob struct {
+	*Request
+	Blob jstream.BytesReader
+}
+
+// Notification is an outbound JSON-RPC 2.0 notification, as written by
+// Conn.Notify (and, internally, by Conn.Call's "$/cancelRequest" on ctx
+// cancellation). There's no inbound counterpart: Run represents an inbound
+// notification as a Request with HasID false instead, so that it can be
+// dispatched to a Handler the same way a Request is.
+type Notification struct {
+	Method string
+	Params interface{}
+}
+
+// Response is a reply to a previously issued Request, as returned by Call.
+// Exactly one of Result or Err is set.
+type Response struct {
+	ID     int64
+	Result json.RawMessage
+	Err    *Error
+}
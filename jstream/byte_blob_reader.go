@@ -3,9 +3,25 @@ package jstream
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/binary"
+	"fmt"
 	"io"
+	"io/ioutil"
 )
 
+// blobReader is implemented by every Byte Blob reading strategy (see
+// byteBlobReader and chunkedByteBlobReader). It's a superset of BytesReader,
+// adding the buffered method StreamReader needs in order to pick up, with
+// multiReader, wherever a Byte Blob's reader left off once the Byte Blob
+// itself has been fully read.
+type blobReader interface {
+	BytesReader
+
+	// buffered returns whatever bytes were read off of the Byte Blob's
+	// underlying io.Reader but weren't actually part of the Byte Blob.
+	buffered() io.Reader
+}
+
 type delimReader struct {
 	r     io.Reader
 	delim byte
@@ -25,12 +41,32 @@ func (dr *delimReader) Read(b []byte) (int, error) {
 	return n, err
 }
 
+// byteBlobReader implements BytesReader (see jstream.go).
+//
+// If rawSize is zero the Byte Blob is assumed to have been written with
+// EncodeBytes: dr/dec base64-decode its contents and watch for the trailing
+// bbEnd/bbCancel delimiter. Otherwise it's assumed to have been written with
+// EncodeBytesSized: dr is unused, and dec reads exactly rawSize raw bytes off
+// of r (via io.LimitReader), with no delimiter to watch for.
 type byteBlobReader struct {
-	dr  *delimReader
-	dec io.Reader
+	dr      *delimReader // nil if rawSize > 0
+	dec     io.Reader
+	rawSize int64
+	rawRest io.Reader // nil unless rawSize > 0; see buffered
 }
 
-func newByteBlobReader(r io.Reader) *byteBlobReader {
+func newByteBlobReader(r io.Reader, rawSize int64) *byteBlobReader {
+	if rawSize > 0 {
+		// json.Encoder always writes a trailing newline immediately after
+		// encoding the BytesStart element; discard it before reading the raw
+		// payload. Unlike base64 (whose decoder silently ignores embedded
+		// newlines), raw bytes can't tolerate an extra leading byte. Since
+		// io.CopyN never asks the underlying Reader for more than it needs,
+		// this (like the io.LimitReader below) can't accidentally consume any
+		// of the next element's bytes.
+		io.CopyN(ioutil.Discard, r, 1)
+		return &byteBlobReader{dec: io.LimitReader(r, rawSize), rawSize: rawSize, rawRest: r}
+	}
 	dr := &delimReader{r: r}
 	return &byteBlobReader{
 		dr:  dr,
@@ -39,6 +75,9 @@ func newByteBlobReader(r io.Reader) *byteBlobReader {
 }
 
 func (bbr *byteBlobReader) Read(into []byte) (int, error) {
+	if bbr.rawSize > 0 {
+		return bbr.dec.Read(into)
+	}
 	n, err := bbr.dec.Read(into)
 	if bbr.dr.delim == bbEnd {
 		return n, io.EOF
@@ -48,8 +87,123 @@ func (bbr *byteBlobReader) Read(into []byte) (int, error) {
 	return n, err
 }
 
+// ContentLength implements BytesReader.
+func (bbr *byteBlobReader) ContentLength() int64 {
+	if bbr.rawSize > 0 {
+		return bbr.rawSize
+	}
+	return -1
+}
+
 // returns the bytes which were read off the underlying io.Reader but which
 // haven't been consumed yet.
 func (bbr *byteBlobReader) buffered() io.Reader {
+	if bbr.rawSize > 0 {
+		// rawRest is the same Reader bbr.dec (an io.LimitReader) wraps, so
+		// whatever the LimitReader didn't consume is still sitting unread on
+		// rawRest, ready to pick up from.
+		return bbr.rawRest
+	}
 	return bytes.NewBuffer(bbr.dr.rest)
 }
+
+// chunk tags used by the chunked Byte Blob framing (see
+// StreamWriter.EncodeBytesChunked and jstream's package doc).
+const (
+	chunkTagData   byte = 1 // followed by a varint length; a length of 0 ends the Byte Blob
+	chunkTagCancel byte = 2
+)
+
+// byteReader adapts an io.Reader into an io.ByteReader by reading a single
+// byte at a time, via io.ReadFull, rather than the usual bufio.Reader
+// approach of buffering ahead. This guarantees it never reads past whatever
+// it's explicitly asked for, which chunkedByteBlobReader relies on in order
+// to hand an untouched underlying io.Reader back via buffered once the Byte
+// Blob has been fully read.
+type byteReader struct{ r io.Reader }
+
+func (br byteReader) ReadByte() (byte, error) {
+	var b [1]byte
+	_, err := io.ReadFull(br.r, b[:])
+	return b[0], err
+}
+
+// chunkedByteBlobReader implements blobReader (see jstream.go) for a Byte
+// Blob written with StreamWriter.EncodeBytesChunked: the Byte Blob is framed
+// as a sequence of tagged, length-delimited chunks rather than a single
+// fixed-size or delimiter-terminated run, so neither the total size nor a
+// reserved trailing byte needs to be known ahead of time.
+type chunkedByteBlobReader struct {
+	r   io.Reader
+	br  byteReader
+	cur io.Reader // the current chunk's body, or nil if a new chunk header needs reading
+
+	done bool
+	err  error // valid once done is true; io.EOF, ErrCanceled, or a framing error
+}
+
+func newChunkedByteBlobReader(r io.Reader) *chunkedByteBlobReader {
+	// as in newByteBlobReader's rawSize case, discard the trailing newline
+	// json.Encoder always writes immediately after encoding the BytesStart
+	// element; the chunk frames which follow are raw and can't tolerate an
+	// extra leading byte.
+	io.CopyN(ioutil.Discard, r, 1)
+	return &chunkedByteBlobReader{r: r, br: byteReader{r: r}}
+}
+
+func (cbr *chunkedByteBlobReader) Read(into []byte) (int, error) {
+	for {
+		if cbr.done {
+			return 0, cbr.err
+		}
+
+		if cbr.cur != nil {
+			n, err := cbr.cur.Read(into)
+			if n > 0 {
+				return n, nil
+			} else if err == io.EOF {
+				cbr.cur = nil
+				continue
+			} else if err != nil {
+				cbr.done, cbr.err = true, err
+				return 0, err
+			}
+			continue
+		}
+
+		tag, err := cbr.br.ReadByte()
+		if err != nil {
+			cbr.done, cbr.err = true, err
+			return 0, err
+		}
+
+		switch tag {
+		case chunkTagData:
+			length, err := binary.ReadUvarint(cbr.br)
+			if err != nil {
+				cbr.done, cbr.err = true, err
+				return 0, err
+			} else if length == 0 {
+				cbr.done, cbr.err = true, io.EOF
+				return 0, io.EOF
+			}
+			cbr.cur = io.LimitReader(cbr.r, int64(length))
+		case chunkTagCancel:
+			cbr.done, cbr.err = true, ErrCanceled
+			return 0, ErrCanceled
+		default:
+			cbr.done, cbr.err = true, fmt.Errorf("jstream: unknown chunked byte blob tag %d", tag)
+			return 0, cbr.err
+		}
+	}
+}
+
+// ContentLength implements BytesReader. A chunked Byte Blob's total size is
+// never known up-front, so this always returns -1.
+func (cbr *chunkedByteBlobReader) ContentLength() int64 { return -1 }
+
+// buffered implements blobReader. Since byteReader never reads more off r
+// than it's asked for, and each chunk body is read via an io.LimitReader
+// which does the same, r itself is already positioned exactly where the
+// next Element begins.
+func (cbr *chunkedByteBlobReader) buffered() io.Reader { return cbr.r }
@@ -3,6 +3,7 @@ package jstream
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/binary"
 	"io"
 	"io/ioutil"
 	. "testing"
@@ -60,7 +61,7 @@ func (bt bbrTest) mkBytes() []byte {
 
 func (bt bbrTest) do(t *T) bool {
 	buf := bytes.NewBuffer(bt.mkBytes())
-	bbr := newByteBlobReader(buf)
+	bbr := newByteBlobReader(buf, 0)
 
 	into := make([]byte, bt.intoSize)
 	outBuf := new(bytes.Buffer)
@@ -81,6 +82,107 @@ func (bt bbrTest) do(t *T) bool {
 	return assert.Equal(t, bt.wsSuffix, fullRest, bt.msgAndArgs()...)
 }
 
+// writeTestChunk writes a single chunkTagData frame for chunk, the same way
+// StreamWriter.EncodeBytesChunked's writeChunk does, for use building up
+// chunked Byte Blob test bodies by hand.
+func writeTestChunk(buf *bytes.Buffer, chunk []byte) {
+	var varintBuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(varintBuf[:], uint64(len(chunk)))
+	buf.WriteByte(chunkTagData)
+	buf.Write(varintBuf[:n])
+	buf.Write(chunk)
+}
+
+// mkChunkedBytes is the chunked-framing counterpart to mkBytes, splitting
+// the body across multiple chunk frames (to exercise the multi-chunk case)
+// rather than encoding it as a single base64 run.
+func (bt bbrTest) mkChunkedBytes() []byte {
+	const chunkSize = 97 // arbitrary and not a divisor of most body sizes
+
+	buf := new(bytes.Buffer)
+	// newChunkedByteBlobReader expects (and discards) the trailing newline
+	// json.Encoder always writes right after the BytesStart element; mimic
+	// that here too, same as the real wire format would have.
+	buf.WriteByte('\n')
+	body := bt.body
+	if bt.shouldCancel {
+		body = body[:len(body)/2]
+	}
+	for len(body) > 0 {
+		n := chunkSize
+		if n > len(body) {
+			n = len(body)
+		}
+		writeTestChunk(buf, body[:n])
+		body = body[n:]
+	}
+
+	if bt.shouldCancel {
+		buf.WriteByte(chunkTagCancel)
+	} else {
+		writeTestChunk(buf, nil)
+	}
+
+	buf.Write(bt.wsSuffix)
+	return buf.Bytes()
+}
+
+func (bt bbrTest) doChunked(t *T) bool {
+	buf := bytes.NewBuffer(bt.mkChunkedBytes())
+	cbr := newChunkedByteBlobReader(buf)
+
+	into := make([]byte, bt.intoSize)
+	outBuf := new(bytes.Buffer)
+	_, err := io.CopyBuffer(outBuf, cbr, into)
+	if bt.shouldCancel {
+		return assert.Equal(t, ErrCanceled, err, bt.msgAndArgs()...)
+	}
+	if !assert.NoError(t, err, bt.msgAndArgs()...) {
+		return false
+	}
+	if !assert.Equal(t, bt.body, outBuf.Bytes(), bt.msgAndArgs()...) {
+		return false
+	}
+	if !assert.EqualValues(t, -1, cbr.ContentLength(), bt.msgAndArgs()...) {
+		return false
+	}
+
+	fullRest := buf.Bytes()
+	if len(bt.wsSuffix) == 0 {
+		return assert.Empty(t, fullRest, bt.msgAndArgs()...)
+	}
+	return assert.Equal(t, bt.wsSuffix, fullRest, bt.msgAndArgs()...)
+}
+
+func TestChunkedByteBlobReader(t *T) {
+	// some sanity tests
+	bbrTest{
+		body:     []byte{2, 3, 4, 5},
+		intoSize: 4,
+	}.doChunked(t)
+	bbrTest{
+		body:     []byte{2, 3, 4, 5},
+		intoSize: 3,
+	}.doChunked(t)
+	bbrTest{
+		body:         []byte{2, 3, 4, 5},
+		shouldCancel: true,
+		intoSize:     3,
+	}.doChunked(t)
+
+	// fuzz this bitch
+	for i := 0; i < 50000; i++ {
+		bt := randBBRTest(0, 1000)
+		if !bt.doChunked(t) {
+			return
+		}
+		bt.shouldCancel = true
+		if !bt.doChunked(t) {
+			return
+		}
+	}
+}
+
 func TestByteBlobReader(t *T) {
 	// some sanity tests
 	bbrTest{
@@ -134,6 +236,22 @@ func BenchmarkByteBlobReader(b *B) {
 		return benches
 	}
 
+	mkChunkedTestSet := func(minBodySize, maxBodySize int) []bench {
+		n := 100
+		benches := make([]bench, n)
+		for i := range benches {
+			bt := randBBRTest(minBodySize, maxBodySize)
+			body := bt.mkChunkedBytes()
+			benches[i] = bench{
+				bt:    bt,
+				body:  body,
+				buf:   bytes.NewReader(nil),
+				cpBuf: make([]byte, bt.intoSize),
+			}
+		}
+		return benches
+	}
+
 	testRaw := func(b *B, benches []bench) {
 		j := 0
 		for i := 0; i < b.N; i++ {
@@ -153,12 +271,25 @@ func BenchmarkByteBlobReader(b *B) {
 				j = 0
 			}
 			benches[j].buf.Reset(benches[j].body)
-			bbr := newByteBlobReader(benches[j].buf)
+			bbr := newByteBlobReader(benches[j].buf, 0)
 			io.CopyBuffer(ioutil.Discard, bbr, benches[j].cpBuf)
 			j++
 		}
 	}
 
+	testChunked := func(b *B, benches []bench) {
+		j := 0
+		for i := 0; i < b.N; i++ {
+			if j >= len(benches) {
+				j = 0
+			}
+			benches[j].buf.Reset(benches[j].body)
+			cbr := newChunkedByteBlobReader(benches[j].buf)
+			io.CopyBuffer(ioutil.Discard, cbr, benches[j].cpBuf)
+			j++
+		}
+	}
+
 	benches := []struct {
 		name                     string
 		minBodySize, maxBodySize int
@@ -173,6 +304,7 @@ func BenchmarkByteBlobReader(b *B) {
 	for i := range benches {
 		b.Run(benches[i].name, func(b *B) {
 			set := mkTestSet(benches[i].minBodySize, benches[i].maxBodySize)
+			chunkedSet := mkChunkedTestSet(benches[i].minBodySize, benches[i].maxBodySize)
 			b.StartTimer()
 			b.Run("raw", func(b *B) {
 				testRaw(b, set)
@@ -180,6 +312,9 @@ func BenchmarkByteBlobReader(b *B) {
 			b.Run("bbr", func(b *B) {
 				testBBR(b, set)
 			})
+			b.Run("chunked", func(b *B) {
+				testChunked(b, chunkedSet)
+			})
 			b.StopTimer()
 		})
 	}
@@ -0,0 +1,162 @@
+package jstream
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec identifies a compression algorithm a StreamWriter may wrap its
+// underlying io.Writer with, via WithCompression.
+type Codec string
+
+// The Codecs supported by WithCompression.
+const (
+	Zstd   Codec = "zstd"
+	Gzip   Codec = "gzip"
+	Snappy Codec = "snappy"
+)
+
+// flushWriteCloser is the common interface of the three compressors
+// newWriter may return: each buffers internally and so needs Flush (see
+// autoFlushWriter) in addition to the usual Write/Close.
+type flushWriteCloser interface {
+	io.WriteCloser
+	Flush() error
+}
+
+func (c Codec) newWriter(w io.Writer) (flushWriteCloser, error) {
+	switch c {
+	case Zstd:
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedFastest))
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	case Snappy:
+		return snappy.NewWriter(w), nil
+	default:
+		return nil, fmt.Errorf("jstream: unknown compression codec %q", c)
+	}
+}
+
+// skipLeadingWS returns an io.Reader equivalent to r but with any leading
+// jstream whitespace (spaces, tabs, carriage returns, newlines) consumed.
+// It's used when constructing a decompressing io.Reader right after a
+// streamHead handshake element, since the json.Encoder.Encode call which
+// wrote that handshake always leaves its own trailing newline in front of
+// whatever comes next, and a Codec's Reader expects to see its magic bytes
+// first, not that separator.
+func skipLeadingWS(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return io.MultiReader(bytes.NewReader([]byte{b}), br), nil
+	}
+}
+
+func (c Codec) newReader(r io.Reader) (io.Reader, error) {
+	switch c {
+	case Zstd:
+		return zstd.NewReader(r)
+	case Gzip:
+		return gzip.NewReader(r)
+	case Snappy:
+		return snappy.NewReader(r), nil
+	default:
+		return nil, fmt.Errorf("jstream: unknown compression codec %q", c)
+	}
+}
+
+// streamHead is an optional handshake element, written (if at all) as the
+// very first thing on a Stream, before any element the writer's caller
+// asked for. It's never exposed as an Element; StreamReader's Next detects
+// and consumes it transparently. See WithCompression and WithRawByteBlobs.
+type streamHead struct {
+	StreamHead bool `json:"streamHead"`
+
+	// Compression is set if the bytes following this handshake (both
+	// further jstream elements and, for rawSize/bytesChunked Byte Blobs,
+	// their raw bytes) are wrapped in the named Codec.
+	Compression Codec `json:"compression,omitempty"`
+
+	// RawByteBlobs is set if the writer defaults EncodeBytes to
+	// EncodeBytesChunked's framing rather than base64. This is purely
+	// informational: a Byte Blob's framing is already fully self-described
+	// by its own element (see rawSize/bytesChunked in jstream.go), so a
+	// StreamReader needs no special handling for it. It's still advertised
+	// here, alongside Compression, so a peer (or some other piece of code
+	// introspecting the handshake) doesn't have to wait for the first Byte
+	// Blob to learn how this StreamWriter is configured.
+	RawByteBlobs bool `json:"rawByteBlobs,omitempty"`
+}
+
+// streamWriterOpts holds the state populated by StreamWriterOptions.
+type streamWriterOpts struct {
+	compression  Codec
+	rawByteBlobs bool
+}
+
+// StreamWriterOption is a value which adjusts the behavior of a StreamWriter,
+// given to NewStreamWriter.
+type StreamWriterOption func(*streamWriterOpts)
+
+// WithCompression causes the StreamWriter's underlying io.Writer to be
+// wrapped with the given Codec, after a streamHead handshake element
+// advertising that choice is written (uncompressed) as the very first thing
+// on the Stream. A StreamReader on the other end detects and applies this
+// handshake automatically; no corresponding option is needed when
+// constructing it.
+//
+// Neither the handshake nor the wrapping happen until the first Encode* call
+// (so that NewStreamWriter itself can't fail), and every Write made through
+// the resulting compressor is immediately flushed to the underlying
+// io.Writer, since none of the supported Codecs flush on their own and
+// StreamWriter has no Close of its own to do so at the end. This trades some
+// of the compression ratio/throughput a larger internal buffer would give
+// for never needing one, in keeping with jstream's stated preference for
+// simplicity over efficiency (see the package doc).
+func WithCompression(codec Codec) StreamWriterOption {
+	return func(opts *streamWriterOpts) {
+		opts.compression = codec
+	}
+}
+
+// WithRawByteBlobs causes EncodeBytes to use EncodeBytesChunked's raw,
+// length-prefixed framing instead of base64. This is primarily useful
+// alongside WithCompression, since base64 both wastes ~33% of bandwidth and
+// defeats compression by turning binary data into (already
+// high-entropy-looking) text, but is independently useful any time the
+// sizeHint EncodeBytes takes isn't needed.
+func WithRawByteBlobs() StreamWriterOption {
+	return func(opts *streamWriterOpts) {
+		opts.rawByteBlobs = true
+	}
+}
+
+// autoFlushWriter wraps a compressor's io.Writer so that every Write is
+// immediately flushed to the underlying stream. See WithCompression.
+type autoFlushWriter struct {
+	w interface {
+		io.Writer
+		Flush() error
+	}
+}
+
+func (afw *autoFlushWriter) Write(p []byte) (int, error) {
+	n, err := afw.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, afw.w.Flush()
+}
@@ -0,0 +1,120 @@
+package jstream
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	. "testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoCompressionWritesNoHead(t *T) {
+	buf := new(bytes.Buffer)
+	w := NewStreamWriter(buf)
+	assert.NoError(t, w.EncodeValue("foo"))
+	assert.NotContains(t, buf.String(), "streamHead")
+
+	r := NewStreamReader(buf)
+	var got string
+	assert.NoError(t, r.Next().Value(&got))
+	assert.Equal(t, "foo", got)
+}
+
+func TestWithCompression(t *T) {
+	for _, codec := range []Codec{Zstd, Gzip, Snappy} {
+		t.Run(string(codec), func(t *T) {
+			buf := new(bytes.Buffer)
+			w := NewStreamWriter(buf, WithCompression(codec))
+
+			vals := []interface{}{"foo", float64(42), map[string]interface{}{"a": "b"}}
+			for _, v := range vals {
+				assert.NoError(t, w.EncodeValue(v))
+			}
+			payload := []byte(strings.Repeat("hello world ", 64))
+			assert.NoError(t, w.EncodeBytes(uint(len(payload)), bytes.NewReader(payload)))
+
+			r := NewStreamReader(buf)
+			for _, expected := range vals {
+				el := r.Next()
+				assert.NoError(t, el.Err)
+				var got interface{}
+				assert.NoError(t, el.Value(&got))
+				assert.Equal(t, expected, got)
+			}
+
+			el := r.Next()
+			assert.NoError(t, el.Err)
+			br, err := el.Bytes()
+			assert.NoError(t, err)
+			got, err := ioutil.ReadAll(br)
+			assert.NoError(t, err)
+			assert.Equal(t, payload, got)
+		})
+	}
+}
+
+func TestWithCompressionUnknownCodec(t *T) {
+	buf := new(bytes.Buffer)
+	w := NewStreamWriter(buf, WithCompression("bogus"))
+	assert.Error(t, w.EncodeValue("foo"))
+}
+
+func TestWithRawByteBlobs(t *T) {
+	buf := new(bytes.Buffer)
+	w := NewStreamWriter(buf, WithRawByteBlobs())
+
+	payload := []byte("some raw bytes")
+	assert.NoError(t, w.EncodeBytes(0, bytes.NewReader(payload)))
+
+	// WithRawByteBlobs still triggers a streamHead handshake (it has
+	// something to advertise), so the raw bytes must be decoded past that
+	// first before checking that the Byte Blob itself used bytesChunked
+	// framing, not base64.
+	raw := buf.Bytes()
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	var sh streamHead
+	assert.NoError(t, dec.Decode(&sh))
+	assert.True(t, sh.RawByteBlobs)
+
+	var el element
+	assert.NoError(t, dec.Decode(&el))
+	assert.True(t, el.BytesChunked)
+
+	r := NewStreamReader(bytes.NewReader(raw))
+	rEl := r.Next()
+	assert.NoError(t, rEl.Err)
+	br, err := rEl.Bytes()
+	assert.NoError(t, err)
+	got, err := ioutil.ReadAll(br)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, got)
+}
+
+func TestWithCompressionAndRawByteBlobs(t *T) {
+	buf := new(bytes.Buffer)
+	w := NewStreamWriter(buf, WithCompression(Zstd), WithRawByteBlobs())
+
+	payload := []byte(strings.Repeat("raw and compressed ", 64))
+	assert.NoError(t, w.EncodeValue("before"))
+	assert.NoError(t, w.EncodeBytes(0, bytes.NewReader(payload)))
+	assert.NoError(t, w.EncodeValue("after"))
+
+	r := NewStreamReader(buf)
+
+	var before, after string
+	assert.NoError(t, r.Next().Value(&before))
+	assert.Equal(t, "before", before)
+
+	el := r.Next()
+	assert.NoError(t, el.Err)
+	br, err := el.Bytes()
+	assert.NoError(t, err)
+	got, err := ioutil.ReadAll(br)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, got)
+
+	assert.NoError(t, r.Next().Value(&after))
+	assert.Equal(t, "after", after)
+}
@@ -0,0 +1,96 @@
+package jstream
+
+import (
+	"context"
+	"io"
+)
+
+// runContext runs fn in its own goroutine and returns its result, unless
+// ctx is canceled first, in which case onCancel (if non-nil) is called and
+// ctx.Err() is returned immediately instead. fn is not stopped when this
+// happens; it keeps running in the background against whatever it was
+// already blocked on, and its result is discarded once it eventually
+// finishes. onCancel exists to give whatever's on the other end of that
+// blocked operation a well-formed signal that it's been abandoned, rather
+// than leaving it to notice a truncated read or write on its own.
+func runContext(ctx context.Context, fn func() error, onCancel func()) error {
+	doneCh := make(chan error, 1)
+	go func() { doneCh <- fn() }()
+
+	select {
+	case err := <-doneCh:
+		return err
+	case <-ctx.Done():
+		if onCancel != nil {
+			onCancel()
+		}
+		return ctx.Err()
+	}
+}
+
+// NextContext is like Next, but returns early with ctx.Err() if ctx is
+// canceled before the next Element has finished being read.
+//
+// The underlying read is not interrupted by this; it keeps blocking in the
+// background. If sr's underlying io.Reader also implements io.Closer, it's
+// Closed on cancellation, so that read unblocks (with some error) instead
+// of leaking forever. Either way, sr must not be used again afterwards,
+// the same as if its io.Reader had been closed out from under it directly.
+func (sr *StreamReader) NextContext(ctx context.Context) Element {
+	var el Element
+	err := runContext(ctx, func() error {
+		el = sr.Next()
+		return nil
+	}, func() {
+		if c, ok := sr.orig.(io.Closer); ok {
+			c.Close()
+		}
+	})
+	if err != nil {
+		return Element{Err: err}
+	}
+	return el
+}
+
+// EncodeValueContext is like EncodeValue, but returns ctx.Err() if ctx is
+// canceled before the write completes.
+//
+// Unlike EncodeBytesContext and EncodeStreamContext there's no sentinel to
+// emit on cancellation: a JSONValue element is never well-formed until it's
+// been written in full, so there's nothing better to do than leave the
+// in-flight write to finish (or fail) in the background and consider sw
+// broken regardless of which happens.
+func (sw *StreamWriter) EncodeValueContext(ctx context.Context, i interface{}) error {
+	return runContext(ctx, func() error {
+		return sw.EncodeValue(i)
+	}, nil)
+}
+
+// EncodeBytesContext is like EncodeBytes, but returns ctx.Err() if ctx is
+// canceled before the copy from r completes, writing the same cancellation
+// sentinel Cancel would (r itself is not stopped; the copy keeps running in
+// the background against it).
+func (sw *StreamWriter) EncodeBytesContext(ctx context.Context, sizeHint uint, r io.Reader) error {
+	return runContext(ctx, func() error {
+		return sw.EncodeBytes(sizeHint, r)
+	}, func() {
+		sw.Cancel()
+	})
+}
+
+// EncodeStreamContext is like EncodeStream, but returns ctx.Err() if ctx is
+// canceled before fn returns (fn is not stopped; it keeps running, and
+// writing through sw, in the background).
+//
+// On cancellation a streamCancel element is written directly, the same as
+// if fn itself had returned an error, so the peer sees a well-formed
+// termination rather than a Stream that simply stops. Since this happens
+// concurrently with fn's own still-running writes, sw should be considered
+// broken afterwards regardless of whether fn notices ctx being canceled.
+func (sw *StreamWriter) EncodeStreamContext(ctx context.Context, sizeHint uint, fn func(*StreamWriter) error) error {
+	return runContext(ctx, func() error {
+		return sw.EncodeStream(sizeHint, fn)
+	}, func() {
+		sw.enc.Encode(element{StreamCancel: true})
+	})
+}
@@ -0,0 +1,98 @@
+package jstream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	. "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextContext(t *T) {
+	t.Run("success", func(t *T) {
+		buf := new(bytes.Buffer)
+		w := NewStreamWriter(buf)
+		assert.NoError(t, w.EncodeValue("foo"))
+
+		r := NewStreamReader(buf)
+		el := r.NextContext(context.Background())
+		assert.NoError(t, el.Err)
+		var got string
+		assert.NoError(t, el.Value(&got))
+		assert.Equal(t, "foo", got)
+	})
+
+	t.Run("canceled", func(t *T) {
+		srvConn, cliConn := net.Pipe()
+		defer srvConn.Close()
+		defer cliConn.Close()
+
+		r := NewStreamReader(cliConn)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		el := r.NextContext(ctx)
+		assert.Equal(t, context.Canceled, el.Err)
+	})
+}
+
+func TestEncodeValueContextCanceled(t *T) {
+	srvConn, cliConn := net.Pipe()
+	defer srvConn.Close()
+	defer cliConn.Close()
+
+	w := NewStreamWriter(cliConn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// cliConn's net.Pipe has no buffer, so this Encode call blocks until
+	// something reads from srvConn; since nothing does, it should be
+	// aborted by the already-canceled ctx instead.
+	err := w.EncodeValueContext(ctx, "foo")
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestEncodeBytesContextCanceled(t *T) {
+	srvConn, cliConn := net.Pipe()
+	defer srvConn.Close()
+	defer cliConn.Close()
+
+	w := NewStreamWriter(cliConn)
+
+	readDoneCh := make(chan struct{})
+	go func() {
+		defer close(readDoneCh)
+		r := NewStreamReader(srvConn)
+		el := r.Next()
+		if el.Err != nil {
+			return
+		}
+		br, err := el.Bytes()
+		if err != nil {
+			return
+		}
+		_, err = io.Copy(io.Discard, br)
+		if !errors.Is(err, ErrCanceled) {
+			t.Errorf("expected ErrCanceled, got %v", err)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	// pr blocks forever, so the copy inside EncodeBytesContext never
+	// completes on its own; ctx's cancellation is what ends this call.
+	pr, pw := io.Pipe()
+	defer pw.Close()
+
+	err := w.EncodeBytesContext(ctx, 0, pr)
+	assert.Equal(t, context.Canceled, err)
+
+	<-readDoneCh
+}
@@ -0,0 +1,50 @@
+package jrpcstream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mediocregopher/mediocre-go-lib/jstream"
+)
+
+// Open writes a call header naming method and meta (marshaled as the
+// header's Meta field) to w, then blocks on an embedded Stream, handing fn
+// a StreamWriter to write the call's request messages to, exactly as
+// jstream.StreamWriter.EncodeStreamContext's fn does (fn returning nil ends
+// the request Stream normally; returning an error cancels it, and that
+// error is returned from Open).
+//
+// Open doesn't read the response Stream the peer writes back; call
+// Response, on the StreamReader reading whatever the peer writes to the
+// other direction of this connection, to get that. Since a full-duplex
+// connection's two directions are independent, Open is usually run in its
+// own goroutine so that Response can be read concurrently rather than only
+// after Open has returned (which, for a long-lived request Stream, may be
+// a while).
+//
+// If ctx is canceled before fn returns, the request Stream is canceled
+// (see EncodeStreamContext) and Open returns ctx.Err().
+func Open(ctx context.Context, w *jstream.StreamWriter, method string, meta interface{}, fn func(out *jstream.StreamWriter) error) error {
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("jrpcstream: marshaling meta: %w", err)
+	}
+
+	if err := w.EncodeValueContext(ctx, callHeader{Method: method, Meta: b}); err != nil {
+		return err
+	}
+	return w.EncodeStreamContext(ctx, 0, fn)
+}
+
+// Response reads the response Stream a peer writes back for a call opened
+// with Open, returning a StreamReader for its messages: JSONValue results,
+// Byte Blob payloads, and possibly a terminal Status (see DecodeStatus), in
+// whatever order/mix the serving Handler chose to write them.
+func Response(r *jstream.StreamReader) (*jstream.StreamReader, error) {
+	el := r.Next()
+	if el.Err != nil {
+		return nil, el.Err
+	}
+	return el.Stream()
+}
@@ -0,0 +1,111 @@
+package jrpcstream
+
+import (
+	"context"
+	"io"
+
+	"github.com/mediocregopher/mediocre-go-lib/jstream"
+)
+
+// Conn serves inbound calls read off a jstream.StreamReader, dispatching
+// each to a Handler registered via Handle and writing its reply back on a
+// jstream.StreamWriter. See the package doc for how a call's request and
+// response Streams relate, and for the one-call-at-a-time limitation that
+// comes with carrying them over a single jstream pair.
+type Conn struct {
+	r *jstream.StreamReader
+	w *jstream.StreamWriter
+
+	handlers map[string]Handler
+}
+
+// NewConn returns a Conn which reads calls off r and writes responses to w.
+func NewConn(r *jstream.StreamReader, w *jstream.StreamWriter) *Conn {
+	return &Conn{r: r, w: w, handlers: map[string]Handler{}}
+}
+
+// Handle registers h as the Handler for inbound calls naming method. A
+// later call to Handle with the same method replaces the previous Handler.
+func (c *Conn) Handle(method string, h Handler) {
+	c.handlers[method] = h
+}
+
+// drainStream reads sr till jstream.ErrStreamEnded or jstream.ErrCanceled,
+// discarding every Element along the way. It's used to consume a call's
+// request Stream in full when there's no Handler registered to hand it to.
+func drainStream(sr *jstream.StreamReader) error {
+	for {
+		el := sr.Next()
+		if el.Err == jstream.ErrStreamEnded || el.Err == jstream.ErrCanceled {
+			return nil
+		} else if el.Err != nil {
+			return el.Err
+		} else if err := el.Discard(); err != nil {
+			return err
+		}
+	}
+}
+
+// serve writes the response Stream for a single call, either dispatching
+// to the Handler registered for method or, if there is none, draining in
+// and responding with a CodeMethodNotFound Status.
+func (c *Conn) serve(ctx context.Context, method string, in *jstream.StreamReader) error {
+	h, ok := c.handlers[method]
+
+	return c.w.EncodeStreamContext(ctx, 0, func(out *jstream.StreamWriter) error {
+		if !ok {
+			if err := drainStream(in); err != nil {
+				return err
+			}
+			return EncodeStatus(out, Status{
+				Code:    CodeMethodNotFound,
+				Message: "unknown method: " + method,
+			})
+		}
+		return h(ctx, in, out)
+	})
+}
+
+// Run reads calls off c's StreamReader in a loop, dispatching each to its
+// registered Handler (or responding with CodeMethodNotFound if none
+// matches) and writing the reply as the call's response Stream, until the
+// StreamReader is exhausted (a read returning io.EOF, jstream.ErrStreamEnded,
+// or jstream.ErrCanceled, in which case Run returns nil) or some other
+// error is hit.
+//
+// Run serves each call in turn before reading the next call's header, per
+// the package doc's one-call-at-a-time limitation; there's nothing to join
+// on independently of Run itself returning.
+//
+// If ctx is canceled while a call is being served, that call's response
+// Stream is canceled (see jstream.StreamWriter.EncodeStreamContext) and Run
+// returns ctx.Err().
+func (c *Conn) Run(ctx context.Context) error {
+	for {
+		el := c.r.Next()
+		if el.Err == io.EOF || el.Err == jstream.ErrStreamEnded || el.Err == jstream.ErrCanceled {
+			return nil
+		} else if el.Err != nil {
+			return el.Err
+		}
+
+		var hdr callHeader
+		if err := el.Value(&hdr); err != nil {
+			return err
+		}
+
+		reqEl := c.r.Next()
+		if reqEl.Err != nil {
+			return reqEl.Err
+		}
+		in, err := reqEl.Stream()
+		if err != nil {
+			return err
+		}
+
+		callCtx := context.WithValue(ctx, metaCtxKey{}, hdr.Meta)
+		if err := c.serve(callCtx, hdr.Method, in); err != nil {
+			return err
+		}
+	}
+}
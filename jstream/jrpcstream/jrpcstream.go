@@ -0,0 +1,164 @@
+// Package jrpcstream implements gRPC-style bidirectional streaming RPC
+// directly on top of jstream's embedded Stream element, taking design cues
+// from Drone's move from its own polling protocol to gRPC for agent<>server
+// communication.
+//
+// A client opens a call by writing a JSONValue header, shaped
+// {"method":"...","meta":{...}}, followed immediately by an embedded Stream
+// carrying whatever request messages it wants to send (see Open). The
+// server reads the header, dispatches to whichever Handler was registered
+// for that method (see Conn.Handle), and responds with its own embedded
+// Stream (see Response), carrying JSONValue results, Byte Blob payloads, or
+// both, in whatever order/mix the Handler chooses, optionally ending with a
+// terminal JSONValue shaped {"status":{...}} (see Status) analogous to a
+// gRPC trailing status.
+//
+// Unlike jrpc2.Conn, which multiplexes arbitrarily many outstanding Calls
+// over a single jstream pair by giving each JSON-RPC message its own ID,
+// jrpcstream carries one call at a time to completion: a call's request
+// and response are each a single embedded Stream, which, per jstream's own
+// contract, must be fully read before anything else on that
+// StreamReader/StreamWriter can happen. A server wanting to handle calls
+// concurrently needs a separate Conn (and, usually, connection) for each
+// one in flight at a time.
+//
+// Cancellation propagates in both directions by writing a streamCancel
+// element into whichever embedded Stream is currently open: Open and Conn's
+// dispatch both do this automatically when their ctx is canceled, via
+// jstream's own EncodeStreamContext.
+//
+// Either side may also periodically write a reserved JSONValue shaped
+// {"ping":true} (see EncodePing) into an open Stream, to keep an idle
+// connection from being closed by a proxy or load balancer sitting between
+// peers; NextSkipPing discards these transparently, so callers reading a
+// request or response Stream never see them.
+package jrpcstream
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/mediocregopher/mediocre-go-lib/jstream"
+)
+
+// Handler serves the request Stream of a single inbound call, as
+// dispatched by Conn.Run. It should write its reply to out as an embedded
+// Stream's worth of Elements, optionally ending with a terminal Status (see
+// EncodeStatus), and must fully drain in (till jstream.ErrStreamEnded or
+// jstream.ErrCanceled) before returning, since in's underlying
+// StreamReader can't be used again otherwise.
+//
+// If Handler returns a non-nil error the response Stream is canceled
+// (written as a streamCancel) rather than ended normally, and that error is
+// returned from Run.
+type Handler func(ctx context.Context, in *jstream.StreamReader, out *jstream.StreamWriter) error
+
+// The Status codes jrpcstream itself may write; a Handler is free to use
+// these, or its own application-specific codes, in a Status it encodes via
+// EncodeStatus.
+const (
+	CodeOK             = 0
+	CodeInternalError  = -32603
+	CodeMethodNotFound = -32601
+)
+
+// Status is the terminal message a Handler may write to its response
+// Stream as its last Element, analogous to a gRPC trailing status. Conn
+// doesn't require one to be written, or inspect one if it is; it's purely a
+// convention a Handler and its caller may choose to follow, via
+// EncodeStatus and DecodeStatus.
+type Status struct {
+	Code    int64           `json:"code,omitempty"`
+	Message string          `json:"message,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// statusMsg is the JSON shape a Status is carried as, when encoded as a
+// JSONValue Element's value: {"status":{...}}.
+type statusMsg struct {
+	Status *Status `json:"status,omitempty"`
+}
+
+// EncodeStatus writes st to out as a JSONValue Element shaped
+// {"status":{...}}. It's usually the last Element a Handler writes to its
+// response Stream, but nothing enforces that.
+func EncodeStatus(out *jstream.StreamWriter, st Status) error {
+	return out.EncodeValue(statusMsg{Status: &st})
+}
+
+// DecodeStatus returns the Status el carries, and true, if el is a
+// JSONValue Element shaped {"status":{...}}; otherwise it returns false,
+// and el should be treated as an ordinary response message instead.
+func DecodeStatus(el jstream.Element) (Status, bool) {
+	if typ, err := el.Type(); err != nil || typ != jstream.TypeJSONValue {
+		return Status{}, false
+	}
+	var msg statusMsg
+	if err := el.Value(&msg); err != nil || msg.Status == nil {
+		return Status{}, false
+	}
+	return *msg.Status, true
+}
+
+// pingMsg is the JSON shape a keepalive ping is carried as, when encoded as
+// a JSONValue Element's value: {"ping":true}.
+type pingMsg struct {
+	Ping bool `json:"ping,omitempty"`
+}
+
+// EncodePing writes a single keepalive ping Element to w, as a JSONValue
+// shaped {"ping":true}. It's meant to be written periodically (e.g. off a
+// time.Ticker) into an open request or response Stream, to keep an idle
+// connection alive; NextSkipPing discards these automatically on the
+// reading side.
+//
+// Like any other write to a jstream.StreamWriter, this isn't safe to call
+// concurrently with whatever else is writing to w; a goroutine doing this
+// periodically must coordinate with that other writer (e.g. by routing
+// through a single goroutine which owns w) rather than calling this
+// directly from its own.
+func EncodePing(w *jstream.StreamWriter) error {
+	return w.EncodeValue(pingMsg{Ping: true})
+}
+
+// isPing returns true if el is a JSONValue Element shaped {"ping":true}.
+func isPing(el jstream.Element) bool {
+	if typ, err := el.Type(); err != nil || typ != jstream.TypeJSONValue {
+		return false
+	}
+	var msg pingMsg
+	return el.Value(&msg) == nil && msg.Ping
+}
+
+// NextSkipPing is like (*jstream.StreamReader).Next, but transparently
+// discards any keepalive pings written via EncodePing instead of returning
+// them, since they carry no information a caller ever needs to see.
+func NextSkipPing(r *jstream.StreamReader) jstream.Element {
+	for {
+		el := r.Next()
+		if el.Err != nil || !isPing(el) {
+			return el
+		}
+	}
+}
+
+// callHeader is the JSON shape a call's header is carried as, when encoded
+// as a JSONValue Element's value: {"method":"...","meta":{...}}. It's
+// immediately followed, as the next jstream Element, by the call's request
+// Stream.
+type callHeader struct {
+	Method string          `json:"method"`
+	Meta   json.RawMessage `json:"meta,omitempty"`
+}
+
+// metaCtxKey is the context.Value key a call's Meta is stored under, for
+// MetaFromContext to retrieve inside a Handler.
+type metaCtxKey struct{}
+
+// MetaFromContext returns the Meta a client passed to Open for the call
+// currently being served, or nil if there was none (or ctx isn't one Run
+// gave to a Handler).
+func MetaFromContext(ctx context.Context) json.RawMessage {
+	meta, _ := ctx.Value(metaCtxKey{}).(json.RawMessage)
+	return meta
+}
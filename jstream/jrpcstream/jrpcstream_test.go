@@ -0,0 +1,212 @@
+package jrpcstream
+
+import (
+	"bytes"
+	"context"
+	"net"
+	. "testing"
+
+	"github.com/mediocregopher/mediocre-go-lib/jstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestConnPair starts a Conn, with handlers registered, serving one end
+// of a net.Pipe in the background, and returns the StreamReader/StreamWriter
+// pair a test uses to act as the client on the other end.
+//
+// Since net.Pipe is synchronous and unbuffered, a client (Open/Response)
+// must always be driven from its own goroutine(s) concurrently with
+// reading/writing the other direction, the same as Run already is here;
+// otherwise a single pending write on either side, with nothing yet
+// reading the other, deadlocks the whole test.
+func newTestConnPair(t *T, handlers map[string]Handler) (*jstream.StreamReader, *jstream.StreamWriter) {
+	srvNetConn, cliNetConn := net.Pipe()
+
+	srv := NewConn(jstream.NewStreamReader(srvNetConn), jstream.NewStreamWriter(srvNetConn))
+	for method, h := range handlers {
+		srv.Handle(method, h)
+	}
+	go srv.Run(context.Background())
+
+	return jstream.NewStreamReader(cliNetConn), jstream.NewStreamWriter(cliNetConn)
+}
+
+func echoHandler() Handler {
+	return func(ctx context.Context, in *jstream.StreamReader, out *jstream.StreamWriter) error {
+		for {
+			el := NextSkipPing(in)
+			if el.Err == jstream.ErrStreamEnded {
+				return EncodeStatus(out, Status{Code: CodeOK})
+			} else if el.Err == jstream.ErrCanceled {
+				return nil
+			} else if el.Err != nil {
+				return el.Err
+			}
+
+			var s string
+			if err := el.Value(&s); err != nil {
+				return err
+			}
+			if err := out.EncodeValue(s); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func TestConnCall(t *T) {
+	cliR, cliW := newTestConnPair(t, map[string]Handler{"echo": echoHandler()})
+
+	openErrCh := make(chan error, 1)
+	go func() {
+		openErrCh <- Open(context.Background(), cliW, "echo", map[string]string{"k": "v"}, func(out *jstream.StreamWriter) error {
+			if err := out.EncodeValue("hello"); err != nil {
+				return err
+			}
+			return out.EncodeValue("world")
+		})
+	}()
+
+	respR, err := Response(cliR)
+	require.NoError(t, err)
+
+	var got []string
+	var st Status
+	for {
+		el := respR.Next()
+		if el.Err == jstream.ErrStreamEnded {
+			break
+		}
+		require.NoError(t, el.Err)
+
+		if s, ok := DecodeStatus(el); ok {
+			st = s
+			continue
+		}
+		var s string
+		require.NoError(t, el.Value(&s))
+		got = append(got, s)
+	}
+
+	assert.Equal(t, []string{"hello", "world"}, got)
+	assert.Equal(t, int64(CodeOK), st.Code)
+	require.NoError(t, <-openErrCh)
+}
+
+func TestConnMethodNotFound(t *T) {
+	cliR, cliW := newTestConnPair(t, nil)
+
+	openErrCh := make(chan error, 1)
+	go func() {
+		openErrCh <- Open(context.Background(), cliW, "nope", nil, func(out *jstream.StreamWriter) error {
+			return nil
+		})
+	}()
+
+	respR, err := Response(cliR)
+	require.NoError(t, err)
+
+	el := respR.Next()
+	require.NoError(t, el.Err)
+	st, ok := DecodeStatus(el)
+	require.True(t, ok)
+	assert.Equal(t, int64(CodeMethodNotFound), st.Code)
+
+	el = respR.Next()
+	assert.Equal(t, jstream.ErrStreamEnded, el.Err)
+	require.NoError(t, <-openErrCh)
+}
+
+func TestConnRequestCanceled(t *T) {
+	canceledCh := make(chan struct{})
+	h := Handler(func(ctx context.Context, in *jstream.StreamReader, out *jstream.StreamWriter) error {
+		el := in.Next()
+		if el.Err == jstream.ErrCanceled {
+			close(canceledCh)
+		}
+		return nil
+	})
+
+	cliR, cliW := newTestConnPair(t, map[string]Handler{"cancelme": h})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	startedCh := make(chan struct{})
+	openErrCh := make(chan error, 1)
+	go func() {
+		openErrCh <- Open(ctx, cliW, "cancelme", nil, func(out *jstream.StreamWriter) error {
+			close(startedCh)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	}()
+
+	// drain whatever the server writes back, concurrently, so neither side
+	// deadlocks on an unread write while the request is canceled below.
+	respDoneCh := make(chan struct{})
+	go func() {
+		defer close(respDoneCh)
+		respR, err := Response(cliR)
+		if err != nil {
+			return
+		}
+		for respR.Next().Err == nil {
+		}
+	}()
+
+	<-startedCh
+	cancel()
+	assert.Equal(t, context.Canceled, <-openErrCh)
+	<-canceledCh
+	<-respDoneCh
+}
+
+func TestMetaFromContext(t *T) {
+	gotMetaCh := make(chan string, 1)
+	h := Handler(func(ctx context.Context, in *jstream.StreamReader, out *jstream.StreamWriter) error {
+		gotMetaCh <- string(MetaFromContext(ctx))
+		return drainStream(in)
+	})
+
+	cliR, cliW := newTestConnPair(t, map[string]Handler{"m": h})
+
+	openErrCh := make(chan error, 1)
+	go func() {
+		openErrCh <- Open(context.Background(), cliW, "m", map[string]string{"foo": "bar"}, func(out *jstream.StreamWriter) error {
+			return nil
+		})
+	}()
+
+	// the handler only sends to gotMetaCh once its response Stream's
+	// streamStart frame has been written, which itself blocks till
+	// something reads it; drain the response concurrently so that isn't
+	// stuck waiting on the gotMetaCh receive below.
+	respErrCh := make(chan error, 1)
+	go func() {
+		respR, err := Response(cliR)
+		if err != nil {
+			respErrCh <- err
+			return
+		}
+		respErrCh <- respR.Next().Err
+	}()
+
+	assert.JSONEq(t, `{"foo":"bar"}`, <-gotMetaCh)
+	assert.Equal(t, jstream.ErrStreamEnded, <-respErrCh)
+	require.NoError(t, <-openErrCh)
+}
+
+func TestNextSkipPing(t *T) {
+	buf := new(bytes.Buffer)
+	w := jstream.NewStreamWriter(buf)
+	require.NoError(t, w.EncodeValue("before"))
+	require.NoError(t, EncodePing(w))
+	require.NoError(t, w.EncodeValue("after"))
+
+	r := jstream.NewStreamReader(buf)
+	var before, after string
+	require.NoError(t, NextSkipPing(r).Value(&before))
+	require.NoError(t, NextSkipPing(r).Value(&after))
+	assert.Equal(t, "before", before)
+	assert.Equal(t, "after", after)
+}
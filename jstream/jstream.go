@@ -1,6 +1,6 @@
 // Package jstream defines and implements the JSON Stream protocol
 //
-// Purpose
+// # Purpose
 //
 // The purpose of the jstream protocol is to provide a very simple layer on top
 // of an existing JSON implementation to allow for streaming arbitrary numbers
@@ -8,9 +8,9 @@
 // allow for embedding streams within each other.
 //
 // The order of priorities when designing jstream is as follows:
-//	1) Protocol simplicity
-//	2) Implementation simplicity
-//	3) Efficiency, both in parsing speed and bandwidth
+//  1. Protocol simplicity
+//  2. Implementation simplicity
+//  3. Efficiency, both in parsing speed and bandwidth
 //
 // The justification for this is that protocol simplicity generally spills into
 // implementation simplicity anyway, and accounts for future languages which
@@ -34,10 +34,10 @@
 //
 // There are three jstream element types:
 //
-// * JSON Value: Any JSON value
-// * Byte Blob: A stream of bytes of unknown, and possibly infinite, size
-// * Stream: A heterogenous sequence of jstream elements of unknown, and
-//   possibly infinite, size
+//   - JSON Value: Any JSON value
+//   - Byte Blob: A stream of bytes of unknown, and possibly infinite, size
+//   - Stream: A heterogenous sequence of jstream elements of unknown, and
+//     possibly infinite, size
 //
 // JSON Value elements are defined as being JSON objects with a `val` field. The
 // value of that field is the JSON Value.
@@ -61,6 +61,27 @@
 // The trailing delimeter (`$` or `!`) is required to be sent even if the hint
 // is sent.
 //
+// Alternatively, the JSON object may contain a `rawSize` field, which gives
+// the exact number of bytes in the Byte Blob. If `rawSize` is given then the
+// bytes immediately following the JSON object are raw (not base64 encoded),
+// number exactly `rawSize` of them, and are *not* followed by a trailing `$`
+// or `!`; the size itself is the framing. `rawSize` and `sizeHint` are
+// mutually exclusive.
+//
+//	{ "bytesStart":true, "rawSize":4 }l8wE
+//
+// Finally, the JSON object may instead contain a `bytesChunked` field with a
+// value of `true`, for a Byte Blob whose total size isn't known up-front but
+// which shouldn't pay base64's ~33% overhead. If `bytesChunked` is given then
+// the bytes immediately following the JSON object are, like `rawSize`, raw,
+// but are framed as a sequence of length-delimited chunks rather than a
+// single fixed-size run: each chunk is a single tag byte followed, if the tag
+// indicates a data chunk, by the chunk's length as a varint (see
+// encoding/binary.Uvarint) and then exactly that many raw bytes. The sequence
+// is terminated by either a data chunk tag with a zero length or a cancel tag
+// (which has no length or bytes of its own). `bytesChunked` is mutually
+// exclusive with both `rawSize` and `sizeHint`.
+//
 // Stream elements are defined as being a JSON object with a `streamStart` field
 // with a value of `true`. Immediately following the JSON object will be zero
 // more jstream elements of any type, possibly separated by whitespace. Finally
@@ -104,19 +125,18 @@
 // Finally, the byte stream off of which the jstream is based (i.e. the
 // io.Reader) is implicitly treated as a Stream, with the Stream ending when the
 // byte stream is closed.
-//
 package jstream
 
-// TODO figure out how to expose the json.Encoder/Decoders so that users can set
-// custom options on them (like UseNumber and whatnot)
-
 import (
+	"bytes"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"sync"
 )
 
 // byte blob constants
@@ -166,7 +186,9 @@ type element struct {
 	StreamEnd    bool `json:"streamEnd,omitempty"`
 	StreamCancel bool `json:"streamCancel,omitempty"`
 
-	SizeHint uint `json:"sizeHint,omitempty"`
+	SizeHint     uint  `json:"sizeHint,omitempty"`
+	RawSize      int64 `json:"rawSize,omitempty"`
+	BytesChunked bool  `json:"bytesChunked,omitempty"`
 }
 
 // Element is a single jstream element which is read off a StreamReader.
@@ -224,7 +246,10 @@ func (el Element) Value(i interface{}) error {
 	if err := el.assertType(TypeJSONValue); err != nil {
 		return err
 	}
-	return json.Unmarshal(el.element.Value, i)
+	if el.sr == nil || el.sr.decOpt == nil {
+		return json.Unmarshal(el.element.Value, i)
+	}
+	return el.sr.newDecoder(bytes.NewReader(el.element.Value)).Decode(i)
 }
 
 // SizeHint returns the size hint which may have been optionally sent for
@@ -234,16 +259,28 @@ func (el Element) SizeHint() uint {
 	return el.element.SizeHint
 }
 
-// Bytes returns an io.Reader which will contain the contents of a ByteBlob
-// element. The io.Reader _must_ be read till io.EOF or ErrCanceled before the
-// StreamReader may be used again.
+// BytesReader is returned by Element.Bytes. It's a plain io.Reader, with the
+// addition of ContentLength, for callers which want to pre-allocate a buffer
+// to read the Byte Blob's contents into.
+type BytesReader interface {
+	io.Reader
+
+	// ContentLength returns the exact number of bytes which will be read, if
+	// the Byte Blob was written with a known size (see
+	// StreamWriter.EncodeBytesSized), or -1 if it's unknown.
+	ContentLength() int64
+}
+
+// Bytes returns a BytesReader which will contain the contents of a ByteBlob
+// element. The BytesReader _must_ be read till io.EOF or ErrCanceled before
+// the StreamReader may be used again.
 //
 // This method should not be called more than once.
-func (el Element) Bytes() (io.Reader, error) {
+func (el Element) Bytes() (BytesReader, error) {
 	if err := el.assertType(TypeByteBlob); err != nil {
 		return nil, err
 	}
-	return el.sr.readBytes(), nil
+	return el.sr.readBytes(el.element.RawSize, el.element.BytesChunked), nil
 }
 
 // Stream returns the embedded stream represented by this Element as a
@@ -303,14 +340,53 @@ type StreamReader struct {
 
 	// only one of these can be set at a time
 	dec *json.Decoder
-	bbr *byteBlobReader
+	bbr blobReader
+
+	// set by readHead, on the very first Next call, if the JSON object it
+	// decoded to check for a streamHead handshake turned out to be the
+	// caller's first real Element instead; Next returns it before decoding
+	// anything further. See readHead.
+	firstEl *element
+
+	// set by DecoderOptions, and re-applied to every json.Decoder sr
+	// constructs (see newDecoder), since multiReader causes a fresh one to
+	// be built at every Byte Blob or embedded Stream boundary.
+	decOpt func(*json.Decoder)
 }
 
 // NewStreamReader takes an io.Reader and interprets it as a jstream Stream.
+//
+// NewStreamReader takes no options of its own: compression and Byte Blob
+// framing are negotiated entirely by the writer, via a streamHead handshake
+// element which Next transparently detects and consumes the first time it's
+// called, before decoding the caller's first real Element. See
+// StreamWriter's WithCompression and WithRawByteBlobs.
 func NewStreamReader(r io.Reader) *StreamReader {
 	return &StreamReader{orig: r}
 }
 
+// DecoderOptions registers fn to be called against sr's underlying
+// json.Decoder, so that options like UseNumber or DisallowUnknownFields can
+// be set. It must be called before the first call to Next, since that's
+// when the json.Decoder is first constructed.
+//
+// Because multiReader causes sr to discard and rebuild its json.Decoder at
+// every Byte Blob or embedded Stream boundary, fn is stored and re-applied
+// to each new one (see newDecoder) rather than being applied just once.
+func (sr *StreamReader) DecoderOptions(fn func(*json.Decoder)) {
+	sr.decOpt = fn
+}
+
+// newDecoder constructs a json.Decoder reading from r, applying decOpt if
+// one was registered via DecoderOptions.
+func (sr *StreamReader) newDecoder(r io.Reader) *json.Decoder {
+	dec := json.NewDecoder(r)
+	if sr.decOpt != nil {
+		sr.decOpt(dec)
+	}
+	return dec
+}
+
 // pulls buffered bytes out of either the json.Decoder or byteBlobReader, if
 // possible, and returns an io.MultiReader of those and orig. Will also set the
 // json.Decoder/byteBlobReader to nil if that's where the bytes came from.
@@ -338,14 +414,28 @@ func (sr *StreamReader) multiReader() io.Reader {
 // If the underlying io.Reader is closed the returned Err field will be io.EOF.
 func (sr *StreamReader) Next() Element {
 	if sr.dec == nil {
-		sr.dec = json.NewDecoder(sr.multiReader())
+		sr.dec = sr.newDecoder(sr.multiReader())
+		if err := sr.readHead(); err != nil {
+			return Element{Err: err}
+		}
+	}
+
+	if sr.firstEl != nil {
+		el := *sr.firstEl
+		sr.firstEl = nil
+		return sr.wrapElement(el)
 	}
 
 	var el element
+	if err := sr.dec.Decode(&el); err != nil {
+		return Element{Err: err}
+	}
+	return sr.wrapElement(el)
+}
+
+func (sr *StreamReader) wrapElement(el element) Element {
 	var err error
-	if err = sr.dec.Decode(&el); err != nil {
-		// welp
-	} else if el.StreamEnd {
+	if el.StreamEnd {
 		err = ErrStreamEnded
 	} else if el.StreamCancel {
 		err = ErrCanceled
@@ -356,11 +446,95 @@ func (sr *StreamReader) Next() Element {
 	return Element{sr: sr, element: el}
 }
 
-func (sr *StreamReader) readBytes() *byteBlobReader {
-	sr.bbr = newByteBlobReader(sr.multiReader())
+// readHead decodes the first JSON object off sr.dec and checks whether it's
+// a streamHead handshake element (see WithCompression). If it is, it's
+// applied (swapping in a decompressing io.Reader, if compression was
+// negotiated) and consumed. If it isn't, the object was actually the
+// caller's first real Element, so it's stashed in sr.firstEl for Next to
+// return instead of decoding a new one.
+//
+// This is called once, right after sr.dec is first initialized, so that a
+// plain StreamReader (reading from a peer which never writes a streamHead)
+// pays for the check with a single extra json.RawMessage decode rather than
+// needing the caller to opt in.
+func (sr *StreamReader) readHead() error {
+	var raw json.RawMessage
+	if err := sr.dec.Decode(&raw); err != nil {
+		return err
+	}
+
+	var sh streamHead
+	if err := json.Unmarshal(raw, &sh); err == nil && sh.StreamHead {
+		if sh.Compression == "" {
+			return nil
+		}
+		rest, err := skipLeadingWS(sr.multiReader())
+		if err != nil {
+			return err
+		}
+		r, err := sh.Compression.newReader(rest)
+		if err != nil {
+			return err
+		}
+		sr.orig = r
+		sr.dec = sr.newDecoder(sr.orig)
+		return nil
+	}
+
+	var el element
+	if err := json.Unmarshal(raw, &el); err != nil {
+		return err
+	}
+	sr.firstEl = &el
+	return nil
+}
+
+func (sr *StreamReader) readBytes(rawSize int64, chunked bool) blobReader {
+	if chunked {
+		sr.bbr = newChunkedByteBlobReader(sr.multiReader())
+	} else {
+		sr.bbr = newByteBlobReader(sr.multiReader(), rawSize)
+	}
 	return sr.bbr
 }
 
+// NextBatch decodes up to max Elements (or len(dst), whichever is smaller)
+// into dst, in the same order Next would produce them, and returns the
+// number decoded.
+//
+// This exists so that a caller reading many small Elements in a row can
+// reuse a single dst slice across calls, rather than letting each one
+// allocate and return its own. It doesn't change how much gets read off the
+// underlying io.Reader per Element; sr's json.Decoder buffer is already
+// shared across Next calls.
+//
+// NextBatch stops as soon as an error is encountered (including
+// ErrStreamEnded or ErrCanceled, same as Next), in which case the erroring
+// Element is the last one written to dst, at index n-1. It also stops right
+// after decoding a ByteBlob or Stream Element, since, per Next's own
+// contract, that Element must be fully consumed (via Element.Bytes or
+// Element.Stream) before sr can be used again, and so no further Elements
+// can be decoded into dst until then.
+func (sr *StreamReader) NextBatch(dst []Element, max int) (int, error) {
+	if max > len(dst) {
+		max = len(dst)
+	}
+
+	var n int
+	for n < max {
+		el := sr.Next()
+		dst[n] = el
+		n++
+
+		if el.Err != nil {
+			return n, el.Err
+		} else if typ, _ := el.Type(); typ != TypeJSONValue {
+			return n, nil
+		}
+	}
+	return n, nil
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 
 // StreamWriter represents a Stream to which Elements may be written using any
@@ -368,24 +542,137 @@ func (sr *StreamReader) readBytes() *byteBlobReader {
 type StreamWriter struct {
 	w   io.Writer
 	enc *json.Encoder
+
+	opts     streamWriterOpts
+	headOnce sync.Once
+	headErr  error
+
+	// set by EncoderOptions, and re-applied to every json.Encoder sw
+	// constructs (see newEncoder), since writeHead swaps in a fresh one
+	// once a compressor is wrapped in.
+	encOpt func(*json.Encoder)
 }
 
 // NewStreamWriter takes an io.Writer and returns a StreamWriter which will
 // write to it.
-func NewStreamWriter(w io.Writer) *StreamWriter {
-	return &StreamWriter{w: w, enc: json.NewEncoder(w)}
+//
+// By default no compression is used and Byte Blobs are base64 encoded; see
+// WithCompression and WithRawByteBlobs to change either.
+func NewStreamWriter(w io.Writer, options ...StreamWriterOption) *StreamWriter {
+	var opts streamWriterOpts
+	for _, opt := range options {
+		opt(&opts)
+	}
+	sw := &StreamWriter{w: w, opts: opts}
+	sw.enc = sw.newEncoder(w)
+	return sw
+}
+
+// EncoderOptions registers fn to be called against sw's underlying
+// json.Encoder, so that options like SetEscapeHTML or SetIndent can be set.
+// fn is applied immediately, and again to any json.Encoder sw constructs
+// later (see newEncoder), since writeHead discards and rebuilds it once a
+// compressor is wrapped in via WithCompression.
+func (sw *StreamWriter) EncoderOptions(fn func(*json.Encoder)) {
+	sw.encOpt = fn
+	if sw.encOpt != nil {
+		sw.encOpt(sw.enc)
+	}
+}
+
+// newEncoder constructs a json.Encoder writing to w, applying encOpt if one
+// was registered via EncoderOptions.
+func (sw *StreamWriter) newEncoder(w io.Writer) *json.Encoder {
+	enc := json.NewEncoder(w)
+	if sw.encOpt != nil {
+		sw.encOpt(enc)
+	}
+	return enc
+}
+
+// writeHead writes the streamHead handshake element, if opts calls for one,
+// and wraps sw.w/sw.enc with a compressor, if one was negotiated. It's
+// called at the top of every Encode* method, but only does any of this the
+// first time, via headOnce, so that a StreamWriter with no options behaves
+// exactly as it did before this existed.
+func (sw *StreamWriter) writeHead() error {
+	sw.headOnce.Do(func() {
+		if sw.opts.compression == "" && !sw.opts.rawByteBlobs {
+			return
+		}
+
+		sw.headErr = sw.enc.Encode(streamHead{
+			StreamHead:   true,
+			Compression:  sw.opts.compression,
+			RawByteBlobs: sw.opts.rawByteBlobs,
+		})
+		if sw.headErr != nil || sw.opts.compression == "" {
+			return
+		}
+
+		cw, err := sw.opts.compression.newWriter(sw.w)
+		if err != nil {
+			sw.headErr = err
+			return
+		}
+		sw.w = &autoFlushWriter{w: cw}
+		sw.enc = sw.newEncoder(sw.w)
+	})
+	return sw.headErr
 }
 
 // EncodeValue marshals the given value and writes it to the Stream as a
 // JSONValue element.
 func (sw *StreamWriter) EncodeValue(i interface{}) error {
-	b, err := json.Marshal(i)
+	if err := sw.writeHead(); err != nil {
+		return err
+	}
+	b, err := sw.marshalValue(i)
 	if err != nil {
 		return err
 	}
 	return sw.enc.Encode(element{Value: b})
 }
 
+// marshalValue marshals i the same way EncodeValues does, via an encoder
+// constructed with newEncoder, so that options registered via
+// EncoderOptions (e.g. SetEscapeHTML) are honored no matter which Encode*
+// method is used.
+func (sw *StreamWriter) marshalValue(i interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := sw.newEncoder(buf).Encode(i); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// EncodeValues is like EncodeValue, but writes vals as a sequence of
+// JSONValue elements in one call, marshaling each one into a single reused
+// buffer instead of letting every EncodeValue call allocate its own via
+// json.Marshal.
+func (sw *StreamWriter) EncodeValues(vals []interface{}) error {
+	if err := sw.writeHead(); err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	valEnc := sw.newEncoder(buf)
+
+	for _, v := range vals {
+		buf.Reset()
+		if err := valEnc.Encode(v); err != nil {
+			return err
+		}
+
+		// trim the trailing newline json.Encoder.Encode always adds
+		b := bytes.TrimRight(buf.Bytes(), "\n")
+		if err := sw.enc.Encode(element{Value: b}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // EncodeBytes copies the given io.Reader, until io.EOF, onto the Stream as a
 // ByteBlob element. This method will block until copying is completed or an
 // error is encountered.
@@ -396,7 +683,19 @@ func (sw *StreamWriter) EncodeValue(i interface{}) error {
 //
 // sizeHint may be given if it's known or can be guessed how many bytes the
 // io.Reader will read out.
+//
+// If the StreamWriter was constructed with WithRawByteBlobs this instead
+// writes r using EncodeBytesChunked's framing, skipping base64 (and
+// sizeHint, which that framing has no use for) entirely.
 func (sw *StreamWriter) EncodeBytes(sizeHint uint, r io.Reader) error {
+	if sw.opts.rawByteBlobs {
+		return sw.EncodeBytesChunked(r)
+	}
+
+	if err := sw.writeHead(); err != nil {
+		return err
+	}
+
 	if err := sw.enc.Encode(element{
 		BytesStart: true,
 		SizeHint:   sizeHint,
@@ -421,6 +720,118 @@ func (sw *StreamWriter) EncodeBytes(sizeHint uint, r io.Reader) error {
 	return nil
 }
 
+// EncodeBytesSized is like EncodeBytes, but size must be the exact number of
+// bytes r will produce. Knowing the size up front lets the bytes be written
+// raw, skipping EncodeBytes's base64 encoding, and lets the peer's
+// BytesReader pre-allocate a buffer and know when it's done without having to
+// scan for a trailing delimiter (see BytesReader.ContentLength).
+//
+// Unlike EncodeBytes, there's no way to cancel an EncodeBytesSized part way
+// through: the size has already been committed to the peer as the Byte
+// Blob's framing. If r returns an error, or doesn't produce exactly size
+// bytes, that error (or a new one, in the latter case) is returned and the
+// StreamWriter should be considered broken and discarded.
+func (sw *StreamWriter) EncodeBytesSized(size int64, r io.Reader) error {
+	if err := sw.writeHead(); err != nil {
+		return err
+	}
+
+	if err := sw.enc.Encode(element{
+		BytesStart: true,
+		RawSize:    size,
+	}); err != nil {
+		return err
+	}
+
+	n, err := io.Copy(sw.w, io.LimitReader(r, size))
+	if err != nil {
+		return err
+	} else if n != size {
+		return fmt.Errorf("jstream: EncodeBytesSized wrote %d of %d expected bytes", n, size)
+	}
+	return nil
+}
+
+// EncodeBytesChunked is like EncodeBytes, but, like EncodeBytesSized, writes
+// r's bytes raw rather than base64 encoding them. Unlike EncodeBytesSized it
+// doesn't require r's total size to be known up-front: r is copied in chunks
+// of up to 32KiB, each written as its own length-delimited frame, terminated
+// by a zero-length chunk once r returns io.EOF. This also means, unlike
+// EncodeBytesSized, that a chunked Byte Blob _can_ still be canceled part way
+// through, the same as one written with EncodeBytes.
+//
+// If the io.Reader returns any error which isn't io.EOF then the Byte Blob is
+// canceled and that error is returned from this method. Otherwise nil is
+// returned.
+func (sw *StreamWriter) EncodeBytesChunked(r io.Reader) error {
+	if err := sw.writeHead(); err != nil {
+		return err
+	}
+
+	if err := sw.enc.Encode(element{
+		BytesStart:   true,
+		BytesChunked: true,
+	}); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 32*1024)
+	varintBuf := make([]byte, binary.MaxVarintLen64)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if wErr := sw.writeChunk(varintBuf, buf[:n]); wErr != nil {
+				return wErr
+			}
+		}
+		if err == io.EOF {
+			return sw.writeChunk(varintBuf, nil)
+		} else if err != nil {
+			// as with EncodeBytes, this is best-effort; if it fails the
+			// StreamWriter is broken regardless.
+			sw.w.Write([]byte{chunkTagCancel})
+			return err
+		}
+	}
+}
+
+// writeChunk writes a single chunkTagData frame, using varintBuf as scratch
+// space for the chunk's varint-encoded length. A nil/empty chunk is the
+// zero-length chunk which terminates a chunked Byte Blob.
+func (sw *StreamWriter) writeChunk(varintBuf, chunk []byte) error {
+	n := binary.PutUvarint(varintBuf, uint64(len(chunk)))
+	if _, err := sw.w.Write(append([]byte{chunkTagData}, varintBuf[:n]...)); err != nil {
+		return err
+	}
+	if len(chunk) == 0 {
+		return nil
+	}
+	_, err := sw.w.Write(chunk)
+	return err
+}
+
+// Cancel writes the same delimiter EncodeBytes (or, if this StreamWriter
+// was constructed with WithRawByteBlobs, EncodeBytesChunked) would use to
+// prematurely cancel a ByteBlob, directly to the underlying io.Writer,
+// bypassing the JSON encoder entirely.
+//
+// This is intended for use by code which needs to abort a call sitting atop
+// a StreamWriter (e.g. because its Context was canceled) without access to
+// whatever Encode* call may currently be blocked writing to it. Since that
+// delimiter is meaningless outside of an in-progress ByteBlob, the peer's
+// StreamReader will desync and return some error off of its next Next or
+// Bytes call (exactly which depends on what was being written when Cancel
+// was called); as with EncodeBytes's own best-effort cancellation, the
+// StreamWriter should be considered broken and discarded afterwards.
+func (sw *StreamWriter) Cancel() error {
+	b := byte(bbCancel)
+	if sw.opts.rawByteBlobs {
+		b = chunkTagCancel
+	}
+	_, err := sw.w.Write([]byte{b})
+	return err
+}
+
 // EncodeStream encodes an embedded Stream element onto the Stream. The callback
 // is given a new StreamWriter which represents the embedded Stream and to which
 // any elemens may be written. This methods blocks until the callback has
@@ -433,6 +844,10 @@ func (sw *StreamWriter) EncodeBytes(sizeHint uint, r io.Reader) error {
 // sizeHint may be given if it's known or can be guessed how many elements will
 // be in the embedded Stream.
 func (sw *StreamWriter) EncodeStream(sizeHint uint, fn func(*StreamWriter) error) error {
+	if err := sw.writeHead(); err != nil {
+		return err
+	}
+
 	if err := sw.enc.Encode(element{
 		StreamStart: true,
 		SizeHint:    sizeHint,
@@ -446,3 +861,14 @@ func (sw *StreamWriter) EncodeStream(sizeHint uint, fn func(*StreamWriter) error
 	}
 	return sw.enc.Encode(element{StreamEnd: true})
 }
+
+// EncodeStreamSized is like EncodeStream, but, unlike sizeHint, size is
+// guaranteed to be the exact number of elements fn will write rather than
+// just an estimate. It's still sent as the element's sizeHint, since a Stream
+// element's contents are always individually JSON-framed and so, unlike a
+// Byte Blob, there's no raw encoding for a known count to unlock; this exists
+// so that a size known to be exact can be passed in as an int, rather than
+// forcing the caller to convert it to a uint sizeHint themselves.
+func (sw *StreamWriter) EncodeStreamSized(size int64, fn func(*StreamWriter) error) error {
+	return sw.EncodeStream(uint(size), fn)
+}
@@ -244,3 +244,71 @@ func TestEncoderDecoder(t *T) {
 		do(tc.stream...)
 	}
 }
+
+func TestEncodeValues(t *T) {
+	vals := []interface{}{"foo", 42, map[string]interface{}{"a": "b"}}
+
+	buf := new(bytes.Buffer)
+	w := NewStreamWriter(buf)
+	assert.NoError(t, w.EncodeValues(vals))
+
+	r := NewStreamReader(buf)
+	for _, expected := range vals {
+		el := r.Next()
+		assert.NoError(t, el.Err)
+
+		var got interface{}
+		assert.NoError(t, el.Value(&got))
+		assert.Equal(t, expected, got)
+	}
+	assert.Equal(t, io.EOF, r.Next().Err)
+}
+
+func TestNextBatch(t *T) {
+	buf := new(bytes.Buffer)
+	w := NewStreamWriter(buf)
+	assert.NoError(t, w.EncodeValue("foo"))
+	assert.NoError(t, w.EncodeValue("bar"))
+	assert.NoError(t, w.EncodeBytes(0, bytes.NewBufferString("baz")))
+	assert.NoError(t, w.EncodeValue("biz"))
+
+	r := NewStreamReader(buf)
+
+	// max larger than len(dst) should be capped at len(dst)
+	dst := make([]Element, 2)
+	n, err := r.NextBatch(dst, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	var got0, got1 string
+	assert.NoError(t, dst[0].Value(&got0))
+	assert.NoError(t, dst[1].Value(&got1))
+	assert.Equal(t, "foo", got0)
+	assert.Equal(t, "bar", got1)
+
+	// the ByteBlob should stop the batch after being decoded, even though
+	// there's room left in dst
+	n, err = r.NextBatch(dst, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	typ, err := dst[0].Type()
+	assert.NoError(t, err)
+	assert.Equal(t, TypeByteBlob, typ)
+
+	br, err := dst[0].Bytes()
+	assert.NoError(t, err)
+	all, err := ioutil.ReadAll(br)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("baz"), all)
+
+	n, err = r.NextBatch(dst, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	var got3 string
+	assert.NoError(t, dst[0].Value(&got3))
+	assert.Equal(t, "biz", got3)
+
+	n, err = r.NextBatch(dst, 2)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 1, n)
+}
@@ -0,0 +1,74 @@
+package jstream
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	. "testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecoderOptionsUseNumber(t *T) {
+	buf := new(bytes.Buffer)
+	w := NewStreamWriter(buf)
+	assert.NoError(t, w.EncodeValue(42))
+
+	r := NewStreamReader(buf)
+	r.DecoderOptions(func(dec *json.Decoder) {
+		dec.UseNumber()
+	})
+
+	var got interface{}
+	assert.NoError(t, r.Next().Value(&got))
+	assert.Equal(t, json.Number("42"), got)
+}
+
+func TestDecoderOptionsReappliedAcrossByteBlob(t *T) {
+	buf := new(bytes.Buffer)
+	w := NewStreamWriter(buf)
+	assert.NoError(t, w.EncodeBytes(0, bytes.NewReader([]byte("blob"))))
+	assert.NoError(t, w.EncodeValue(42))
+
+	r := NewStreamReader(buf)
+	r.DecoderOptions(func(dec *json.Decoder) {
+		dec.UseNumber()
+	})
+
+	el := r.Next()
+	assert.NoError(t, el.Err)
+	br, err := el.Bytes()
+	assert.NoError(t, err)
+	_, err = ioutil.ReadAll(br)
+	assert.NoError(t, err)
+
+	// the json.Decoder reading the next element was rebuilt after the Byte
+	// Blob boundary; UseNumber must still be in effect on it.
+	var got interface{}
+	assert.NoError(t, r.Next().Value(&got))
+	assert.Equal(t, json.Number("42"), got)
+}
+
+func TestEncoderOptionsSetEscapeHTML(t *T) {
+	buf := new(bytes.Buffer)
+	w := NewStreamWriter(buf)
+	w.EncoderOptions(func(enc *json.Encoder) {
+		enc.SetEscapeHTML(false)
+	})
+	assert.NoError(t, w.EncodeValue("<b>"))
+	assert.Contains(t, buf.String(), "<b>")
+}
+
+func TestEncoderOptionsReappliedAfterCompression(t *T) {
+	buf := new(bytes.Buffer)
+	w := NewStreamWriter(buf, WithCompression(Zstd))
+	w.EncoderOptions(func(enc *json.Encoder) {
+		enc.SetEscapeHTML(false)
+	})
+	assert.NoError(t, w.EncodeValue("<b>"))
+
+	r := NewStreamReader(buf)
+	var got string
+	assert.NoError(t, r.Next().Value(&got))
+	assert.Equal(t, "<b>", got)
+}
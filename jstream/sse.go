@@ -0,0 +1,268 @@
+package jstream
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sseWriterOpts holds the options configurable via SSEWriterOpt.
+type sseWriterOpts struct {
+	keepAlive time.Duration
+	retry     time.Duration
+}
+
+var defaultSSEWriterOpts = sseWriterOpts{
+	keepAlive: 15 * time.Second,
+	retry:     3 * time.Second,
+}
+
+// SSEWriterOpt is a value which adjusts the behavior of NewSSEWriter.
+type SSEWriterOpt func(*sseWriterOpts)
+
+// SSEWriterKeepAlive sets the interval at which an SSEWriter will write a
+// comment-only keepalive frame if no Element has been written within it,
+// keeping an idle connection from being timed out by the client or an
+// intermediate proxy. A value of 0 disables keepalives entirely.
+//
+// The default is 15 seconds.
+func SSEWriterKeepAlive(d time.Duration) SSEWriterOpt {
+	return func(opts *sseWriterOpts) {
+		opts.keepAlive = d
+	}
+}
+
+// SSEWriterRetry sets the "retry" field written at the start of the stream,
+// which tells a reconnecting SSE client (e.g. a browser's EventSource) how
+// long to wait before reconnecting.
+//
+// The default is 3 seconds.
+func SSEWriterRetry(d time.Duration) SSEWriterOpt {
+	return func(opts *sseWriterOpts) {
+		opts.retry = d
+	}
+}
+
+// sseFrameWriter is an io.Writer which wraps every Write in a Server-Sent
+// Events frame and flushes it immediately, so that it may be used as the
+// underlying io.Writer of a StreamWriter.
+type sseFrameWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher // may be nil
+
+	l  sync.Mutex
+	id uint64
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+}
+
+func newSSEFrameWriter(w http.ResponseWriter, opts sseWriterOpts) *sseFrameWriter {
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+
+	flusher, _ := w.(http.Flusher)
+	fw := &sseFrameWriter{
+		w:       w,
+		flusher: flusher,
+		stopCh:  make(chan struct{}),
+	}
+
+	fmt.Fprintf(fw.w, "retry: %d\n\n", opts.retry.Milliseconds())
+	fw.flush()
+
+	if opts.keepAlive > 0 {
+		go fw.keepAliveLoop(opts.keepAlive)
+	}
+
+	return fw
+}
+
+func (fw *sseFrameWriter) flush() {
+	if fw.flusher != nil {
+		fw.flusher.Flush()
+	}
+}
+
+func (fw *sseFrameWriter) keepAliveLoop(d time.Duration) {
+	t := time.NewTicker(d)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			fw.l.Lock()
+			fmt.Fprint(fw.w, ": keepalive\n\n")
+			fw.flush()
+			fw.l.Unlock()
+		case <-fw.stopCh:
+			return
+		}
+	}
+}
+
+func (fw *sseFrameWriter) stop() {
+	fw.stopOnce.Do(func() { close(fw.stopCh) })
+}
+
+// Write implements the io.Writer interface. p is base64 encoded (so that it
+// may contain arbitrary bytes, e.g. the raw JSON/byte-blob wire format a
+// StreamWriter writes) and sent as a single "data" field, tagged with an
+// incrementing "id" field which an SSEReader on the other end can later
+// report back as a resume token (see SSEReader.LastEventID).
+//
+// If p contains a byte blob cancellation (bbCancel) or a Stream cancellation
+// (element.StreamCancel), the frame is additionally tagged with
+// "event: cancel", so that a browser's EventSource can react to the
+// cancellation without having to decode and inspect the jstream wire format
+// itself.
+func (fw *sseFrameWriter) Write(p []byte) (int, error) {
+	fw.l.Lock()
+	defer fw.l.Unlock()
+
+	fw.id++
+
+	if bytes.IndexByte(p, bbCancel) >= 0 || bytes.Contains(p, []byte(`"streamCancel":true`)) {
+		fmt.Fprint(fw.w, "event: cancel\n")
+	}
+
+	fmt.Fprintf(fw.w, "id: %d\n", fw.id)
+	fmt.Fprintf(fw.w, "data: %s\n\n", base64.StdEncoding.EncodeToString(p))
+	fw.flush()
+
+	return len(p), nil
+}
+
+// SSEWriter is a StreamWriter whose Elements are written out formatted as
+// Server-Sent Events, the inverse of SSEReader. It must be Closed once no
+// longer needed, to stop its keepalive goroutine (see SSEWriterKeepAlive).
+type SSEWriter struct {
+	*StreamWriter
+	fw *sseFrameWriter
+}
+
+// NewSSEWriter returns an SSEWriter which writes its Elements to w formatted
+// as Server-Sent Events
+// (https://html.spec.whatwg.org/multipage/server-sent-events.html), so that a
+// jstream producer may be consumed directly by a browser's EventSource, or
+// any other SSE client, in addition to another jstream consumer (via
+// NewSSEReader).
+func NewSSEWriter(w http.ResponseWriter, opts ...SSEWriterOpt) *SSEWriter {
+	o := defaultSSEWriterOpts
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	fw := newSSEFrameWriter(w, o)
+	return &SSEWriter{
+		StreamWriter: NewStreamWriter(fw),
+		fw:           fw,
+	}
+}
+
+// Close stops this SSEWriter's keepalive goroutine. It should always be
+// called, generally via defer, once the SSEWriter is no longer needed.
+func (sw *SSEWriter) Close() error {
+	sw.fw.stop()
+	return nil
+}
+
+// sseFrameReader is an io.Reader which parses Server-Sent Events frames off
+// of an underlying io.Reader and reconstructs the original bytes which were
+// written to them (by an sseFrameWriter), so that it may be used as the
+// underlying io.Reader of a StreamReader.
+type sseFrameReader struct {
+	br  *bufio.Reader
+	buf bytes.Buffer
+
+	lastEventID string
+	pendingErr  error
+}
+
+func newSSEFrameReader(r io.Reader) *sseFrameReader {
+	return &sseFrameReader{br: bufio.NewReader(r)}
+}
+
+// processLine handles a single line of the SSE frame (with any trailing
+// line-ending already stripped), accumulating any "data" field's decoded
+// bytes into fr.buf and recording the most recent "id" field seen.
+func (fr *sseFrameReader) processLine(line string) error {
+	switch {
+	case line == "", strings.HasPrefix(line, ":"):
+		// a blank line ends a message, and a line starting with ":" is a
+		// comment (e.g. a keepalive); neither affects the byte stream.
+	case strings.HasPrefix(line, "id:"):
+		fr.lastEventID = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+	case strings.HasPrefix(line, "data:"):
+		b64 := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		decoded, err := base64.StdEncoding.DecodeString(b64)
+		if err != nil {
+			return fmt.Errorf("decoding sse data field: %w", err)
+		}
+		fr.buf.Write(decoded)
+	default:
+		// "event" and "retry" fields aren't needed to reconstruct the
+		// underlying jstream byte stream; they exist for the benefit of
+		// browser/EventSource clients consuming the SSEWriter directly.
+	}
+	return nil
+}
+
+func (fr *sseFrameReader) fill() {
+	for fr.buf.Len() == 0 && fr.pendingErr == nil {
+		line, err := fr.br.ReadString('\n')
+		if line != "" {
+			if perr := fr.processLine(strings.TrimRight(line, "\r\n")); perr != nil {
+				fr.pendingErr = perr
+				return
+			}
+		}
+		if err != nil {
+			fr.pendingErr = err
+			return
+		}
+	}
+}
+
+func (fr *sseFrameReader) Read(p []byte) (int, error) {
+	fr.fill()
+	if fr.buf.Len() > 0 {
+		return fr.buf.Read(p)
+	}
+	return 0, fr.pendingErr
+}
+
+// SSEReader is a StreamReader whose Elements are read out of a Server-Sent
+// Events formatted io.Reader, the inverse of SSEWriter.
+type SSEReader struct {
+	*StreamReader
+	fr *sseFrameReader
+}
+
+// NewSSEReader takes an io.Reader of Server-Sent Events frames, as written by
+// an SSEWriter, and returns an SSEReader which can be used to read the
+// underlying jstream Elements out of it.
+func NewSSEReader(r io.Reader) *SSEReader {
+	fr := newSSEFrameReader(r)
+	return &SSEReader{
+		StreamReader: NewStreamReader(fr),
+		fr:           fr,
+	}
+}
+
+// LastEventID returns the most recently seen SSE "id" field off of the
+// underlying io.Reader, which may be used as a resume token: if this
+// SSEReader's connection is lost, reconnecting and setting the
+// Last-Event-ID header to this value (which a browser's EventSource does
+// automatically on reconnect) allows a cooperating SSEWriter-based handler
+// to pick up the Stream from this point, if it supports doing so.
+func (sr *SSEReader) LastEventID() string {
+	return sr.fr.lastEventID
+}
@@ -0,0 +1,87 @@
+package jstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http/httptest"
+	. "testing"
+
+	"github.com/mediocregopher/mediocre-go-lib/mrand"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSEWriterReader(t *T) {
+	rec := httptest.NewRecorder()
+	sw := NewSSEWriter(rec, SSEWriterKeepAlive(0))
+	defer sw.Close()
+
+	val := map[string]string{"foo": "bar"}
+	body := mrand.Bytes(256)
+
+	assert.NoError(t, sw.EncodeValue(val))
+	assert.NoError(t, sw.EncodeBytes(uint(len(body)), bytes.NewBuffer(body)))
+	assert.NoError(t, sw.EncodeStream(1, func(innerW *StreamWriter) error {
+		return innerW.EncodeValue(val)
+	}))
+
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+
+	sr := NewSSEReader(rec.Body)
+
+	var gotVal map[string]string
+	el := sr.Next()
+	assert.NoError(t, el.Err)
+	assert.NoError(t, el.Value(&gotVal))
+	assert.Equal(t, val, gotVal)
+
+	el = sr.Next()
+	assert.NoError(t, el.Err)
+	br, err := el.Bytes()
+	assert.NoError(t, err)
+	gotBody := new(bytes.Buffer)
+	_, err = gotBody.ReadFrom(br)
+	assert.NoError(t, err)
+	assert.Equal(t, body, gotBody.Bytes())
+
+	el = sr.Next()
+	assert.NoError(t, el.Err)
+	innerR, err := el.Stream()
+	assert.NoError(t, err)
+	innerEl := innerR.Next()
+	assert.NoError(t, innerEl.Err)
+	assert.NoError(t, innerEl.Value(&gotVal))
+	assert.Equal(t, val, gotVal)
+	assert.Equal(t, ErrStreamEnded, innerR.Next().Err)
+
+	assert.NotEmpty(t, sr.LastEventID())
+}
+
+func TestSSEWriterCancel(t *T) {
+	rec := httptest.NewRecorder()
+	sw := NewSSEWriter(rec, SSEWriterKeepAlive(0))
+	defer sw.Close()
+
+	body := mrand.Bytes(64)
+	err := sw.EncodeBytes(uint(len(body)), io.MultiReader(
+		bytes.NewBuffer(body),
+		errReader{},
+	))
+	assert.Error(t, err)
+	assert.Contains(t, rec.Body.String(), "event: cancel")
+
+	sr := NewSSEReader(rec.Body)
+	el := sr.Next()
+	assert.NoError(t, el.Err)
+	br, err := el.Bytes()
+	assert.NoError(t, err)
+	_, err = io.Copy(ioutil.Discard, br)
+	assert.Equal(t, ErrCanceled, err)
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("read error")
+}
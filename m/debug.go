@@ -0,0 +1,137 @@
+package m
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	_ "expvar" // registers /debug/vars on http.DefaultServeMux
+	"fmt"
+	"net"
+	"net/http"
+	_ "net/http/pprof" // registers /debug/pprof/* on http.DefaultServeMux
+	"strings"
+
+	"github.com/mediocregopher/mediocre-go-lib/mcfg"
+	"github.com/mediocregopher/mediocre-go-lib/mcmp"
+	"github.com/mediocregopher/mediocre-go-lib/merr"
+	"github.com/mediocregopher/mediocre-go-lib/mlog"
+	"github.com/mediocregopher/mediocre-go-lib/mrun"
+)
+
+// instDebugServer sets up the debug HTTP endpoint used by
+// RootServiceComponent: pprof and expvar (both registered onto
+// http.DefaultServeMux by their respective packages), plus routes specific to
+// this framework for inspecting the resolved configuration, the component
+// tree, and the current log level.
+func instDebugServer(cmp *mcmp.Component) {
+	cmp = cmp.Child("debug")
+
+	addr := mcfg.String(cmp, "addr",
+		mcfg.ParamDefault("127.0.0.1:4444"),
+		mcfg.ParamUsage("Address to listen on for debug endpoints (pprof, expvar, config, etc...)."))
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/pprof/", http.DefaultServeMux)
+	mux.Handle("/debug/vars", http.DefaultServeMux)
+	mux.HandleFunc("/debug/config", newDebugConfigHandler(cmp))
+	mux.HandleFunc("/debug/components", newDebugComponentsHandler(cmp))
+	mux.HandleFunc("/debug/log-level", newDebugLogLevelHandler(cmp))
+
+	srv := &http.Server{Handler: mux}
+	var listener net.Listener
+
+	mrun.InitHook(cmp, func(ctx context.Context) error {
+		cmp.Annotate("addr", *addr)
+		var err error
+		if listener, err = net.Listen("tcp", *addr); err != nil {
+			return merr.Wrap(cmp.Context(), err)
+		}
+
+		go func() {
+			mlog.From(cmp).Info(ctx, "serving debug endpoints")
+			if err := srv.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+				mlog.From(cmp).Error(ctx, "error serving debug listener", err)
+			}
+		}()
+		return nil
+	})
+
+	mrun.ShutdownHook(cmp, func(ctx context.Context) error {
+		mlog.From(cmp).Info(ctx, "shutting down debug server")
+		if err := srv.Shutdown(ctx); err != nil {
+			return merr.Wrap(cmp.Context(), err)
+		}
+		return nil
+	})
+}
+
+func newDebugConfigHandler(cmp *mcmp.Component) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type paramJSON struct {
+			Path     string      `json:"path"`
+			Name     string      `json:"name"`
+			Usage    string      `json:"usage"`
+			Required bool        `json:"required"`
+			Value    interface{} `json:"value"`
+		}
+
+		params := mcfg.CollectParams(cmp)
+		out := make([]paramJSON, len(params))
+		for i, param := range params {
+			out[i] = paramJSON{
+				Path:     strings.Join(param.Component.Path(), "-"),
+				Name:     param.Name,
+				Usage:    param.Usage,
+				Required: param.Required,
+				Value:    param.Into,
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+	}
+}
+
+func newDebugComponentsHandler(cmp *mcmp.Component) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type cmpJSON struct {
+			Path     string    `json:"path"`
+			Children []cmpJSON `json:"children,omitempty"`
+		}
+
+		var walk func(*mcmp.Component) cmpJSON
+		walk = func(c *mcmp.Component) cmpJSON {
+			out := cmpJSON{Path: strings.Join(c.Path(), "-")}
+			for _, child := range c.Children() {
+				out.Children = append(out.Children, walk(child))
+			}
+			return out
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(walk(cmp))
+	}
+}
+
+func newDebugLogLevelHandler(cmp *mcmp.Component) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := mlog.GetLogger(cmp)
+
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprintln(w, logger.MaxLevelUint())
+		case http.MethodPut, http.MethodPost:
+			body := make([]byte, 64)
+			n, _ := r.Body.Read(body)
+			lvl, err := mlog.LevelFromString(strings.TrimSpace(string(body[:n])))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			logger.SetMaxLevel(lvl)
+			fmt.Fprintln(w, lvl)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
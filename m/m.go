@@ -7,6 +7,7 @@ import (
 	"context"
 	"os"
 	"os/signal"
+	"strings"
 	"time"
 
 	"github.com/mediocregopher/mediocre-go-lib/mcfg"
@@ -100,7 +101,29 @@ func RootServiceComponent() *mcmp.Component {
 	// info logs for long-running services.
 	cmp.SetValue(cmpKeyInfoLog, true)
 
-	// TODO set up the debug endpoint.
+	// additional, third-party configuration sources (e.g. etcd, Consul,
+	// Vault) can be layered on by name, without RootServiceComponent needing
+	// to import any of them directly. See mcfg.RegisterSource.
+	configSources := mcfg.String(cmp, "config-source",
+		mcfg.ParamDefault(""),
+		mcfg.ParamUsage("Comma-separated list of URIs (e.g. \"consul://127.0.0.1:8500/myapp\") "+
+			"to additionally source configuration from, resolved via mcfg.SourceFromURI."))
+	mrun.InitHook(cmp, func(context.Context) error {
+		if *configSources == "" {
+			return nil
+		}
+
+		srcs, err := mcfg.SourcesFromURIs(strings.Split(*configSources, ",")...)
+		if err != nil {
+			return merr.Wrap(err, cmp.Context())
+		} else if err := mcfg.Populate(cmp, srcs); err != nil {
+			return merr.Wrap(err, cmp.Context())
+		}
+		return nil
+	})
+
+	instDebugServer(cmp)
+
 	return cmp
 }
 
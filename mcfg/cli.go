@@ -1,14 +1,15 @@
 package mcfg
 
 import (
-	"context"
 	"fmt"
 	"io"
 	"os"
 	"reflect"
 	"sort"
 	"strings"
+	"sync"
 
+	"github.com/mediocregopher/mediocre-go-lib/mcmp"
 	"github.com/mediocregopher/mediocre-go-lib/mctx"
 	"github.com/mediocregopher/mediocre-go-lib/merr"
 )
@@ -25,64 +26,60 @@ type cliTail struct {
 	descr string
 }
 
-// WithCLITail returns a Context which modifies the behavior of SourceCLI's
-// Parse. Normally when SourceCLI encounters an unexpected Arg it will
-// immediately return an error. This function modifies the Context to indicate
-// to Parse that the unexpected Arg, and all subsequent Args (i.e. the tail),
-// should be set to the returned []string value.
+// CLITail adjusts the behavior of SourceCLI's Parse for the given Component.
+// Normally when SourceCLI encounters an unexpected Arg it will immediately
+// return an error. This function adjusts cmp to indicate to Parse that the
+// unexpected Arg, and all subsequent Args (i.e. the tail), should be set to
+// the returned []string value.
 //
 // The descr (optional) will be appended to the "Usage" line which is printed
 // with the help document when "-h" is passed in.
-func WithCLITail(ctx context.Context, descr string) (context.Context, *[]string) {
-	if ctx.Value(cliKeyTail) != nil {
-		panic("WithCLITail already called in this Context")
-	}
+func CLITail(cmp *mcmp.Component, descr string) *[]string {
 	tailPtr := new([]string)
-	ctx = context.WithValue(ctx, cliKeyTail, cliTail{
+	cmp.SetValue(cliKeyTail, cliTail{
 		dst:   tailPtr,
 		descr: descr,
 	})
-	return ctx, tailPtr
+	return tailPtr
 }
 
-func populateCLITail(ctx context.Context, tail []string) bool {
-	ct, ok := ctx.Value(cliKeyTail).(cliTail)
+func populateCLITail(cmp *mcmp.Component, tail []string) bool {
+	ct, ok := cmp.Value(cliKeyTail).(cliTail)
 	if ok {
 		*ct.dst = tail
 	}
 	return ok
 }
 
-func getCLITailDescr(ctx context.Context) string {
-	ct, _ := ctx.Value(cliKeyTail).(cliTail)
+func getCLITailDescr(cmp *mcmp.Component) string {
+	ct, _ := cmp.Value(cliKeyTail).(cliTail)
 	return ct.descr
 }
 
 type subCmd struct {
 	name, descr string
 	flag        *bool
-	callback    func(context.Context) context.Context
+	callback    func(*mcmp.Component)
 }
 
-// WithCLISubCommand establishes a sub-command which can be activated on the
+// CLISubCommand establishes a sub-command which can be activated on the
 // command-line. When a sub-command is given on the command-line, the bool
 // returned for that sub-command will be set to true.
 //
-// Additionally, the Context which was passed into Parse (i.e. the one passed
-// into Populate) will be passed into the given callback, and the returned one
-// used for subsequent parsing. This allows for setting sub-command specific
-// Params, sub-command specific runtime behavior (via mrun.WithStartHook),
-// support for sub-sub-commands, and more. The callback may be nil.
+// Additionally, cmp will be passed into the given callback, which may use it
+// to add sub-command specific Params, sub-command specific runtime behavior
+// (via mrun.InitHook), support for sub-sub-commands (by calling
+// CLISubCommand again), and more. The callback may be nil.
 //
-// If any sub-commands have been defined on a Context which is passed into
-// Parse, it is assumed that a sub-command is required on the command-line.
+// If any sub-commands have been defined on a Component which is passed into
+// Populate, it is assumed that a sub-command is required on the command-line.
 //
 // Sub-commands must be specified before any other options on the command-line.
-func WithCLISubCommand(ctx context.Context, name, descr string, callback func(context.Context) context.Context) (context.Context, *bool) {
-	m, _ := ctx.Value(cliKeySubCmdM).(map[string]subCmd)
+func CLISubCommand(cmp *mcmp.Component, name, descr string, callback func(*mcmp.Component)) *bool {
+	m, _ := cmp.Value(cliKeySubCmdM).(map[string]subCmd)
 	if m == nil {
 		m = map[string]subCmd{}
-		ctx = context.WithValue(ctx, cliKeySubCmdM, m)
+		cmp.SetValue(cliKeySubCmdM, m)
 	}
 
 	flag := new(bool)
@@ -92,7 +89,7 @@ func WithCLISubCommand(ctx context.Context, name, descr string, callback func(co
 		flag:     flag,
 		callback: callback,
 	}
-	return ctx, flag
+	return flag
 }
 
 // SourceCLI is a Source which will parse configuration from the CLI.
@@ -100,10 +97,10 @@ func WithCLISubCommand(ctx context.Context, name, descr string, callback func(co
 // Possible CLI options are generated by joining a Param's Path and Name with
 // dashes. For example:
 //
-//	ctx := mctx.New()
-//	ctx = mctx.ChildOf(ctx, "foo")
-//	ctx = mctx.ChildOf(ctx, "bar")
-//	addr := mcfg.String(ctx, "addr", "", "Some address")
+//	cmp := new(mcmp.Component)
+//	cmpFoo := cmp.Child("foo")
+//	cmpFooBar := cmpFoo.Child("bar")
+//	addr := mcfg.String(cmpFooBar, "addr")
 //	// the CLI option to fill addr will be "--foo-bar-addr"
 //
 // If the "-h" option is seen then a help page will be printed to
@@ -115,10 +112,24 @@ func WithCLISubCommand(ctx context.Context, name, descr string, callback func(co
 // example: `--boolean-flag=1` or `--boolean-flag=false`. Using the
 // space-separated format will not work. If a boolean has no equal-separated
 // value it is assumed to be setting the value to `true`, as would be expected.
+//
+// SourceCLI implements StructureResolver, so that any sub-command selected on
+// the command-line (see CLISubCommand) is resolved, and its Params added,
+// before any Source is asked to Parse. See Populate's doc comment for more.
 type SourceCLI struct {
 	Args []string // if nil then os.Args[1:] is used
 
 	DisableHelpPage bool
+
+	// EnvPrefix, if set, is used when computing the Env option name shown
+	// alongside each option on the help page, so that it matches the Prefix
+	// a sibling SourceEnv is configured with. It has no effect beyond the
+	// help page.
+	EnvPrefix string
+
+	resolveOnce  sync.Once
+	subCmdPrefix []string
+	resolvedArgs []string
 }
 
 const (
@@ -128,55 +139,73 @@ const (
 	cliHelpArg   = "-h"
 )
 
-// Parse implements the method for the Source interface
-func (cli *SourceCLI) Parse(ctx context.Context) (context.Context, []ParamValue, error) {
-	args := cli.Args
-	if cli.Args == nil {
-		args = os.Args[1:]
-	}
-	return cli.parse(ctx, nil, args)
+// resolve walks cmp for any sub-commands registered via CLISubCommand,
+// consuming matching leading Args and invoking each selected sub-command's
+// callback (which may itself add further Params, or further sub-commands, to
+// cmp). It's idempotent: the walk is only ever performed once per SourceCLI,
+// since invoking a sub-command's callback more than once would double-add
+// its Params and panic.
+//
+// If an expected sub-command isn't found (or is missing entirely), the help
+// page is printed and the process exits, the same as SourceCLI does for any
+// other invalid usage.
+func (cli *SourceCLI) resolve(cmp *mcmp.Component) ([]string, []string) {
+	cli.resolveOnce.Do(func() {
+		args := cli.Args
+		if args == nil {
+			args = os.Args[1:]
+		}
+
+		var prefix []string
+		for {
+			subCmdM, _ := cmp.Value(cliKeySubCmdM).(map[string]subCmd)
+			if len(subCmdM) == 0 {
+				break
+			}
+
+			sc, newArgs, ok := cli.getSubCmd(subCmdM, args)
+			if !ok {
+				pM, _ := cli.cliParams(CollectParams(cmp))
+				cli.printHelp(cmp, os.Stderr, prefix, pM)
+				os.Stderr.Sync()
+				os.Exit(1)
+			}
+
+			cmp.SetValue(cliKeySubCmdM, nil)
+			*sc.flag = true
+			prefix = append(prefix, sc.name)
+			args = newArgs
+			if sc.callback != nil {
+				sc.callback(cmp)
+			}
+		}
+
+		cli.subCmdPrefix, cli.resolvedArgs = prefix, args
+	})
+	return cli.subCmdPrefix, cli.resolvedArgs
 }
 
-func (cli *SourceCLI) parse(
-	ctx context.Context,
-	subCmdPrefix, args []string,
-) (
-	context.Context,
-	[]ParamValue,
-	error,
-) {
-	pM, err := cli.cliParams(CollectParams(ctx))
+// ResolveStructure implements the StructureResolver interface.
+func (cli *SourceCLI) ResolveStructure(cmp *mcmp.Component) error {
+	cli.resolve(cmp)
+	return nil
+}
+
+// Parse implements the method for the Source interface.
+func (cli *SourceCLI) Parse(cmp *mcmp.Component) ([]ParamValue, error) {
+	subCmdPrefix, args := cli.resolve(cmp)
+
+	pM, err := cli.cliParams(CollectParams(cmp))
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	printHelpAndExit := func() {
-		cli.printHelp(ctx, os.Stderr, subCmdPrefix, pM)
+		cli.printHelp(cmp, os.Stderr, subCmdPrefix, pM)
 		os.Stderr.Sync()
 		os.Exit(1)
 	}
 
-	// if sub-commands were defined on this Context then handle that first. One
-	// of them should have been given, in which case send the Context through
-	// the callback to obtain a new one (which presumably has further config
-	// options the previous didn't) and call parse again.
-	subCmdM, _ := ctx.Value(cliKeySubCmdM).(map[string]subCmd)
-	if len(subCmdM) > 0 {
-		subCmd, args, ok := cli.getSubCmd(subCmdM, args)
-		if !ok {
-			printHelpAndExit()
-		}
-		ctx = context.WithValue(ctx, cliKeySubCmdM, nil)
-		if subCmd.callback != nil {
-			ctx = subCmd.callback(ctx)
-		}
-		subCmdPrefix = append(subCmdPrefix, subCmd.name)
-		*subCmd.flag = true
-		return cli.parse(ctx, subCmdPrefix, args)
-	}
-
-	// if sub-commands were not set, then proceed with normal command-line arg
-	// processing.
 	pvs := make([]ParamValue, 0, len(args))
 	var (
 		key        string
@@ -208,11 +237,11 @@ func (cli *SourceCLI) parse(
 				break
 			}
 			if !pOk {
-				if ok := populateCLITail(ctx, args[i:]); ok {
-					return ctx, pvs, nil
+				if ok := populateCLITail(cmp, args[i:]); ok {
+					return pvs, nil
 				}
-				ctx := mctx.Annotate(context.Background(), "param", arg)
-				return nil, nil, merr.New("unexpected config parameter", ctx)
+				ctx := mctx.Annotate(cmp.Context(), "param", arg)
+				return nil, merr.New("unexpected config parameter", ctx)
 			}
 		}
 
@@ -231,7 +260,7 @@ func (cli *SourceCLI) parse(
 
 		pvs = append(pvs, ParamValue{
 			Name:  p.Name,
-			Path:  mctx.Path(p.Context),
+			Path:  p.Component.Path(),
 			Value: p.fuzzyParse(pvStrVal),
 		})
 
@@ -242,11 +271,11 @@ func (cli *SourceCLI) parse(
 		pvStrValOk = false
 	}
 	if pOk && !pvStrValOk {
-		ctx := mctx.Annotate(p.Context, "param", key)
-		return nil, nil, merr.New("param expected a value", ctx)
+		ctx := mctx.Annotate(p.Component.Context(), "param", key)
+		return nil, merr.New("param expected a value", ctx)
 	}
 
-	return ctx, pvs, nil
+	return pvs, nil
 }
 
 func (cli *SourceCLI) getSubCmd(subCmdM map[string]subCmd, args []string) (subCmd, []string, bool) {
@@ -265,14 +294,14 @@ func (cli *SourceCLI) getSubCmd(subCmdM map[string]subCmd, args []string) (subCm
 func (cli *SourceCLI) cliParams(params []Param) (map[string]Param, error) {
 	m := map[string]Param{}
 	for _, p := range params {
-		key := strings.Join(append(mctx.Path(p.Context), p.Name), cliKeyJoin)
+		key := strings.Join(append(p.Component.Path(), p.Name), cliKeyJoin)
 		m[cliKeyPrefix+key] = p
 	}
 	return m, nil
 }
 
 func (cli *SourceCLI) printHelp(
-	ctx context.Context,
+	cmp *mcmp.Component,
 	w io.Writer,
 	subCmdPrefix []string,
 	pM map[string]Param,
@@ -313,7 +342,7 @@ func (cli *SourceCLI) printHelp(
 		subCmd
 	}
 
-	subCmdM, _ := ctx.Value(cliKeySubCmdM).(map[string]subCmd)
+	subCmdM, _ := cmp.Value(cliKeySubCmdM).(map[string]subCmd)
 	subCmdA := make([]subCmdEntry, 0, len(subCmdM))
 	for name, subCmd := range subCmdM {
 		subCmdA = append(subCmdA, subCmdEntry{name: name, subCmd: subCmd})
@@ -333,7 +362,7 @@ func (cli *SourceCLI) printHelp(
 	if len(pA) > 0 {
 		fmt.Fprint(w, " [options]")
 	}
-	if descr := getCLITailDescr(ctx); descr != "" {
+	if descr := getCLITailDescr(cmp); descr != "" {
 		fmt.Fprintf(w, " %s", descr)
 	}
 	fmt.Fprint(w, "\n\n")
@@ -358,6 +387,10 @@ func (cli *SourceCLI) printHelp(
 				fmt.Fprintf(w, " (Default: %s)", defVal)
 			}
 			fmt.Fprint(w, "\n")
+			envName := envParamName(cli.EnvPrefix, p.Component.Path(), p.Name)
+			fileKey := fileParamKey(p.Component.Path(), p.Name)
+			fmt.Fprintf(w, "\t\tEnv: %s\n", envName)
+			fmt.Fprintf(w, "\t\tFile key: %s\n", fileKey)
 			if usage := p.Usage; usage != "" {
 				// make all usages end with a period, because I say so
 				usage = strings.TrimSpace(usage)
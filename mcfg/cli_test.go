@@ -48,17 +48,27 @@ $`)
 Options:
 
 	--baz2 \(Required\)
+		Env: BAZ2
+		File key: baz2
 		Required string param.
 
 	--baz3 \(Required\)
+		Env: BAZ3
+		File key: baz3
 
 	--bar \(Flag\)
+		Env: BAR
+		File key: bar
 		Test bool param.
 
 	--baz \(Default: "baz"\)
+		Env: BAZ
+		File key: baz
 		Test string param.
 
 	--foo \(Default: 5\)
+		Env: FOO
+		File key: foo
 		Test int param.
 
 $`)
@@ -68,17 +78,27 @@ $`)
 Options:
 
 	--baz2 \(Required\)
+		Env: BAZ2
+		File key: baz2
 		Required string param.
 
 	--baz3 \(Required\)
+		Env: BAZ3
+		File key: baz3
 
 	--bar \(Flag\)
+		Env: BAR
+		File key: bar
 		Test bool param.
 
 	--baz \(Default: "baz"\)
+		Env: BAZ
+		File key: baz
 		Test string param.
 
 	--foo \(Default: 5\)
+		Env: FOO
+		File key: foo
 		Test int param.
 
 $`)
@@ -95,17 +115,27 @@ Sub-commands:
 Options:
 
 	--baz2 \(Required\)
+		Env: BAZ2
+		File key: baz2
 		Required string param.
 
 	--baz3 \(Required\)
+		Env: BAZ3
+		File key: baz3
 
 	--bar \(Flag\)
+		Env: BAR
+		File key: bar
 		Test bool param.
 
 	--baz \(Default: "baz"\)
+		Env: BAZ
+		File key: baz
 		Test string param.
 
 	--foo \(Default: 5\)
+		Env: FOO
+		File key: foo
 		Test int param.
 
 $`)
@@ -121,17 +151,27 @@ Sub-commands:
 Options:
 
 	--baz2 \(Required\)
+		Env: BAZ2
+		File key: baz2
 		Required string param.
 
 	--baz3 \(Required\)
+		Env: BAZ3
+		File key: baz3
 
 	--bar \(Flag\)
+		Env: BAR
+		File key: bar
 		Test bool param.
 
 	--baz \(Default: "baz"\)
+		Env: BAZ
+		File key: baz
 		Test string param.
 
 	--foo \(Default: 5\)
+		Env: FOO
+		File key: foo
 		Test int param.
 
 $`)
@@ -1,10 +1,10 @@
 package mcfg
 
 import (
-	"context"
 	"os"
 	"strings"
 
+	"github.com/mediocregopher/mediocre-go-lib/mcmp"
 	"github.com/mediocregopher/mediocre-go-lib/mctx"
 	"github.com/mediocregopher/mediocre-go-lib/merr"
 )
@@ -16,12 +16,11 @@ import (
 // underscores and making all characters uppercase, as well as changing all
 // dashes to underscores.
 //
-//	ctx := mctx.New()
-//	ctx = mctx.ChildOf(ctx, "foo")
-//	ctx = mctx.ChildOf(ctx, "bar")
-//	addr := mcfg.String(ctx, "srv-addr", "", "Some address")
+//	cmp := new(mcmp.Component)
+//	cmpFoo := cmp.Child("foo")
+//	cmpFooBar := cmpFoo.Child("bar")
+//	addr := mcfg.String(cmpFooBar, "srv-addr")
 //	// the Env option to fill addr will be "FOO_BAR_SRV_ADDR"
-//
 type SourceEnv struct {
 	// In the format key=value. Defaults to os.Environ() if nil.
 	Env []string
@@ -33,25 +32,34 @@ type SourceEnv struct {
 }
 
 func (env *SourceEnv) expectedName(path []string, name string) string {
+	return envParamName(env.Prefix, path, name)
+}
+
+// envParamName computes the Env option name a Param at the given path/name
+// would be expected to be set with, given an optional prefix (see
+// SourceEnv.Prefix). It's also used by SourceCLI's help page, so that it can
+// show the Env option name alongside each CLI option without needing a
+// SourceEnv instance of its own.
+func envParamName(prefix string, path []string, name string) string {
 	out := strings.Join(append(path, name), "_")
-	if env.Prefix != "" {
-		out = env.Prefix + "_" + out
+	if prefix != "" {
+		out = prefix + "_" + out
 	}
 	out = strings.Replace(out, "-", "_", -1)
 	out = strings.ToUpper(out)
 	return out
 }
 
-// Parse implements the method for the Source interface
-func (env *SourceEnv) Parse(ctx context.Context, params []Param) (context.Context, []ParamValue, error) {
+// Parse implements the method for the Source interface.
+func (env *SourceEnv) Parse(cmp *mcmp.Component) ([]ParamValue, error) {
 	kvs := env.Env
 	if kvs == nil {
 		kvs = os.Environ()
 	}
 
 	pM := map[string]Param{}
-	for _, p := range params {
-		name := env.expectedName(mctx.Path(p.Context), p.Name)
+	for _, p := range CollectParams(cmp) {
+		name := env.expectedName(p.Component.Path(), p.Name)
 		pM[name] = p
 	}
 
@@ -59,18 +67,18 @@ func (env *SourceEnv) Parse(ctx context.Context, params []Param) (context.Contex
 	for _, kv := range kvs {
 		split := strings.SplitN(kv, "=", 2)
 		if len(split) != 2 {
-			ctx := mctx.Annotate(context.Background(), "kv", kv)
-			return nil, nil, merr.New("malformed environment key/value pair", ctx)
+			ctx := mctx.Annotate(cmp.Context(), "kv", kv)
+			return nil, merr.New("malformed environment key/value pair", ctx)
 		}
 		k, v := split[0], split[1]
 		if p, ok := pM[k]; ok {
 			pvs = append(pvs, ParamValue{
 				Name:  p.Name,
-				Path:  mctx.Path(p.Context),
+				Path:  p.Component.Path(),
 				Value: p.fuzzyParse(v),
 			})
 		}
 	}
 
-	return ctx, pvs, nil
+	return pvs, nil
 }
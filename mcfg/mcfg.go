@@ -7,6 +7,7 @@
 package mcfg
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
@@ -18,16 +19,6 @@ import (
 	"github.com/mediocregopher/mediocre-go-lib/merr"
 )
 
-// TODO Sources:
-// - JSON file
-// - YAML file
-
-// TODO WithCLISubCommand does not play nice with the expected use-case of
-// having CLI params overwrite Env ones. If Env is specified first in the
-// Sources slice then it won't know about any extra Params which might get added
-// due to a sub-command, but if it's specified second then Env values will
-// overwrite CLI ones.
-
 func sortParams(params []Param) {
 	sort.Slice(params, func(i, j int) bool {
 		a, b := params[i], params[j]
@@ -91,12 +82,29 @@ func paramHash(path []string, name string) string {
 // values will be used, and if any parameters are required this will error.
 //
 // Populating Params can affect the Component itself, for example in the case of
-// sub-commands.
+// sub-commands. Because of this, Populate first performs a structure-resolving
+// pass: if src (or, recursively, any element of it, in the case of Sources)
+// implements StructureResolver, its ResolveStructure method is called first,
+// before any Param values are collected. This ensures that Params which only
+// come to exist because of a sub-command being selected (see CLISubCommand)
+// are visible to every Source, e.g. SourceEnv, by the time values are
+// collected, regardless of the order the Sources were given in. The precedence
+// of the values themselves (which Source wins when more than one sets the same
+// Param) is unaffected by this and continues to be determined purely by
+// ordering, with later Sources winning; see Sources' doc comment. A Source
+// combining CLI and Env would therefore generally put the SourceCLI last, so
+// that it both resolves sub-commands early and wins on value precedence.
 func Populate(cmp *mcmp.Component, src Source) error {
 	if src == nil {
 		src = ParamValues(nil)
 	}
 
+	if sr, ok := src.(StructureResolver); ok {
+		if err := sr.ResolveStructure(cmp); err != nil {
+			return err
+		}
+	}
+
 	pvs, err := src.Parse(cmp)
 	if err != nil {
 		return err
@@ -148,3 +156,39 @@ func Populate(cmp *mcmp.Component, src Source) error {
 
 	return nil
 }
+
+// PopulateSources is a convenience function which wraps the given Sources in
+// a Sources (see its doc comment for layering/precedence rules) and passes
+// the result into Populate. The two calls below are equivalent:
+//
+//	mcfg.PopulateSources(cmp, srcA, srcB, srcC)
+//	mcfg.Populate(cmp, mcfg.Sources{srcA, srcB, srcC})
+func PopulateSources(cmp *mcmp.Component, srcs ...Source) error {
+	return Populate(cmp, Sources(srcs))
+}
+
+// PopulateWatch is like Populate, but afterwards, if src (or, recursively,
+// any element of it, in the case of Sources) implements Watcher, it also
+// begins watching src for live updates to any Params created with
+// ParamWatchable, blocking until ctx is canceled or a Watcher returns an
+// error. See SourceKV.Watch for the canonical example of a live-reloading
+// Source, and OnChange for reacting to the updates it makes.
+//
+// If src does not implement Watcher this simply blocks until ctx is
+// canceled.
+func PopulateWatch(ctx context.Context, cmp *mcmp.Component, src Source) error {
+	if src == nil {
+		src = ParamValues(nil)
+	}
+
+	if err := Populate(cmp, src); err != nil {
+		return err
+	}
+
+	w, ok := src.(Watcher)
+	if !ok {
+		<-ctx.Done()
+		return nil
+	}
+	return w.Watch(ctx, cmp)
+}
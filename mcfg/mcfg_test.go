@@ -4,6 +4,7 @@ import (
 	. "testing"
 
 	"github.com/mediocregopher/mediocre-go-lib/mcmp"
+	"github.com/mediocregopher/mediocre-go-lib/mtest/massert"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -48,6 +49,52 @@ func TestPopulate(t *T) {
 	}
 }
 
+// TestPopulateSourcesSubCommand covers the case that used to be documented as
+// a known bug: a Param only comes to exist because SourceCLI selected a
+// sub-command, but another Source earlier in precedence (SourceEnv) still
+// needs to see and fill it, while SourceCLI (listed last, so it wins
+// precedence) overrides it if it sets the Param too.
+func TestPopulateSourcesSubCommand(t *T) {
+	var bar *string
+	cmp := new(mcmp.Component)
+	foo := Int(cmp, "foo")
+	CLISubCommand(cmp, "serve", "Run the server.", func(cmp *mcmp.Component) {
+		bar = String(cmp, "bar")
+	})
+
+	err := Populate(cmp, Sources{
+		&SourceEnv{Env: []string{"FOO=1", "BAR=from-env"}},
+		&SourceCLI{Args: []string{"serve", "--bar=from-cli"}},
+	})
+	massert.Require(t,
+		massert.Nil(err),
+		massert.Equal(1, *foo),
+		massert.Equal("from-cli", *bar),
+	)
+}
+
+// TestPopulateSourcesSubCommandEnvOnly confirms that, absent any CLI-set
+// value, the sub-command-specific Param can still be filled by an earlier
+// Source (SourceEnv) - i.e. that Param only exists on the Component because
+// SourceCLI's structure-resolving pass ran before any Source's Parse was
+// called, regardless of SourceCLI's position in the Sources slice.
+func TestPopulateSourcesSubCommandEnvOnly(t *T) {
+	var bar *string
+	cmp := new(mcmp.Component)
+	CLISubCommand(cmp, "serve", "Run the server.", func(cmp *mcmp.Component) {
+		bar = String(cmp, "bar")
+	})
+
+	err := Populate(cmp, Sources{
+		&SourceEnv{Env: []string{"BAR=from-env"}},
+		&SourceCLI{Args: []string{"serve"}},
+	})
+	massert.Require(t,
+		massert.Nil(err),
+		massert.Equal("from-env", *bar),
+	)
+}
+
 func TestParamDefaultOrRequired(t *T) {
 	{
 		cmp := new(mcmp.Component)
@@ -0,0 +1,117 @@
+// Package mcfgconsul provides an mcfg.KVStore implementation backed by
+// Consul's KV store, for use with mcfg.SourceKV.
+package mcfgconsul
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+
+	"github.com/mediocregopher/mediocre-go-lib/mcfg"
+)
+
+// init registers this package's KVStore under the "consul" scheme, so that
+// mcfg.SourceFromURI("consul://127.0.0.1:8500/myapp") can construct one
+// without the mcfg package itself needing to depend on Consul.
+func init() {
+	mcfg.RegisterSource("consul", func(params url.Values) (mcfg.Source, error) {
+		client, err := consulapi.NewClient(&consulapi.Config{Address: params.Get("host")})
+		if err != nil {
+			return nil, err
+		}
+		return NewSource(client, params.Get("path")), nil
+	})
+}
+
+// KVStore implements mcfg.KVStore using a Consul API client.
+type KVStore struct {
+	Client *consulapi.Client
+
+	// Prefix is prepended to every key which is read or watched.
+	Prefix string
+}
+
+// NewSource initializes a KVStore around the given Consul client and wraps
+// it in an mcfg.SourceKV.
+func NewSource(client *consulapi.Client, prefix string) *mcfg.SourceKV {
+	return &mcfg.SourceKV{Store: &KVStore{Client: client, Prefix: prefix}}
+}
+
+// Get implements the mcfg.KVStore interface.
+func (kv *KVStore) Get(ctx context.Context) (map[string][]byte, error) {
+	pairs, _, err := kv.Client.KV().List(kv.Prefix, (&consulapi.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string][]byte, len(pairs))
+	for _, pair := range pairs {
+		m[trimPrefix(kv.Prefix, pair.Key)] = pair.Value
+	}
+	return m, nil
+}
+
+// Watch implements the mcfg.KVStore interface, polling Consul's KV store
+// using blocking queries (Consul doesn't provide a streaming watch API).
+func (kv *KVStore) Watch(ctx context.Context) (<-chan mcfg.KVUpdate, error) {
+	updateCh := make(chan mcfg.KVUpdate)
+
+	go func() {
+		defer close(updateCh)
+
+		var lastIndex uint64
+		seen := map[string][]byte{}
+
+		for {
+			opts := (&consulapi.QueryOptions{WaitIndex: lastIndex, WaitTime: 5 * time.Minute}).WithContext(ctx)
+			pairs, meta, err := kv.Client.KV().List(kv.Prefix, opts)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				continue
+			}
+			lastIndex = meta.LastIndex
+
+			cur := map[string][]byte{}
+			for _, pair := range pairs {
+				key := trimPrefix(kv.Prefix, pair.Key)
+				cur[key] = pair.Value
+				if prev, ok := seen[key]; !ok || string(prev) != string(pair.Value) {
+					select {
+					case updateCh <- mcfg.KVUpdate{Key: key, Value: pair.Value}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+			for key := range seen {
+				if _, ok := cur[key]; !ok {
+					select {
+					case updateCh <- mcfg.KVUpdate{Key: key, Deleted: true}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+			seen = cur
+
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+		}
+	}()
+
+	return updateCh, nil
+}
+
+func trimPrefix(prefix, key string) string {
+	if len(key) >= len(prefix) {
+		return key[len(prefix):]
+	}
+	return key
+}
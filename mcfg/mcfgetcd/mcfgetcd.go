@@ -0,0 +1,88 @@
+// Package mcfgetcd provides an mcfg.KVStore implementation backed by etcd v3,
+// for use with mcfg.SourceKV.
+package mcfgetcd
+
+import (
+	"context"
+	"net/url"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/mediocregopher/mediocre-go-lib/mcfg"
+)
+
+// init registers this package's KVStore under the "etcd" scheme, so that
+// mcfg.SourceFromURI("etcd://127.0.0.1:2379/myapp") can construct one without
+// the mcfg package itself needing to depend on etcd.
+func init() {
+	mcfg.RegisterSource("etcd", func(params url.Values) (mcfg.Source, error) {
+		client, err := clientv3.New(clientv3.Config{
+			Endpoints: []string{params.Get("host")},
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewSource(client, params.Get("path")), nil
+	})
+}
+
+// KVStore implements mcfg.KVStore using an etcd v3 client.
+type KVStore struct {
+	Client *clientv3.Client
+
+	// Prefix is prepended to every key which is read or watched.
+	Prefix string
+}
+
+// NewSource initializes a KVStore around the given etcd client and wraps it
+// in an mcfg.SourceKV.
+func NewSource(client *clientv3.Client, prefix string) *mcfg.SourceKV {
+	return &mcfg.SourceKV{Store: &KVStore{Client: client, Prefix: prefix}}
+}
+
+// Get implements the mcfg.KVStore interface.
+func (kv *KVStore) Get(ctx context.Context) (map[string][]byte, error) {
+	resp, err := kv.Client.Get(ctx, kv.Prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string][]byte, len(resp.Kvs))
+	for _, kvPair := range resp.Kvs {
+		m[trimPrefix(kv.Prefix, string(kvPair.Key))] = kvPair.Value
+	}
+	return m, nil
+}
+
+// Watch implements the mcfg.KVStore interface.
+func (kv *KVStore) Watch(ctx context.Context) (<-chan mcfg.KVUpdate, error) {
+	watchCh := kv.Client.Watch(ctx, kv.Prefix, clientv3.WithPrefix())
+	updateCh := make(chan mcfg.KVUpdate)
+
+	go func() {
+		defer close(updateCh)
+		for resp := range watchCh {
+			for _, ev := range resp.Events {
+				update := mcfg.KVUpdate{
+					Key:     trimPrefix(kv.Prefix, string(ev.Kv.Key)),
+					Value:   ev.Kv.Value,
+					Deleted: ev.Type == clientv3.EventTypeDelete,
+				}
+				select {
+				case updateCh <- update:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return updateCh, nil
+}
+
+func trimPrefix(prefix, key string) string {
+	if len(key) >= len(prefix) {
+		return key[len(prefix):]
+	}
+	return key
+}
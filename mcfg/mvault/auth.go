@@ -0,0 +1,78 @@
+package mvault
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+// defaultKubernetesJWTPath is where Kubernetes mounts a pod's service
+// account token, which is what KubernetesAuth presents to Vault's
+// Kubernetes auth method.
+const defaultKubernetesJWTPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// AuthMethod logs into Vault using some particular auth method, returning
+// the resulting auth Secret (whose Auth field carries the client token and
+// lease information).
+type AuthMethod interface {
+	Login(ctx context.Context, client *vaultapi.Client) (*vaultapi.Secret, error)
+}
+
+type authFunc func(ctx context.Context, client *vaultapi.Client) (*vaultapi.Secret, error)
+
+func (fn authFunc) Login(ctx context.Context, client *vaultapi.Client) (*vaultapi.Secret, error) {
+	return fn(ctx, client)
+}
+
+// TokenAuth is an AuthMethod which simply sets the client's token directly,
+// without performing a login call. It returns a nil Secret, so no renewal is
+// performed.
+func TokenAuth(token string) AuthMethod {
+	return authFunc(func(_ context.Context, client *vaultapi.Client) (*vaultapi.Secret, error) {
+		client.SetToken(token)
+		return nil, nil
+	})
+}
+
+// AppRoleAuth is an AuthMethod which logs in using Vault's AppRole auth
+// method (auth/approle/login).
+func AppRoleAuth(roleID, secretID string) AuthMethod {
+	return authFunc(func(ctx context.Context, client *vaultapi.Client) (*vaultapi.Secret, error) {
+		secret, err := client.Logical().WriteWithContext(ctx, "auth/approle/login", map[string]interface{}{
+			"role_id":   roleID,
+			"secret_id": secretID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("logging into vault via approle: %w", err)
+		}
+		client.SetToken(secret.Auth.ClientToken)
+		return secret, nil
+	})
+}
+
+// KubernetesAuth is an AuthMethod which logs in using Vault's Kubernetes
+// auth method (auth/kubernetes/login), presenting the service account token
+// found at jwtPath (or defaultKubernetesJWTPath, if jwtPath is empty) as
+// proof of identity.
+func KubernetesAuth(role, jwtPath string) AuthMethod {
+	if jwtPath == "" {
+		jwtPath = defaultKubernetesJWTPath
+	}
+	return authFunc(func(ctx context.Context, client *vaultapi.Client) (*vaultapi.Secret, error) {
+		jwt, err := ioutil.ReadFile(jwtPath)
+		if err != nil {
+			return nil, fmt.Errorf("reading kubernetes service account token from %q: %w", jwtPath, err)
+		}
+		secret, err := client.Logical().WriteWithContext(ctx, "auth/kubernetes/login", map[string]interface{}{
+			"role": role,
+			"jwt":  string(jwt),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("logging into vault via kubernetes: %w", err)
+		}
+		client.SetToken(secret.Auth.ClientToken)
+		return secret, nil
+	})
+}
@@ -0,0 +1,209 @@
+// Package mvault implements an mcfg.Source which fetches secret Params from
+// a HashiCorp Vault KV v2 mount.
+//
+// Only Params explicitly marked via mcfg.ParamSecret are fetched from Vault;
+// every other Param continues to be populated from whichever other Sources
+// (CLI, env, etc...) are composed alongside this one. mcfg.ParamSecret's loc
+// argument is expected in the form "<path>#<field>", e.g.
+// "kv/data/db#password": <path> is passed as-is to Vault's KV v2 read API
+// (so it includes the mount's "data/" infix), and <field> names the key
+// within that secret's data to use as the Param's value.
+package mvault
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	vaultapi "github.com/hashicorp/vault/api"
+
+	"github.com/mediocregopher/mediocre-go-lib/mcfg"
+	"github.com/mediocregopher/mediocre-go-lib/mcmp"
+	"github.com/mediocregopher/mediocre-go-lib/merr"
+	"github.com/mediocregopher/mediocre-go-lib/mlog"
+	"github.com/mediocregopher/mediocre-go-lib/mrun"
+)
+
+// init registers this package's Source under the "vault" scheme, so that
+// mcfg.SourceFromURI("vault://127.0.0.1:8200?auth=token&token=...") can
+// construct one without the mcfg package itself needing to depend on Vault.
+//
+// The auth query parameter selects the AuthMethod: "token" (token),
+// "approle" (role-id, secret-id), or "kubernetes" (role, jwt-path).
+func init() {
+	mcfg.RegisterSource("vault", func(params url.Values) (mcfg.Source, error) {
+		config := vaultapi.DefaultConfig()
+		config.Address = "https://" + params.Get("host")
+		client, err := vaultapi.NewClient(config)
+		if err != nil {
+			return nil, err
+		}
+
+		var auth AuthMethod
+		switch authMethod := params.Get("auth"); authMethod {
+		case "", "token":
+			auth = TokenAuth(params.Get("token"))
+		case "approle":
+			auth = AppRoleAuth(params.Get("role-id"), params.Get("secret-id"))
+		case "kubernetes":
+			auth = KubernetesAuth(params.Get("role"), params.Get("jwt-path"))
+		default:
+			return nil, fmt.Errorf("unknown vault auth method %q", authMethod)
+		}
+
+		return NewSource(new(mcmp.Component), client, auth), nil
+	})
+}
+
+// Source is an mcfg.Source which fetches the value of every Param marked
+// via mcfg.ParamSecret from a Vault KV v2 mount.
+//
+// Source must be constructed via NewSource, which registers an InitHook
+// (performing the initial login to Vault) and, if the resulting auth token
+// is renewable, a background thread which keeps it renewed for as long as
+// the Component it's attached to is running.
+type Source struct {
+	cmp    *mcmp.Component
+	client *vaultapi.Client
+	auth   AuthMethod
+
+	l     sync.RWMutex
+	cache map[string]*vaultapi.Secret // vault path -> last-read secret
+}
+
+// NewSource initializes a Source which authenticates to Vault (using the
+// given AuthMethod) and fetches secrets using the given client.
+//
+// cmp is used for the Source's own Init/Shutdown lifecycle (logging in, and
+// renewing that login's lease); it's independent of whatever Component the
+// Source later reads Params from via Parse.
+func NewSource(cmp *mcmp.Component, client *vaultapi.Client, auth AuthMethod) *Source {
+	src := &Source{
+		cmp:    cmp.Child("vault"),
+		client: client,
+		auth:   auth,
+		cache:  map[string]*vaultapi.Secret{},
+	}
+
+	threadCtx := context.Background()
+	var watcher *vaultapi.LifetimeWatcher
+
+	mrun.InitHook(src.cmp, func(ctx context.Context) error {
+		mlog.From(src.cmp).Info("logging into vault", ctx)
+		authSecret, err := src.auth.Login(ctx, src.client)
+		if err != nil {
+			return merr.Wrap(err, src.cmp.Context(), ctx)
+		}
+
+		if authSecret == nil || authSecret.Auth == nil || !authSecret.Auth.Renewable {
+			return nil
+		}
+
+		watcher, err = src.client.NewLifetimeWatcher(&vaultapi.LifetimeWatcherInput{Secret: authSecret})
+		if err != nil {
+			return merr.Wrap(err, src.cmp.Context(), ctx)
+		}
+
+		threadCtx = mrun.WithThreads(threadCtx, 1, func() error {
+			go watcher.Start()
+			defer watcher.Stop()
+
+			mlog.From(src.cmp).Info("watching vault login lease for renewal", ctx)
+			for {
+				select {
+				case err := <-watcher.DoneCh():
+					return err
+				case <-watcher.RenewCh():
+					mlog.From(src.cmp).Info("vault login lease renewed", ctx)
+				}
+			}
+		})
+		return nil
+	})
+
+	mrun.ShutdownHook(src.cmp, func(ctx context.Context) error {
+		if watcher != nil {
+			watcher.Stop()
+		}
+		return merr.Wrap(mrun.Wait(threadCtx, ctx.Done()), src.cmp.Context(), ctx)
+	})
+
+	return src
+}
+
+// parseLoc splits a mcfg.ParamSecret location of the form "path#field" into
+// its path and field components.
+func parseLoc(loc string) (path, field string, err error) {
+	i := strings.LastIndexByte(loc, '#')
+	if i < 0 {
+		return "", "", fmt.Errorf("secret location %q is missing a #field suffix", loc)
+	}
+	return loc[:i], loc[i+1:], nil
+}
+
+func (src *Source) readCached(ctx context.Context, path string) (*vaultapi.Secret, error) {
+	src.l.RLock()
+	secret, ok := src.cache[path]
+	src.l.RUnlock()
+	if ok {
+		return secret, nil
+	}
+
+	secret, err := src.client.Logical().ReadWithContext(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("reading vault secret %q: %w", path, err)
+	} else if secret == nil {
+		return nil, fmt.Errorf("no vault secret found at %q", path)
+	}
+
+	src.l.Lock()
+	src.cache[path] = secret
+	src.l.Unlock()
+	return secret, nil
+}
+
+// Parse implements the method for the mcfg.Source interface.
+//
+// Only Params with a non-empty Secret field (see mcfg.ParamSecret) are
+// considered; all others are ignored, leaving them to whatever other
+// Sources this one is composed with via mcfg.Sources.
+func (src *Source) Parse(cmp *mcmp.Component) ([]mcfg.ParamValue, error) {
+	ctx := context.Background()
+
+	var pvs []mcfg.ParamValue
+	for _, param := range mcfg.CollectParams(cmp) {
+		if param.Secret == "" {
+			continue
+		}
+
+		path, field, err := parseLoc(param.Secret)
+		if err != nil {
+			return nil, err
+		}
+
+		secret, err := src.readCached(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+
+		data, _ := secret.Data["data"].(map[string]interface{})
+		val, ok := data[field]
+		if !ok {
+			return nil, fmt.Errorf("vault secret %q has no field %q", path, field)
+		}
+		s, ok := val.(string)
+		if !ok {
+			return nil, fmt.Errorf("vault secret %q field %q: expected a string, got %T", path, field, val)
+		}
+
+		pvs = append(pvs, mcfg.ParamValue{
+			Name:  param.Name,
+			Path:  param.Component.Path(),
+			Value: mcfg.ParamFuzzyParse(param, s),
+		})
+	}
+
+	return pvs, nil
+}
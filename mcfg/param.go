@@ -47,6 +47,17 @@ type Param struct {
 	// automatically filled in by AddParam when the Param is added to the
 	// Component.
 	Component *mcmp.Component
+
+	// Secret, if set via ParamSecret, identifies where this Param's value
+	// should be read from within a secrets backend (e.g. mcfg/mvault)
+	// rather than from the usual Sources (CLI, env, etc...).
+	Secret string
+
+	// Watchable, if set via ParamWatchable, indicates that this Param's
+	// value may be changed at runtime by a Source which supports watching
+	// for live updates (e.g. SourceKV.Watch, via PopulateWatch). Params are
+	// not watchable by default.
+	Watchable bool
 }
 
 // ParamOption is a modifier which can be passed into most Param-generating
@@ -110,10 +121,41 @@ func ParamUsage(usage string) ParamOption {
 	}
 }
 
+// ParamSecret returns a ParamOption which marks the Param as being sourced
+// from a secrets backend rather than the usual Sources, and gives the
+// location at which the backend can find it. The format of loc is
+// backend-specific; see mcfg/mvault's doc string for the format it expects.
+func ParamSecret(loc string) ParamOption {
+	return func(param *Param) {
+		param.Secret = loc
+	}
+}
+
+// ParamWatchable returns a ParamOption which marks the Param as eligible to
+// be live-updated at runtime by a watching Source (see SourceKV.Watch and
+// PopulateWatch). Params are not watchable by default, so that a value which
+// is assumed to be immutable once read (e.g. a database DSN used to
+// establish a connection pool) can't unexpectedly change out from underneath
+// the code using it.
+func ParamWatchable() ParamOption {
+	return func(param *Param) {
+		param.Watchable = true
+	}
+}
+
 func paramFullName(path []string, name string) string {
 	return strings.Join(append(path, name), "-")
 }
 
+// ParamFuzzyParse converts a string value, e.g. one read from an external
+// secrets backend that only deals in strings (see mcfg/mvault), into the
+// JSON form a Source's ParamValue is expected to carry, using the same
+// rules SourceCLI and SourceKV use: true/false for IsBool Params, quoted for
+// IsString Params, passed through as-is otherwise.
+func ParamFuzzyParse(p Param, v string) json.RawMessage {
+	return p.fuzzyParse(v)
+}
+
 func (p Param) fuzzyParse(v string) json.RawMessage {
 	if p.IsBool {
 		if v == "" || v == "0" || v == "false" {
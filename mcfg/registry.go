@@ -0,0 +1,78 @@
+package mcfg
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+)
+
+// SourceFactory constructs a Source from the query parameters of a URI whose
+// scheme was registered via RegisterSource.
+type SourceFactory func(params url.Values) (Source, error)
+
+var (
+	registryL sync.RWMutex
+	registry  = map[string]SourceFactory{}
+)
+
+// RegisterSource makes a SourceFactory available under the given name (used
+// as a URI scheme by SourceFromURI), so that third-party mcfg Source
+// implementations (e.g. for etcd, Consul, or Vault) can be discovered by
+// name without mcfg itself depending on them.
+//
+// RegisterSource is expected to be called from an init function. It panics
+// if name has already been registered.
+func RegisterSource(name string, factory SourceFactory) {
+	registryL.Lock()
+	defer registryL.Unlock()
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("mcfg: Source %q already registered", name))
+	}
+	registry[name] = factory
+}
+
+// SourceFromURI resolves a URI (e.g. "consul://127.0.0.1:8500/myapp") into a
+// Source using the SourceFactory which was registered, via RegisterSource,
+// under the URI's scheme. The factory receives the URI's query parameters,
+// along with "host" and "path" entries populated from the URI's host and
+// path components (so e.g. "consul://127.0.0.1:8500/myapp" results in
+// host=127.0.0.1:8500 and path=/myapp), so that backend-specific options
+// can additionally be given via the query string, e.g.
+// "etcd://127.0.0.1:2379/myapp?dial-timeout=5s".
+func SourceFromURI(uri string) (Source, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("parsing config source URI %q: %w", uri, err)
+	}
+
+	registryL.RLock()
+	factory, ok := registry[u.Scheme]
+	registryL.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no mcfg Source registered for scheme %q", u.Scheme)
+	}
+
+	params := u.Query()
+	params.Set("host", u.Host)
+	params.Set("path", u.Path)
+
+	src, err := factory(params)
+	if err != nil {
+		return nil, fmt.Errorf("constructing %q Source from URI %q: %w", u.Scheme, uri, err)
+	}
+	return src, nil
+}
+
+// SourcesFromURIs is a convenience which resolves each of the given URIs via
+// SourceFromURI and composes the results into a Sources.
+func SourcesFromURIs(uris ...string) (Sources, error) {
+	srcs := make(Sources, len(uris))
+	for i, uri := range uris {
+		src, err := SourceFromURI(uri)
+		if err != nil {
+			return nil, err
+		}
+		srcs[i] = src
+	}
+	return srcs, nil
+}
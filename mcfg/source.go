@@ -1,6 +1,7 @@
 package mcfg
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/mediocregopher/mediocre-go-lib/mcmp"
@@ -32,6 +33,34 @@ type Source interface {
 	Parse(*mcmp.Component) ([]ParamValue, error)
 }
 
+// StructureResolver may optionally be implemented by a Source which adds new
+// Params to a Component as a side effect of being parsed, e.g. SourceCLI's
+// support for sub-commands (see CLISubCommand).
+//
+// Populate calls ResolveStructure on every given Source which implements
+// this interface before it collects Params and asks any Source for
+// ParamValues. This guarantees that Params added as a result of, e.g., a
+// sub-command being selected on the CLI are visible to every other Source
+// (like SourceEnv) by the time values are actually collected, regardless of
+// where in a Sources slice the structure-resolving Source falls relative to
+// the others. See Populate's doc comment for more on why this separation
+// matters.
+type StructureResolver interface {
+	ResolveStructure(*mcmp.Component) error
+}
+
+// Watcher may optionally be implemented by a Source which, having already
+// Parsed once, can additionally watch for subsequent changes to the Params
+// it Parsed and push new values for them into the Component live (see
+// SourceKV.Watch for the canonical example). Only Params created with
+// ParamWatchable are eligible to be updated this way.
+//
+// Watch is expected to block until ctx is canceled or an unrecoverable error
+// is encountered. See PopulateWatch.
+type Watcher interface {
+	Watch(ctx context.Context, cmp *mcmp.Component) error
+}
+
 // ParamValues is simply a slice of ParamValue elements, which implements Parse
 // by always returning itself as-is.
 type ParamValues []ParamValue
@@ -48,7 +77,11 @@ func (pvs ParamValues) Parse(*mcmp.Component) ([]ParamValue, error) {
 // over previous ones.
 type Sources []Source
 
-var _ Source = Sources{}
+var (
+	_ Source            = Sources{}
+	_ StructureResolver = Sources{}
+	_ Watcher           = Sources{}
+)
 
 // Parse implements the method for the Source interface.
 func (ss Sources) Parse(cmp *mcmp.Component) ([]ParamValue, error) {
@@ -63,3 +96,56 @@ func (ss Sources) Parse(cmp *mcmp.Component) ([]ParamValue, error) {
 	}
 	return pvs, nil
 }
+
+// ResolveStructure implements the StructureResolver interface. It calls
+// ResolveStructure, in order, on every element of ss which implements
+// StructureResolver, so that e.g. a sub-command selected by a SourceCLI
+// within ss has its Params added before any element of ss is asked to Parse.
+func (ss Sources) ResolveStructure(cmp *mcmp.Component) error {
+	for _, s := range ss {
+		sr, ok := s.(StructureResolver)
+		if !ok {
+			continue
+		}
+		if err := sr.ResolveStructure(cmp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Watch implements the Watcher interface. It calls Watch, concurrently, on
+// every element of ss which implements Watcher, and blocks until ctx is
+// canceled or one of them returns an error, in which case the others are
+// stopped and that error is returned. If ctx is canceled normally, and none
+// of the elements errored beforehand, nil is returned.
+func (ss Sources) Watch(ctx context.Context, cmp *mcmp.Component) error {
+	var watchers []Watcher
+	for _, s := range ss {
+		if w, ok := s.(Watcher); ok {
+			watchers = append(watchers, w)
+		}
+	}
+	if len(watchers) == 0 {
+		<-ctx.Done()
+		return nil
+	}
+
+	innerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errCh := make(chan error, len(watchers))
+	for _, w := range watchers {
+		w := w
+		go func() { errCh <- w.Watch(innerCtx, cmp) }()
+	}
+
+	var firstErr error
+	for range watchers {
+		if err := <-errCh; err != nil && firstErr == nil && ctx.Err() == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+	return firstErr
+}
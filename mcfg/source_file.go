@@ -0,0 +1,235 @@
+package mcfg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/mediocregopher/mediocre-go-lib/mcmp"
+	"gopkg.in/yaml.v3"
+)
+
+// SourceFile is a Source which parses configuration from a structured file on
+// disk (JSON, YAML, or TOML, auto-detected from the file's extension).
+//
+// Param values may be given either as a flat key, joining a Param's Component
+// path and Name with dots (e.g. "foo.bar.addr"), or as nested maps (e.g.
+// `foo: {bar: {addr: ...}}`). Nested maps take precedence if both forms are
+// present.
+//
+// Before being parsed, the file's contents are interpolated with
+// os.Expand-style ${VAR} references to environment variables, and any
+// "!include <path>" string value (quoted, e.g. `foo: "!include foo.yaml"` in
+// YAML, so it isn't mistaken for a YAML custom tag) or single-key
+// {"$ref": "<path>"} map is replaced with the (recursively resolved)
+// contents of the referenced file, itself relative to the including file's
+// directory. This allows a base config to be split across files and shared
+// between deployments.
+//
+// Multiple SourceFiles may be combined in a Sources to layer, e.g., a base
+// config with environment-specific overlays; later Sources take precedence
+// over earlier ones.
+type SourceFile struct {
+	// Path to the file to read. The file extension (.json, .yml, .yaml, or
+	// .toml) determines how it's parsed.
+	Path string
+}
+
+// maxIncludeDepth bounds how many levels of !include/$ref nesting
+// resolveIncludes will follow, as a guard against include cycles.
+const maxIncludeDepth = 16
+
+// interpolateEnv replaces ${VAR} references in b with the value of the VAR
+// environment variable. A reference to an unset variable is replaced with an
+// empty string, matching os.Expand's behavior.
+func interpolateEnv(b []byte) []byte {
+	return []byte(os.Expand(string(b), os.Getenv))
+}
+
+// ConfigFileEnvVar is the environment variable which SourceFileFromEnv looks
+// at by convention.
+const ConfigFileEnvVar = "MCFG_FILE"
+
+// SourceFileFromEnv returns a SourceFile whose Path is read from the given
+// environment variable (ConfigFileEnvVar, by convention). If the environment
+// variable isn't set then a Source which produces no ParamValues is returned,
+// so that it's always safe to include in a mcfg.Sources.
+func SourceFileFromEnv(envVar string) Source {
+	path := os.Getenv(envVar)
+	if path == "" {
+		return ParamValues(nil)
+	}
+	return &SourceFile{Path: path}
+}
+
+func (sf *SourceFile) unmarshal(b []byte) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+
+	switch ext := strings.ToLower(filepath.Ext(sf.Path)); ext {
+	case ".json":
+		if err := json.Unmarshal(b, &m); err != nil {
+			return nil, fmt.Errorf("parsing %q as JSON: %w", sf.Path, err)
+		}
+	case ".yml", ".yaml":
+		if err := yaml.Unmarshal(b, &m); err != nil {
+			return nil, fmt.Errorf("parsing %q as YAML: %w", sf.Path, err)
+		}
+	case ".toml":
+		if err := toml.Unmarshal(b, &m); err != nil {
+			return nil, fmt.Errorf("parsing %q as TOML: %w", sf.Path, err)
+		}
+	default:
+		return nil, fmt.Errorf("unrecognized config file extension %q", ext)
+	}
+
+	return m, nil
+}
+
+// parseFile reads, env-interpolates, and unmarshals path, then resolves any
+// !include/$ref directives found within (relative to path's directory).
+// depth guards against include cycles.
+func parseFile(path string, depth int) (map[string]interface{}, error) {
+	if depth > maxIncludeDepth {
+		return nil, fmt.Errorf("%q exceeds max include depth of %d, likely an include cycle", path, maxIncludeDepth)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file %q: %w", path, err)
+	}
+
+	sf := &SourceFile{Path: path}
+	m, err := sf.unmarshal(interpolateEnv(b))
+	if err != nil {
+		return nil, err
+	}
+
+	resolved, err := resolveIncludes(m, filepath.Dir(path), depth+1)
+	if err != nil {
+		return nil, err
+	}
+	return resolved.(map[string]interface{}), nil
+}
+
+// resolveIncludes walks v (as decoded from a config file) looking for
+// "!include <path>" string values (see SourceFile's doc comment on why
+// these must be quoted in YAML) and single-key {"$ref": "<path>"} maps,
+// replacing each with the parsed contents of the referenced file (itself
+// resolved relative to baseDir).
+func resolveIncludes(v interface{}, baseDir string, depth int) (interface{}, error) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		if ref, ok := x["$ref"]; ok && len(x) == 1 {
+			refPath, ok := ref.(string)
+			if !ok {
+				return nil, fmt.Errorf("$ref value must be a string, got %T", ref)
+			}
+			return parseFile(filepath.Join(baseDir, refPath), depth)
+		}
+
+		out := make(map[string]interface{}, len(x))
+		for k, vv := range x {
+			resolved, err := resolveIncludes(vv, baseDir, depth)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = resolved
+		}
+		return out, nil
+
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, vv := range x {
+			resolved, err := resolveIncludes(vv, baseDir, depth)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+
+	case string:
+		if rest := strings.TrimPrefix(x, "!include "); rest != x {
+			return parseFile(filepath.Join(baseDir, strings.TrimSpace(rest)), depth)
+		}
+		return x, nil
+
+	default:
+		return x, nil
+	}
+}
+
+// fileParamKey computes the flat dotted key a Param at the given path/name
+// would be expected to be set with (see SourceFile's doc comment). It's also
+// used by SourceCLI's help page, so that it can show the file key alongside
+// each CLI option without needing a SourceFile instance of its own.
+func fileParamKey(path []string, name string) string {
+	return strings.Join(append(append([]string{}, path...), name), ".")
+}
+
+func lookupNested(m map[string]interface{}, path []string, name string) (interface{}, bool) {
+	cur := m
+	for _, p := range path {
+		next, ok := cur[p]
+		if !ok {
+			return nil, false
+		}
+		nextM, ok := next.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		cur = nextM
+	}
+	v, ok := cur[name]
+	return v, ok
+}
+
+// Parse implements the method for the Source interface.
+func (sf *SourceFile) Parse(cmp *mcmp.Component) ([]ParamValue, error) {
+	m, err := parseFile(sf.Path, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var pvs []ParamValue
+	for _, param := range CollectParams(cmp) {
+		path := param.Component.Path()
+
+		dottedName := fileParamKey(path, param.Name)
+
+		v, ok := lookupNested(m, path, param.Name)
+		if !ok {
+			v, ok = m[dottedName]
+		}
+		if !ok {
+			continue
+		}
+
+		raw, err := paramValueToRaw(param, v)
+		if err != nil {
+			return nil, fmt.Errorf("param %q in %q: %w", dottedName, sf.Path, err)
+		}
+
+		pvs = append(pvs, ParamValue{
+			Name:  param.Name,
+			Path:  path,
+			Value: raw,
+		})
+	}
+
+	return pvs, nil
+}
+
+// paramValueToRaw converts a value decoded from a config file into the
+// json.RawMessage form expected by ParamValue, honoring IsString/IsBool the
+// same way Param.fuzzyParse does for string-based Sources.
+func paramValueToRaw(param Param, v interface{}) (json.RawMessage, error) {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return json.Marshal(v)
+	}
+	return param.fuzzyParse(fmt.Sprint(v)), nil
+}
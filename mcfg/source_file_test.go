@@ -0,0 +1,122 @@
+package mcfg
+
+import (
+	"os"
+	"path/filepath"
+	. "testing"
+
+	"github.com/mediocregopher/mediocre-go-lib/mcmp"
+	"github.com/mediocregopher/mediocre-go-lib/mtest/massert"
+)
+
+func writeTmpFile(t *T, name, contents string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSourceFileJSON(t *T) {
+	path := writeTmpFile(t, "cfg.json", `{
+		"a": 4,
+		"foo": {"b": "bbb", "c": true}
+	}`)
+
+	cmp := new(mcmp.Component)
+	a := Int(cmp, "a", ParamRequired())
+	cmpFoo := cmp.Child("foo")
+	b := String(cmpFoo, "b", ParamRequired())
+	c := Bool(cmpFoo, "c")
+
+	err := Populate(cmp, &SourceFile{Path: path})
+	massert.Require(t,
+		massert.Nil(err),
+		massert.Equal(4, *a),
+		massert.Equal("bbb", *b),
+		massert.Equal(true, *c),
+	)
+}
+
+func TestSourceFileYAML(t *T) {
+	path := writeTmpFile(t, "cfg.yaml", "a: 4\nfoo:\n  b: bbb\n  c: true\n")
+
+	cmp := new(mcmp.Component)
+	a := Int(cmp, "a", ParamRequired())
+	cmpFoo := cmp.Child("foo")
+	b := String(cmpFoo, "b", ParamRequired())
+	c := Bool(cmpFoo, "c")
+
+	err := Populate(cmp, &SourceFile{Path: path})
+	massert.Require(t,
+		massert.Nil(err),
+		massert.Equal(4, *a),
+		massert.Equal("bbb", *b),
+		massert.Equal(true, *c),
+	)
+}
+
+func TestSourceFileFlatKeys(t *T) {
+	path := writeTmpFile(t, "cfg.json", `{"foo.b": "bbb"}`)
+
+	cmp := new(mcmp.Component)
+	cmpFoo := cmp.Child("foo")
+	b := String(cmpFoo, "b", ParamRequired())
+
+	err := Populate(cmp, &SourceFile{Path: path})
+	massert.Require(t,
+		massert.Nil(err),
+		massert.Equal("bbb", *b),
+	)
+}
+
+func TestSourceFileEnvInterpolation(t *T) {
+	os.Setenv("MCFG_TEST_B", "bbb")
+	defer os.Unsetenv("MCFG_TEST_B")
+
+	path := writeTmpFile(t, "cfg.yaml", "foo:\n  b: ${MCFG_TEST_B}\n")
+
+	cmp := new(mcmp.Component)
+	cmpFoo := cmp.Child("foo")
+	b := String(cmpFoo, "b", ParamRequired())
+
+	err := Populate(cmp, &SourceFile{Path: path})
+	massert.Require(t,
+		massert.Nil(err),
+		massert.Equal("bbb", *b),
+	)
+}
+
+func TestSourceFileInclude(t *T) {
+	path := writeTmpFile(t, "cfg.yaml", "foo: \"!include foo.yaml\"\n")
+	if err := os.WriteFile(filepath.Join(filepath.Dir(path), "foo.yaml"), []byte("b: bbb\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmp := new(mcmp.Component)
+	cmpFoo := cmp.Child("foo")
+	b := String(cmpFoo, "b", ParamRequired())
+
+	err := Populate(cmp, &SourceFile{Path: path})
+	massert.Require(t,
+		massert.Nil(err),
+		massert.Equal("bbb", *b),
+	)
+}
+
+func TestSourceFileLayering(t *T) {
+	basePath := writeTmpFile(t, "base.json", `{"a": 1, "foo": {"b": "base"}}`)
+	overlayPath := writeTmpFile(t, "overlay.json", `{"foo": {"b": "overlay"}}`)
+
+	cmp := new(mcmp.Component)
+	a := Int(cmp, "a", ParamRequired())
+	cmpFoo := cmp.Child("foo")
+	b := String(cmpFoo, "b", ParamRequired())
+
+	err := Populate(cmp, Sources{&SourceFile{Path: basePath}, &SourceFile{Path: overlayPath}})
+	massert.Require(t,
+		massert.Nil(err),
+		massert.Equal(1, *a),
+		massert.Equal("overlay", *b),
+	)
+}
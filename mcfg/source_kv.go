@@ -0,0 +1,188 @@
+package mcfg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mediocregopher/mediocre-go-lib/mcmp"
+)
+
+// KVUpdate describes a single key having changed within a KVStore, as
+// observed by KVStore.Watch.
+type KVUpdate struct {
+	Key   string
+	Value []byte
+
+	// Deleted is true if the key was removed, in which case Value is empty.
+	Deleted bool
+}
+
+// KVStore is the minimal interface a KV-store-backed configuration backend
+// (e.g. etcd or Consul) must implement in order to back a SourceKV. See
+// mcfg/mcfgetcd and mcfg/mcfgconsul for concrete implementations.
+type KVStore interface {
+	// Get returns every currently-set key/value pair under the store's
+	// configured prefix.
+	Get(ctx context.Context) (map[string][]byte, error)
+
+	// Watch streams KVUpdates for keys under the store's configured prefix
+	// as they change, until the Context is canceled.
+	Watch(ctx context.Context) (<-chan KVUpdate, error)
+}
+
+// SourceKV is a Source which parses configuration out of a KVStore (e.g.
+// etcd or Consul), and which can additionally Watch the store for live
+// updates.
+//
+// Keys are expected to follow the same layout as paramFullName (a Param's
+// Component path joined with its name), but with "-" swapped for "/", e.g. a
+// Param under path ["foo","bar"] named "addr" is expected at key
+// "foo/bar/addr" (optionally underneath a configured key prefix).
+type SourceKV struct {
+	Store KVStore
+}
+
+var (
+	_ Source  = (*SourceKV)(nil)
+	_ Watcher = (*SourceKV)(nil)
+)
+
+func kvKeyForParam(path []string, name string) string {
+	return strings.Join(append(append([]string{}, path...), name), "/")
+}
+
+// Parse implements the method for the Source interface.
+func (kv *SourceKV) Parse(cmp *mcmp.Component) ([]ParamValue, error) {
+	kvs, err := kv.Store.Get(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("fetching keys from KVStore: %w", err)
+	}
+
+	paramM := map[string]Param{}
+	for _, param := range CollectParams(cmp) {
+		paramM[kvKeyForParam(param.Component.Path(), param.Name)] = param
+	}
+
+	pvs := make([]ParamValue, 0, len(kvs))
+	for k, v := range kvs {
+		param, ok := paramM[strings.Trim(k, "/")]
+		if !ok {
+			continue
+		}
+		pvs = append(pvs, ParamValue{
+			Name:  param.Name,
+			Path:  param.Component.Path(),
+			Value: param.fuzzyParse(string(v)),
+		})
+	}
+
+	return pvs, nil
+}
+
+// Watch streams updates from the KVStore and, for every update whose key
+// corresponds to a watchable Param (see ParamWatchable) previously added to
+// cmp (or one of its descendants), re-unmarshals the new value into that
+// Param's Into field under a write lock, then fires any hooks registered via
+// OnReload or OnChange. Updates for Params which weren't created with
+// ParamWatchable are ignored.
+//
+// Watch blocks until ctx is canceled or the KVStore returns an error.
+func (kv *SourceKV) Watch(ctx context.Context, cmp *mcmp.Component) error {
+	updateCh, err := kv.Store.Watch(ctx)
+	if err != nil {
+		return fmt.Errorf("watching KVStore: %w", err)
+	}
+
+	paramM := map[string]Param{}
+	for _, param := range CollectParams(cmp) {
+		paramM[kvKeyForParam(param.Component.Path(), param.Name)] = param
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case up, ok := <-updateCh:
+			if !ok {
+				return nil
+			}
+			if up.Deleted {
+				continue
+			}
+
+			param, ok := paramM[strings.Trim(up.Key, "/")]
+			if !ok || !param.Watchable {
+				continue
+			}
+
+			raw := param.fuzzyParse(string(up.Value))
+			if err := reloadParam(param, raw); err != nil {
+				return fmt.Errorf("reloading param from key %q: %w", up.Key, err)
+			}
+			fireReloadHooks(param.Component, param.Name, raw)
+			fireChangeHooks(param.Component, param.Name, raw)
+		}
+	}
+}
+
+func reloadParam(param Param, raw json.RawMessage) error {
+	paramL.Lock()
+	defer paramL.Unlock()
+	return json.Unmarshal(raw, param.Into)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// ReloadFunc is called by OnReload whenever a live-reloading Source (e.g.
+// SourceKV.Watch) updates the value of a Param.
+type ReloadFunc func(paramName string, newVal json.RawMessage)
+
+type cmpReloadHooksKey int
+
+var paramL sync.RWMutex
+
+// OnReload registers fn to be called whenever a Param on cmp (but not its
+// descendants) is updated by a live-reloading Source, e.g. SourceKV.Watch.
+// This lets subsystems (mlog's log level, a database client's pool size,
+// etc...) react to configuration changes without restarting the process.
+func OnReload(cmp *mcmp.Component, fn ReloadFunc) {
+	hooks, _ := cmp.Value(cmpReloadHooksKey(0)).([]ReloadFunc)
+	hooks = append(hooks, fn)
+	cmp.SetValue(cmpReloadHooksKey(0), hooks)
+}
+
+func fireReloadHooks(cmp *mcmp.Component, paramName string, newVal json.RawMessage) {
+	hooks, _ := cmp.Value(cmpReloadHooksKey(0)).([]ReloadFunc)
+	for _, fn := range hooks {
+		fn(paramName, newVal)
+	}
+}
+
+type cmpChangeHooksKey string
+
+// OnChange registers fn to be called whenever the Param with the given name
+// on cmp (but not its descendants) is updated by a live-reloading Source
+// (e.g. SourceKV.Watch). This is like OnReload, but scoped to a single
+// Param, for subsystems which only care about one particular value changing
+// (e.g. swapping out a single connection pool) rather than inspecting every
+// reload on the Component themselves.
+//
+// The Param must have been created with ParamWatchable, otherwise fn will
+// never be called.
+func OnChange(cmp *mcmp.Component, name string, fn ReloadFunc) {
+	key := cmpChangeHooksKey(strings.ToLower(name))
+	hooks, _ := cmp.Value(key).([]ReloadFunc)
+	hooks = append(hooks, fn)
+	cmp.SetValue(key, hooks)
+}
+
+func fireChangeHooks(cmp *mcmp.Component, paramName string, newVal json.RawMessage) {
+	key := cmpChangeHooksKey(paramName)
+	hooks, _ := cmp.Value(key).([]ReloadFunc)
+	for _, fn := range hooks {
+		fn(paramName, newVal)
+	}
+}
@@ -0,0 +1,106 @@
+package mcfg
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	. "testing"
+	"time"
+
+	"github.com/mediocregopher/mediocre-go-lib/mcmp"
+	"github.com/stretchr/testify/assert"
+)
+
+// memKVStore is a bare-bones in-memory KVStore, sufficient for testing
+// SourceKV's Parse and Watch.
+type memKVStore struct {
+	l sync.Mutex
+	m map[string][]byte
+
+	updateCh chan KVUpdate
+}
+
+func newMemKVStore(init map[string][]byte) *memKVStore {
+	return &memKVStore{m: init, updateCh: make(chan KVUpdate, 16)}
+}
+
+func (s *memKVStore) Get(ctx context.Context) (map[string][]byte, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	m := make(map[string][]byte, len(s.m))
+	for k, v := range s.m {
+		m[k] = v
+	}
+	return m, nil
+}
+
+func (s *memKVStore) Watch(ctx context.Context) (<-chan KVUpdate, error) {
+	return s.updateCh, nil
+}
+
+func (s *memKVStore) set(key string, val []byte) {
+	s.updateCh <- KVUpdate{Key: key, Value: val}
+}
+
+// TestSourceKVWatchable covers ParamWatchable's gating of SourceKV.Watch's
+// live updates, and OnChange firing alongside OnReload when a watchable
+// Param is updated.
+func TestSourceKVWatchable(t *T) {
+	store := newMemKVStore(map[string][]byte{"a": []byte("1"), "b": []byte("2")})
+	cmp := new(mcmp.Component)
+	a := Int(cmp, "a", ParamWatchable())
+	b := Int(cmp, "b")
+
+	src := &SourceKV{Store: store}
+	assert.NoError(t, Populate(cmp, src))
+	assert.Equal(t, 1, *a)
+	assert.Equal(t, 2, *b)
+
+	var reloadedNames []string
+	OnReload(cmp, func(name string, _ json.RawMessage) {
+		reloadedNames = append(reloadedNames, name)
+	})
+
+	var changedVal int
+	OnChange(cmp, "a", func(_ string, raw json.RawMessage) {
+		json.Unmarshal(raw, &changedVal)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		store.set("a", []byte("10"))
+		store.set("b", []byte("20"))
+		close(store.updateCh)
+	}()
+
+	err := src.Watch(ctx, cmp)
+	cancel()
+	assert.NoError(t, err)
+
+	// only "a" is watchable, so only it should have been live-updated, and
+	// only it should have fired OnReload/OnChange.
+	assert.Equal(t, 10, *a)
+	assert.Equal(t, 2, *b)
+	assert.Equal(t, []string{"a"}, reloadedNames)
+	assert.Equal(t, 10, changedVal)
+}
+
+// TestPopulateWatch covers PopulateWatch's initial one-shot Populate followed
+// by handing off to the Source's Watch method.
+func TestPopulateWatch(t *T) {
+	store := newMemKVStore(map[string][]byte{"a": []byte("1")})
+	cmp := new(mcmp.Component)
+	a := Int(cmp, "a", ParamWatchable())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		store.set("a", []byte("99"))
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	err := PopulateWatch(ctx, cmp, &SourceKV{Store: store})
+	assert.True(t, err == nil || err == context.Canceled)
+	assert.Equal(t, 99, *a)
+}
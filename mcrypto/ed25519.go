@@ -0,0 +1,257 @@
+package mcrypto
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/mediocregopher/mediocre-go-lib/mlog"
+)
+
+var errMalformedEd25519Key = errors.New("malformed ed25519 key")
+
+// NewEd25519KeyPair generates and returns a complementary Ed25519
+// public/private key pair.
+//
+// Unlike PublicKey/PrivateKey (which are RSA-based and also support
+// encryption via an accompanying Curve25519 key), Ed25519PublicKey/
+// Ed25519PrivateKey only implement Verifier/Signer; there is no Ed25519-based
+// Encrypter/Decrypter.
+func NewEd25519KeyPair() (Ed25519PublicKey, Ed25519PrivateKey) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return Ed25519PublicKey{pub: pub}, Ed25519PrivateKey{priv: priv}
+}
+
+// NewEd25519Signer deterministically generates an Ed25519 key pair from seed
+// (see ed25519.NewKeyFromSeed) and returns its public half, for distributing
+// to Verifiers, and private half, for signing.
+//
+// A service can keep seed (or just the resulting Ed25519PrivateKey) to
+// itself and publish only the Ed25519PublicKey half to untrusted clients
+// (frontends, mobile apps, etc...) for them to verify with, something a
+// single shared Secret can't do since the same Secret bytes are needed for
+// both signing and verifying.
+func NewEd25519Signer(seed [32]byte) (Ed25519PublicKey, Ed25519PrivateKey) {
+	priv := ed25519.NewKeyFromSeed(seed[:])
+	pub := priv.Public().(ed25519.PublicKey)
+	return Ed25519PublicKey{pub: pub}, Ed25519PrivateKey{priv: priv}
+}
+
+// NewEd25519Verifier wraps an ed25519.PublicKey (e.g. one received from a
+// remote service, as opposed to one generated locally via NewEd25519KeyPair
+// or NewEd25519Signer) as an Ed25519PublicKey, for verifying Signatures
+// produced by the corresponding Ed25519PrivateKey.
+func NewEd25519Verifier(pub ed25519.PublicKey) Ed25519PublicKey {
+	return Ed25519PublicKey{pub: pub}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Ed25519PublicKey is a wrapper around an ed25519.PublicKey which implements
+// the Verifier interface, and adds marshaling/unmarshaling methods.
+type Ed25519PublicKey struct {
+	pub ed25519.PublicKey
+}
+
+// Signature doesn't carry any indication of which algorithm produced it;
+// verification is always dispatched by calling verify on whichever Verifier
+// (Secret, PublicKey, Ed25519PublicKey, ...) the caller already knows is
+// appropriate, so no changes to Signature's wire format are needed to
+// support a new algorithm here.
+func (pk Ed25519PublicKey) verify(s Signature, r io.Reader) error {
+	data, err := readAll(sigPrefixReader(r, uint8(len(s.sig)), s.salt, s.t))
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(pk.pub, data, s.sig) {
+		return mlog.ErrWithKV(ErrInvalidSig, s)
+	}
+	return nil
+}
+
+func (pk Ed25519PublicKey) String() string {
+	return edPubKeyV0 + hex.EncodeToString(pk.pub)
+}
+
+// KV implements the method for the mlog.KVer interface
+func (pk Ed25519PublicKey) KV() map[string]interface{} {
+	return map[string]interface{}{"ed25519PublicKey": pk.String()}
+}
+
+// MarshalText implements the method for the encoding.TextMarshaler interface
+func (pk Ed25519PublicKey) MarshalText() ([]byte, error) {
+	return []byte(pk.String()), nil
+}
+
+// UnmarshalText implements the method for the encoding.TextUnmarshaler
+// interface
+func (pk *Ed25519PublicKey) UnmarshalText(b []byte) error {
+	str := string(b)
+
+	strEnc, ok := stripPrefix(str, edPubKeyV0)
+	if !ok || len(strEnc) != hex.EncodedLen(ed25519.PublicKeySize) {
+		return mlog.ErrWithKV(errMalformedEd25519Key, mlog.KV{"pubKeyStr": str})
+	}
+
+	b, err := hex.DecodeString(strEnc)
+	if err != nil {
+		return mlog.ErrWithKV(err, mlog.KV{"pubKeyStr": str})
+	}
+	pk.pub = ed25519.PublicKey(b)
+	return nil
+}
+
+// MarshalJSON implements the method for the json.Marshaler interface
+func (pk Ed25519PublicKey) MarshalJSON() ([]byte, error) {
+	return json.Marshal(pk.String())
+}
+
+// UnmarshalJSON implements the method for the json.Unmarshaler interface
+func (pk *Ed25519PublicKey) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return pk.UnmarshalText([]byte(s))
+}
+
+// MarshalPEM marshals pk as a PEM-encoded PKIX public key block, for interop
+// with tools which expect standard PEM-formatted keys, as opposed to this
+// package's usual hex-with-prefix String format.
+func (pk Ed25519PublicKey) MarshalPEM() ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pk.pub)
+	if err != nil {
+		return nil, mlog.ErrWithKV(err, pk)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// UnmarshalEd25519PublicKeyPEM parses a PEM-encoded PKIX public key block, as
+// produced by Ed25519PublicKey.MarshalPEM, into an Ed25519PublicKey.
+func UnmarshalEd25519PublicKeyPEM(b []byte) (Ed25519PublicKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return Ed25519PublicKey{}, mlog.ErrWithKV(errMalformedEd25519Key, mlog.KV{"pem": string(b)})
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return Ed25519PublicKey{}, mlog.ErrWithKV(err, mlog.KV{"pem": string(b)})
+	}
+
+	edPub, ok := pub.(ed25519.PublicKey)
+	if !ok {
+		return Ed25519PublicKey{}, mlog.ErrWithKV(errMalformedEd25519Key, mlog.KV{"pem": string(b)})
+	}
+	return Ed25519PublicKey{pub: edPub}, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Ed25519PrivateKey is a wrapper around an ed25519.PrivateKey which
+// implements the Signer interface, and adds marshaling/unmarshaling methods.
+type Ed25519PrivateKey struct {
+	priv ed25519.PrivateKey
+}
+
+func (pk Ed25519PrivateKey) sign(r io.Reader) (Signature, error) {
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		panic(err)
+	}
+
+	t := time.Now()
+	data, err := readAll(sigPrefixReader(r, ed25519.SignatureSize, salt, t))
+	if err != nil {
+		return Signature{}, err
+	}
+
+	sig := ed25519.Sign(pk.priv, data)
+	return Signature{sig: sig, salt: salt, t: t}, nil
+}
+
+func (pk Ed25519PrivateKey) String() string {
+	return edPrivKeyV0 + hex.EncodeToString(pk.priv)
+}
+
+// KV implements the method for the mlog.KVer interface
+func (pk Ed25519PrivateKey) KV() map[string]interface{} {
+	return map[string]interface{}{"ed25519PrivateKey": pk.String()}
+}
+
+// MarshalText implements the method for the encoding.TextMarshaler interface
+func (pk Ed25519PrivateKey) MarshalText() ([]byte, error) {
+	return []byte(pk.String()), nil
+}
+
+// UnmarshalText implements the method for the encoding.TextUnmarshaler
+// interface
+func (pk *Ed25519PrivateKey) UnmarshalText(b []byte) error {
+	str := string(b)
+
+	strEnc, ok := stripPrefix(str, edPrivKeyV0)
+	if !ok || len(strEnc) != hex.EncodedLen(ed25519.PrivateKeySize) {
+		return mlog.ErrWithKV(errMalformedEd25519Key, mlog.KV{"privKeyStr": str})
+	}
+
+	b, err := hex.DecodeString(strEnc)
+	if err != nil {
+		return mlog.ErrWithKV(err, mlog.KV{"privKeyStr": str})
+	}
+	pk.priv = ed25519.PrivateKey(b)
+	return nil
+}
+
+// MarshalJSON implements the method for the json.Marshaler interface
+func (pk Ed25519PrivateKey) MarshalJSON() ([]byte, error) {
+	return json.Marshal(pk.String())
+}
+
+// UnmarshalJSON implements the method for the json.Unmarshaler interface
+func (pk *Ed25519PrivateKey) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return pk.UnmarshalText([]byte(s))
+}
+
+// MarshalPEM marshals pk as a PEM-encoded PKCS8 private key block, for
+// interop with tools which expect standard PEM-formatted keys, as opposed to
+// this package's usual hex-with-prefix String format.
+func (pk Ed25519PrivateKey) MarshalPEM() ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(pk.priv)
+	if err != nil {
+		return nil, mlog.ErrWithKV(err, pk)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// UnmarshalEd25519PrivateKeyPEM parses a PEM-encoded PKCS8 private key block,
+// as produced by Ed25519PrivateKey.MarshalPEM, into an Ed25519PrivateKey.
+func UnmarshalEd25519PrivateKeyPEM(b []byte) (Ed25519PrivateKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return Ed25519PrivateKey{}, mlog.ErrWithKV(errMalformedEd25519Key, mlog.KV{"pem": string(b)})
+	}
+
+	priv, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return Ed25519PrivateKey{}, mlog.ErrWithKV(err, mlog.KV{"pem": string(b)})
+	}
+
+	edPriv, ok := priv.(ed25519.PrivateKey)
+	if !ok {
+		return Ed25519PrivateKey{}, mlog.ErrWithKV(errMalformedEd25519Key, mlog.KV{"pem": string(b)})
+	}
+	return Ed25519PrivateKey{priv: edPriv}, nil
+}
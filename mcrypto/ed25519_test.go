@@ -0,0 +1,63 @@
+package mcrypto
+
+import (
+	. "testing"
+
+	"github.com/ansel1/merry"
+	"github.com/mediocregopher/mediocre-go-lib/mrand"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEd25519KeyPair(t *T) {
+	pub, priv := NewEd25519KeyPair()
+
+	str := mrand.Hex(512)
+	sig := SignString(priv, str)
+	assert.NoError(t, VerifyString(pub, sig, str))
+
+	otherPub, _ := NewEd25519KeyPair()
+	err := VerifyString(otherPub, sig, str)
+	assert.True(t, merry.Is(err, ErrInvalidSig))
+
+	// marshaling/unmarshaling round-trips, and the result verifies the same
+	pubStr, err := pub.MarshalText()
+	assert.NoError(t, err)
+	var pub2 Ed25519PublicKey
+	assert.NoError(t, pub2.UnmarshalText(pubStr))
+	assert.NoError(t, VerifyString(pub2, sig, str))
+
+	privStr, err := priv.MarshalText()
+	assert.NoError(t, err)
+	var priv2 Ed25519PrivateKey
+	assert.NoError(t, priv2.UnmarshalText(privStr))
+	assert.NoError(t, VerifyString(pub, SignString(priv2, str), str))
+}
+
+func TestEd25519Signer(t *T) {
+	var seed [32]byte
+	copy(seed[:], mrand.Bytes(32))
+
+	pub, priv := NewEd25519Signer(seed)
+	pub2, priv2 := NewEd25519Signer(seed)
+	assert.Equal(t, pub, pub2)
+	assert.Equal(t, priv, priv2)
+
+	str := mrand.Hex(512)
+	assert.NoError(t, VerifyString(NewEd25519Verifier(pub.pub), SignString(priv, str), str))
+}
+
+func TestEd25519KeyPEM(t *T) {
+	pub, priv := NewEd25519KeyPair()
+
+	pubPEM, err := pub.MarshalPEM()
+	assert.NoError(t, err)
+	pub2, err := UnmarshalEd25519PublicKeyPEM(pubPEM)
+	assert.NoError(t, err)
+	assert.Equal(t, pub, pub2)
+
+	privPEM, err := priv.MarshalPEM()
+	assert.NoError(t, err)
+	priv2, err := UnmarshalEd25519PrivateKeyPEM(privPEM)
+	assert.NoError(t, err)
+	assert.Equal(t, priv, priv2)
+}
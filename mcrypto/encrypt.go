@@ -0,0 +1,195 @@
+package mcrypto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/mediocregopher/mediocre-go-lib/merr"
+)
+
+var (
+	errMalformedCiphertext = errors.New("malformed ciphertext")
+
+	// ErrInvalidCiphertext is returned by Decrypter related functions when a
+	// Ciphertext can't be decrypted, e.g. it was encrypted using a different
+	// key, or has been tampered with.
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
+
+// cryptoAlgo is a versioned identifier for the underlying algorithm used to
+// produce a Ciphertext's bytes. Storing this on the Ciphertext itself means
+// new algorithms can be added in the future without breaking the ability to
+// decode (though not necessarily decrypt) Ciphertexts produced by older ones.
+type cryptoAlgo uint8
+
+const (
+	// cryptoAlgoSecretbox indicates a Ciphertext was produced by a Secret,
+	// using NaCl's secretbox (XSalsa20-Poly1305).
+	cryptoAlgoSecretbox cryptoAlgo = iota
+
+	// cryptoAlgoBox indicates a Ciphertext was produced by a PublicKey, using
+	// NaCl's anonymous box (X25519-XSalsa20-Poly1305).
+	cryptoAlgoBox
+)
+
+// Ciphertext marshals/unmarshals the encrypted bytes produced internally by
+// an Encrypter, along with the algorithm and nonce (if any) needed to decrypt
+// them, and the timestamp the encryption took place.
+type Ciphertext struct {
+	algo  cryptoAlgo
+	nonce []byte // never more than 255 bytes long
+	ct    []byte
+	t     time.Time
+}
+
+// Time returns the timestamp the Ciphertext was generated at.
+func (c Ciphertext) Time() time.Time {
+	return c.t
+}
+
+func (c Ciphertext) String() string {
+	// ts:8 + algo:1 + nonceHeader:1 + nonce + ct
+	b := make([]byte, 10+len(c.nonce)+len(c.ct))
+	binary.BigEndian.PutUint64(b, uint64(c.t.UnixNano()))
+	b[8] = uint8(c.algo)
+	ptr := 9
+	b[ptr], ptr = uint8(len(c.nonce)), ptr+1
+	ptr += copy(b[ptr:], c.nonce)
+	copy(b[ptr:], c.ct)
+	return encryptedV0 + hex.EncodeToString(b)
+}
+
+// KV implements the method for the mlog.KVer interface
+func (c Ciphertext) KV() map[string]interface{} {
+	return map[string]interface{}{"ciphertext": c.String()}
+}
+
+// MarshalText implements the method for the encoding.TextMarshaler interface
+func (c Ciphertext) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements the method for the encoding.TextUnmarshaler
+// interface
+func (c *Ciphertext) UnmarshalText(b []byte) error {
+	str := string(b)
+	strEnc, ok := stripPrefix(str, encryptedV0)
+	if !ok || len(strEnc) < hex.EncodedLen(10) {
+		return merr.Wrap(errMalformedCiphertext)
+	}
+
+	b, err := hex.DecodeString(strEnc)
+	if err != nil {
+		return merr.Wrap(err)
+	}
+
+	unixNano := int64(binary.BigEndian.Uint64(b[:8]))
+	c.t = time.Unix(0, unixNano).Local()
+	c.algo = cryptoAlgo(b[8])
+	b = b[9:]
+
+	if len(b) < 1+int(b[0]) {
+		return merr.Wrap(errMalformedCiphertext)
+	}
+	c.nonce = b[1 : 1+b[0]]
+	c.ct = b[1+b[0]:]
+	return nil
+}
+
+// MarshalJSON implements the method for the json.Marshaler interface
+func (c Ciphertext) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+// UnmarshalJSON implements the method for the json.Unmarshaler interface
+func (c *Ciphertext) UnmarshalJSON(b []byte) error {
+	var str string
+	if err := json.Unmarshal(b, &str); err != nil {
+		return err
+	}
+	return c.UnmarshalText([]byte(str))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Encrypter is some entity which can encrypt arbitrary data into a Ciphertext
+// which can later be decrypted by a Decrypter.
+type Encrypter interface {
+	encrypt(io.Reader) (Ciphertext, error)
+}
+
+// Decrypter is some entity which can decrypt Ciphertexts produced by an
+// Encrypter back into the original data.
+type Decrypter interface {
+	// writes the decrypted data to the io.Writer, or returns an error if the
+	// Ciphertext couldn't be decrypted.
+	decrypt(Ciphertext, io.Writer) error
+}
+
+// Encrypt reads all data from the io.Reader and encrypts it using the given
+// Encrypter.
+func Encrypt(e Encrypter, r io.Reader) (Ciphertext, error) {
+	return e.encrypt(r)
+}
+
+// EncryptBytes uses the Encrypter to encrypt the given []byte.
+func EncryptBytes(e Encrypter, b []byte) Ciphertext {
+	ct, err := e.encrypt(bytes.NewBuffer(b))
+	if err != nil {
+		panic(err)
+	}
+	return ct
+}
+
+// EncryptString uses the Encrypter to encrypt the given string.
+func EncryptString(e Encrypter, in string) Ciphertext {
+	return EncryptBytes(e, []byte(in))
+}
+
+// Decrypt uses the Decrypter to decrypt the Ciphertext, writing the
+// decrypted data to the io.Writer.
+//
+// Returns ErrInvalidCiphertext (use merr.Equal(err, mcrypto.ErrInvalidCiphertext)
+// to check) if the Ciphertext couldn't be decrypted.
+func Decrypt(d Decrypter, c Ciphertext, w io.Writer) error {
+	return d.decrypt(c, w)
+}
+
+// DecryptBytes uses the Decrypter to decrypt the Ciphertext, returning the
+// decrypted data as a []byte.
+//
+// Returns ErrInvalidCiphertext (use merr.Equal(err, mcrypto.ErrInvalidCiphertext)
+// to check) if the Ciphertext couldn't be decrypted.
+func DecryptBytes(d Decrypter, c Ciphertext) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := d.decrypt(c, buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecryptString uses the Decrypter to decrypt the Ciphertext, returning the
+// decrypted data as a string.
+//
+// Returns ErrInvalidCiphertext (use merr.Equal(err, mcrypto.ErrInvalidCiphertext)
+// to check) if the Ciphertext couldn't be decrypted.
+func DecryptString(d Decrypter, c Ciphertext) (string, error) {
+	b, err := DecryptBytes(d, c)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// readAll is a small wrapper around ioutil.ReadAll, used by Encrypter
+// implementations which must have all of the plaintext in memory at once
+// (nacl's box and secretbox do not support streaming).
+func readAll(r io.Reader) ([]byte, error) {
+	return ioutil.ReadAll(r)
+}
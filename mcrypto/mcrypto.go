@@ -27,6 +27,20 @@ const (
 	encryptedV0 = "0n" // n for "n"-crypted, harharhar
 	pubKeyV0    = "0l" // b for pub"l"ic key
 	privKeyV0   = "0v" // v for pri"v"ate key
+
+	// pubKeyV1 and privKeyV1 are the same as pubKeyV0/privKeyV0, but for
+	// PublicKey/PrivateKey instances which also carry a Curve25519 key pair
+	// (see PublicKey.encrypt/PrivateKey.decrypt). Keys marshaled with the V0
+	// prefixes are still unmarshalable, they just won't support encryption.
+	pubKeyV1  = "1l"
+	privKeyV1 = "1v"
+
+	// edPubKeyV0 and edPrivKeyV0 are used by Ed25519PublicKey/
+	// Ed25519PrivateKey, which are distinct from PublicKey/PrivateKey (those
+	// are RSA-based) and so get their own prefixes rather than reusing
+	// pubKeyV0/privKeyV0.
+	edPubKeyV0  = "0e" // e for "e"d25519
+	edPrivKeyV0 = "0f" // f, follows e
 )
 
 func stripPrefix(s, prefix string) (string, bool) {
@@ -0,0 +1,202 @@
+// Package mjwt produces and validates JSON Web Tokens (JWTs), using the
+// Signer/Verifier interfaces from the mcrypto package to do the actual
+// signing/verifying.
+//
+// Unlike most JWT implementations, the signature segment of a token produced
+// by this package is not a raw signature, but the marshaled form of an
+// mcrypto.Signature, salt and timestamp included. This means two tokens
+// issued for the same claims will never be identical, and that exp/nbf/iat
+// (which this package manages itself, see Issue) are also covered by the
+// signature twice over: once as part of the JSON payload, and again as part
+// of the salted input mcrypto.Signature itself hashes.
+package mjwt
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/mediocregopher/mediocre-go-lib/mcrypto"
+	"github.com/mediocregopher/mediocre-go-lib/merr"
+)
+
+var (
+	// ErrMalformedToken is returned by Parse when the token isn't even
+	// shaped like a JWT.
+	ErrMalformedToken = errors.New("malformed token")
+
+	// ErrUnsupportedAlg is returned by Parse when the token's alg header
+	// doesn't match the one expected for the given Verifier, or is "none".
+	ErrUnsupportedAlg = errors.New("unsupported or disallowed algorithm")
+
+	// ErrExpired is returned by Parse when the token's exp claim is in the
+	// past.
+	ErrExpired = errors.New("token is expired")
+
+	// ErrNotYetValid is returned by Parse when the token's nbf claim is in
+	// the future.
+	ErrNotYetValid = errors.New("token is not yet valid")
+)
+
+const (
+	algHS256 = "HS256"
+
+	// PublicKey/PrivateKey sign using RSA-PSS/SHA256, which JWT calls PS256
+	// (RFC 7518 §3.5). This package doesn't support EdDSA, since PrivateKey
+	// wraps an rsa.PrivateKey rather than an ed25519 one.
+	algPS256 = "PS256"
+)
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+func algFor(i interface{}) (string, bool) {
+	switch i.(type) {
+	case mcrypto.Secret:
+		return algHS256, true
+	case mcrypto.PrivateKey:
+		return algPS256, true
+	case mcrypto.PublicKey:
+		return algPS256, true
+	default:
+		return "", false
+	}
+}
+
+func b64Encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func b64Decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+// Issue marshals the given claims to JSON, sets exp (if ttl is greater than
+// 0), nbf, and iat on the resulting object, and returns the result as a
+// signed JWT string.
+//
+// s must be an mcrypto.Secret or mcrypto.PrivateKey; any other Signer
+// implementation will result in an error.
+func Issue(s mcrypto.Signer, claims interface{}, ttl time.Duration) (string, error) {
+	alg, ok := algFor(s)
+	if !ok {
+		return "", merr.Wrap(context.Background(), ErrUnsupportedAlg)
+	}
+
+	claimsB, err := json.Marshal(claims)
+	if err != nil {
+		return "", merr.Wrap(context.Background(), err)
+	}
+
+	claimsMap := map[string]interface{}{}
+	if err := json.Unmarshal(claimsB, &claimsMap); err != nil {
+		return "", merr.Wrap(context.Background(), err)
+	}
+
+	now := time.Now()
+	claimsMap["iat"] = now.Unix()
+	claimsMap["nbf"] = now.Unix()
+	if ttl > 0 {
+		claimsMap["exp"] = now.Add(ttl).Unix()
+	} else {
+		delete(claimsMap, "exp")
+	}
+
+	payloadB, err := json.Marshal(claimsMap)
+	if err != nil {
+		return "", merr.Wrap(context.Background(), err)
+	}
+
+	headerB, err := json.Marshal(header{Alg: alg, Typ: "JWT"})
+	if err != nil {
+		return "", merr.Wrap(context.Background(), err)
+	}
+
+	signingInput := b64Encode(headerB) + "." + b64Encode(payloadB)
+	sig := mcrypto.SignString(s, signingInput)
+
+	return signingInput + "." + b64Encode([]byte(sig.String())), nil
+}
+
+// Parse verifies the given JWT string using v, and if valid unmarshals its
+// claims into the given claims value (which should be a pointer, as with
+// json.Unmarshal).
+//
+// v must be an mcrypto.Secret or mcrypto.PublicKey; any other Verifier
+// implementation will result in an error.
+//
+// Returns ErrMalformedToken, ErrUnsupportedAlg, ErrExpired, or
+// ErrNotYetValid (use merr.Equal(err, mjwt.ErrExpired), etc, to check) if the
+// token is invalid for one of those reasons. Otherwise any error returned by
+// v's underlying Verify call (e.g. mcrypto.ErrInvalidSig) is returned as-is.
+func Parse(v mcrypto.Verifier, token string, claims interface{}) error {
+	expectedAlg, ok := algFor(v)
+	if !ok {
+		return merr.Wrap(context.Background(), ErrUnsupportedAlg)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return merr.Wrap(context.Background(), ErrMalformedToken)
+	}
+	headerPart, payloadPart, sigPart := parts[0], parts[1], parts[2]
+
+	headerB, err := b64Decode(headerPart)
+	if err != nil {
+		return merr.Wrap(context.Background(), ErrMalformedToken)
+	}
+	var h header
+	if err := json.Unmarshal(headerB, &h); err != nil {
+		return merr.Wrap(context.Background(), ErrMalformedToken)
+	}
+	if h.Alg == "none" || h.Alg != expectedAlg {
+		return merr.Wrap(context.Background(), ErrUnsupportedAlg)
+	}
+
+	payloadB, err := b64Decode(payloadPart)
+	if err != nil {
+		return merr.Wrap(context.Background(), ErrMalformedToken)
+	}
+
+	sigB, err := b64Decode(sigPart)
+	if err != nil {
+		return merr.Wrap(context.Background(), ErrMalformedToken)
+	}
+	var sig mcrypto.Signature
+	if err := sig.UnmarshalText(sigB); err != nil {
+		return merr.Wrap(context.Background(), ErrMalformedToken)
+	}
+
+	signingInput := headerPart + "." + payloadPart
+	if err := mcrypto.VerifyString(v, sig, signingInput); err != nil {
+		return err
+	}
+
+	var registered struct {
+		Exp *int64 `json:"exp"`
+		Nbf *int64 `json:"nbf"`
+	}
+	if err := json.Unmarshal(payloadB, &registered); err != nil {
+		return merr.Wrap(context.Background(), ErrMalformedToken)
+	}
+
+	now := time.Now().Unix()
+	if registered.Exp != nil && now >= *registered.Exp {
+		return merr.Wrap(context.Background(), ErrExpired)
+	}
+	if registered.Nbf != nil && now < *registered.Nbf {
+		return merr.Wrap(context.Background(), ErrNotYetValid)
+	}
+
+	if claims != nil {
+		if err := json.Unmarshal(payloadB, claims); err != nil {
+			return merr.Wrap(context.Background(), err)
+		}
+	}
+	return nil
+}
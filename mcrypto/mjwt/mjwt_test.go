@@ -0,0 +1,75 @@
+package mjwt
+
+import (
+	. "testing"
+	"time"
+
+	"github.com/mediocregopher/mediocre-go-lib/mcrypto"
+	"github.com/mediocregopher/mediocre-go-lib/mrand"
+	"github.com/stretchr/testify/assert"
+)
+
+type testClaims struct {
+	UserID string `json:"userId"`
+}
+
+func TestIssueParseSecret(t *T) {
+	secret := mcrypto.NewSecret(mrand.Bytes(16))
+
+	token, err := Issue(secret, testClaims{UserID: "123"}, time.Minute)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+
+	var claims testClaims
+	assert.NoError(t, Parse(secret, token, &claims))
+	assert.Equal(t, "123", claims.UserID)
+
+	otherSecret := mcrypto.NewSecret(mrand.Bytes(16))
+	assert.Error(t, Parse(otherSecret, token, &claims))
+}
+
+func TestIssueParseKeyPair(t *T) {
+	pub, priv := mcrypto.NewWeakKeyPair()
+
+	token, err := Issue(priv, testClaims{UserID: "456"}, time.Minute)
+	assert.NoError(t, err)
+
+	var claims testClaims
+	assert.NoError(t, Parse(pub, token, &claims))
+	assert.Equal(t, "456", claims.UserID)
+
+	otherPub, _ := mcrypto.NewWeakKeyPair()
+	assert.Error(t, Parse(otherPub, token, &claims))
+}
+
+func TestIssueNoTTL(t *T) {
+	secret := mcrypto.NewSecret(mrand.Bytes(16))
+
+	token, err := Issue(secret, testClaims{UserID: "789"}, 0)
+	assert.NoError(t, err)
+
+	var claims testClaims
+	assert.NoError(t, Parse(secret, token, &claims))
+	assert.Equal(t, "789", claims.UserID)
+}
+
+func TestParseExpired(t *T) {
+	secret := mcrypto.NewSecret(mrand.Bytes(16))
+
+	token, err := Issue(secret, testClaims{UserID: "123"}, time.Nanosecond)
+	assert.NoError(t, err)
+
+	time.Sleep(10 * time.Millisecond)
+
+	var claims testClaims
+	err = Parse(secret, token, &claims)
+	assert.Error(t, err)
+}
+
+func TestParseMalformed(t *T) {
+	secret := mcrypto.NewSecret(mrand.Bytes(16))
+	var claims testClaims
+
+	assert.Error(t, Parse(secret, "not-a-jwt", &claims))
+	assert.Error(t, Parse(secret, "a.b.c", &claims))
+}
@@ -11,9 +11,11 @@ import (
 	"errors"
 	"io"
 	"math/big"
+	"strings"
 	"time"
 
 	"github.com/mediocregopher/mediocre-go-lib/mlog"
+	"golang.org/x/crypto/nacl/box"
 )
 
 var (
@@ -38,7 +40,46 @@ func newKeyPair(bits int) (PublicKey, PrivateKey) {
 	if err != nil {
 		panic(err)
 	}
-	return PublicKey{priv.PublicKey}, PrivateKey{priv}
+
+	boxPub, boxPriv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	return PublicKey{PublicKey: priv.PublicKey, boxPub: boxPub},
+		PrivateKey{PrivateKey: priv, boxPub: boxPub, boxPriv: boxPriv}
+}
+
+// UnmarshalVerifier unmarshals a text-encoded public key produced by either
+// PublicKey.MarshalText or Ed25519PublicKey.MarshalText, dispatching to
+// whichever backend the key's prefix indicates, and returns it as a Verifier.
+//
+// This is useful any time a single piece of code (e.g. config loading) needs
+// to accept a public key without its caller having to know ahead of time
+// which algorithm produced it.
+func UnmarshalVerifier(b []byte) (Verifier, error) {
+	if strings.HasPrefix(string(b), edPubKeyV0) {
+		var pk Ed25519PublicKey
+		err := pk.UnmarshalText(b)
+		return pk, err
+	}
+	var pk PublicKey
+	err := pk.UnmarshalText(b)
+	return pk, err
+}
+
+// UnmarshalSigner is the private-key counterpart to UnmarshalVerifier,
+// dispatching to whichever of PrivateKey/Ed25519PrivateKey the key's prefix
+// indicates.
+func UnmarshalSigner(b []byte) (Signer, error) {
+	if strings.HasPrefix(string(b), edPrivKeyV0) {
+		var pk Ed25519PrivateKey
+		err := pk.UnmarshalText(b)
+		return pk, err
+	}
+	var pk PrivateKey
+	err := pk.UnmarshalText(b)
+	return pk, err
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -46,9 +87,33 @@ func newKeyPair(bits int) (PublicKey, PrivateKey) {
 // PublicKey is a wrapper around an rsa.PublicKey which simplifies using it and
 // adds marshaling/unmarshaling methods.
 //
-// A PublicKey automatically implements the Verifier interface.
+// A PublicKey automatically implements the Verifier interface. If it was
+// generated via NewKeyPair/NewWeakKeyPair (as opposed to being unmarshaled
+// from an older, V0 marshaled form) it also implements the Encrypter
+// interface, via a Curve25519 key pair generated alongside the RSA one.
 type PublicKey struct {
 	rsa.PublicKey
+	boxPub *[32]byte
+}
+
+// encrypt implements the method for the Encrypter interface, using NaCl's
+// anonymous box construction. It panics if pk was unmarshaled from an older,
+// V0 marshaled form, which doesn't carry the Curve25519 key this requires.
+func (pk PublicKey) encrypt(r io.Reader) (Ciphertext, error) {
+	if pk.boxPub == nil {
+		panic("PublicKey has no Curve25519 key to encrypt with")
+	}
+
+	data, err := readAll(r)
+	if err != nil {
+		return Ciphertext{}, err
+	}
+
+	ct, err := box.SealAnonymous(nil, data, pk.boxPub, rand.Reader)
+	if err != nil {
+		return Ciphertext{}, mlog.ErrWithKV(err, pk)
+	}
+	return Ciphertext{algo: cryptoAlgoBox, ct: ct, t: time.Now()}, nil
 }
 
 func (pk PublicKey) verify(s Signature, r io.Reader) error {
@@ -65,11 +130,21 @@ func (pk PublicKey) verify(s Signature, r io.Reader) error {
 
 func (pk PublicKey) String() string {
 	nB := pk.N.Bytes()
-	b := make([]byte, 8+len(nB))
-	// the exponent is never negative so this is fine
+
+	if pk.boxPub == nil {
+		b := make([]byte, 8+len(nB))
+		// the exponent is never negative so this is fine
+		binary.BigEndian.PutUint64(b, uint64(pk.E))
+		copy(b[8:], nB)
+		return pubKeyV0 + hex.EncodeToString(b)
+	}
+
+	// E:8 + boxPub:32 + N
+	b := make([]byte, 8+32+len(nB))
 	binary.BigEndian.PutUint64(b, uint64(pk.E))
-	copy(b[8:], nB)
-	return pubKeyV0 + hex.EncodeToString(b)
+	copy(b[8:40], pk.boxPub[:])
+	copy(b[40:], nB)
+	return pubKeyV1 + hex.EncodeToString(b)
 }
 
 // KV implements the method for the mlog.KVer interface
@@ -86,7 +161,27 @@ func (pk PublicKey) MarshalText() ([]byte, error) {
 // interface
 func (pk *PublicKey) UnmarshalText(b []byte) error {
 	str := string(b)
-	strEnc, ok := stripPrefix(str, pubKeyV0)
+
+	strEnc, ok := stripPrefix(str, pubKeyV1)
+	if ok {
+		if len(strEnc) <= hex.EncodedLen(8+32) {
+			return mlog.ErrWithKV(errMalformedPublicKey, mlog.KV{"pubKeyStr": str})
+		}
+
+		b, err := hex.DecodeString(strEnc)
+		if err != nil {
+			return mlog.ErrWithKV(err, mlog.KV{"pubKeyStr": str})
+		}
+
+		pk.E = int(binary.BigEndian.Uint64(b))
+		pk.boxPub = new([32]byte)
+		copy(pk.boxPub[:], b[8:40])
+		pk.N = new(big.Int)
+		pk.N.SetBytes(b[40:])
+		return nil
+	}
+
+	strEnc, ok = stripPrefix(str, pubKeyV0)
 	if !ok || len(strEnc) <= hex.EncodedLen(8) {
 		return mlog.ErrWithKV(errMalformedPublicKey, mlog.KV{"pubKeyStr": str})
 	}
@@ -121,9 +216,32 @@ func (pk *PublicKey) UnmarshalJSON(b []byte) error {
 // PrivateKey is a wrapper around an rsa.PrivateKey which simplifies using it
 // and adds marshaling/unmarshaling methods.
 //
-// A PrivateKey automatically implements the Signer interface.
+// A PrivateKey automatically implements the Signer interface. If it was
+// generated via NewKeyPair/NewWeakKeyPair (as opposed to being unmarshaled
+// from an older, V0 marshaled form) it also implements the Decrypter
+// interface, via a Curve25519 key pair generated alongside the RSA one.
 type PrivateKey struct {
 	*rsa.PrivateKey
+	boxPub  *[32]byte
+	boxPriv *[32]byte
+}
+
+// decrypt implements the method for the Decrypter interface, using NaCl's
+// anonymous box construction. It returns ErrInvalidCiphertext if pk was
+// unmarshaled from an older, V0 marshaled form, which doesn't carry the
+// Curve25519 key this requires.
+func (pk PrivateKey) decrypt(c Ciphertext, w io.Writer) error {
+	if c.algo != cryptoAlgoBox || pk.boxPub == nil || pk.boxPriv == nil {
+		return mlog.ErrWithKV(ErrInvalidCiphertext, c)
+	}
+
+	data, ok := box.OpenAnonymous(nil, c.ct, pk.boxPub, pk.boxPriv)
+	if !ok {
+		return mlog.ErrWithKV(ErrInvalidCiphertext, c)
+	}
+
+	_, err := w.Write(data)
+	return err
 }
 
 func (pk PrivateKey) sign(r io.Reader) (Signature, error) {
@@ -143,9 +261,14 @@ func (pk PrivateKey) sign(r io.Reader) (Signature, error) {
 }
 
 func (pk PrivateKey) String() string {
+	haveBox := pk.boxPub != nil && pk.boxPriv != nil
+
 	numBytes := binary.MaxVarintLen64 * 3 // public exponent, N, and D
 	nB, dB := pk.PublicKey.N.Bytes(), pk.D.Bytes()
 	numBytes += len(nB) + len(dB)
+	if haveBox {
+		numBytes += 64
+	}
 
 	primes := make([][]byte, len(pk.Primes))
 	for i, prime := range pk.Primes {
@@ -154,6 +277,10 @@ func (pk PrivateKey) String() string {
 	}
 
 	b, ptr := make([]byte, numBytes), 0
+	if haveBox {
+		ptr += copy(b[ptr:], pk.boxPub[:])
+		ptr += copy(b[ptr:], pk.boxPriv[:])
+	}
 	ptr += binary.PutUvarint(b[ptr:], uint64(pk.E))
 	ptr += binary.PutUvarint(b[ptr:], uint64(len(nB)))
 	ptr += copy(b[ptr:], nB)
@@ -165,6 +292,9 @@ func (pk PrivateKey) String() string {
 		ptr += copy(b[ptr:], prime)
 	}
 
+	if haveBox {
+		return privKeyV1 + hex.EncodeToString(b[:ptr])
+	}
 	return privKeyV0 + hex.EncodeToString(b[:ptr])
 }
 
@@ -182,8 +312,12 @@ func (pk PrivateKey) MarshalText() ([]byte, error) {
 // interface
 func (pk *PrivateKey) UnmarshalText(b []byte) error {
 	str := string(b)
-	strEnc, ok := stripPrefix(str, privKeyV0)
-	if !ok {
+
+	haveBox := false
+	strEnc, ok := stripPrefix(str, privKeyV1)
+	if ok {
+		haveBox = true
+	} else if strEnc, ok = stripPrefix(str, privKeyV0); !ok {
 		return mlog.ErrWithKV(errMalformedPrivateKey, mlog.KV{"privKeyStr": str})
 	}
 
@@ -192,6 +326,17 @@ func (pk *PrivateKey) UnmarshalText(b []byte) error {
 		return mlog.ErrWithKV(err, mlog.KV{"privKeyStr": str})
 	}
 
+	if haveBox {
+		if len(b) < 64 {
+			return mlog.ErrWithKV(errMalformedPrivateKey, mlog.KV{"privKeyStr": str})
+		}
+		pk.boxPub = new([32]byte)
+		copy(pk.boxPub[:], b[:32])
+		pk.boxPriv = new([32]byte)
+		copy(pk.boxPriv[:], b[32:64])
+		b = b[64:]
+	}
+
 	e, n := binary.Uvarint(b)
 	if n <= 0 {
 		return mlog.ErrWithKV(errMalformedPrivateKey, mlog.KV{"privKeyStr": str})
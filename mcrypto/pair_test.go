@@ -3,6 +3,7 @@ package mcrypto
 import (
 	. "testing"
 
+	"github.com/ansel1/merry"
 	"github.com/mediocregopher/mediocre-go-lib/mrand"
 	"github.com/stretchr/testify/assert"
 )
@@ -14,4 +15,56 @@ func TestKeyPair(t *T) {
 	str := mrand.Hex(512)
 	sig := SignString(priv, str)
 	assert.NoError(t, VerifyString(pub, sig, str))
+
+	// test encrypting/decrypting
+	ct := EncryptString(pub, str)
+	got, err := DecryptString(priv, ct)
+	assert.NoError(t, err)
+	assert.Equal(t, str, got)
+
+	otherPub, otherPriv := NewWeakKeyPair()
+	_, err = DecryptString(otherPriv, ct)
+	assert.True(t, merry.Is(err, ErrInvalidCiphertext))
+
+	otherCt := EncryptString(otherPub, str)
+	_, err = DecryptString(priv, otherCt)
+	assert.True(t, merry.Is(err, ErrInvalidCiphertext))
+}
+
+func TestUnmarshalVerifierSigner(t *T) {
+	str := mrand.Hex(512)
+
+	rsaPub, rsaPriv := NewWeakKeyPair()
+	rsaPubStr, err := rsaPub.MarshalText()
+	assert.NoError(t, err)
+	rsaPrivStr, err := rsaPriv.MarshalText()
+	assert.NoError(t, err)
+
+	edPub, edPriv := NewEd25519KeyPair()
+	edPubStr, err := edPub.MarshalText()
+	assert.NoError(t, err)
+	edPrivStr, err := edPriv.MarshalText()
+	assert.NoError(t, err)
+
+	gotRSAPub, err := UnmarshalVerifier(rsaPubStr)
+	assert.NoError(t, err)
+	assert.IsType(t, PublicKey{}, gotRSAPub)
+
+	gotEdPub, err := UnmarshalVerifier(edPubStr)
+	assert.NoError(t, err)
+	assert.IsType(t, Ed25519PublicKey{}, gotEdPub)
+
+	gotRSAPriv, err := UnmarshalSigner(rsaPrivStr)
+	assert.NoError(t, err)
+	assert.IsType(t, PrivateKey{}, gotRSAPriv)
+
+	gotEdPriv, err := UnmarshalSigner(edPrivStr)
+	assert.NoError(t, err)
+	assert.IsType(t, Ed25519PrivateKey{}, gotEdPriv)
+
+	sig := SignString(gotRSAPriv, str)
+	assert.NoError(t, VerifyString(gotRSAPub, sig, str))
+
+	sig = SignString(gotEdPriv, str)
+	assert.NoError(t, VerifyString(gotEdPub, sig, str))
 }
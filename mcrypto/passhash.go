@@ -0,0 +1,203 @@
+package mcrypto
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mediocregopher/mediocre-go-lib/mlog"
+	"golang.org/x/crypto/argon2"
+)
+
+var (
+	errMalformedPassword = errors.New("malformed password hash")
+
+	// ErrInvalidPassword is returned by Password.Verify when plain doesn't
+	// match the hashed password.
+	ErrInvalidPassword = errors.New("invalid password")
+)
+
+// PasswordOpts are optional parameters used to configure NewPassword's use of
+// Argon2id. A zero value PasswordOpts is equivalent to the defaults
+// described on each field.
+type PasswordOpts struct {
+	// Time is the number of Argon2id passes to make over memory.
+	//
+	// Defaults to 1.
+	Time uint32
+
+	// Memory is the amount of memory, in KiB, Argon2id is allowed to use.
+	//
+	// Defaults to 64*1024 (64MiB).
+	Memory uint32
+
+	// Threads is the degree of parallelism Argon2id uses.
+	//
+	// Defaults to 4.
+	Threads uint8
+
+	// SaltLen is the number of salt bytes read from crypto/rand for a new
+	// Password.
+	//
+	// Defaults to 16.
+	SaltLen uint32
+
+	// KeyLen is the number of tag bytes Argon2id produces.
+	//
+	// Defaults to 32.
+	KeyLen uint32
+}
+
+func (o PasswordOpts) withDefaults() PasswordOpts {
+	if o.Time == 0 {
+		o.Time = 1
+	}
+	if o.Memory == 0 {
+		o.Memory = 64 * 1024
+	}
+	if o.Threads == 0 {
+		o.Threads = 4
+	}
+	if o.SaltLen == 0 {
+		o.SaltLen = 16
+	}
+	if o.KeyLen == 0 {
+		o.KeyLen = 32
+	}
+	return o
+}
+
+// Password is an Argon2id hash of a plaintext password (e.g. one a user
+// logs in with), suitable for storing and later verifying login attempts
+// against without ever storing the plaintext itself.
+//
+// Its serialized form (see String/MarshalText) follows the same
+// self-describing, dollar-sign-delimited style as EncodedSecret, so a
+// Password can be re-Verify'd even after its cost parameters have since been
+// tuned.
+type Password struct {
+	time, memory uint32
+	threads      uint8
+	salt, tag    []byte
+}
+
+// NewPassword hashes plain using Argon2id, with a fresh salt read from
+// crypto/rand and the parameters given by opts (a zero value PasswordOpts
+// uses time=1, memory=64MiB, threads=4, a 16-byte salt, and a 32-byte tag).
+func NewPassword(plain string, opts PasswordOpts) Password {
+	o := opts.withDefaults()
+
+	salt := make([]byte, o.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		panic(err)
+	}
+
+	tag := argon2.IDKey([]byte(plain), salt, o.Time, o.Memory, o.Threads, o.KeyLen)
+	return Password{time: o.Time, memory: o.Memory, threads: o.Threads, salt: salt, tag: tag}
+}
+
+// Verify returns nil if plain hashes to the same tag as p, using p's
+// embedded parameters and salt, and ErrInvalidPassword otherwise.
+func (p Password) Verify(plain string) error {
+	tag := argon2.IDKey([]byte(plain), p.salt, p.time, p.memory, p.threads, uint32(len(p.tag)))
+	if subtle.ConstantTimeCompare(tag, p.tag) != 1 {
+		return mlog.ErrWithKV(ErrInvalidPassword, p)
+	}
+	return nil
+}
+
+// String returns p's Argon2id parameters, salt, and tag encoded as:
+//
+//	$argon2id$v=19$m=65536,t=1,p=4$<hex salt>$<hex tag>
+func (p Password) String() string {
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.memory, p.time, p.threads,
+		hex.EncodeToString(p.salt), hex.EncodeToString(p.tag))
+}
+
+// KV implements the method for the mlog.KVer interface. The tag and salt are
+// not included, since a Password is meant to guard a secret.
+func (p Password) KV() map[string]interface{} {
+	return map[string]interface{}{
+		"passwordMemory":  p.memory,
+		"passwordTime":    p.time,
+		"passwordThreads": p.threads,
+	}
+}
+
+// MarshalText implements the method for the encoding.TextMarshaler interface
+func (p Password) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements the method for the encoding.TextUnmarshaler
+// interface
+func (p *Password) UnmarshalText(b []byte) error {
+	str := string(b)
+	fields := strings.Split(str, "$")
+	// a well formed string looks like ["", argon2id, v=.., m=..,t=..,p=..,
+	// saltHex, tagHex]
+	if len(fields) != 6 || fields[0] != "" || fields[1] != "argon2id" {
+		return mlog.ErrWithKV(errMalformedPassword, mlog.KV{"passwordStr": str})
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(fields[2], "v=%d", &version); err != nil {
+		return mlog.ErrWithKV(errMalformedPassword, mlog.KV{"passwordStr": str})
+	}
+
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(fields[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return mlog.ErrWithKV(errMalformedPassword, mlog.KV{"passwordStr": str})
+	}
+
+	salt, err := hex.DecodeString(fields[4])
+	if err != nil {
+		return mlog.ErrWithKV(err, mlog.KV{"passwordStr": str})
+	}
+
+	tag, err := hex.DecodeString(fields[5])
+	if err != nil {
+		return mlog.ErrWithKV(err, mlog.KV{"passwordStr": str})
+	}
+
+	p.time, p.memory, p.threads = time, memory, threads
+	p.salt, p.tag = salt, tag
+	return nil
+}
+
+// MarshalJSON implements the method for the json.Marshaler interface
+func (p Password) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+// UnmarshalJSON implements the method for the json.Unmarshaler interface
+func (p *Password) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return p.UnmarshalText([]byte(s))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// defaultDeriveKeyOpts are the Argon2id parameters DeriveKey uses; they
+// match PasswordOpts' own defaults, since both are deriving key material
+// from a passphrase using the same underlying KDF.
+var defaultDeriveKeyOpts = PasswordOpts{}.withDefaults()
+
+// DeriveKey stretches password into a keyLen byte key using Argon2id, e.g.
+// for use as a NaCl secretbox key (see NewSecret) derived directly from a
+// user-supplied passphrase rather than random bytes. salt should be randomly
+// generated once and stored alongside (not secret alongside) the derived
+// key's other parameters, the same as with NewSecretFromPassword.
+func DeriveKey(password, salt []byte, keyLen int) []byte {
+	o := defaultDeriveKeyOpts
+	return argon2.IDKey(password, salt, o.Time, o.Memory, o.Threads, uint32(keyLen))
+}
@@ -0,0 +1,52 @@
+package mcrypto
+
+import (
+	. "testing"
+
+	"github.com/ansel1/merry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPassword(t *T) {
+	pass := NewPassword("hunter2", PasswordOpts{})
+	assert.NoError(t, pass.Verify("hunter2"))
+	assert.True(t, merry.Is(pass.Verify("wrongpassword"), ErrInvalidPassword))
+
+	// marshaling/unmarshaling round-trips, and the result still verifies
+	str, err := pass.MarshalText()
+	assert.NoError(t, err)
+
+	var pass2 Password
+	assert.NoError(t, pass2.UnmarshalText(str))
+	assert.NoError(t, pass2.Verify("hunter2"))
+	assert.True(t, merry.Is(pass2.Verify("wrongpassword"), ErrInvalidPassword))
+
+	// a second hash of the same password has a different salt/tag, and so a
+	// different serialized form
+	pass3 := NewPassword("hunter2", PasswordOpts{})
+	assert.NotEqual(t, pass.String(), pass3.String())
+	assert.NoError(t, pass3.Verify("hunter2"))
+}
+
+func TestPasswordOpts(t *T) {
+	pass := NewPassword("hunter2", PasswordOpts{Time: 2, Memory: 8 * 1024, Threads: 1, KeyLen: 16})
+	assert.NoError(t, pass.Verify("hunter2"))
+
+	str, err := pass.MarshalText()
+	assert.NoError(t, err)
+
+	var pass2 Password
+	assert.NoError(t, pass2.UnmarshalText(str))
+	assert.Equal(t, pass, pass2)
+}
+
+func TestDeriveKey(t *T) {
+	salt := []byte("0123456789abcdef")
+	key1 := DeriveKey([]byte("hunter2"), salt, 32)
+	key2 := DeriveKey([]byte("hunter2"), salt, 32)
+	assert.Equal(t, key1, key2)
+	assert.Len(t, key1, 32)
+
+	key3 := DeriveKey([]byte("hunter3"), salt, 32)
+	assert.NotEqual(t, key1, key3)
+}
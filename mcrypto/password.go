@@ -0,0 +1,162 @@
+package mcrypto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mediocregopher/mediocre-go-lib/mlog"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+var errMalformedEncodedSecret = errors.New("malformed encoded secret")
+
+// kdfParams describes which key-derivation function, and with what
+// parameters, should be used to stretch a password into key bytes.
+type kdfParams struct {
+	kdf                       string // "scrypt" or "pbkdf2"
+	scryptN, scryptR, scryptP int
+	pbkdf2Iter                int
+}
+
+// defaultKDFParams matches the scrypt parameters recommended by the scrypt
+// paper for interactive logins as of this writing.
+var defaultKDFParams = kdfParams{
+	kdf:     "scrypt",
+	scryptN: 32768,
+	scryptR: 8,
+	scryptP: 1,
+}
+
+// KDFOpt is used to configure the key-derivation function used by
+// NewSecretFromPassword and EncodeKDFParams. The zero value of KDFOpt's
+// parameters is scrypt with N=32768, r=8, p=1.
+type KDFOpt func(*kdfParams)
+
+// KDFScrypt configures scrypt, with the given parameters, as the KDF used to
+// derive a Secret's key from a password. This is the default if no KDFOpt is
+// given.
+func KDFScrypt(N, r, p int) KDFOpt {
+	return func(o *kdfParams) {
+		o.kdf = "scrypt"
+		o.scryptN, o.scryptR, o.scryptP = N, r, p
+	}
+}
+
+// KDFPBKDF2 configures PBKDF2-HMAC-SHA256, with the given number of
+// iterations, as the KDF used to derive a Secret's key from a password,
+// instead of the default scrypt.
+func KDFPBKDF2(iter int) KDFOpt {
+	return func(o *kdfParams) {
+		o.kdf = "pbkdf2"
+		o.pbkdf2Iter = iter
+	}
+}
+
+func (o kdfParams) derive(password, salt []byte) ([]byte, error) {
+	switch o.kdf {
+	case "pbkdf2":
+		return pbkdf2.Key(password, salt, o.pbkdf2Iter, 32, sha256.New), nil
+	case "scrypt", "":
+		return scrypt.Key(password, salt, o.scryptN, o.scryptR, o.scryptP, 32)
+	default:
+		return nil, fmt.Errorf("unknown kdf %q", o.kdf)
+	}
+}
+
+// NewSecretFromPassword stretches password into a 32 byte key, using salt
+// and scrypt (N=32768, r=8, p=1 by default; see KDFScrypt) or
+// PBKDF2-HMAC-SHA256 (see KDFPBKDF2) as selected by opts, and returns a
+// Secret initialized with that key.
+//
+// This is intended for the common case where an operator has a human
+// passphrase to work with, rather than a cryptographically random key; use
+// NewSecret directly if random key bytes are already available. salt should
+// be randomly generated once and then stored alongside (not secret
+// alongside) the Secret's other parameters; see EncodeKDFParams.
+func NewSecretFromPassword(password, salt []byte, opts ...KDFOpt) (Secret, error) {
+	o := defaultKDFParams
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	key, err := o.derive(password, salt)
+	if err != nil {
+		return Secret{}, mlog.ErrWithKV(err, mlog.KV{"kdf": o.kdf})
+	}
+	return NewSecret(key), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// EncodedSecret is a self-describing string encoding of the salt and KDF
+// parameters (but not the password itself) needed to reproduce a Secret
+// previously generated via NewSecretFromPassword. It looks like one of:
+//
+//	$scrypt$N=32768,r=8,p=1$<hex salt>$
+//	$pbkdf2$iter=100000$<hex salt>$
+//
+// This lets an operator rotate a deployment's KDF/parameters/salt via config
+// (storing the EncodedSecret there) while keeping the actual password in
+// whatever separate, more tightly held, location it already lives.
+type EncodedSecret string
+
+// EncodeKDFParams returns the EncodedSecret which would be produced by a
+// NewSecretFromPassword call using salt and opts, for storing in config; use
+// ParseEncodedSecret with the matching password to reconstruct the Secret.
+func EncodeKDFParams(salt []byte, opts ...KDFOpt) EncodedSecret {
+	o := defaultKDFParams
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	saltHex := hex.EncodeToString(salt)
+	switch o.kdf {
+	case "pbkdf2":
+		return EncodedSecret(fmt.Sprintf("$pbkdf2$iter=%d$%s$", o.pbkdf2Iter, saltHex))
+	default:
+		return EncodedSecret(fmt.Sprintf("$scrypt$N=%d,r=%d,p=%d$%s$", o.scryptN, o.scryptR, o.scryptP, saltHex))
+	}
+}
+
+// ParseEncodedSecret parses enc, as produced by EncodeKDFParams, and
+// re-derives the Secret that NewSecretFromPassword(password, salt, ...)
+// would have produced using the KDF, parameters, and salt embedded in enc.
+func ParseEncodedSecret(enc EncodedSecret, password []byte) (Secret, error) {
+	fields := strings.Split(string(enc), "$")
+	// a well formed string looks like ["", kdf, params, salt, ""]
+	if len(fields) != 5 || fields[0] != "" {
+		return Secret{}, mlog.ErrWithKV(errMalformedEncodedSecret, mlog.KV{"encodedSecret": string(enc)})
+	}
+
+	kdf, paramsStr, saltHex := fields[1], fields[2], fields[3]
+
+	salt, err := hex.DecodeString(saltHex)
+	if err != nil {
+		return Secret{}, mlog.ErrWithKV(err, mlog.KV{"encodedSecret": string(enc)})
+	}
+
+	var opt KDFOpt
+	switch kdf {
+	case "scrypt":
+		var N, r, p int
+		if _, err := fmt.Sscanf(paramsStr, "N=%d,r=%d,p=%d", &N, &r, &p); err != nil {
+			return Secret{}, mlog.ErrWithKV(errMalformedEncodedSecret, mlog.KV{"encodedSecret": string(enc)})
+		}
+		opt = KDFScrypt(N, r, p)
+	case "pbkdf2":
+		iter, err := strconv.Atoi(strings.TrimPrefix(paramsStr, "iter="))
+		if err != nil {
+			return Secret{}, mlog.ErrWithKV(errMalformedEncodedSecret, mlog.KV{"encodedSecret": string(enc)})
+		}
+		opt = KDFPBKDF2(iter)
+	default:
+		return Secret{}, mlog.ErrWithKV(errMalformedEncodedSecret, mlog.KV{"encodedSecret": string(enc)})
+	}
+
+	return NewSecretFromPassword(password, salt, opt)
+}
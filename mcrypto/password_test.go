@@ -0,0 +1,54 @@
+package mcrypto
+
+import (
+	. "testing"
+
+	"github.com/mediocregopher/mediocre-go-lib/mrand"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSecretFromPassword(t *T) {
+	password := []byte("hunter2")
+	salt := mrand.Bytes(16)
+
+	secret, err := NewSecretFromPassword(password, salt)
+	assert.NoError(t, err)
+
+	str := mrand.Hex(512)
+	sig := SignString(secret, str)
+	assert.NoError(t, VerifyString(secret, sig, str))
+
+	// the same password/salt/KDF should always produce the same key
+	secret2, err := NewSecretFromPassword(password, salt)
+	assert.NoError(t, err)
+	assert.NoError(t, VerifyString(secret2, sig, str))
+
+	// a different KDF should produce a different key
+	secret3, err := NewSecretFromPassword(password, salt, KDFPBKDF2(10000))
+	assert.NoError(t, err)
+	err = VerifyString(secret3, sig, str)
+	assert.Error(t, err)
+}
+
+func TestEncodedSecret(t *T) {
+	password := []byte("hunter2")
+	salt := mrand.Bytes(16)
+
+	cases := []EncodedSecret{
+		EncodeKDFParams(salt),
+		EncodeKDFParams(salt, KDFScrypt(16384, 8, 1)),
+		EncodeKDFParams(salt, KDFPBKDF2(10000)),
+	}
+
+	for _, enc := range cases {
+		secret, err := ParseEncodedSecret(enc, password)
+		assert.NoError(t, err, "enc:%q", enc)
+
+		str := mrand.Hex(512)
+		sig := SignString(secret, str)
+		assert.NoError(t, VerifyString(secret, sig, str), "enc:%q", enc)
+	}
+
+	_, err := ParseEncodedSecret("not an encoded secret", password)
+	assert.Error(t, err)
+}
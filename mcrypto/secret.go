@@ -8,21 +8,29 @@ import (
 	"time"
 
 	"github.com/mediocregopher/mediocre-go-lib/mlog"
+	"golang.org/x/crypto/nacl/secretbox"
 )
 
 // Secret contains a set of bytes which are inteded to remain secret within some
 // context (e.g. a backend application keeping a secret from the frontend).
 //
-// Secret inherently implements the Signer and Verifier interfaces.
+// Secret inherently implements the Signer, Verifier, Encrypter, and Decrypter
+// interfaces.
 //
 // Secret can be initialized with NewSecret or NewWeakSecret. The Signatures
 // produced by these will be of differing lengths, but either can Verify a
 // Signature made by the other as long as the secret bytes they are initialized
-// with are the same.
+// with are the same. Ciphertexts produced by either can likewise be Decrypted
+// by the other, as long as the secret bytes match.
 type Secret struct {
 	sigSize uint8 // in bytes, shouldn't be more than 32, cause sha256
 	secret  []byte
 
+	// TimeFormat controls which TimeFormat the Signatures produced by this
+	// Secret use. It defaults to TimeFormatUnixNano; set it (e.g.
+	// secret.TimeFormat = TimeFormatBech32) to opt into a different one.
+	TimeFormat TimeFormat
+
 	// only used during tests
 	testNow time.Time
 }
@@ -70,7 +78,7 @@ func (s Secret) sign(r io.Reader) (Signature, error) {
 
 	t := s.now()
 	sig, err := s.signRaw(r, s.sigSize, salt, t)
-	return Signature{sig: sig, salt: salt, t: t}, err
+	return Signature{sig: sig, salt: salt, t: t, timeFormat: s.TimeFormat}, err
 }
 
 func (s Secret) verify(sig Signature, r io.Reader) error {
@@ -82,3 +90,47 @@ func (s Secret) verify(sig Signature, r io.Reader) error {
 	}
 	return nil
 }
+
+// secretboxKey deterministically derives a 32 byte secretbox key from the
+// Secret's underlying (possibly differently sized) secret bytes.
+func (s Secret) secretboxKey() *[32]byte {
+	key := sha256.Sum256(s.secret)
+	return &key
+}
+
+func (s Secret) encrypt(r io.Reader) (Ciphertext, error) {
+	data, err := readAll(r)
+	if err != nil {
+		return Ciphertext{}, err
+	}
+
+	var nonce [24]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		panic(err)
+	}
+
+	ct := secretbox.Seal(nil, data, &nonce, s.secretboxKey())
+	return Ciphertext{
+		algo:  cryptoAlgoSecretbox,
+		nonce: nonce[:],
+		ct:    ct,
+		t:     s.now(),
+	}, nil
+}
+
+func (s Secret) decrypt(c Ciphertext, w io.Writer) error {
+	if c.algo != cryptoAlgoSecretbox || len(c.nonce) != 24 {
+		return mlog.ErrWithKV(ErrInvalidCiphertext, c)
+	}
+
+	var nonce [24]byte
+	copy(nonce[:], c.nonce)
+
+	data, ok := secretbox.Open(nil, c.ct, &nonce, s.secretboxKey())
+	if !ok {
+		return mlog.ErrWithKV(ErrInvalidCiphertext, c)
+	}
+
+	_, err := w.Write(data)
+	return err
+}
@@ -52,3 +52,89 @@ func TestSecretSignVerify(t *T) {
 		prevWeakSig = thisWeakSig
 	}
 }
+
+// TestSecretSignVerifyBech32 is TestSecretSignVerify's counterpart for
+// TimeFormatBech32, additionally verifying that corrupting a single
+// character of a TimeFormatBech32 Signature's string is always caught
+// (via its Bech32 checksum) before VerifyString's HMAC check even runs.
+func TestSecretSignVerifyBech32(t *T) {
+	secret := NewSecret(mrand.Bytes(16))
+	secret.TimeFormat = TimeFormatBech32
+	var prevStr string
+	var prevSig Signature
+	for i := 0; i < 1000; i++ {
+		now := time.Now().Round(0)
+		secret.testNow = now
+
+		thisStr := mrand.Hex(512)
+		thisSig := SignString(secret, thisStr)
+		thisSigStr := thisSig.String()
+
+		assert.Equal(t, now, thisSig.Time())
+		assert.NotEmpty(t, thisSigStr)
+		assert.NoError(t, VerifyString(secret, thisSig, thisStr))
+
+		var thisSig2 Signature
+		assert.NoError(t, thisSig2.UnmarshalText([]byte(thisSigStr)))
+		assert.Equal(t, thisSigStr, thisSig2.String())
+		assert.True(t, now.Equal(thisSig2.Time()))
+		assert.NoError(t, VerifyString(secret, thisSig2, thisStr))
+
+		if prevStr != "" {
+			assert.NotEqual(t, prevSig.String(), thisSigStr)
+			err := VerifyString(secret, prevSig, thisStr)
+			assert.True(t, merry.Is(err, ErrInvalidSig))
+		}
+		prevStr = thisStr
+		prevSig = thisSig
+	}
+
+	// corrupting any single character of the Bech32 string should always be
+	// caught before UnmarshalText even produces a Signature to Verify.
+	str := SignString(secret, "corruptMe").String()
+	for i := len(sigBech32HRP) + 1; i < len(str); i++ {
+		corrupted := []byte(str)
+		corrupted[i]++
+		if corrupted[i] == str[i] {
+			corrupted[i]++
+		}
+		var sig Signature
+		assert.Error(t, sig.UnmarshalText(corrupted), "corrupting char %d of %q should have been caught", i, str)
+	}
+}
+
+func TestSecretEncryptDecrypt(t *T) {
+	secretRaw := mrand.Bytes(16)
+	secret := NewSecret(secretRaw)
+	weakSecret := NewWeakSecret(secretRaw)
+	otherSecret := NewSecret(mrand.Bytes(16))
+
+	for i := 0; i < 1000; i++ {
+		thisStr := mrand.Hex(512)
+		thisCt := EncryptString(secret, thisStr)
+		thisWeakCt := EncryptString(weakSecret, thisStr)
+
+		// either secret should be able to decrypt either ciphertext, since
+		// they share the same underlying secret bytes
+		got, err := DecryptString(secret, thisCt)
+		assert.NoError(t, err)
+		assert.Equal(t, thisStr, got)
+
+		got, err = DecryptString(weakSecret, thisWeakCt)
+		assert.NoError(t, err)
+		assert.Equal(t, thisStr, got)
+
+		got, err = DecryptString(secret, thisWeakCt)
+		assert.NoError(t, err)
+		assert.Equal(t, thisStr, got)
+
+		got, err = DecryptString(weakSecret, thisCt)
+		assert.NoError(t, err)
+		assert.Equal(t, thisStr, got)
+
+		// a secret with different underlying bytes should never be able to
+		// decrypt a ciphertext produced by this one
+		_, err = DecryptString(otherSecret, thisCt)
+		assert.True(t, merry.Is(err, ErrInvalidCiphertext))
+	}
+}
@@ -7,9 +7,11 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"strings"
 	"time"
 
 	"github.com/mediocregopher/mediocre-go-lib/merr"
+	"github.com/mediocregopher/mediocre-go-lib/mtime"
 )
 
 var (
@@ -21,6 +23,31 @@ var (
 	ErrInvalidSig = errors.New("invalid signature")
 )
 
+// TimeFormat determines how a Signature's timestamp, and by extension its
+// entire marshaled form, is represented as text.
+type TimeFormat uint8
+
+const (
+	// TimeFormatUnixNano is the default TimeFormat: a version-prefixed hex
+	// string with the timestamp encoded as a raw UnixNano int64, as this
+	// package has always produced.
+	TimeFormatUnixNano TimeFormat = iota
+
+	// TimeFormatBech32 marshals the Signature as a single Bech32 string
+	// (see mtime.Bech32Encode), using an mtime.MonoTS in place of the raw
+	// timestamp. Bech32's checksum catches a single mistyped or mis-copied
+	// character before the (comparatively expensive, and non-specific)
+	// HMAC/RSA verification ever runs, which matters for signatures a human
+	// might read, copy, or retype (e.g. out of a log and into a support
+	// ticket); the result is also safe to use unescaped in URLs and DNS
+	// labels.
+	TimeFormatBech32
+)
+
+// sigBech32HRP is the Bech32 human-readable-part prefix used by Signature's
+// TimeFormatBech32 encoding.
+const sigBech32HRP = "sig"
+
 // Signature marshals/unmarshals an actual signature, produced internally by a
 // Signer, along with the timestamp the signing took place and a random salt.
 //
@@ -28,8 +55,9 @@ var (
 // included in the signature's input data, and so are also checked by the
 // Verifier.
 type Signature struct {
-	sig, salt []byte // neither of these should ever be more than 255 bytes long
-	t         time.Time
+	sig, salt  []byte // neither of these should ever be more than 255 bytes long
+	t          time.Time
+	timeFormat TimeFormat
 }
 
 // Time returns the timestamp the Signature was generated at
@@ -38,6 +66,10 @@ func (s Signature) Time() time.Time {
 }
 
 func (s Signature) String() string {
+	if s.timeFormat == TimeFormatBech32 {
+		return s.stringBech32()
+	}
+
 	// ts:8 + saltHeader:1 + salt + sigHeader:1 + sig
 	b := make([]byte, 10+len(s.salt)+len(s.sig))
 	// It will be year 2286 before the nano doesn't fit in uint64
@@ -50,6 +82,31 @@ func (s Signature) String() string {
 	return sigV0 + hex.EncodeToString(b)
 }
 
+// monoTSBinaryLen is the number of bytes an mtime.MonoTS marshals to via
+// MarshalBinary.
+const monoTSBinaryLen = 16
+
+func (s Signature) stringBech32() string {
+	tsB, err := mtime.NewMonoTS(s.t).MarshalBinary()
+	if err != nil {
+		// MonoTS.MarshalBinary never actually returns an error.
+		panic(err)
+	}
+
+	b := make([]byte, 0, len(tsB)+2+len(s.salt)+len(s.sig))
+	b = append(b, tsB...)
+	b = append(b, uint8(len(s.salt)))
+	b = append(b, s.salt...)
+	b = append(b, uint8(len(s.sig)))
+	b = append(b, s.sig...)
+
+	str, err := mtime.Bech32Encode(sigBech32HRP, b)
+	if err != nil {
+		panic(err)
+	}
+	return str
+}
+
 // KV implements the method for the mlog.KVer interface
 func (s Signature) KV() map[string]interface{} {
 	return map[string]interface{}{"sig": s.String()}
@@ -64,6 +121,10 @@ func (s Signature) MarshalText() ([]byte, error) {
 // interface
 func (s *Signature) UnmarshalText(b []byte) error {
 	str := string(b)
+	if strings.HasPrefix(strings.ToLower(str), sigBech32HRP+"1") {
+		return s.unmarshalTextBech32(str)
+	}
+
 	strEnc, ok := stripPrefix(str, sigV0)
 	if !ok || len(strEnc) < hex.EncodedLen(10) {
 		return merr.Wrap(errMalformedSig)
@@ -91,6 +152,42 @@ func (s *Signature) UnmarshalText(b []byte) error {
 
 	s.salt = readBytes()
 	s.sig = readBytes()
+	s.timeFormat = TimeFormatUnixNano
+	return err
+}
+
+func (s *Signature) unmarshalTextBech32(str string) error {
+	hrp, data, err := mtime.Bech32Decode(str)
+	if err != nil {
+		return merr.Wrap(err)
+	} else if hrp != sigBech32HRP {
+		return merr.Wrap(errMalformedSig)
+	} else if len(data) < monoTSBinaryLen+2 {
+		return merr.Wrap(errMalformedSig)
+	}
+
+	var monoTS mtime.MonoTS
+	if err := monoTS.UnmarshalBinary(data[:monoTSBinaryLen]); err != nil {
+		return merr.Wrap(err)
+	}
+	s.t = monoTS.Wall
+
+	b := data[monoTSBinaryLen:]
+	readBytes := func() []byte {
+		if err != nil {
+			return nil
+		} else if len(b) < 1+int(b[0]) {
+			err = merr.Wrap(errMalformedSig)
+			return nil
+		}
+		out := b[1 : 1+b[0]]
+		b = b[1+b[0]:]
+		return out
+	}
+
+	s.salt = readBytes()
+	s.sig = readBytes()
+	s.timeFormat = TimeFormatBech32
 	return err
 }
 
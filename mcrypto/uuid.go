@@ -3,10 +3,12 @@ package mcrypto
 import (
 	"bytes"
 	"crypto/rand"
+	"encoding/base32"
 	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/mediocregopher/mediocre-go-lib/mlog"
@@ -14,6 +16,31 @@ import (
 
 var errMalformedUUID = errors.New("malformed UUID string")
 
+// crockfordEncoding is the base32 alphabet used by ULID, which omits the
+// letters I, L, O, and U to avoid confusion with 1 and 0.
+var crockfordEncoding = base32.NewEncoding("0123456789ABCDEFGHJKMNPQRSTVWXYZ").WithPadding(base32.NoPadding)
+
+// uuidKind describes which of the byte layouts supported by UUID is in use,
+// so that String and Time know how to render/decode the embedded timestamp.
+type uuidKind int
+
+const (
+	// uuidKindV0 is this package's bespoke layout: big-endian UnixNano
+	// followed by 8 random bytes, rendered with the uuidV0 prefix.
+	uuidKindV0 uuidKind = iota
+
+	// uuidKindULID is a standard ULID: a 48-bit big-endian millisecond
+	// timestamp followed by 80 bits of randomness, rendered as 26 characters
+	// of Crockford base32.
+	uuidKindULID
+
+	// uuidKindUUIDv7 is an RFC 9562 UUIDv7: a 48-bit big-endian millisecond
+	// timestamp, a 4-bit version, 12 bits of random "rand_a", a 2-bit
+	// variant, and 62 bits of random "rand_b", rendered in canonical
+	// 8-4-4-4-12 hex form.
+	uuidKindUUIDv7
+)
+
 // UUID is a universally unique identifier which embeds within it a timestamp.
 //
 // Only Unmarshal methods should be called on the zero UUID value.
@@ -22,9 +49,11 @@ var errMalformedUUID = errors.New("malformed UUID string")
 // method, or by comparing their string forms.
 //
 // The string form of UUIDs (returned by String or MarshalText) are
-// lexigraphically order-able by their embedded timestamp.
+// lexigraphically order-able by their embedded timestamp, for all of the
+// layouts UUID supports (see NewUUID, NewULID, and NewUUIDv7).
 type UUID struct {
-	b []byte
+	b    []byte
+	kind uuidKind
 }
 
 // NewUUID populates and returns a new UUID instance which embeds the given time
@@ -34,22 +63,84 @@ func NewUUID(t time.Time) UUID {
 	if _, err := rand.Read(b[8:]); err != nil {
 		panic(err)
 	}
-	return UUID{b: b}
+	return UUID{b: b, kind: uuidKindV0}
+}
+
+// NewULID populates and returns a new UUID instance using the standard ULID
+// layout: a 48-bit millisecond timestamp followed by 80 bits of randomness.
+// The resulting UUID's String form is a 26 character Crockford base32
+// encoding, interoperable with other systems which produce/consume ULIDs.
+func NewULID(t time.Time) UUID {
+	b := make([]byte, 16)
+	putUint48(b[:6], uint64(t.UnixMilli()))
+	if _, err := rand.Read(b[6:]); err != nil {
+		panic(err)
+	}
+	return UUID{b: b, kind: uuidKindULID}
+}
+
+// NewUUIDv7 populates and returns a new UUID instance using the RFC 9562
+// UUIDv7 layout: a 48-bit millisecond timestamp, a 4-bit version, 12 bits of
+// randomness, a 2-bit variant, and 62 more bits of randomness. The resulting
+// UUID's String form is the canonical 36 character hyphenated hex
+// representation, interoperable with other systems which produce/consume
+// UUIDv7s.
+func NewUUIDv7(t time.Time) UUID {
+	b := make([]byte, 16)
+	putUint48(b[:6], uint64(t.UnixMilli()))
+	if _, err := rand.Read(b[6:]); err != nil {
+		panic(err)
+	}
+	b[6] = 0x70 | (b[6] & 0x0F) // version 7
+	b[8] = 0x80 | (b[8] & 0x3F) // variant 0b10
+	return UUID{b: b, kind: uuidKindUUIDv7}
+}
+
+func putUint48(b []byte, v uint64) {
+	b[0] = byte(v >> 40)
+	b[1] = byte(v >> 32)
+	b[2] = byte(v >> 24)
+	b[3] = byte(v >> 16)
+	b[4] = byte(v >> 8)
+	b[5] = byte(v)
+}
+
+func uint48(b []byte) uint64 {
+	return uint64(b[0])<<40 | uint64(b[1])<<32 | uint64(b[2])<<24 |
+		uint64(b[3])<<16 | uint64(b[4])<<8 | uint64(b[5])
 }
 
 func (u UUID) String() string {
-	return uuidV0 + hex.EncodeToString(u.b)
+	switch u.kind {
+	case uuidKindULID:
+		// ULID puts its 2 padding bits first, so shift right by 2 bits.
+		sb := make([]byte, 17)
+		for i, c := range u.b {
+			sb[i] |= c >> 2
+			sb[i+1] = c << 6
+		}
+		return crockfordEncoding.EncodeToString(sb)[:26]
+	case uuidKindUUIDv7:
+		hexStr := hex.EncodeToString(u.b)
+		return hexStr[:8] + "-" + hexStr[8:12] + "-" + hexStr[12:16] + "-" + hexStr[16:20] + "-" + hexStr[20:]
+	default:
+		return uuidV0 + hex.EncodeToString(u.b)
+	}
 }
 
 // Equal returns whether or not the two UUID's are the same value
 func (u UUID) Equal(u2 UUID) bool {
-	return bytes.Equal(u.b, u2.b)
+	return u.kind == u2.kind && bytes.Equal(u.b, u2.b)
 }
 
 // Time unpacks and returns the timestamp embedded in the UUID
 func (u UUID) Time() time.Time {
-	unixNano := int64(binary.BigEndian.Uint64(u.b[:8]))
-	return time.Unix(0, unixNano).Local()
+	switch u.kind {
+	case uuidKindULID, uuidKindUUIDv7:
+		return time.UnixMilli(int64(uint48(u.b))).Local()
+	default:
+		return time.Unix(0, int64(binary.BigEndian.Uint64(u.b[:8]))).Local()
+	}
 }
 
 // KV implements the method for the mlog.KVer interface
@@ -63,19 +154,51 @@ func (u UUID) MarshalText() ([]byte, error) {
 }
 
 // UnmarshalText implements the method for the encoding.TextUnmarshaler
-// interface
+// interface. It auto-detects, by prefix/length, whether the string is this
+// package's legacy uuidV0 format, a 26 character ULID, or a 36 character
+// hyphenated UUIDv7.
 func (u *UUID) UnmarshalText(b []byte) error {
 	str := string(b)
-	strEnc, ok := stripPrefix(str, uuidV0)
-	if !ok || len(strEnc) != hex.EncodedLen(16) {
-		return mlog.ErrWithKV(errMalformedUUID, mlog.KV{"uuidStr": str})
+
+	if strEnc, ok := stripPrefix(str, uuidV0); ok {
+		if len(strEnc) != hex.EncodedLen(16) {
+			return mlog.ErrWithKV(errMalformedUUID, mlog.KV{"uuidStr": str})
+		}
+		decoded, err := hex.DecodeString(strEnc)
+		if err != nil {
+			return mlog.ErrWithKV(err, mlog.KV{"uuidStr": str})
+		}
+		u.b, u.kind = decoded, uuidKindV0
+		return nil
 	}
-	b, err := hex.DecodeString(strEnc)
-	if err != nil {
-		return mlog.ErrWithKV(err, mlog.KV{"uuidStr": str})
+
+	switch len(str) {
+	case 26:
+		// pad to whole base32 blocks; the 2 leading padding bits must be 0.
+		d, err := crockfordEncoding.DecodeString(strings.ToUpper(str) + "000000")
+		if err != nil || len(d) != 20 || d[0]>>6 != 0 {
+			return mlog.ErrWithKV(errMalformedUUID, mlog.KV{"uuidStr": str})
+		}
+		decoded := make([]byte, 16)
+		for i := range decoded {
+			decoded[i] = d[i]<<2 | d[i+1]>>6
+		}
+		u.b, u.kind = decoded, uuidKindULID
+		return nil
+	case 36:
+		if str[8] != '-' || str[13] != '-' || str[18] != '-' || str[23] != '-' {
+			return mlog.ErrWithKV(errMalformedUUID, mlog.KV{"uuidStr": str})
+		}
+		hexStr := str[:8] + str[9:13] + str[14:18] + str[19:23] + str[24:]
+		decoded, err := hex.DecodeString(hexStr)
+		if err != nil || len(decoded) != 16 {
+			return mlog.ErrWithKV(errMalformedUUID, mlog.KV{"uuidStr": str})
+		}
+		u.b, u.kind = decoded, uuidKindUUIDv7
+		return nil
+	default:
+		return mlog.ErrWithKV(errMalformedUUID, mlog.KV{"uuidStr": str})
 	}
-	u.b = b
-	return nil
 }
 
 // MarshalJSON implements the method for the json.Marshaler interface
@@ -37,3 +37,68 @@ func TestUUID(t *T) {
 		assert.True(t, this.Equal(this2), "this:%q this2:%q", this, this2)
 	}
 }
+
+func TestULID(t *T) {
+	thisT := time.Now().Round(time.Millisecond)
+	var prev UUID
+	for i := 0; i < 1000; i++ {
+		this := NewULID(thisT)
+
+		// basic
+		assert.Len(t, this.String(), 26)
+
+		// comparisons with prev
+		assert.False(t, prev.Equal(this))
+		assert.NotEqual(t, prev.String(), this.String())
+		if i > 0 {
+			assert.True(t, this.String() > prev.String())
+		}
+		prev = this
+
+		// check time unpacking
+		assert.Equal(t, thisT, this.Time())
+
+		// check marshal/unmarshal
+		thisStr, err := this.MarshalText()
+		require.NoError(t, err)
+		var this2 UUID
+		require.NoError(t, this2.UnmarshalText(thisStr))
+		assert.True(t, this.Equal(this2), "this:%q this2:%q", this, this2)
+
+		thisT = thisT.Add(time.Millisecond)
+	}
+}
+
+func TestUUIDv7(t *T) {
+	thisT := time.Now().Round(time.Millisecond)
+	var prevT time.Time
+	var prev UUID
+	for i := 0; i < 1000; i++ {
+		require.True(t, thisT.After(prevT) || i == 0)
+		this := NewUUIDv7(thisT)
+
+		// basic
+		str := this.String()
+		require.Len(t, str, 36)
+		assert.Equal(t, byte('7'), str[14])
+		assert.True(t, str[19] == '8' || str[19] == '9' || str[19] == 'a' || str[19] == 'b')
+
+		// comparisons with prev
+		assert.False(t, prev.Equal(this))
+		assert.NotEqual(t, prev.String(), this.String())
+		assert.True(t, str > prev.String())
+		prevT, prev = thisT, this
+
+		// check time unpacking
+		assert.Equal(t, thisT, this.Time())
+
+		// check marshal/unmarshal
+		thisStr, err := this.MarshalText()
+		require.NoError(t, err)
+		var this2 UUID
+		require.NoError(t, this2.UnmarshalText(thisStr))
+		assert.True(t, this.Equal(this2), "this:%q this2:%q", this, this2)
+
+		thisT = thisT.Add(time.Millisecond)
+	}
+}
@@ -0,0 +1,223 @@
+package mctx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ExportedSpan is the serializable representation of a Span, as produced
+// during Flush and passed to an Exporter.
+type ExportedSpan struct {
+	TraceID      string            `json:"trace_id"`
+	SpanID       string            `json:"span_id"`
+	ParentSpanID string            `json:"parent_span_id,omitempty"`
+	Name         string            `json:"name"`
+	Start        time.Time         `json:"start"`
+	End          time.Time         `json:"end"`
+	Status       string            `json:"status"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+	Events       []SpanEvent       `json:"events,omitempty"`
+}
+
+// Exporter is used to write out a trace's worth of ExportedSpans, generally to
+// some external tracing system.
+type Exporter interface {
+	Export([]ExportedSpan) error
+}
+
+// Flush gathers the given Span and all of its descendants (in breadth-first
+// order) into a single trace, and passes them to the given Exporter. It's
+// meant to be called on a trace's root Span once all of the work it
+// represents (and all of that work's children) has completed.
+func Flush(root *Span, exp Exporter) error {
+	var spans []ExportedSpan
+	root.visit(func(s *Span) bool {
+		s.l.Lock()
+		defer s.l.Unlock()
+		spans = append(spans, ExportedSpan{
+			TraceID:      s.traceID,
+			SpanID:       s.spanID,
+			ParentSpanID: s.parentSpanID,
+			Name:         s.name,
+			Start:        s.start,
+			End:          s.end,
+			Status:       s.status.String(),
+			Attributes:   s.Attributes(),
+			Events:       append([]SpanEvent{}, s.events...),
+		})
+		return true
+	})
+	return exp.Export(spans)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// NoopExporter is an Exporter which discards every span given to it. It's
+// useful as the default/zero-config Exporter, e.g. for local development or
+// tests, where a real tracing backend isn't available and simply skipping
+// export is preferable to plumbing a nil check everywhere an Exporter is
+// used.
+type NoopExporter struct{}
+
+// Export implements the Exporter interface.
+func (NoopExporter) Export([]ExportedSpan) error { return nil }
+
+////////////////////////////////////////////////////////////////////////////////
+
+// NDJSONExporter is an Exporter which writes each ExportedSpan as its own
+// line of JSON, in the same newline-delimited-JSON style used by mlog's
+// NewJSONMessageHandler, making a trace's spans trivial to ship through the
+// same log pipelines as regular log messages.
+type NDJSONExporter struct {
+	w io.Writer
+}
+
+// NewNDJSONExporter initializes and returns an NDJSONExporter which writes to
+// the given io.Writer.
+func NewNDJSONExporter(w io.Writer) *NDJSONExporter {
+	return &NDJSONExporter{w: w}
+}
+
+// Export implements the Exporter interface.
+func (e *NDJSONExporter) Export(spans []ExportedSpan) error {
+	enc := json.NewEncoder(e.w)
+	for _, span := range spans {
+		if err := enc.Encode(span); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewUDPExporter dials addr over UDP and returns an NDJSONExporter which
+// writes to the resulting connection, for shipping spans to an agent (e.g. a
+// local Datadog agent or similar collector listening for NDJSON-over-UDP
+// traces) without holding open a TCP/HTTP connection for every flush.
+func NewUDPExporter(addr string) (*NDJSONExporter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewNDJSONExporter(conn), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// OTLPHTTPExporterOpts are optional parameters used to construct an
+// OTLPHTTPExporter. A nil *OTLPHTTPExporterOpts is equivalent to an empty one.
+type OTLPHTTPExporterOpts struct {
+	// Client is used to make the HTTP requests which spans are exported over.
+	//
+	// Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+func (o *OTLPHTTPExporterOpts) withDefaults() *OTLPHTTPExporterOpts {
+	out := new(OTLPHTTPExporterOpts)
+	if o != nil {
+		*out = *o
+	}
+	if out.Client == nil {
+		out.Client = http.DefaultClient
+	}
+	return out
+}
+
+// OTLPHTTPExporter is an Exporter which POSTs spans, encoded as OTLP/JSON, to
+// a collector endpoint (e.g. "http://localhost:4318/v1/traces").
+type OTLPHTTPExporter struct {
+	endpoint string
+	opts     *OTLPHTTPExporterOpts
+}
+
+// NewOTLPHTTPExporter initializes and returns an OTLPHTTPExporter which POSTs
+// to the given endpoint.
+func NewOTLPHTTPExporter(endpoint string, opts *OTLPHTTPExporterOpts) *OTLPHTTPExporter {
+	return &OTLPHTTPExporter{
+		endpoint: endpoint,
+		opts:     opts.withDefaults(),
+	}
+}
+
+// otlpKeyValue and otlpAttributes mirror just enough of the OTLP/JSON span
+// shape (see
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/trace/v1/trace.proto)
+// to carry this package's Span data; they are not a complete implementation
+// of the format.
+type otlpKeyValue struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+func otlpAttributes(attrs map[string]string) []otlpKeyValue {
+	kvs := make([]otlpKeyValue, 0, len(attrs))
+	for k, v := range attrs {
+		kv := otlpKeyValue{Key: k}
+		kv.Value.StringValue = v
+		kvs = append(kvs, kv)
+	}
+	return kvs
+}
+
+// Export implements the Exporter interface.
+func (e *OTLPHTTPExporter) Export(spans []ExportedSpan) error {
+	otlpSpans := make([]otlpSpan, len(spans))
+	for i, span := range spans {
+		otlpSpans[i] = otlpSpan{
+			TraceID:           span.TraceID,
+			SpanID:            span.SpanID,
+			ParentSpanID:      span.ParentSpanID,
+			Name:              span.Name,
+			StartTimeUnixNano: fmt.Sprint(span.Start.UnixNano()),
+			EndTimeUnixNano:   fmt.Sprint(span.End.UnixNano()),
+			Attributes:        otlpAttributes(span.Attributes),
+		}
+	}
+
+	body := map[string]interface{}{
+		"resourceSpans": []map[string]interface{}{{
+			"scopeSpans": []map[string]interface{}{{
+				"spans": otlpSpans,
+			}},
+		}},
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", e.endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := e.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("exporting trace: unexpected status code %d", res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+// Package mctxprop carries mctx annotations (see mctx.WithAnnotator and
+// mctx.EvaluateAnnotations) across process boundaries, by serializing the
+// flattened set of annotations (see Annotations.StringMap) into a single
+// header/metadata entry on the way out, and re-hydrating them (via
+// mctx.WithAnnotator) on the way in.
+//
+// Because annotations are meant to be freely added throughout a codebase for
+// the benefit of local logging and error output, not every annotation is
+// necessarily safe or useful to hand to another process; Opts.AllowKeys and
+// Opts.MaxSize let a caller bound what actually goes over the wire.
+package mctxprop
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+
+	"github.com/mediocregopher/mediocre-go-lib/mctx"
+	"github.com/mediocregopher/mediocre-go-lib/mlog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// TextMapCarrier is implemented by whatever medium annotations are being
+// carried over, e.g. HTTP headers or gRPC metadata. See HeaderCarrier and
+// MetadataCarrier.
+//
+// Keys returns every key currently set on the carrier; Inject/Extract don't
+// need it themselves (they only ever touch Opts.Header), but it's included
+// so a TextMapCarrier has the same shape as other text-map carrier
+// abstractions (e.g. OpenTelemetry's propagation.TextMapCarrier) and so
+// future Opts which need to scan existing keys can use it.
+type TextMapCarrier interface {
+	Get(key string) string
+	Set(key, value string)
+	Keys() []string
+}
+
+// DefaultHeader is the header/metadata key which annotations are carried
+// under by default.
+const DefaultHeader = "X-Mctx-Annotations"
+
+// DefaultMaxSize is the default value of Opts.MaxSize.
+const DefaultMaxSize = 4096
+
+// Opts are optional parameters used by Inject/Extract and the middleware
+// constructors in this package. A nil *Opts is equivalent to an empty one.
+type Opts struct {
+	// Header is the header/metadata key annotations are carried under.
+	//
+	// Defaults to DefaultHeader.
+	Header string
+
+	// AllowKeys, if non-nil, restricts Inject to only sending annotations
+	// whose mctx.Annotations.StringMap key appears in this list; every other
+	// annotation is silently left off the wire. This is meant to prevent
+	// sensitive in-process-only annotations (e.g. raw credentials) from
+	// leaking to another service.
+	//
+	// Defaults to nil, meaning every annotation is sent.
+	AllowKeys []string
+
+	// MaxSize caps the encoded size, in bytes, of the header/metadata value
+	// Inject produces. If the allowed annotations don't fit, the ones
+	// dropped to make them fit are logged (via mlog.WarnString, using ctx)
+	// as a warning rather than silently lost.
+	//
+	// Defaults to DefaultMaxSize.
+	MaxSize int
+}
+
+func (o *Opts) withDefaults() *Opts {
+	out := new(Opts)
+	if o != nil {
+		*out = *o
+	}
+	if out.Header == "" {
+		out.Header = DefaultHeader
+	}
+	if out.MaxSize == 0 {
+		out.MaxSize = DefaultMaxSize
+	}
+	return out
+}
+
+func (o *Opts) allowed(key string) bool {
+	if o.AllowKeys == nil {
+		return true
+	}
+	for _, allowed := range o.AllowKeys {
+		if allowed == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Inject evaluates ctx's annotations (see mctx.EvaluateAnnotations), flattens
+// them (see mctx.Annotations.StringMap), and sets the allowed subset (see
+// Opts.AllowKeys) onto carrier under Opts.Header, URL-form-encoded (the same
+// encoding as a URL's query string).
+//
+// Annotations are added in ascending key order, and any annotation whose
+// addition would push the encoded result past Opts.MaxSize is dropped, while
+// later ones may still be added; whatever was dropped is logged as a warning
+// through mlog rather than being silently lost.
+func Inject(ctx context.Context, carrier TextMapCarrier, opts *Opts) {
+	o := opts.withDefaults()
+
+	aa := mctx.EvaluateAnnotations(ctx, nil).StringMap()
+	if len(aa) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(aa))
+	for k := range aa {
+		if o.allowed(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	vals := make(url.Values, len(keys))
+	var encoded string
+	var dropped []string
+	for _, k := range keys {
+		vals.Set(k, aa[k])
+		if next := vals.Encode(); len(next) <= o.MaxSize {
+			encoded = next
+			continue
+		}
+		vals.Del(k)
+		dropped = append(dropped, k)
+	}
+
+	if len(dropped) > 0 {
+		mlog.WarnString(ctx, fmt.Sprintf(
+			"mctxprop: dropped %d annotation(s) (%v) exceeding %d byte size cap",
+			len(dropped), dropped, o.MaxSize,
+		))
+	}
+
+	if encoded != "" {
+		carrier.Set(o.Header, encoded)
+	}
+}
+
+// Extract looks for Opts.Header on carrier, and if found, parses it (as
+// produced by Inject) and returns a Context descending from ctx with the
+// decoded annotations attached (via mctx.WithAnnotator), so that e.g.
+// mlog.FromCtx(ctx).Info(...) will automatically include them.
+//
+// If Opts.Header isn't present, or doesn't parse, ctx is returned unchanged.
+func Extract(ctx context.Context, carrier TextMapCarrier, opts *Opts) context.Context {
+	o := opts.withDefaults()
+
+	raw := carrier.Get(o.Header)
+	if raw == "" {
+		return ctx
+	}
+
+	vals, err := url.ParseQuery(raw)
+	if err != nil {
+		mlog.Warn(ctx, "mctxprop: failed to parse annotations header", err)
+		return ctx
+	}
+
+	aa := make(mctx.Annotations, len(vals))
+	for k, v := range vals {
+		if len(v) > 0 {
+			aa[k] = v[0]
+		}
+	}
+	if len(aa) == 0 {
+		return ctx
+	}
+
+	return mctx.WithAnnotator(ctx, aa)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// HeaderCarrier adapts an http.Header into a TextMapCarrier.
+type HeaderCarrier http.Header
+
+// Get implements the TextMapCarrier interface.
+func (c HeaderCarrier) Get(key string) string { return http.Header(c).Get(key) }
+
+// Set implements the TextMapCarrier interface.
+func (c HeaderCarrier) Set(key, value string) { http.Header(c).Set(key, value) }
+
+// Keys implements the TextMapCarrier interface.
+func (c HeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// MetadataCarrier adapts a gRPC metadata.MD into a TextMapCarrier.
+type MetadataCarrier metadata.MD
+
+// Get implements the TextMapCarrier interface.
+func (c MetadataCarrier) Get(key string) string {
+	vals := metadata.MD(c).Get(key)
+	if len(vals) == 0 {
+		return ""
+	}
+	return vals[0]
+}
+
+// Set implements the TextMapCarrier interface.
+func (c MetadataCarrier) Set(key, value string) { metadata.MD(c).Set(key, value) }
+
+// Keys implements the TextMapCarrier interface.
+func (c MetadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Middleware wraps next, extracting annotations out of each request's
+// headers (see Extract) and attaching them to the request's Context before
+// calling next, so handlers further down the stack pick them up via any of
+// the usual mctx/mlog Context-based APIs.
+func Middleware(opts *Opts) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			ctx := Extract(r.Context(), HeaderCarrier(r.Header), opts)
+			next.ServeHTTP(rw, r.WithContext(ctx))
+		})
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// RoundTripper wraps next, injecting whatever annotations are attached to
+// each outgoing request's Context (see Inject) into its headers before
+// sending it, so the receiving side's Middleware can pick them back up.
+func RoundTripper(next http.RoundTripper, opts *Opts) http.RoundTripper {
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		r = r.Clone(r.Context())
+		Inject(r.Context(), HeaderCarrier(r.Header), opts)
+		return next.RoundTrip(r)
+	})
+}
+
+// UnaryServerInterceptor extracts annotations out of the incoming gRPC
+// call's metadata (see Extract) and attaches them to the Context passed to
+// the handler.
+func UnaryServerInterceptor(opts *Opts) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			ctx = Extract(ctx, MetadataCarrier(md), opts)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// UnaryClientInterceptor injects whatever annotations are attached to ctx
+// (see Inject) into the outgoing gRPC call's metadata, so the server's
+// UnaryServerInterceptor can pick them back up.
+func UnaryClientInterceptor(opts *Opts) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		callOpts ...grpc.CallOption,
+	) error {
+		md := metadata.MD{}
+		Inject(ctx, MetadataCarrier(md), opts)
+		for k, vals := range md {
+			for _, v := range vals {
+				ctx = metadata.AppendToOutgoingContext(ctx, k, v)
+			}
+		}
+		return invoker(ctx, method, req, reply, cc, callOpts...)
+	}
+}
@@ -0,0 +1,81 @@
+package mctxprop
+
+import (
+	"context"
+	"net/http"
+	. "testing"
+
+	"github.com/mediocregopher/mediocre-go-lib/mctx"
+	"github.com/mediocregopher/mediocre-go-lib/mtest/massert"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestInjectExtractHeader(t *T) {
+	ctx := context.Background()
+	ctx = mctx.Annotate(ctx, "userID", "123")
+	ctx = mctx.Annotate(ctx, "reqID", "abc")
+
+	h := make(http.Header)
+	Inject(ctx, HeaderCarrier(h), nil)
+
+	massert.Require(t,
+		massert.Equal(true, h.Get(DefaultHeader) != ""),
+	)
+
+	out := Extract(context.Background(), HeaderCarrier(h), nil)
+	aa := mctx.EvaluateAnnotations(out, nil)
+	massert.Require(t,
+		massert.Equal("123", aa["userID"]),
+		massert.Equal("abc", aa["reqID"]),
+	)
+}
+
+func TestInjectAllowKeys(t *T) {
+	ctx := context.Background()
+	ctx = mctx.Annotate(ctx, "userID", "123")
+	ctx = mctx.Annotate(ctx, "secret", "dontleakme")
+
+	h := make(http.Header)
+	Inject(ctx, HeaderCarrier(h), &Opts{AllowKeys: []string{"userID"}})
+
+	out := Extract(context.Background(), HeaderCarrier(h), nil)
+	aa := mctx.EvaluateAnnotations(out, nil)
+	massert.Require(t,
+		massert.Equal("123", aa["userID"]),
+		massert.Equal(nil, aa["secret"]),
+	)
+}
+
+func TestInjectMaxSize(t *T) {
+	ctx := context.Background()
+	ctx = mctx.Annotate(ctx, "a", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	ctx = mctx.Annotate(ctx, "b", "b")
+
+	h := make(http.Header)
+	Inject(ctx, HeaderCarrier(h), &Opts{MaxSize: 10})
+
+	out := Extract(context.Background(), HeaderCarrier(h), nil)
+	aa := mctx.EvaluateAnnotations(out, nil)
+
+	// "a"'s value is too large to fit in the cap at all, so only "b" (which
+	// sorts after "a" but is small enough to fit once "a" is dropped) should
+	// have made it through.
+	massert.Require(t,
+		massert.Equal(nil, aa["a"]),
+		massert.Equal("b", aa["b"]),
+	)
+}
+
+func TestInjectExtractMetadata(t *T) {
+	ctx := context.Background()
+	ctx = mctx.Annotate(ctx, "userID", "123")
+
+	md := metadata.MD{}
+	Inject(ctx, MetadataCarrier(md), nil)
+
+	out := Extract(context.Background(), MetadataCarrier(md), nil)
+	aa := mctx.EvaluateAnnotations(out, nil)
+	massert.Require(t,
+		massert.Equal("123", aa["userID"]),
+	)
+}
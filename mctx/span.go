@@ -0,0 +1,233 @@
+package mctx
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mediocregopher/mediocre-go-lib/mrand"
+)
+
+type spanKey int
+
+// SpanStatus describes the outcome of the work a Span represents.
+type SpanStatus int
+
+// All possible values of SpanStatus.
+const (
+	SpanStatusUnset SpanStatus = iota
+	SpanStatusOK
+	SpanStatusError
+)
+
+// String implements the fmt.Stringer interface.
+func (s SpanStatus) String() string {
+	switch s {
+	case SpanStatusOK:
+		return "OK"
+	case SpanStatusError:
+		return "Error"
+	default:
+		return "Unset"
+	}
+}
+
+// SpanEvent is a timestamped, named occurrence within the lifetime of a Span,
+// as added by Span.AddEvent.
+type SpanEvent struct {
+	Name       string
+	Time       time.Time
+	Attributes map[string]interface{}
+}
+
+// Span represents a single unit of work within a trace, as created by
+// StartSpan. A Span is a thin wrapper around an mctx Context node (as created
+// by NewChild) which additionally tracks timing, status, and events, and
+// which is linked to its parent and children Spans for the purposes of trace
+// export (see Exporter).
+//
+// All methods on Span are thread-safe.
+type Span struct {
+	l sync.Mutex
+
+	ctx context.Context
+
+	name                          string
+	traceID, spanID, parentSpanID string
+	start, end                    time.Time
+	status                        SpanStatus
+	events                        []SpanEvent
+
+	children []*Span
+	flushed  bool
+}
+
+// StartSpan creates and returns a new Span with the given name, as a child of
+// whatever Span (if any) is embedded in the given Context. The returned
+// Context carries the new Span, and should be used for any work done on
+// behalf of it, including any further calls to StartSpan.
+//
+// The returned Span must have End called on it once the work it represents
+// has completed; FinishSpan(ctx) does this (and, for a root Span, flushes the
+// trace) without needing to keep the *Span value around. As a safety net
+// against work which never reaches that point (e.g. a cancelled request), the
+// returned Context also has FinishSpan registered against its Done channel
+// (via context.AfterFunc), so the Span (and, if applicable, its trace) are
+// still finished/flushed even if the caller never does so explicitly.
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	parent, _ := ctx.Value(spanKey(0)).(*Span)
+
+	span := &Span{
+		ctx:   NewChild(ctx, name),
+		name:  name,
+		start: time.Now(),
+	}
+
+	if parent != nil {
+		span.traceID = parent.traceID
+		span.parentSpanID = parent.spanID
+	} else {
+		span.traceID = mrand.DefaultRand.Hex(32)
+	}
+	span.spanID = mrand.DefaultRand.Hex(16)
+
+	if parent != nil {
+		parent.l.Lock()
+		parent.children = append(parent.children, span)
+		parent.l.Unlock()
+	}
+
+	ctx = context.WithValue(span.ctx, spanKey(0), span)
+	context.AfterFunc(ctx, func() { FinishSpan(ctx) })
+	return ctx, span
+}
+
+// SpanFromContext returns the Span embedded in the Context by StartSpan, or
+// false if the Context doesn't carry one.
+func SpanFromContext(ctx context.Context) (*Span, bool) {
+	span, ok := ctx.Value(spanKey(0)).(*Span)
+	return span, ok
+}
+
+type exporterKey int
+
+// WithExporter returns a Context with exp embedded, for automatic use by
+// FinishSpan (including the implicit one StartSpan registers against the
+// Context's Done channel) when ending a root Span, i.e. one with no parent.
+//
+// exp is looked up once a trace's root Span ends, so it only needs to be
+// attached once, near the root of a Context tree (e.g. where an inbound
+// request is first handled), for every Span descending from it to end up
+// flushed through it.
+func WithExporter(ctx context.Context, exp Exporter) context.Context {
+	return context.WithValue(ctx, exporterKey(0), exp)
+}
+
+// ExporterFromContext returns the Exporter previously embedded via
+// WithExporter, or false if ctx doesn't carry one.
+func ExporterFromContext(ctx context.Context) (Exporter, bool) {
+	exp, ok := ctx.Value(exporterKey(0)).(Exporter)
+	return exp, ok
+}
+
+// FinishSpan ends the Span embedded in ctx (see StartSpan), if any, and, if
+// the Span has no parent and ctx carries an Exporter (see WithExporter),
+// flushes the completed trace to it.
+//
+// FinishSpan is called automatically once ctx is done (see StartSpan), so
+// most callers don't need to call it directly; it's exposed for callers
+// which want to end a Span (and flush its trace) earlier than that, without
+// holding on to the *Span value StartSpan returned. It's a no-op if ctx
+// doesn't carry a Span, and safe to call more than once for the same Span.
+func FinishSpan(ctx context.Context) error {
+	span, ok := SpanFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	span.End()
+
+	if span.parentSpanID != "" {
+		return nil
+	}
+
+	span.l.Lock()
+	alreadyFlushed := span.flushed
+	span.flushed = true
+	span.l.Unlock()
+	if alreadyFlushed {
+		return nil
+	}
+
+	exp, ok := ExporterFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return Flush(span, exp)
+}
+
+// TraceID returns the id of the trace this Span is a part of. All Spans
+// descending from the same root Span share the same TraceID.
+func (s *Span) TraceID() string { return s.traceID }
+
+// SpanID returns this Span's own id, unique within its trace.
+func (s *Span) SpanID() string { return s.spanID }
+
+// ParentSpanID returns the SpanID of this Span's parent, or the empty string
+// if this Span has no parent.
+func (s *Span) ParentSpanID() string { return s.parentSpanID }
+
+// SetStatus sets the Span's status, overwriting whatever was set previously.
+func (s *Span) SetStatus(status SpanStatus) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.status = status
+}
+
+// AddEvent records that an event with the given name and attributes occurred
+// at the current time within the lifetime of the Span.
+func (s *Span) AddEvent(name string, attributes map[string]interface{}) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.events = append(s.events, SpanEvent{
+		Name:       name,
+		Time:       time.Now(),
+		Attributes: attributes,
+	})
+}
+
+// Attributes returns the Span's current set of attributes, as gathered from
+// any annotations (see Annotate) which have been set on the Span's Context.
+func (s *Span) Attributes() map[string]string {
+	return EvaluateAnnotations(s.ctx, nil).StringMap()
+}
+
+// End marks the Span as having completed at the current time. A Span which
+// has already been ended is unaffected by further calls to End.
+func (s *Span) End() {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if !s.end.IsZero() {
+		return
+	}
+	s.end = time.Now()
+}
+
+// visit calls callback once for this Span and then, in breadth-first order,
+// for every Span descending from it. If callback returns false then visit
+// returns without visiting any more Spans.
+func (s *Span) visit(callback func(*Span) bool) {
+	queue := []*Span{s}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		if !callback(cur) {
+			return
+		}
+
+		cur.l.Lock()
+		children := append([]*Span{}, cur.children...)
+		cur.l.Unlock()
+		queue = append(queue, children...)
+	}
+}
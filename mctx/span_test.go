@@ -0,0 +1,59 @@
+package mctx
+
+import (
+	"context"
+	"sync"
+	. "testing"
+
+	"github.com/mediocregopher/mediocre-go-lib/mtest/massert"
+)
+
+type captureExporter struct {
+	l     sync.Mutex
+	spans []ExportedSpan
+}
+
+func (e *captureExporter) Export(spans []ExportedSpan) error {
+	e.l.Lock()
+	defer e.l.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func TestFinishSpanFlushesTrace(t *T) {
+	exp := new(captureExporter)
+	ctx := WithExporter(context.Background(), exp)
+
+	ctx, rootSpan := StartSpan(ctx, "root")
+	childCtx, childSpan := StartSpan(ctx, "child")
+
+	massert.Fatal(t, massert.Equal(rootSpan.TraceID(), childSpan.TraceID()))
+	massert.Fatal(t, massert.Equal(rootSpan.SpanID(), childSpan.ParentSpanID()))
+
+	massert.Fatal(t, massert.Equal(nil, FinishSpan(childCtx)))
+	massert.Fatal(t, massert.Equal(nil, FinishSpan(ctx)))
+
+	// finishing the root a second time (e.g. via the Context's Done, on top
+	// of an explicit call) shouldn't flush the trace twice.
+	massert.Fatal(t, massert.Equal(nil, FinishSpan(ctx)))
+
+	exp.l.Lock()
+	defer exp.l.Unlock()
+	massert.Fatal(t, massert.Comment(
+		massert.Len(exp.spans, 2),
+		"spans: %#v", exp.spans,
+	))
+}
+
+func TestFinishSpanNoExporter(t *T) {
+	ctx, _ := StartSpan(context.Background(), "root")
+	massert.Fatal(t, massert.Equal(nil, FinishSpan(ctx)))
+}
+
+func TestFinishSpanNoSpan(t *T) {
+	massert.Fatal(t, massert.Equal(nil, FinishSpan(context.Background())))
+}
+
+func TestNoopExporter(t *T) {
+	massert.Fatal(t, massert.Equal(nil, NoopExporter{}.Export(nil)))
+}
@@ -2,17 +2,58 @@ package mctx
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"path/filepath"
 	"runtime"
 	"strings"
-	"text/tabwriter"
 )
 
 // MaxStackSize indicates the maximum number of stack frames which will be
 // stored when embedding stack traces in errors.
 var MaxStackSize = 50
 
+// thisPackage is this package's own import path, as it'll show up in
+// runtime.Frame.Function (e.g. "github.com/.../mctx.WithStack"). It's
+// derived at runtime, rather than hardcoded, so it stays correct under a
+// fork or rename.
+var thisPackage = framePackage(func() string {
+	pc, _, _, _ := runtime.Caller(0)
+	return runtime.FuncForPC(pc).Name()
+}())
+
+// framePackage returns the package portion of a runtime.Frame.Function
+// value, e.g. "github.com/.../mctx" for "github.com/.../mctx.WithStack" or
+// "github.com/.../mctx.(*Foo).Bar".
+func framePackage(function string) string {
+	slash := strings.LastIndexByte(function, '/')
+	dot := strings.IndexByte(function[slash+1:], '.')
+	if dot < 0 {
+		return function
+	}
+	return function[:slash+1+dot]
+}
+
+// DefaultFilter is a filter function, for use with Stacktrace.Filter or
+// Opts.Filter, which skips frames belonging to the runtime, reflect, and
+// testing packages, as well as this package itself, none of which are
+// usually useful when trying to track down where a Context (or an error
+// carrying one) actually originated.
+var DefaultFilter = func(frame runtime.Frame) bool {
+	switch pkg := framePackage(frame.Function); {
+	case pkg == "runtime" || strings.HasPrefix(pkg, "runtime/"):
+		return false
+	case pkg == "reflect":
+		return false
+	case pkg == "testing" || strings.HasPrefix(pkg, "testing/"):
+		return false
+	case pkg == thisPackage:
+		return false
+	default:
+		return true
+	}
+}
+
 type ctxStackKey int
 
 // Stacktrace represents a stack trace at a particular point in execution.
@@ -48,6 +89,44 @@ func (s Stacktrace) Frames() []runtime.Frame {
 	return out
 }
 
+// frameForPC returns the runtime.Frame corresponding to a single PC, as
+// captured within a Stacktrace's frames slice.
+func frameForPC(pc uintptr) runtime.Frame {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return frame
+}
+
+// Filter returns a copy of the Stacktrace containing only those frames for
+// which keep returns true.
+func (s Stacktrace) Filter(keep func(runtime.Frame) bool) Stacktrace {
+	frames := make([]uintptr, 0, len(s.frames))
+	for _, pc := range s.frames {
+		if keep(frameForPC(pc)) {
+			frames = append(frames, pc)
+		}
+	}
+	return Stacktrace{frames: frames}
+}
+
+// TrimBelow returns a copy of the Stacktrace with every frame below (i.e.
+// further from the point the Stacktrace was captured than) the deepest
+// frame belonging to the package at pkgPrefix removed. This is useful for
+// cutting off runtime/testing scaffolding (go test's tRunner, runtime.main,
+// etc...) once the trace has unwound back out of the caller's own package.
+//
+// If no frame belongs to pkgPrefix, the Stacktrace is returned unchanged.
+func (s Stacktrace) TrimBelow(pkgPrefix string) Stacktrace {
+	cut := len(s.frames)
+	for i, pc := range s.frames {
+		if pkg := framePackage(frameForPC(pc).Function); pkg == pkgPrefix {
+			cut = i + 1
+		}
+	}
+	frames := make([]uintptr, cut)
+	copy(frames, s.frames[:cut])
+	return Stacktrace{frames: frames}
+}
+
 // String returns a string representing the top-most frame of the stack.
 func (s Stacktrace) String() string {
 	if len(s.frames) == 0 {
@@ -59,30 +138,86 @@ func (s Stacktrace) String() string {
 	return fmt.Sprintf("%s/%s:%d", dir, file, frame.Line)
 }
 
-// FullString returns the full stack trace.
+// FullString returns the full stack trace, rendered one frame per two
+// lines in the same format used by an uncaught panic (and understood by
+// `go tool pprof` and most editors/IDEs for jump-to-frame navigation):
+//
+//	<function>()
+//		<file>:<line>
 func (s Stacktrace) FullString() string {
 	sb := new(strings.Builder)
-	tw := tabwriter.NewWriter(sb, 0, 4, 4, ' ', 0)
 	for _, frame := range s.Frames() {
-		file := fmt.Sprintf("%s:%d", frame.File, frame.Line)
-		fmt.Fprintf(tw, "%s\t%s\n", file, frame.Function)
-	}
-	if err := tw.Flush(); err != nil {
-		panic(err)
+		fmt.Fprintf(sb, "%s()\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
 	}
 	return sb.String()
 }
 
+// stacktraceFrameJSON is the JSON representation of a single frame within a
+// Stacktrace, as produced by Stacktrace.MarshalJSON.
+type stacktraceFrameJSON struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Func string `json:"func"`
+}
+
+// MarshalJSON implements the json.Marshaler interface. The Stacktrace is
+// represented as an array of {file,line,func} objects, one per frame, so
+// that stacks can be shipped structurally (e.g. through mlog) rather than
+// as an opaque string.
+func (s Stacktrace) MarshalJSON() ([]byte, error) {
+	frames := s.Frames()
+	out := make([]stacktraceFrameJSON, len(frames))
+	for i, frame := range frames {
+		out[i] = stacktraceFrameJSON{File: frame.File, Line: frame.Line, Func: frame.Function}
+	}
+	return json.Marshal(out)
+}
+
+// Opts are parameters given to WithStackOpts, customizing how a Stacktrace
+// is captured.
+type Opts struct {
+	// Skip is the number of frames to skip from the top of the stack, not
+	// counting the WithStackOpts call itself.
+	Skip int
+
+	// Max is the maximum number of stack frames to capture. If 0,
+	// MaxStackSize is used.
+	Max int
+
+	// Filter, if non-nil, is applied to the captured stack via
+	// Stacktrace.Filter before it's embedded into the Context, e.g.
+	// DefaultFilter. If nil, no filtering is done.
+	Filter func(runtime.Frame) bool
+}
+
 // WithStack returns a Context with the current stacktrace embedded in it (as a
 // Stacktrace type). If skip is non-zero it will skip that many frames from the
 // top of the stack. The frame containing the WithStack call itself is always
 // excluded.
 func WithStack(ctx context.Context, skip int) context.Context {
-	stackSlice := make([]uintptr, MaxStackSize)
-	// incr skip once for WithStack, and once for runtime.Callers
-	l := runtime.Callers(skip+2, stackSlice)
+	// +1 to account for this function's own frame, so that Opts.Skip within
+	// WithStackOpts ends up counting from the same point it would if the
+	// caller had invoked WithStackOpts directly.
+	return WithStackOpts(ctx, Opts{Skip: skip + 1})
+}
+
+// WithStackOpts is like WithStack, but allows further customizing how the
+// stack is captured via Opts.
+func WithStackOpts(ctx context.Context, opts Opts) context.Context {
+	max := opts.Max
+	if max == 0 {
+		max = MaxStackSize
+	}
+
+	stackSlice := make([]uintptr, max)
+	// incr skip once for WithStackOpts, and once for runtime.Callers
+	l := runtime.Callers(opts.Skip+2, stackSlice)
 	stack := Stacktrace{frames: stackSlice[:l]}
 
+	if opts.Filter != nil {
+		stack = stack.Filter(opts.Filter)
+	}
+
 	return context.WithValue(ctx, ctxStackKey(0), stack)
 }
 
@@ -0,0 +1,62 @@
+package mctx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// traceparentHeader and tracestateHeader are the W3C Trace Context header
+// names (see https://www.w3.org/TR/trace-context/).
+const (
+	traceparentHeader = "Traceparent"
+	tracestateHeader  = "Tracestate"
+)
+
+// InjectTraceparent sets the W3C "traceparent" (and, if given, "tracestate")
+// headers on h to describe the given Span, so that a request can carry trace
+// context to a downstream service. It's meant to be called from mnet/mhttp
+// client middleware.
+func InjectTraceparent(h http.Header, span *Span, tracestate string) {
+	h.Set(traceparentHeader, fmt.Sprintf(
+		"00-%s-%s-01", span.TraceID(), span.SpanID(),
+	))
+	if tracestate != "" {
+		h.Set(tracestateHeader, tracestate)
+	}
+}
+
+// ExtractTraceparent parses the W3C "traceparent" (and "tracestate") headers,
+// if present in h, and returns a Span descending from the described remote
+// parent, embedded in the returned Context. ok will be false, the given ctx
+// returned unchanged, and span nil, if h doesn't contain a valid traceparent
+// header.
+//
+// It's meant to be called from mnet/mhttp server middleware, using the name
+// of the local operation being performed (e.g. the request's route) as name.
+func ExtractTraceparent(ctx context.Context, h http.Header, name string) (rctx context.Context, span *Span, tracestate string, ok bool) {
+	traceID, parentSpanID, ok := parseTraceparent(h.Get(traceparentHeader))
+	if !ok {
+		return ctx, nil, "", false
+	}
+
+	rctx, span = StartSpan(ctx, name)
+	span.traceID = traceID
+	span.parentSpanID = parentSpanID
+
+	return rctx, span, h.Get(tracestateHeader), true
+}
+
+// parseTraceparent parses a "traceparent" header value of the form
+// "version-traceId-parentId-flags", returning the traceId and parentId. Only
+// version "00" is supported.
+func parseTraceparent(val string) (traceID, parentID string, ok bool) {
+	parts := strings.Split(val, "-")
+	if len(parts) != 4 || parts[0] != "00" {
+		return "", "", false
+	} else if len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
@@ -0,0 +1,125 @@
+package mctx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Root walks up ctx's ancestors, as established by NewChild, and returns the
+// topmost one. If ctx has no ancestors (i.e. it was never passed into
+// NewChild) then ctx itself is returned.
+//
+// NOTE like parentOf, the ancestor Root returns is the Context which was
+// originally passed into NewChild, not any later Context it may have been
+// reassigned to (e.g. via WithChild). Callers which build up a tree bottom-up
+// (calling WithChild on a parent only after all of its children have been
+// created) should call Root (and Walk) using the final, fully-built root
+// Context, rather than relying on a child's view of its ancestors to reflect
+// later changes.
+func Root(ctx context.Context) context.Context {
+	for {
+		parent := parentOf(ctx)
+		if parent == nil {
+			return ctx
+		}
+		ctx = parent
+	}
+}
+
+// Walk performs a deterministic depth-first traversal of ctx and all of its
+// descendants (as established by WithChild), calling callback with each
+// visited Context's Path and the Context itself. Children are visited in the
+// order they were added via WithChild.
+//
+// If callback returns false then Walk stops entirely, visiting no further
+// Contexts.
+func Walk(ctx context.Context, callback func(path []string, ctx context.Context) bool) {
+	walk(ctx, callback)
+}
+
+func walk(ctx context.Context, callback func(path []string, ctx context.Context) bool) bool {
+	if !callback(Path(ctx), ctx) {
+		return false
+	}
+	for _, child := range Children(ctx) {
+		if !walk(child, callback) {
+			return false
+		}
+	}
+	return true
+}
+
+// treeNode is the structure MarshalJSON serializes a Context, and each of its
+// descendants, into.
+type treeNode struct {
+	Annotations map[string]string    `json:"annotations,omitempty"`
+	Children    map[string]*treeNode `json:"children,omitempty"`
+}
+
+func newTreeNode(ctx context.Context) *treeNode {
+	node := new(treeNode)
+
+	if aa := EvaluateAnnotations(ctx, nil); len(aa) > 0 {
+		node.Annotations = aa.StringMap()
+	}
+
+	for _, child := range Children(ctx) {
+		name, _ := Name(child)
+		if node.Children == nil {
+			node.Children = map[string]*treeNode{}
+		}
+		node.Children[name] = newTreeNode(child)
+	}
+
+	return node
+}
+
+// MarshalJSON returns a JSON document describing ctx and all of its
+// descendants. Each node in the tree is an object of the form
+// {"annotations": {...}, "children": {name: {...}, ...}}, where "annotations"
+// holds that node's own evaluated annotations (see EvaluateAnnotations) and
+// "children" maps each child's name to its own such object.
+//
+// This is useful for dumping the entire framework configuration/component
+// tree, e.g. at startup or over an HTTP debug endpoint.
+func MarshalJSON(ctx context.Context) ([]byte, error) {
+	return json.Marshal(newTreeNode(ctx))
+}
+
+// dotNodeID returns the identifier MarshalDOT uses for the node at path
+// within its digraph.
+func dotNodeID(path []string) string {
+	if len(path) == 0 {
+		return "root"
+	}
+	return strings.Join(path, "/")
+}
+
+// MarshalDOT returns a Graphviz DOT digraph describing ctx and all of its
+// descendants. Each node is labeled with its name and the number of
+// annotations it carries (see EvaluateAnnotations), and an edge connects
+// each node to its children.
+func MarshalDOT(ctx context.Context) []byte {
+	sb := new(strings.Builder)
+	sb.WriteString("digraph mctx {\n")
+
+	Walk(ctx, func(path []string, nodeCtx context.Context) bool {
+		id := dotNodeID(path)
+		name := "root"
+		if len(path) > 0 {
+			name = path[len(path)-1]
+		}
+		numAnnotations := len(EvaluateAnnotations(nodeCtx, nil))
+
+		fmt.Fprintf(sb, "\t%q [label=%q];\n", id, fmt.Sprintf("%s (%d annotations)", name, numAnnotations))
+		if len(path) > 0 {
+			fmt.Fprintf(sb, "\t%q -> %q;\n", dotNodeID(path[:len(path)-1]), id)
+		}
+		return true
+	})
+
+	sb.WriteString("}\n")
+	return []byte(sb.String())
+}
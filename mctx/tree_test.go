@@ -0,0 +1,97 @@
+package mctx
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	. "testing"
+
+	"github.com/mediocregopher/mediocre-go-lib/mtest/massert"
+)
+
+func TestRoot(t *T) {
+	// NOTE ctx, ctx1, and ctx1a are deliberately never reassigned via
+	// WithChild here, since (like parentOf) Root walks the ancestor a
+	// Context was created from via NewChild, not whatever that ancestor may
+	// have later been reassigned to. See Root's doc comment.
+	ctx := context.Background()
+	ctx1 := NewChild(ctx, "1")
+	ctx1a := NewChild(ctx1, "a")
+
+	massert.Fatal(t, massert.All(
+		massert.Equal(ctx, Root(ctx)),
+		massert.Equal(ctx, Root(ctx1)),
+		massert.Equal(ctx, Root(ctx1a)),
+	))
+}
+
+func TestWalk(t *T) {
+	ctx := context.Background()
+	ctx1 := NewChild(ctx, "1")
+	ctx1a := NewChild(ctx1, "a")
+	ctx1b := NewChild(ctx1, "b")
+	ctx1 = WithChild(ctx1, ctx1a)
+	ctx1 = WithChild(ctx1, ctx1b)
+	ctx2 := NewChild(ctx, "2")
+	ctx = WithChild(ctx, ctx1)
+	ctx = WithChild(ctx, ctx2)
+
+	var paths [][]string
+	Walk(ctx, func(path []string, _ context.Context) bool {
+		paths = append(paths, path)
+		return true
+	})
+
+	massert.Fatal(t, massert.Equal([][]string{
+		nil,
+		{"1"},
+		{"1", "a"},
+		{"1", "b"},
+		{"2"},
+	}, paths))
+
+	// a callback returning false should stop the walk entirely
+	var stoppedAfter [][]string
+	Walk(ctx, func(path []string, _ context.Context) bool {
+		stoppedAfter = append(stoppedAfter, path)
+		return len(stoppedAfter) < 2
+	})
+	massert.Fatal(t, massert.Equal([][]string{nil, {"1"}}, stoppedAfter))
+}
+
+func TestMarshalJSON(t *T) {
+	ctx := context.Background()
+	ctx1 := Annotate(NewChild(ctx, "1"), "k", "v")
+	ctx2 := NewChild(ctx, "2")
+	ctx = WithChild(ctx, ctx1)
+	ctx = WithChild(ctx, ctx2)
+
+	b, err := MarshalJSON(ctx)
+	massert.Fatal(t, massert.Nil(err))
+
+	var got map[string]interface{}
+	massert.Fatal(t, massert.Nil(json.Unmarshal(b, &got)))
+
+	children := got["children"].(map[string]interface{})
+	node1 := children["1"].(map[string]interface{})
+	node2 := children["2"].(map[string]interface{})
+
+	massert.Fatal(t, massert.All(
+		massert.Equal(map[string]interface{}{"k": "v"}, node1["annotations"]),
+		massert.Nil(node2["annotations"]),
+		massert.Nil(node2["children"]),
+	))
+}
+
+func TestMarshalDOT(t *T) {
+	ctx := context.Background()
+	ctx1 := NewChild(ctx, "1")
+	ctx = WithChild(ctx, ctx1)
+
+	dot := string(MarshalDOT(ctx))
+	massert.Fatal(t, massert.All(
+		massert.Equal(true, strings.HasPrefix(dot, "digraph mctx {")),
+		massert.Equal(true, strings.Contains(dot, `"1"`)),
+		massert.Equal(true, strings.Contains(dot, `"root" -> "1"`)),
+	))
+}
@@ -12,6 +12,7 @@ import (
 
 	"cloud.google.com/go/bigquery"
 	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
 )
 
 // TODO this file needs tests
@@ -40,8 +41,15 @@ type BigQuery struct {
 
 // CfgBigQuery configures and returns a BigQuery instance which will be usable
 // once Run is called on the passed in Cfg instance.
+//
+// If the BIGQUERY_EMULATOR_HOST environment variable (or the equivalent
+// emulator-host param) is set, the returned BigQuery connects to that host
+// instead of the real BigQuery API, and skips authentication entirely, as
+// expected by the open-source BigQuery emulator.
 func CfgBigQuery(cfg *mcfg.Cfg) *BigQuery {
 	cfg = cfg.Child("bigquery")
+	emulatorHost := mcfg.String(cfg, "emulator-host",
+		mcfg.ParamUsage("Host (e.g. localhost:9050) of a BigQuery emulator to connect to in place of the real BigQuery API. If set, GCE authentication is skipped entirely."))
 	bq := BigQuery{
 		gce:            CfgGCE(cfg),
 		tables:         map[[2]string]*bigquery.Table{},
@@ -50,8 +58,17 @@ func CfgBigQuery(cfg *mcfg.Cfg) *BigQuery {
 	bq.log = m.Log(cfg, &bq)
 	cfg.Start.Then(func(ctx context.Context) error {
 		bq.log.Info("connecting to bigquery")
+
+		opts := bq.gce.ClientOptions()
+		if *emulatorHost != "" {
+			opts = append(opts,
+				option.WithEndpoint("http://"+*emulatorHost),
+				option.WithoutAuthentication(),
+			)
+		}
+
 		var err error
-		bq.Client, err = bigquery.NewClient(ctx, bq.gce.Project, bq.gce.ClientOptions()...)
+		bq.Client, err = bigquery.NewClient(ctx, bq.gce.Project, opts...)
 		return mlog.ErrWithKV(err, &bq)
 	})
 	return &bq
@@ -0,0 +1,433 @@
+package mdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mediocregopher/mediocre-go-lib/m"
+	"github.com/mediocregopher/mediocre-go-lib/mcfg"
+	"github.com/mediocregopher/mediocre-go-lib/mlog"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+)
+
+// bqMaxBatchSize is the largest number of rows BigQuery's streaming
+// insertAll API accepts in a single call.
+const bqMaxBatchSize = 500
+
+// DeadLetterRow describes a row a StreamingIngester gave up on inserting,
+// paired with the error which caused it to be given up on.
+type DeadLetterRow struct {
+	Row   interface{} `json:"row"`
+	Error string      `json:"error"`
+}
+
+// Sink receives rows which a StreamingIngester has given up on, either
+// because BigQuery rejected them outright (e.g. a schema mismatch) or
+// because they exhausted IngesterOpts.MaxRetries.
+type Sink interface {
+	Put(ctx context.Context, rows []DeadLetterRow) error
+}
+
+type fileSink struct {
+	l    sync.Mutex
+	path string
+}
+
+// FileSink returns a Sink which appends each DeadLetterRow, one per line as
+// JSON, to the file at path. The file is created if it doesn't already
+// exist.
+func FileSink(path string) Sink {
+	return &fileSink{path: path}
+}
+
+func (s *fileSink) Put(ctx context.Context, rows []DeadLetterRow) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	for _, row := range rows {
+		if err := enc.Encode(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type gcsSink struct {
+	bkt    *storage.BucketHandle
+	prefix string
+	ctr    uint64
+}
+
+// GCSSink returns a Sink which writes each batch of rows passed to Put as a
+// single newline-delimited-JSON object into bucket, named
+// "<objectPrefix><n>.json", where n increments on every call to Put.
+func GCSSink(client *storage.Client, bucket, objectPrefix string) Sink {
+	return &gcsSink{bkt: client.Bucket(bucket), prefix: objectPrefix}
+}
+
+func (s *gcsSink) Put(ctx context.Context, rows []DeadLetterRow) error {
+	n := atomic.AddUint64(&s.ctr, 1)
+	w := s.bkt.Object(fmt.Sprintf("%s%d.json", s.prefix, n)).NewWriter(ctx)
+	for _, row := range rows {
+		b, err := json.Marshal(row)
+		if err != nil {
+			w.Close()
+			return err
+		}
+		if _, err := w.Write(append(b, '\n')); err != nil {
+			w.Close()
+			return err
+		}
+	}
+	return w.Close()
+}
+
+type pubsubSink struct {
+	topic *pubsub.Topic
+}
+
+// PubSubSink returns a Sink which publishes each row passed to Put, JSON
+// encoded, as its own message on topic.
+func PubSubSink(topic *pubsub.Topic) Sink {
+	return &pubsubSink{topic: topic}
+}
+
+func (s *pubsubSink) Put(ctx context.Context, rows []DeadLetterRow) error {
+	for _, row := range rows {
+		b, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		if _, err := s.topic.Publish(ctx, &pubsub.Message{Data: b}).Get(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IngesterOpts are used to configure a StreamingIngester returned by
+// BigQuery.Ingester. All fields are optional.
+type IngesterOpts struct {
+	// BatchSize is the number of rows to accumulate before flushing them to
+	// BigQuery in a single insertAll call. BigQuery's streaming quota caps
+	// this at 500; a value which is <= 0 or greater than that is reset to
+	// 500.
+	BatchSize int
+
+	// FlushInterval is the longest a row will sit in the buffer before being
+	// flushed, even if BatchSize hasn't been reached. Defaults to 1 second.
+	FlushInterval time.Duration
+
+	// MaxInFlight caps the number of batches which may be concurrently in
+	// the process of being inserted (and, on failure, retried) at once.
+	// Defaults to 4.
+	MaxInFlight int
+
+	// MaxRetries is the number of times a batch which failed with a
+	// transient error (a 429 or 503 from BigQuery) is retried, with
+	// exponential backoff between attempts, before its rows are given up on
+	// and handed to DeadLetter. bigquery.Uploader.Put already retries
+	// temporary errors internally, indefinitely; this bounds that with a
+	// giving-up point of our own. Defaults to 5.
+	MaxRetries int
+
+	// DeadLetter, if set, receives rows BigQuery rejected outright via a
+	// per-row insert error, or which exhausted MaxRetries. If unset, such
+	// rows are logged and dropped.
+	DeadLetter Sink
+}
+
+func (o IngesterOpts) withDefaults() IngesterOpts {
+	if o.BatchSize <= 0 || o.BatchSize > bqMaxBatchSize {
+		o.BatchSize = bqMaxBatchSize
+	}
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = 1 * time.Second
+	}
+	if o.MaxInFlight <= 0 {
+		o.MaxInFlight = 4
+	}
+	if o.MaxRetries <= 0 {
+		o.MaxRetries = 5
+	}
+	return o
+}
+
+// IngesterStats is a snapshot of a StreamingIngester's Prometheus-style
+// counters/gauges, retrieved via StreamingIngester.Stats.
+type IngesterStats struct {
+	RowsAccepted     int64
+	RowsRejected     int64
+	RowsInflight     int64
+	LastBatchLatency time.Duration
+}
+
+// StreamingIngester batches rows in memory and streams them into a BigQuery
+// table in the background, retrying transient failures and routing rows
+// BigQuery won't accept to a dead-letter Sink rather than failing their
+// whole batch. It's obtained via BigQuery.Ingester.
+type StreamingIngester struct {
+	uploader *bigquery.Uploader
+	log      *mlog.Logger
+	opts     IngesterOpts
+	sem      chan struct{}
+	wg       sync.WaitGroup
+
+	rowsAccepted     int64
+	rowsRejected     int64
+	rowsInflight     int64
+	lastBatchLatency int64 // time.Duration, accessed atomically
+
+	rowCh   chan interface{}
+	flushCh chan chan error
+	doneCh  chan chan error
+}
+
+// Ingester returns a StreamingIngester which streams rows into the given
+// dataset/table (created via Table if it doesn't already exist), batching
+// and retrying inserts in the background. The returned instance is usable
+// once Run is called on cfg; its buffer is flushed and its background loop
+// stopped when cfg is stopped.
+func (bq *BigQuery) Ingester(
+	cfg *mcfg.Cfg,
+	dataset, tableName string,
+	schemaObj interface{},
+	opts IngesterOpts,
+) *StreamingIngester {
+	cfg = cfg.Child("ingester")
+	opts = opts.withDefaults()
+	in := &StreamingIngester{
+		log:     m.Log(cfg, bq),
+		opts:    opts,
+		sem:     make(chan struct{}, opts.MaxInFlight),
+		rowCh:   make(chan interface{}),
+		flushCh: make(chan chan error),
+		doneCh:  make(chan chan error),
+	}
+
+	cfg.Start.Then(func(ctx context.Context) error {
+		_, uploader, err := bq.Table(ctx, dataset, tableName, schemaObj)
+		if err != nil {
+			return mlog.ErrWithKV(err, bq)
+		}
+		in.uploader = uploader
+		go in.loop()
+		return nil
+	})
+
+	cfg.Stop.Then(func(ctx context.Context) error {
+		return in.Close(ctx)
+	})
+
+	return in
+}
+
+// Add queues row to be inserted into BigQuery, blocking until it's been
+// accepted into the buffer or ctx is canceled.
+func (in *StreamingIngester) Add(ctx context.Context, row interface{}) error {
+	select {
+	case in.rowCh <- row:
+		atomic.AddInt64(&in.rowsInflight, 1)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Flush blocks until every row buffered as of this call has been inserted,
+// or given up on and handed to DeadLetter.
+func (in *StreamingIngester) Flush(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	select {
+	case in.flushCh <- errCh:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close flushes the buffer and stops the StreamingIngester's background
+// loop. It's wired into the Cfg passed to Ingester via cfg.Stop, so most
+// callers won't need to call it directly.
+func (in *StreamingIngester) Close(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	select {
+	case in.doneCh <- errCh:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats returns a snapshot of the StreamingIngester's current metrics.
+func (in *StreamingIngester) Stats() IngesterStats {
+	return IngesterStats{
+		RowsAccepted:     atomic.LoadInt64(&in.rowsAccepted),
+		RowsRejected:     atomic.LoadInt64(&in.rowsRejected),
+		RowsInflight:     atomic.LoadInt64(&in.rowsInflight),
+		LastBatchLatency: time.Duration(atomic.LoadInt64(&in.lastBatchLatency)),
+	}
+}
+
+func (in *StreamingIngester) loop() {
+	batch := make([]interface{}, 0, in.opts.BatchSize)
+	ticker := time.NewTicker(in.opts.FlushInterval)
+	defer ticker.Stop()
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		rows := batch
+		batch = make([]interface{}, 0, in.opts.BatchSize)
+		in.sem <- struct{}{}
+		in.wg.Add(1)
+		go func() {
+			defer in.wg.Done()
+			defer func() { <-in.sem }()
+			in.insertWithRetry(context.Background(), rows)
+		}()
+	}
+
+	for {
+		select {
+		case row := <-in.rowCh:
+			batch = append(batch, row)
+			if len(batch) >= in.opts.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case errCh := <-in.flushCh:
+			flush()
+			in.wg.Wait()
+			errCh <- nil
+		case errCh := <-in.doneCh:
+			flush()
+			in.wg.Wait()
+			errCh <- nil
+			return
+		}
+	}
+}
+
+func (in *StreamingIngester) insertWithRetry(ctx context.Context, rows []interface{}) {
+	start := time.Now()
+	defer func() {
+		atomic.StoreInt64(&in.lastBatchLatency, int64(time.Since(start)))
+	}()
+
+	pending := rows
+	for attempt := 0; ; attempt++ {
+		err := in.uploader.Put(ctx, pending)
+		if err == nil {
+			in.accept(len(pending))
+			return
+		}
+
+		if multiErr, ok := err.(bigquery.PutMultiError); ok {
+			rejected := make(map[int]string, len(multiErr))
+			for _, rowErr := range multiErr {
+				rejected[rowErr.RowIndex] = rowErr.Error()
+			}
+			dlRows := make([]DeadLetterRow, 0, len(rejected))
+			for i, row := range pending {
+				if errStr, ok := rejected[i]; ok {
+					dlRows = append(dlRows, DeadLetterRow{Row: row, Error: errStr})
+				}
+			}
+			in.accept(len(pending) - len(dlRows))
+			in.deadLetter(ctx, dlRows)
+			return
+		}
+
+		if !isRetriableBQErr(err) || attempt >= in.opts.MaxRetries {
+			in.giveUp(ctx, pending, err)
+			return
+		}
+
+		in.log.Warn("retrying bigquery batch insert", mlog.KV{"attempt": attempt, "err": err})
+		select {
+		case <-time.After(bqBackoff(attempt)):
+		case <-ctx.Done():
+			in.giveUp(ctx, pending, ctx.Err())
+			return
+		}
+	}
+}
+
+func (in *StreamingIngester) accept(n int) {
+	atomic.AddInt64(&in.rowsAccepted, int64(n))
+	atomic.AddInt64(&in.rowsInflight, -int64(n))
+}
+
+func (in *StreamingIngester) deadLetter(ctx context.Context, rows []DeadLetterRow) {
+	if len(rows) == 0 {
+		return
+	}
+	atomic.AddInt64(&in.rowsRejected, int64(len(rows)))
+	atomic.AddInt64(&in.rowsInflight, -int64(len(rows)))
+	if in.opts.DeadLetter == nil {
+		in.log.Warn("dropping rows rejected by bigquery, no DeadLetter sink configured", mlog.KV{"rows": len(rows)})
+		return
+	}
+	if err := in.opts.DeadLetter.Put(ctx, rows); err != nil {
+		in.log.Error("failed writing rejected rows to dead-letter sink, dropping them", mlog.KV{"err": err})
+	}
+}
+
+func (in *StreamingIngester) giveUp(ctx context.Context, rows []interface{}, cause error) {
+	dlRows := make([]DeadLetterRow, len(rows))
+	for i, row := range rows {
+		dlRows[i] = DeadLetterRow{Row: row, Error: cause.Error()}
+	}
+	in.deadLetter(ctx, dlRows)
+}
+
+// isRetriableBQErr returns true for the status codes BigQuery's streaming
+// insert API uses to indicate the caller should back off and retry: 429
+// (quota exceeded) and 503 (backend unavailable).
+func isRetriableBQErr(err error) bool {
+	gerr, ok := err.(*googleapi.Error)
+	return ok && (gerr.Code == 429 || gerr.Code == 503)
+}
+
+// bqBackoff returns the delay to wait before the attempt'th (0-indexed)
+// retry of a failed batch insert, using capped exponential backoff with
+// jitter.
+func bqBackoff(attempt int) time.Duration {
+	const (
+		base = 500 * time.Millisecond
+		max  = 30 * time.Second
+	)
+	d := base * time.Duration(uint64(1)<<uint(attempt))
+	if d <= 0 || d > max {
+		d = max
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d)/2+1))
+}
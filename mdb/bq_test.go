@@ -0,0 +1,76 @@
+package mdb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	. "testing"
+
+	"github.com/mediocregopher/mediocre-go-lib/mlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/option"
+)
+
+type bqTestRow struct {
+	Foo string
+}
+
+func alreadyExistsJSON(kind string) string {
+	return fmt.Sprintf(`{"error":{"code":409,"message":"Already Exists: %s"}}`, kind)
+}
+
+// TestBigQueryTableAlreadyExists mocks out the BigQuery REST API, having it
+// respond to dataset/table creation with 409s (as the real API does once
+// they've already been created), and asserts that Table still succeeds
+// despite that, and that its result is cached on subsequent calls.
+func TestBigQueryTableAlreadyExists(t *T) {
+	var datasetCalls, tableCalls int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/projects/test-project/datasets", func(w http.ResponseWriter, r *http.Request) {
+		datasetCalls++
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprint(w, alreadyExistsJSON("Dataset test_dataset"))
+	})
+	mux.HandleFunc("/projects/test-project/datasets/test_dataset/tables", func(w http.ResponseWriter, r *http.Request) {
+		tableCalls++
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprint(w, alreadyExistsJSON("Table test_table"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "test-project",
+		option.WithEndpoint(srv.URL),
+		option.WithoutAuthentication(),
+	)
+	require.NoError(t, err)
+
+	bq := &BigQuery{
+		Client:         client,
+		gce:            &GCE{Project: "test-project"},
+		log:            mlog.NewLogger(),
+		tables:         map[[2]string]*bigquery.Table{},
+		tableUploaders: map[[2]string]*bigquery.Uploader{},
+	}
+
+	table, uploader, err := bq.Table(ctx, "test_dataset", "test_table", bqTestRow{})
+	require.NoError(t, err)
+	assert.NotNil(t, table)
+	assert.NotNil(t, uploader)
+	assert.Equal(t, 1, datasetCalls)
+	assert.Equal(t, 1, tableCalls)
+
+	// A second call with the same dataset/table should hit the cache rather
+	// than re-creating anything.
+	table2, uploader2, err := bq.Table(ctx, "test_dataset", "test_table", bqTestRow{})
+	require.NoError(t, err)
+	assert.Same(t, table, table2)
+	assert.Same(t, uploader, uploader2)
+	assert.Equal(t, 1, datasetCalls, "second Table call should use the cache")
+	assert.Equal(t, 1, tableCalls, "second Table call should use the cache")
+}
@@ -0,0 +1,39 @@
+package mdb
+
+import (
+	"context"
+
+	"github.com/mediocregopher/mediocre-go-lib/mcfg"
+)
+
+// CfgGCE configures and returns a GCE instance which will be usable once Run
+// is called on the passed in Cfg instance.
+//
+// This is the CfgBigQuery/CfgGCE-style counterpart to InstGCE, for code which
+// hasn't yet moved off of the Cfg-based configuration style. Both fill in
+// the same Project, CredFile, CredJSON, and Endpoint fields from the same
+// params; the GCE returned by CfgGCE has no Component, and its project param
+// has no default.
+func CfgGCE(cfg *mcfg.Cfg) *GCE {
+	cfg = cfg.Child("gce")
+	gce := GCE{}
+
+	credFile := mcfg.String(cfg, "cred-file",
+		mcfg.ParamUsage("Path to GCE credentials JSON file, if any"))
+	credJSON := mcfg.String(cfg, "cred-json",
+		mcfg.ParamUsage("GCE credentials JSON document, if any (e.g. a service-account key, or a workload-identity-federation external_account config). Takes precedence over cred-file."))
+	project := mcfg.String(cfg, "project",
+		mcfg.ParamUsage("Name of GCE project to use"))
+	endpoint := mcfg.String(cfg, "endpoint",
+		mcfg.ParamUsage("Override the default API endpoint, e.g. to point at an emulator or a non-Google-Cloud endpoint"))
+
+	cfg.Start.Then(func(context.Context) error {
+		gce.CredFile = *credFile
+		gce.CredJSON = *credJSON
+		gce.Project = *project
+		gce.Endpoint = *endpoint
+		return nil
+	})
+
+	return &gce
+}
@@ -0,0 +1,208 @@
+package mbigquery
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/mediocregopher/mediocre-go-lib/mcfg"
+	"github.com/mediocregopher/mediocre-go-lib/mctx"
+	"github.com/mediocregopher/mediocre-go-lib/merr"
+	"github.com/mediocregopher/mediocre-go-lib/mlog"
+	"github.com/mediocregopher/mediocre-go-lib/mrun"
+)
+
+// DirectoryUploader watches a directory of newline-delimited JSON files and
+// streams their rows into a BigQuery table via the Uploader returned by
+// BigQuery.Table. Files are deleted once they've been fully uploaded; if a
+// file's rows are rejected partway through, the file is instead moved into
+// an "errors" subdirectory so nothing is silently lost.
+type DirectoryUploader struct {
+	bq        *BigQuery
+	schemaObj interface{}
+	ctx       context.Context
+
+	dir           *string
+	sweepInterval *time.Duration
+	workers       *int
+	maxBatchSize  *int
+	dataset       *string
+	table         *string
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// WithDirectoryUploader initializes a DirectoryUploader which, once the start
+// event is triggered on the returned Context, watches its configured
+// directory and streams newly appearing files' rows into the configured
+// dataset/table (whose schema is inferred from schemaObj, see
+// BigQuery.Table). Uploading stops, and in-flight files are finished, when
+// the stop event is triggered.
+func WithDirectoryUploader(
+	parent context.Context, bq *BigQuery, schemaObj interface{},
+) (
+	context.Context, *DirectoryUploader,
+) {
+	ctx := mctx.NewChild(parent, "directory-uploader")
+
+	du := &DirectoryUploader{
+		bq:        bq,
+		schemaObj: schemaObj,
+		stopCh:    make(chan struct{}),
+	}
+
+	ctx, du.dir = mcfg.WithRequiredString(ctx, "dir",
+		"Path to the directory of newline-delimited JSON files to upload")
+	ctx, du.sweepInterval = mcfg.WithDuration(ctx, "sweep-interval", 10*time.Second,
+		"How often the directory is checked for new files to upload")
+	ctx, du.workers = mcfg.WithInt(ctx, "workers", 4,
+		"Number of files which may be uploaded concurrently")
+	ctx, du.maxBatchSize = mcfg.WithInt(ctx, "max-batch-size", 500,
+		"Maximum number of rows sent to BigQuery in a single insert call")
+	ctx, du.dataset = mcfg.WithRequiredString(ctx, "dataset",
+		"BigQuery dataset the table belongs to")
+	ctx, du.table = mcfg.WithRequiredString(ctx, "table",
+		"BigQuery table to stream rows into")
+
+	ctx = mrun.WithStartHook(ctx, func(context.Context) error {
+		du.ctx = mctx.MergeAnnotations(ctx, bq.ctx)
+		mlog.Info("starting bigquery directory uploader", du.ctx)
+
+		if err := os.MkdirAll(du.errorsDir(), 0755); err != nil {
+			return merr.Wrap(du.ctx, err)
+		}
+
+		for i := 0; i < *du.workers; i++ {
+			du.wg.Add(1)
+			go du.sweepLoop()
+		}
+		return nil
+	})
+	ctx = mrun.WithStopHook(ctx, func(context.Context) error {
+		close(du.stopCh)
+		du.wg.Wait()
+		return nil
+	})
+
+	du.ctx = ctx
+	return mctx.WithChild(parent, ctx), du
+}
+
+func (du *DirectoryUploader) errorsDir() string {
+	return filepath.Join(*du.dir, "errors")
+}
+
+func (du *DirectoryUploader) sweepLoop() {
+	defer du.wg.Done()
+
+	t := time.NewTicker(*du.sweepInterval)
+	defer t.Stop()
+
+	for {
+		du.sweep()
+		select {
+		case <-t.C:
+		case <-du.stopCh:
+			return
+		}
+	}
+}
+
+// sweep uploads every regular file currently in the watched directory.
+// Multiple workers call sweep concurrently and may pick up the same file;
+// for a worker reaching a file another has already handled, opening,
+// removing, or renaming it fails, and that failure is logged.
+func (du *DirectoryUploader) sweep() {
+	entries, err := os.ReadDir(*du.dir)
+	if err != nil {
+		mlog.Error("reading directory-uploader directory", mctx.Annotate(du.ctx, "err", err.Error()))
+		return
+	}
+
+	for _, entry := range entries {
+		select {
+		case <-du.stopCh:
+			return
+		default:
+		}
+
+		if entry.IsDir() {
+			continue
+		}
+		du.uploadFile(filepath.Join(*du.dir, entry.Name()))
+	}
+}
+
+func (du *DirectoryUploader) uploadFile(path string) {
+	ctx := mctx.Annotate(du.ctx, "file", path)
+
+	_, uploader, err := du.bq.Table(ctx, *du.dataset, *du.table, du.schemaObj)
+	if err != nil {
+		mlog.Error("getting bigquery table/uploader", ctx, mctx.Annotate(ctx, "err", err.Error()))
+		return
+	}
+
+	if err := du.uploadFileRows(ctx, path, uploader.Put); err != nil {
+		mlog.Error("uploading file to bigquery, quarantining", mctx.Annotate(ctx, "err", err.Error()))
+		du.quarantine(ctx, path)
+		return
+	}
+
+	if err := os.Remove(path); err != nil {
+		mlog.Error("removing uploaded file", mctx.Annotate(ctx, "err", err.Error()))
+	}
+}
+
+func (du *DirectoryUploader) uploadFileRows(
+	ctx context.Context, path string, put func(context.Context, interface{}) error,
+) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	schemaType := reflect.TypeOf(du.schemaObj)
+	batch := make([]interface{}, 0, *du.maxBatchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := put(ctx, batch)
+		batch = batch[:0]
+		return err
+	}
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		row := reflect.New(schemaType).Interface()
+		if err := json.Unmarshal(sc.Bytes(), row); err != nil {
+			return err
+		}
+
+		batch = append(batch, row)
+		if len(batch) >= *du.maxBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+func (du *DirectoryUploader) quarantine(ctx context.Context, path string) {
+	dst := filepath.Join(du.errorsDir(), filepath.Base(path))
+	if err := os.Rename(path, dst); err != nil {
+		mlog.Error("quarantining bad upload file", mctx.Annotate(ctx, "err", err.Error(), "dst", dst))
+	}
+}
@@ -4,7 +4,9 @@ package mbigtable
 
 import (
 	"context"
+	"os"
 	"strings"
+	"time"
 
 	"cloud.google.com/go/bigtable"
 	"github.com/mediocregopher/mediocre-go-lib/mcfg"
@@ -13,8 +15,15 @@ import (
 	"github.com/mediocregopher/mediocre-go-lib/merr"
 	"github.com/mediocregopher/mediocre-go-lib/mlog"
 	"github.com/mediocregopher/mediocre-go-lib/mrun"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
 )
 
+// emulatorHostEnvVar is the env var recognized by the official Bigtable
+// tooling (e.g. `gcloud beta emulators bigtable start`) for pointing clients
+// at a local emulator instead of the real GCE API.
+const emulatorHostEnvVar = "BIGTABLE_EMULATOR_HOST"
+
 func isErrAlreadyExists(err error) bool {
 	if err == nil {
 		return false
@@ -27,10 +36,23 @@ type Bigtable struct {
 	*bigtable.Client
 	Instance string
 
+	// Metrics, if set, is called after every Get, Put, and ScanPrefix call
+	// with the table and operation name involved and how long the call took.
+	// See the mprom package's InstrumentBigtable for a convenient way to wire
+	// this up to Prometheus.
+	Metrics func(table, op string, dur time.Duration)
+
 	gce *mdb.GCE
 	ctx context.Context
 }
 
+// observe reports dur to Metrics, if set, for the given table and operation.
+func (bt *Bigtable) observe(table, op string, start time.Time) {
+	if bt.Metrics != nil {
+		bt.Metrics(table, op, time.Since(start))
+	}
+}
+
 // WithBigTable returns a Bigtable instance which will be initialized and
 // configured when the start event is triggered on the returned Context (see
 // mrun.Start). The Bigtable instance will have Close called on it when the
@@ -61,12 +83,39 @@ func WithBigTable(parent context.Context, gce *mdb.GCE, defaultInstance string)
 		}
 	}
 
+	ctx, emulatorAddr := mcfg.WithString(ctx, "emulator-addr", "",
+		"Address of a local bigtable emulator to connect to instead of GCE, e.g. "+
+			"as started by `gcloud beta emulators bigtable start`. Overridden by the "+
+			emulatorHostEnvVar+" env var, if set.")
+
 	ctx = mrun.WithStartHook(ctx, func(innerCtx context.Context) error {
 		bt.Instance = *inst
 
 		bt.ctx = mctx.MergeAnnotations(bt.ctx, bt.gce.Context())
 		bt.ctx = mctx.Annotate(bt.ctx, "instance", bt.Instance)
 
+		addr := *emulatorAddr
+		if envAddr := os.Getenv(emulatorHostEnvVar); envAddr != "" {
+			addr = envAddr
+		}
+
+		if addr != "" {
+			bt.ctx = mctx.Annotate(bt.ctx, "emulatorAddr", addr)
+			mlog.Info("connecting to bigtable emulator", bt.ctx)
+
+			conn, err := grpc.Dial(addr, grpc.WithInsecure())
+			if err != nil {
+				return merr.Wrap(err, bt.ctx)
+			}
+
+			bt.Client, err = bigtable.NewClient(
+				innerCtx,
+				bt.gce.Project, bt.Instance,
+				option.WithGRPCConn(conn),
+			)
+			return merr.Wrap(err, bt.ctx)
+		}
+
 		mlog.Info("connecting to bigtable", bt.ctx)
 		var err error
 		bt.Client, err = bigtable.NewClient(
@@ -122,3 +171,60 @@ func (bt *Bigtable) EnsureTable(ctx context.Context, name string, colFams ...str
 func (bt *Bigtable) Table(tableName string) *bigtable.Table {
 	return bt.Open(tableName)
 }
+
+// Get reads the value of a single cell, at the given column family and
+// column, out of the given row within the named table. It returns nil, nil
+// if no such cell exists.
+func (bt *Bigtable) Get(ctx context.Context, tableName, row, colFam, col string) ([]byte, error) {
+	defer bt.observe(tableName, "get", time.Now())
+
+	ctx = mctx.MergeAnnotations(ctx, bt.ctx)
+	ctx = mctx.Annotate(ctx, "table", tableName, "row", row, "family", colFam, "column", col)
+	mlog.Debug("getting bigtable cell", ctx)
+
+	r, err := bt.Table(tableName).ReadRow(ctx, row,
+		bigtable.RowFilter(bigtable.ChainFilters(
+			bigtable.FamilyFilter(colFam),
+			bigtable.ColumnFilter(col),
+		)),
+	)
+	if err != nil {
+		return nil, merr.Wrap(err, ctx)
+	}
+
+	for _, item := range r[colFam] {
+		return item.Value, nil
+	}
+	return nil, nil
+}
+
+// Put applies the given mutations, in order, to the given row within the
+// named table.
+func (bt *Bigtable) Put(ctx context.Context, tableName, row string, mutations ...*bigtable.Mutation) error {
+	defer bt.observe(tableName, "put", time.Now())
+
+	ctx = mctx.MergeAnnotations(ctx, bt.ctx)
+	ctx = mctx.Annotate(ctx, "table", tableName, "row", row)
+	mlog.Debug("putting bigtable row", ctx)
+
+	table := bt.Table(tableName)
+	for _, mut := range mutations {
+		if err := table.Apply(ctx, row, mut); err != nil {
+			return merr.Wrap(err, ctx)
+		}
+	}
+	return nil
+}
+
+// ScanPrefix calls fn once for every row in the named table whose key begins
+// with prefix, stopping early if fn returns false.
+func (bt *Bigtable) ScanPrefix(ctx context.Context, tableName, prefix string, fn func(bigtable.Row) bool) error {
+	defer bt.observe(tableName, "scan_prefix", time.Now())
+
+	ctx = mctx.MergeAnnotations(ctx, bt.ctx)
+	ctx = mctx.Annotate(ctx, "table", tableName, "prefix", prefix)
+	mlog.Debug("scanning bigtable rows by prefix", ctx)
+
+	err := bt.Table(tableName).ReadRows(ctx, bigtable.PrefixRange(prefix), fn)
+	return merr.Wrap(err, ctx)
+}
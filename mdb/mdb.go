@@ -18,6 +18,19 @@ type GCE struct {
 	cmp      *mcmp.Component
 	Project  string
 	CredFile string
+
+	// CredJSON, if set, is used in place of CredFile: it's the raw contents
+	// of a GCE credentials JSON document (e.g. a service-account key, or an
+	// external_account workload-identity-federation config whose
+	// credential_source is file-sourced, URL-sourced, or executable-sourced
+	// per the GCE workload identity spec) rather than a path to one.
+	// Takes precedence over CredFile if both are set.
+	CredJSON string
+
+	// Endpoint, if set, overrides the default API endpoint used by client
+	// drivers constructed with ClientOptions, e.g. to point at a local
+	// emulator or a non-Google-Cloud-hosted endpoint.
+	Endpoint string
 }
 
 type gceOpts struct {
@@ -48,13 +61,19 @@ func InstGCE(cmp *mcmp.Component, options ...GCEOption) *GCE {
 	gce := GCE{cmp: cmp.Child("gce")}
 	credFile := mcfg.String(gce.cmp, "cred-file",
 		mcfg.ParamUsage("Path to GCE credientials JSON file, if any"))
+	credJSON := mcfg.String(gce.cmp, "cred-json",
+		mcfg.ParamUsage("GCE credentials JSON document, if any (e.g. a service-account key, or a workload-identity-federation external_account config). Takes precedence over cred-file."))
 	project := mcfg.String(gce.cmp, "project",
 		mcfg.ParamDefaultOrRequired(opts.defaultProject),
 		mcfg.ParamUsage("Name of GCE project to use"))
+	endpoint := mcfg.String(gce.cmp, "endpoint",
+		mcfg.ParamUsage("Override the default API endpoint, e.g. to point at an emulator or a non-Google-Cloud endpoint"))
 
 	mrun.InitHook(gce.cmp, func(ctx context.Context) error {
 		gce.Project = *project
 		gce.CredFile = *credFile
+		gce.CredJSON = *credJSON
+		gce.Endpoint = *endpoint
 		gce.cmp.Annotate("project", gce.Project)
 		mlog.From(gce.cmp).Info("GCE config initialized", ctx)
 		return nil
@@ -67,9 +86,14 @@ func InstGCE(cmp *mcmp.Component, options ...GCEOption) *GCE {
 // passed into most GCE client drivers.
 func (gce *GCE) ClientOptions() []option.ClientOption {
 	var opts []option.ClientOption
-	if gce.CredFile != "" {
+	if gce.CredJSON != "" {
+		opts = append(opts, option.WithCredentialsJSON([]byte(gce.CredJSON)))
+	} else if gce.CredFile != "" {
 		opts = append(opts, option.WithCredentialsFile(gce.CredFile))
 	}
+	if gce.Endpoint != "" {
+		opts = append(opts, option.WithEndpoint(gce.Endpoint))
+	}
 	return opts
 }
 
@@ -77,3 +101,12 @@ func (gce *GCE) ClientOptions() []option.ClientOption {
 func (gce *GCE) Context() context.Context {
 	return gce.cmp.Context()
 }
+
+// KV implements the mlog.KVer interface.
+func (gce *GCE) KV() mlog.KV {
+	kv := mlog.KV{"project": gce.Project}
+	if gce.Endpoint != "" {
+		kv["endpoint"] = gce.Endpoint
+	}
+	return kv
+}
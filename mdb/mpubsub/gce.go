@@ -0,0 +1,95 @@
+package mpubsub
+
+import (
+	"context"
+	"errors"
+
+	"cloud.google.com/go/pubsub"
+)
+
+var (
+	errTopicDNE = errors.New("topic does not exist")
+	errSubDNE   = errors.New("subscription does not exist")
+)
+
+// gceBackend implements Backend atop Google Cloud PubSub; it's the Backend
+// InstPubSub uses by default.
+type gceBackend struct {
+	client *pubsub.Client
+}
+
+func (b *gceBackend) Topic(ctx context.Context, name string, create bool) (BackendTopic, error) {
+	var topic *pubsub.Topic
+	if create {
+		var err error
+		topic, err = b.client.CreateTopic(ctx, name)
+		if isErrAlreadyExists(err) {
+			topic = b.client.Topic(name)
+		} else if err != nil {
+			return nil, err
+		}
+	} else {
+		topic = b.client.Topic(name)
+		if exists, err := topic.Exists(ctx); err != nil {
+			return nil, err
+		} else if !exists {
+			return nil, errTopicDNE
+		}
+	}
+	return &gceTopic{client: b.client, topic: topic}, nil
+}
+
+func (b *gceBackend) Close() error {
+	return b.client.Close()
+}
+
+type gceTopic struct {
+	client *pubsub.Client
+	topic  *pubsub.Topic
+}
+
+func (t *gceTopic) Publish(ctx context.Context, data []byte) error {
+	_, err := t.topic.Publish(ctx, &pubsub.Message{Data: data}).Get(ctx)
+	return err
+}
+
+func (t *gceTopic) Subscription(ctx context.Context, name string, create bool) (BackendSubscription, error) {
+	var sub *pubsub.Subscription
+	if create {
+		var err error
+		sub, err = t.client.CreateSubscription(ctx, name, pubsub.SubscriptionConfig{
+			Topic: t.topic,
+		})
+		if isErrAlreadyExists(err) {
+			sub = t.client.Subscription(name)
+		} else if err != nil {
+			return nil, err
+		}
+	} else {
+		sub = t.client.Subscription(name)
+		if exists, err := sub.Exists(ctx); err != nil {
+			return nil, err
+		} else if !exists {
+			return nil, errSubDNE
+		}
+	}
+	return &gceSubscription{sub: sub}, nil
+}
+
+type gceSubscription struct {
+	sub *pubsub.Subscription
+}
+
+func (s *gceSubscription) Receive(ctx context.Context, opts ConsumerOpts, fn func(*Message)) error {
+	s.sub.ReceiveSettings.MaxExtension = opts.Timeout
+	s.sub.ReceiveSettings.MaxOutstandingMessages = opts.Concurrent
+	return s.sub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
+		fn(&Message{
+			Data:       msg.Data,
+			ID:         msg.ID,
+			Attributes: msg.Attributes,
+			Ack:        msg.Ack,
+			Nack:       msg.Nack,
+		})
+	})
+}
@@ -0,0 +1,172 @@
+// Package mkafka implements mpubsub.Backend atop Kafka, using Sarama's
+// consumer group API so that every Subscription maps onto a Kafka consumer
+// group, and processes sharing a Subscription name automatically split up a
+// topic's partitions between them (the same basic model goka layers on top
+// of Sarama).
+package mkafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Shopify/sarama"
+	"github.com/mediocregopher/mediocre-go-lib/mdb/mpubsub"
+)
+
+var errTopicDNE = errors.New("topic does not exist")
+
+// Backend implements mpubsub.Backend atop Kafka.
+type Backend struct {
+	client sarama.Client
+	admin  sarama.ClusterAdmin
+}
+
+// New connects to the given Kafka brokers and returns an mpubsub.Backend
+// implementation backed by them. cfg may be nil, in which case a
+// sarama.NewConfig() with sensible defaults is used.
+func New(brokers []string, cfg *sarama.Config) (mpubsub.Backend, error) {
+	if cfg == nil {
+		cfg = sarama.NewConfig()
+		cfg.Version = sarama.V2_1_0_0
+	}
+	cfg.Producer.Return.Successes = true
+
+	client, err := sarama.NewClient(brokers, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return &Backend{client: client, admin: admin}, nil
+}
+
+// Topic implements the mpubsub.Backend interface.
+func (b *Backend) Topic(ctx context.Context, name string, create bool) (mpubsub.BackendTopic, error) {
+	if create {
+		err := b.admin.CreateTopic(name, &sarama.TopicDetail{
+			NumPartitions:     1,
+			ReplicationFactor: 1,
+		}, false)
+		if err != nil && !errors.Is(err, sarama.ErrTopicAlreadyExists) {
+			return nil, err
+		}
+	} else {
+		topics, err := b.admin.ListTopics()
+		if err != nil {
+			return nil, err
+		} else if _, ok := topics[name]; !ok {
+			return nil, errTopicDNE
+		}
+	}
+
+	producer, err := sarama.NewSyncProducerFromClient(b.client)
+	if err != nil {
+		return nil, err
+	}
+	return &topic{backend: b, name: name, producer: producer}, nil
+}
+
+// Close implements the mpubsub.Backend interface.
+func (b *Backend) Close() error {
+	b.admin.Close()
+	return b.client.Close()
+}
+
+type topic struct {
+	backend  *Backend
+	name     string
+	producer sarama.SyncProducer
+}
+
+func (t *topic) Publish(ctx context.Context, data []byte) error {
+	_, _, err := t.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: t.name,
+		Value: sarama.ByteEncoder(data),
+	})
+	return err
+}
+
+// Subscription implements the mpubsub.BackendTopic interface. name is used
+// directly as the Kafka consumer group ID, so every process calling
+// Subscription with the same name against the same topic forms a single
+// consumer group and shares the topic's partitions.
+func (t *topic) Subscription(ctx context.Context, name string, create bool) (mpubsub.BackendSubscription, error) {
+	group, err := sarama.NewConsumerGroupFromClient(name, t.backend.client)
+	if err != nil {
+		return nil, err
+	}
+	return &subscription{group: group, topic: t.name, rebalanceCh: make(chan bool, 1)}, nil
+}
+
+type subscription struct {
+	group       sarama.ConsumerGroup
+	topic       string
+	rebalanceCh chan bool
+}
+
+// Rebalancing implements the mpubsub.Rebalancer interface, using Sarama's
+// Setup/Cleanup hooks (called just after, and just before, a consumer group
+// rebalance respectively) to report when the group is mid-rebalance.
+func (s *subscription) Rebalancing() <-chan bool {
+	return s.rebalanceCh
+}
+
+// consumerGroupHandler adapts the fn callback passed to Receive into a
+// sarama.ConsumerGroupHandler.
+type consumerGroupHandler struct {
+	fn          func(*mpubsub.Message)
+	rebalanceCh chan bool
+}
+
+func (h consumerGroupHandler) Setup(sarama.ConsumerGroupSession) error {
+	select {
+	case h.rebalanceCh <- false:
+	default:
+	}
+	return nil
+}
+
+func (h consumerGroupHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	select {
+	case h.rebalanceCh <- true:
+	default:
+	}
+	return nil
+}
+
+func (h consumerGroupHandler) ConsumeClaim(
+	sess sarama.ConsumerGroupSession,
+	claim sarama.ConsumerGroupClaim,
+) error {
+	for msg := range claim.Messages() {
+		msg := msg
+		h.fn(&mpubsub.Message{
+			Data: msg.Value,
+			ID:   fmt.Sprintf("%d-%d-%d", msg.Partition, msg.Offset, msg.Timestamp.UnixNano()),
+			Ack:  func() { sess.MarkMessage(msg, "") },
+			// Kafka's consumer group offsets only move forward on Ack
+			// (MarkMessage); there's no way to push a single message back
+			// onto the partition, so Nack is a no-op and an un-Ack'd
+			// message is simply redelivered on the next rebalance/restart.
+			Nack: func() {},
+		})
+	}
+	return nil
+}
+
+// Receive implements the mpubsub.BackendSubscription interface.
+func (s *subscription) Receive(ctx context.Context, opts mpubsub.ConsumerOpts, fn func(*mpubsub.Message)) error {
+	h := consumerGroupHandler{fn: fn, rebalanceCh: s.rebalanceCh}
+	for ctx.Err() == nil {
+		if err := s.group.Consume(ctx, []string{s.topic}, h); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,142 @@
+// Package mmem implements mpubsub.Backend entirely in-memory, with no
+// external broker required. It's meant for unit tests which want to
+// exercise Topic/Subscription/Consume/BatchConsume without depending on a
+// running emulator or broker, not for production use.
+package mmem
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/mediocregopher/mediocre-go-lib/mdb/mpubsub"
+)
+
+// Backend implements mpubsub.Backend in-memory.
+type Backend struct {
+	l      sync.Mutex
+	topics map[string]*topic
+}
+
+// New returns an mpubsub.Backend implementation which keeps all topics and
+// subscriptions in memory, for the lifetime of the process.
+func New() mpubsub.Backend {
+	return &Backend{topics: map[string]*topic{}}
+}
+
+// Topic implements the mpubsub.Backend interface.
+func (b *Backend) Topic(ctx context.Context, name string, create bool) (mpubsub.BackendTopic, error) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	t, ok := b.topics[name]
+	if !ok {
+		if !create {
+			return nil, fmt.Errorf("topic %q does not exist", name)
+		}
+		t = &topic{subs: map[string]*subscription{}}
+		b.topics[name] = t
+	}
+	return t, nil
+}
+
+// Close implements the mpubsub.Backend interface. It's a no-op.
+func (b *Backend) Close() error {
+	return nil
+}
+
+type topic struct {
+	l    sync.Mutex
+	subs map[string]*subscription
+}
+
+// Publish implements the mpubsub.BackendTopic interface by copying the
+// message onto every Subscription which has been created under this topic,
+// regardless of whether anything is currently calling Receive on them.
+func (t *topic) Publish(ctx context.Context, data []byte) error {
+	t.l.Lock()
+	defer t.l.Unlock()
+	for _, s := range t.subs {
+		s.push(&mpubsub.Message{
+			Data: data,
+			Ack:  func() {},
+			Nack: func() {},
+		})
+	}
+	return nil
+}
+
+// Subscription implements the mpubsub.BackendTopic interface.
+func (t *topic) Subscription(ctx context.Context, name string, create bool) (mpubsub.BackendSubscription, error) {
+	t.l.Lock()
+	defer t.l.Unlock()
+
+	s, ok := t.subs[name]
+	if !ok {
+		if !create {
+			return nil, fmt.Errorf("subscription %q does not exist", name)
+		}
+		s = newSubscription()
+		t.subs[name] = s
+	}
+	return s, nil
+}
+
+// subscription is an unbounded, in-memory queue of messages published to the
+// topic since the subscription was created.
+type subscription struct {
+	l       sync.Mutex
+	cond    sync.Cond
+	queue   []*mpubsub.Message
+	stopped bool
+}
+
+func newSubscription() *subscription {
+	s := &subscription{}
+	s.cond.L = &s.l
+	return s
+}
+
+func (s *subscription) push(msg *mpubsub.Message) {
+	s.l.Lock()
+	s.queue = append(s.queue, msg)
+	s.l.Unlock()
+	s.cond.Signal()
+}
+
+// Receive implements the mpubsub.BackendSubscription interface, blocking
+// until ctx is canceled and delivering messages in the order they were
+// published.
+func (s *subscription) Receive(ctx context.Context, opts mpubsub.ConsumerOpts, fn func(*mpubsub.Message)) error {
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.l.Lock()
+			s.stopped = true
+			s.l.Unlock()
+			s.cond.Broadcast()
+		case <-stop:
+		}
+	}()
+
+	s.l.Lock()
+	defer s.l.Unlock()
+	for {
+		for len(s.queue) == 0 && !s.stopped {
+			s.cond.Wait()
+		}
+		if s.stopped {
+			s.stopped = false
+			return nil
+		}
+
+		msg := s.queue[0]
+		s.queue = s.queue[1:]
+
+		s.l.Unlock()
+		fn(msg)
+		s.l.Lock()
+	}
+}
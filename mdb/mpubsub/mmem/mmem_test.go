@@ -0,0 +1,41 @@
+package mmem
+
+import (
+	"context"
+	. "testing"
+
+	"github.com/mediocregopher/mediocre-go-lib/mdb/mpubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackend(t *T) {
+	ctx := context.Background()
+	b := New()
+
+	_, err := b.Topic(ctx, "foo", false)
+	assert.Error(t, err)
+
+	topic, err := b.Topic(ctx, "foo", true)
+	require.NoError(t, err)
+
+	sub, err := topic.Subscription(ctx, "bar", true)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(ctx)
+	msgCh := make(chan *mpubsub.Message)
+	go func() {
+		_ = sub.Receive(ctx, mpubsub.ConsumerOpts{}, func(m *mpubsub.Message) {
+			msgCh <- m
+		})
+		close(msgCh)
+	}()
+
+	require.NoError(t, topic.Publish(ctx, []byte("hello")))
+	msg := <-msgCh
+	assert.Equal(t, []byte("hello"), msg.Data)
+
+	cancel()
+	_, ok := <-msgCh
+	assert.False(t, ok)
+}
@@ -0,0 +1,94 @@
+// Package mnats implements mpubsub.Backend atop NATS, mapping Topics onto
+// NATS subjects and Subscriptions onto NATS queue groups, so that processes
+// sharing a Subscription name split up delivery of a subject's messages
+// between them.
+package mnats
+
+import (
+	"context"
+
+	"github.com/mediocregopher/mediocre-go-lib/mdb/mpubsub"
+	"github.com/nats-io/nats.go"
+)
+
+// Backend implements mpubsub.Backend atop NATS.
+type Backend struct {
+	nc *nats.Conn
+}
+
+// New connects to the given NATS server(s) (see nats.Connect for the url
+// format) and returns an mpubsub.Backend implementation backed by the
+// connection.
+func New(urls string, opts ...nats.Option) (mpubsub.Backend, error) {
+	nc, err := nats.Connect(urls, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Backend{nc: nc}, nil
+}
+
+// Topic implements the mpubsub.Backend interface. NATS has no notion of a
+// pre-declared subject, so create and the non-create existence check are
+// both no-ops: publishing to, or subscribing to, a subject is all that's
+// ever required of it.
+func (b *Backend) Topic(ctx context.Context, name string, create bool) (mpubsub.BackendTopic, error) {
+	return &topic{nc: b.nc, subject: name}, nil
+}
+
+// Close implements the mpubsub.Backend interface.
+func (b *Backend) Close() error {
+	b.nc.Close()
+	return nil
+}
+
+type topic struct {
+	nc      *nats.Conn
+	subject string
+}
+
+func (t *topic) Publish(ctx context.Context, data []byte) error {
+	return t.nc.Publish(t.subject, data)
+}
+
+// Subscription implements the mpubsub.BackendTopic interface. name is used
+// as the NATS queue group name.
+func (t *topic) Subscription(ctx context.Context, name string, create bool) (mpubsub.BackendSubscription, error) {
+	return &subscription{nc: t.nc, subject: t.subject, queue: name}, nil
+}
+
+type subscription struct {
+	nc      *nats.Conn
+	subject string
+	queue   string
+}
+
+// Receive implements the mpubsub.BackendSubscription interface by joining
+// the Topic's subject as part of a queue group named after the
+// Subscription. Core NATS has no concept of message acknowledgement, so Ack
+// and Nack on the Messages passed to fn are both no-ops.
+func (s *subscription) Receive(ctx context.Context, opts mpubsub.ConsumerOpts, fn func(*mpubsub.Message)) error {
+	bufSize := opts.Concurrent
+	if bufSize < 1 {
+		bufSize = 1
+	}
+
+	msgCh := make(chan *nats.Msg, bufSize)
+	sub, err := s.nc.ChanQueueSubscribe(s.subject, s.queue, msgCh)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case msg := <-msgCh:
+			fn(&mpubsub.Message{
+				Data: msg.Data,
+				Ack:  func() {},
+				Nack: func() {},
+			})
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
@@ -1,13 +1,20 @@
-// Package mpubsub implements connecting to Google's PubSub service and
-// simplifying a number of interactions with it.
+// Package mpubsub provides a backend-agnostic publish/subscribe abstraction,
+// along with batching and consumer-group-style helpers on top of it. The
+// default Backend talks to Google Cloud PubSub; see mpubsub/mkafka and
+// mpubsub/mnats for drivers onto other brokers, and mpubsub/mmem for an
+// in-memory Backend suitable for hermetic unit tests.
 package mpubsub
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
 	"sync"
 	"time"
 
 	"cloud.google.com/go/pubsub"
+	"github.com/mediocregopher/mediocre-go-lib/mcfg"
 	"github.com/mediocregopher/mediocre-go-lib/mcmp"
 	"github.com/mediocregopher/mediocre-go-lib/mctx"
 	"github.com/mediocregopher/mediocre-go-lib/mdb"
@@ -18,7 +25,7 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-// TODO Consume (and probably BatchConsume) don't properly handle the Client
+// TODO Consume (and probably BatchConsume) don't properly handle the Backend
 // being closed.
 
 func isErrAlreadyExists(err error) bool {
@@ -29,74 +36,186 @@ func isErrAlreadyExists(err error) bool {
 	return ok && s.Code() == codes.AlreadyExists
 }
 
-// Message aliases the type in the official driver
-type Message = pubsub.Message
+// isErrTransient returns true if err looks like the kind of error Consume
+// should back off and retry on, as opposed to one it should give up on
+// entirely. Errors which don't carry a gRPC status at all (e.g. a raw
+// network error bubbled up from mkafka or mnats) are assumed to be
+// transient, since there's no better way to classify them here.
+func isErrTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	s, ok := status.FromError(err)
+	if !ok {
+		return true
+	}
+	switch s.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted, codes.Internal:
+		return true
+	default:
+		return false
+	}
+}
 
-// PubSub is a wrapper around a pubsub client providing more functionality.
-type PubSub struct {
-	*pubsub.Client
+// Message is a single message published to a Topic and delivered to a
+// Subscription.
+type Message struct {
+	Data []byte
+
+	// ID and Attributes are populated by Backends which have a notion of
+	// them (currently only the GCE one); a Backend which doesn't will leave
+	// these as their zero values.
+	ID         string
+	Attributes map[string]string
+
+	// DeliveryAttempt is the number of times the broker itself has recorded
+	// delivering this Message, for Backends with their own dead-lettering
+	// bookkeeping. It's left 0 by every Backend currently in this repo (none
+	// of their underlying client versions expose it yet); RetryPolicy falls
+	// back to counting Nacks itself, keyed by ID, when this is 0.
+	DeliveryAttempt int
+
+	// Ack marks the Message as having been successfully processed, so it
+	// won't be redelivered. Nack does the opposite, making the Message
+	// available for redelivery.
+	Ack  func()
+	Nack func()
+}
+
+// BackendSubscription is a Backend's view of a subscription to a topic.
+// Receive is the only thing a Backend needs to implement to plug into
+// Consume/BatchConsume: it should block, calling fn once per Message
+// received, until ctx is canceled or an unrecoverable error occurs. opts is
+// passed through from the Consume/BatchConsume call, so a Backend can use
+// e.g. opts.Concurrent to size its own internal prefetch/worker count.
+type BackendSubscription interface {
+	Receive(ctx context.Context, opts ConsumerOpts, fn func(*Message)) error
+}
+
+// Rebalancer may optionally be implemented by a BackendSubscription whose
+// broker has its own notion of consumer-group rebalancing (Kafka's, via
+// mpubsub/mkafka, in particular). If the BackendSubscription returned by a
+// Backend's Subscription method implements this, Consume and BatchConsume
+// read from the returned channel for the lifetime of the call: a value of
+// true moves the Subscription into StateRebalancing, and false moves it back
+// to StateRunning.
+type Rebalancer interface {
+	Rebalancing() <-chan bool
+}
+
+// BackendTopic is a Backend's view of a topic which can be published to and
+// subscribed from.
+type BackendTopic interface {
+	Publish(ctx context.Context, data []byte) error
+	Subscription(ctx context.Context, name string, create bool) (BackendSubscription, error)
+}
 
-	gce *mdb.GCE
+// Backend is implemented by each supported pub/sub broker. See
+// mpubsub/mkafka, mpubsub/mnats, and mpubsub/mmem for implementations
+// besides the GCE-backed one InstPubSub uses by default.
+type Backend interface {
+	Topic(ctx context.Context, name string, create bool) (BackendTopic, error)
+	Close() error
+}
+
+// PubSub wraps a Backend, providing Topic/Subscription types which add
+// batching and consumer-group-style helpers on top of whatever the Backend
+// itself provides.
+type PubSub struct {
+	Backend
 	cmp *mcmp.Component
 }
 
 type pubsubOpts struct {
-	gce *mdb.GCE
+	gce     *mdb.GCE
+	backend Backend
 }
 
 // PubSubOpt is a value which adjusts the behavior of InstPubSub.
 type PubSubOpt func(*pubsubOpts)
 
-// PubSubGCE indicates that InstPubSub should use the given GCE instance rather
-// than instantiate its own.
+// PubSubGCE indicates that InstPubSub should use the given GCE instance,
+// rather than instantiate its own, when the "gce" backend is selected (the
+// default).
 func PubSubGCE(gce *mdb.GCE) PubSubOpt {
 	return func(opts *pubsubOpts) {
 		opts.gce = gce
 	}
 }
 
+// PubSubBackend indicates that InstPubSub should use the given Backend
+// directly, rather than selecting (and instantiating) one via the "backend"
+// config param. This is how non-default Backends, e.g. those from
+// mpubsub/mkafka, mpubsub/mnats, or mpubsub/mmem, get plugged in.
+func PubSubBackend(b Backend) PubSubOpt {
+	return func(opts *pubsubOpts) {
+		opts.backend = b
+	}
+}
+
 // InstPubSub instantiates a PubSub which will be initialized when the Init
 // event is triggered on the given Component. The PubSub instance will have
 // Close called on it when the Shutdown event is triggered on the given
 // Component.
+//
+// Unless PubSubBackend is given, the Backend used is chosen via the
+// "backend" config param, defaulting to "gce" (Google Cloud PubSub).
 func InstPubSub(cmp *mcmp.Component, options ...PubSubOpt) *PubSub {
 	var opts pubsubOpts
 	for _, opt := range options {
 		opt(&opts)
 	}
 
-	ps := PubSub{
-		gce: opts.gce,
-		cmp: cmp.Child("pubsub"),
-	}
-	if ps.gce == nil {
-		ps.gce = mdb.InstGCE(ps.cmp)
+	ps := PubSub{cmp: cmp.Child("pubsub")}
+
+	var backend *string
+	if opts.backend == nil {
+		backend = mcfg.String(ps.cmp, "backend",
+			mcfg.ParamDefault("gce"),
+			mcfg.ParamUsage("Which pub/sub backend to use. Only 'gce' is accepted; other backends are given in code via PubSubBackend"))
 	}
 
 	mrun.InitHook(ps.cmp, func(ctx context.Context) error {
-		mlog.From(ps.cmp).Info("connecting to pubsub", ctx)
-		var err error
-		ps.Client, err = pubsub.NewClient(ctx, ps.gce.Project, ps.gce.ClientOptions()...)
-		return merr.Wrap(err, ps.cmp.Context(), ctx)
+		if opts.backend != nil {
+			ps.Backend = opts.backend
+			return nil
+		}
+
+		switch *backend {
+		case "gce":
+			gce := opts.gce
+			if gce == nil {
+				gce = mdb.InstGCE(ps.cmp)
+			}
+			mlog.From(ps.cmp).Info("connecting to pubsub", ctx)
+			client, err := pubsub.NewClient(ctx, gce.Project, gce.ClientOptions()...)
+			if err != nil {
+				return merr.Wrap(ctx, err)
+			}
+			ps.Backend = &gceBackend{client: client}
+			return nil
+		default:
+			return merr.New(ctx, "unknown pubsub backend "+*backend)
+		}
 	})
 
 	mrun.ShutdownHook(ps.cmp, func(ctx context.Context) error {
 		mlog.From(ps.cmp).Info("closing pubsub", ctx)
-		return ps.Client.Close()
+		return ps.Backend.Close()
 	})
 	return &ps
 }
 
-// Topic provides methods around a particular topic in PubSub
+// Topic provides methods around a particular topic in PubSub.
 type Topic struct {
 	*PubSub
 	Name string
 
-	ctx   context.Context
-	topic *pubsub.Topic
+	ctx     context.Context
+	backend BackendTopic
 }
 
-// Topic returns, after potentially creating, a topic of the given name
+// Topic returns, after potentially creating, a topic of the given name.
 func (ps *PubSub) Topic(ctx context.Context, name string, create bool) (*Topic, error) {
 	t := &Topic{
 		PubSub: ps,
@@ -104,48 +223,106 @@ func (ps *PubSub) Topic(ctx context.Context, name string, create bool) (*Topic,
 		Name:   name,
 	}
 
-	var err error
-	if create {
-		t.topic, err = ps.Client.CreateTopic(ctx, name)
-		if isErrAlreadyExists(err) {
-			t.topic = ps.Client.Topic(name)
-		} else if err != nil {
-			return nil, merr.Wrap(err, t.ctx, ctx)
-		}
-	} else {
-		t.topic = ps.Client.Topic(name)
-		if exists, err := t.topic.Exists(t.ctx); err != nil {
-			return nil, merr.Wrap(err, t.ctx, ctx)
-		} else if !exists {
-			return nil, merr.New("topic dne", t.ctx, ctx)
-		}
+	backend, err := ps.Backend.Topic(ctx, name, create)
+	if err != nil {
+		return nil, merr.Wrap(t.ctx, err)
 	}
+	t.backend = backend
 	return t, nil
 }
 
-// Publish publishes a message with the given data as its body to the Topic
+// Publish publishes a message with the given data as its body to the Topic.
 func (t *Topic) Publish(ctx context.Context, data []byte) error {
-	_, err := t.topic.Publish(ctx, &Message{Data: data}).Get(ctx)
-	if err != nil {
-		return merr.Wrap(err, t.ctx, ctx)
+	if err := t.backend.Publish(ctx, data); err != nil {
+		return merr.Wrap(t.ctx, err)
 	}
 	return nil
 }
 
-// Subscription provides methods around a subscription to a topic in PubSub
+// State describes the current lifecycle state of a Subscription's Consume
+// (or BatchConsume) loop, as returned by Subscription.State and emitted to
+// any channels registered via Subscription.NotifyState.
+type State int
+
+const (
+	// StateStopped is a Subscription's State before Consume/BatchConsume
+	// have been called on it, and again once ctx has been canceled.
+	StateStopped State = iota
+	// StateConnecting means Consume is asking the Backend to start
+	// receiving messages.
+	StateConnecting
+	// StateRunning means messages are actively being received and passed to
+	// the ConsumerFunc/BatchConsumerFunc.
+	StateRunning
+	// StateRebalancing means the Backend has reported (via the Rebalancer
+	// interface) that it's in the middle of reassigning partitions/shards
+	// between processes sharing this Subscription.
+	StateRebalancing
+	// StateRecovering means the last attempt to receive messages ended in a
+	// transient error, and Consume is backing off before retrying.
+	StateRecovering
+)
+
+// String implements the fmt.Stringer interface.
+func (s State) String() string {
+	switch s {
+	case StateStopped:
+		return "stopped"
+	case StateConnecting:
+		return "connecting"
+	case StateRunning:
+		return "running"
+	case StateRebalancing:
+		return "rebalancing"
+	case StateRecovering:
+		return "recovering"
+	default:
+		return "unknown"
+	}
+}
+
+// Subscription provides methods around a subscription to a topic in
+// PubSub.
 type Subscription struct {
 	*Topic
 	Name string
 
-	ctx context.Context
-	sub *pubsub.Subscription
+	ctx     context.Context
+	backend BackendSubscription
 
 	// only used in tests to trigger batch processing
 	batchTestTrigger chan bool
+
+	stateL    sync.Mutex
+	state     State
+	observers []chan State
+
+	deliveriesL sync.Mutex
+	deliveries  map[string]int
+}
+
+// deliveryAttempt returns msg's delivery attempt number (starting at 1),
+// preferring the Backend-reported Message.DeliveryAttempt and falling back
+// to a count of Nacks this Subscription has seen for msg.ID, keyed in
+// memory, when the Backend doesn't populate DeliveryAttempt (or msg has no
+// ID, in which case every delivery is treated as the first).
+func (s *Subscription) deliveryAttempt(msg *Message) int {
+	if msg.DeliveryAttempt > 0 {
+		return msg.DeliveryAttempt
+	} else if msg.ID == "" {
+		return 1
+	}
+	s.deliveriesL.Lock()
+	defer s.deliveriesL.Unlock()
+	if s.deliveries == nil {
+		s.deliveries = map[string]int{}
+	}
+	s.deliveries[msg.ID]++
+	return s.deliveries[msg.ID]
 }
 
-// Subscription returns a Subscription instance, after potentially creating it,
-// for the Topic
+// Subscription returns a Subscription instance, after potentially creating
+// it, for the Topic.
 func (t *Topic) Subscription(ctx context.Context, name string, create bool) (*Subscription, error) {
 	name = t.Name + "_" + name
 	s := &Subscription{
@@ -154,27 +331,44 @@ func (t *Topic) Subscription(ctx context.Context, name string, create bool) (*Su
 		ctx:   mctx.Annotate(t.ctx, "subName", name),
 	}
 
-	var err error
-	if create {
-		s.sub, err = s.CreateSubscription(ctx, name, pubsub.SubscriptionConfig{
-			Topic: t.topic,
-		})
-		if isErrAlreadyExists(err) {
-			s.sub = s.PubSub.Subscription(s.Name)
-		} else if err != nil {
-			return nil, merr.Wrap(err, s.ctx, ctx)
-		}
-	} else {
-		s.sub = s.PubSub.Subscription(s.Name)
-		if exists, err := s.sub.Exists(ctx); err != nil {
-			return nil, merr.Wrap(err, s.ctx, ctx)
-		} else if !exists {
-			return nil, merr.New("sub dne", s.ctx, ctx)
-		}
+	backend, err := t.backend.Subscription(ctx, name, create)
+	if err != nil {
+		return nil, merr.Wrap(s.ctx, err)
 	}
+	s.backend = backend
 	return s, nil
 }
 
+// State returns the Subscription's current lifecycle state, as last set by
+// its Consume or BatchConsume call.
+func (s *Subscription) State() State {
+	s.stateL.Lock()
+	defer s.stateL.Unlock()
+	return s.state
+}
+
+// NotifyState registers ch to receive every state transition made by this
+// Subscription's Consume or BatchConsume call, for as long as ch continues
+// to be read from. Sends to ch are non-blocking, so a slow or abandoned
+// reader misses intermediate transitions rather than stalling Consume.
+func (s *Subscription) NotifyState(ch chan State) {
+	s.stateL.Lock()
+	defer s.stateL.Unlock()
+	s.observers = append(s.observers, ch)
+}
+
+func (s *Subscription) setState(state State) {
+	s.stateL.Lock()
+	defer s.stateL.Unlock()
+	s.state = state
+	for _, ch := range s.observers {
+		select {
+		case ch <- state:
+		default:
+		}
+	}
+}
+
 // ConsumerFunc is a function which messages being consumed will be passed. The
 // returned boolean and returned error are independent. If the bool is false the
 // message will be returned to the queue for retrying later. If an error is
@@ -184,6 +378,85 @@ func (t *Topic) Subscription(ctx context.Context, name string, create bool) (*Su
 // Consume is called).
 type ConsumerFunc func(context.Context, *Message) (bool, error)
 
+// ConsumerBackoff configures the exponential backoff Consume and
+// BatchConsume use between reconnect attempts following a transient error
+// from the Backend.
+type ConsumerBackoff struct {
+	// Default 1s. The delay before the first retry.
+	Base time.Duration
+
+	// Default 30s. The computed delay is capped at this, no matter how many
+	// consecutive transient errors are seen; Jitter is applied after the cap,
+	// so the actual delay can exceed Max by up to Jitter times Max.
+	Max time.Duration
+
+	// Default 0.2. Fraction of the computed delay to randomize by (plus or
+	// minus), so that multiple processes reconnecting at once don't stay in
+	// lockstep.
+	Jitter float64
+}
+
+func (b ConsumerBackoff) withDefaults() ConsumerBackoff {
+	if b.Base == 0 {
+		b.Base = 1 * time.Second
+	}
+	if b.Max == 0 {
+		b.Max = 30 * time.Second
+	}
+	if b.Jitter == 0 {
+		b.Jitter = 0.2
+	}
+	return b
+}
+
+// delay returns how long to wait before the attempt'th retry (0-indexed).
+func (b ConsumerBackoff) delay(attempt int) time.Duration {
+	d := b.Base
+	for i := 0; i < attempt && d < b.Max; i++ {
+		d *= 2
+	}
+	if d > b.Max {
+		d = b.Max
+	}
+	jitter := time.Duration(float64(d) * b.Jitter * (rand.Float64()*2 - 1))
+	if d += jitter; d < 0 {
+		d = 0
+	}
+	return d
+}
+
+// DeadLetterMessage is the JSON structure published to a RetryPolicy's
+// DeadLetterTopic for a message which exceeded MaxDeliveries: its original
+// Data and Attributes, plus how many times delivery of it was attempted.
+type DeadLetterMessage struct {
+	Data            []byte            `json:"data"`
+	Attributes      map[string]string `json:"attributes,omitempty"`
+	DeliveryAttempt int               `json:"deliveryAttempt"`
+}
+
+// RetryPolicy configures what happens to a message which a ConsumerFunc or
+// BatchConsumerFunc keeps returning false (i.e. Nack) for, instead of
+// letting it be redelivered forever.
+type RetryPolicy struct {
+	// Default 0 (disabled). The maximum number of times a message may be
+	// delivered before Consume/BatchConsume give up on it, per
+	// Subscription.deliveryAttempt.
+	MaxDeliveries int
+
+	// If set, a message which has exceeded MaxDeliveries is marshaled into a
+	// DeadLetterMessage and published here instead of being Nacked, with the
+	// original then being Acked so it isn't redelivered again. The topic is
+	// created if it doesn't already exist (the same as Topic does for any
+	// other topic).
+	DeadLetterTopic string
+
+	// OnGiveUp, if set, is called instead of Nack for a message which has
+	// exceeded MaxDeliveries and either has no DeadLetterTopic configured or
+	// failed to publish to it. If it returns nil the message is Acked,
+	// otherwise it's Nacked as it would have been without a RetryPolicy.
+	OnGiveUp func(context.Context, *Message, error) error
+}
+
 // ConsumerOpts are options which effect the behavior of a Consume method call
 type ConsumerOpts struct {
 	// Default 30s. The timeout each message has to complete before its context
@@ -193,11 +466,27 @@ type ConsumerOpts struct {
 	// Default 1. Number of concurrent messages to consume at a time
 	Concurrent int
 
-	// TODO DisableBatchAutoTrigger
-	// Currently there is no auto-trigger behavior, batches only get processed
-	// on a dumb ticker. This is necessary for the way I plan to have the
-	// datastore writing, but it's not the expected behavior of a batch getting
-	// triggered everytime <Concurrent> messages come in.
+	// Backoff configures the delay between reconnect attempts made after a
+	// transient error from the Backend.
+	Backoff ConsumerBackoff
+
+	// Default equal to Concurrent. The number of messages BatchConsume
+	// collects into a group before flushing it early, without waiting for
+	// the Timeout/2 ticker (unless DisableBatchAutoTrigger is set). This is
+	// independent of Concurrent, so that a Backend can be told to prefetch
+	// more messages at once than fit in a single batch.
+	MaxBatchSize int
+
+	// Default false. If true, BatchConsume never flushes a group early based
+	// on MaxBatchSize; groups are only flushed by the Timeout/2 ticker, which
+	// was the only way batches got flushed before this option existed.
+	DisableBatchAutoTrigger bool
+
+	// Retry configures what happens to a message which keeps being Nacked
+	// instead of Acked, to guard against poison-message loops. The zero
+	// value leaves retries uncapped, which was the only behavior available
+	// before this option existed.
+	Retry RetryPolicy
 }
 
 func (co ConsumerOpts) withDefaults() ConsumerOpts {
@@ -207,41 +496,143 @@ func (co ConsumerOpts) withDefaults() ConsumerOpts {
 	if co.Concurrent == 0 {
 		co.Concurrent = 1
 	}
+	if co.MaxBatchSize == 0 {
+		co.MaxBatchSize = co.Concurrent
+	}
+	co.Backoff = co.Backoff.withDefaults()
 	return co
 }
 
 // Consume uses the given ConsumerFunc and ConsumerOpts to process messages off
-// the Subscription
+// the Subscription.
+//
+// Consume blocks until ctx is canceled or the Backend returns an error which
+// isErrTransient deems fatal. While running it loops reconnecting to the
+// Backend on transient errors, backing off between attempts as configured by
+// ConsumerOpts.Backoff, and reports its progress through State/NotifyState so
+// callers can wire up health checks or metrics.
 func (s *Subscription) Consume(ctx context.Context, fn ConsumerFunc, opts ConsumerOpts) {
 	opts = opts.withDefaults()
-	s.sub.ReceiveSettings.MaxExtension = opts.Timeout
-	s.sub.ReceiveSettings.MaxOutstandingMessages = opts.Concurrent
+	defer s.setState(StateStopped)
+
+	if rebalancer, ok := s.backend.(Rebalancer); ok {
+		rebalanceCh := rebalancer.Rebalancing()
+		go func() {
+			for {
+				select {
+				case rebalancing, ok := <-rebalanceCh:
+					if !ok {
+						return
+					} else if rebalancing {
+						s.setState(StateRebalancing)
+					} else {
+						s.setState(StateRunning)
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
 
+	attempt := 0
 	for {
-		err := s.sub.Receive(ctx, func(ctx context.Context, msg *Message) {
+		s.setState(StateConnecting)
+		started := time.Now()
+		s.setState(StateRunning)
+
+		err := s.backend.Receive(ctx, opts, func(msg *Message) {
 			innerCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
 			defer cancel()
 
-			ok, err := fn(innerCtx, msg)
-			if err != nil {
+			ok, fnErr := fn(innerCtx, msg)
+			if fnErr != nil {
 				mlog.From(s.cmp).Warn("error consuming pubsub message",
-					s.ctx, ctx, innerCtx, merr.Context(err))
+					s.ctx, ctx, innerCtx, merr.Context(fnErr))
 			}
 
 			if ok {
 				msg.Ack()
-			} else {
+				return
+			}
+
+			if opts.Retry.MaxDeliveries <= 0 {
+				msg.Nack()
+				return
+			}
+
+			if attempt := s.deliveryAttempt(msg); attempt <= opts.Retry.MaxDeliveries {
+				msg.Nack()
+			} else if giveUpErr := s.giveUp(innerCtx, msg, opts.Retry, attempt, fnErr); giveUpErr != nil {
+				mlog.From(s.cmp).Warn("giving up on pubsub message failed, nacking instead",
+					s.ctx, ctx, innerCtx, merr.Context(giveUpErr))
 				msg.Nack()
+			} else {
+				msg.Ack()
 			}
 		})
+
 		if ctx.Err() == context.Canceled || err == nil {
 			return
-		} else if err != nil {
-			mlog.From(s.cmp).Warn("error consuming from pubsub",
+		} else if !isErrTransient(err) {
+			mlog.From(s.cmp).Error("fatal error consuming from pubsub, giving up",
 				s.ctx, ctx, merr.Context(err))
-			time.Sleep(1 * time.Second)
+			return
+		}
+
+		// a connection which stayed up longer than the max backoff delay is
+		// taken as evidence that whatever was wrong has cleared up, so the
+		// next failure starts backing off from the base again.
+		if time.Since(started) > opts.Backoff.Max {
+			attempt = 0
+		}
+
+		s.setState(StateRecovering)
+		mlog.From(s.cmp).Warn("transient error consuming from pubsub, reconnecting",
+			s.ctx, ctx, merr.Context(err))
+
+		select {
+		case <-time.After(opts.Backoff.delay(attempt)):
+		case <-ctx.Done():
+			return
+		}
+		attempt++
+	}
+}
+
+// giveUp is called on a message which has exceeded its RetryPolicy's
+// MaxDeliveries, and returns nil (meaning the message should be Acked) if it
+// was successfully routed to a DeadLetterTopic or handled by OnGiveUp.
+func (s *Subscription) giveUp(
+	ctx context.Context, msg *Message, policy RetryPolicy, attempt int, cause error,
+) error {
+	if policy.DeadLetterTopic != "" {
+		if err := s.publishDeadLetter(ctx, msg, policy.DeadLetterTopic, attempt); err == nil {
+			return nil
+		} else if policy.OnGiveUp == nil {
+			return err
 		}
 	}
+	if policy.OnGiveUp != nil {
+		return policy.OnGiveUp(ctx, msg, cause)
+	}
+	return errors.New("message exceeded RetryPolicy.MaxDeliveries and no DeadLetterTopic or OnGiveUp is configured")
+}
+
+func (s *Subscription) publishDeadLetter(ctx context.Context, msg *Message, topicName string, attempt int) error {
+	dlTopic, err := s.PubSub.Topic(ctx, topicName, true)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(DeadLetterMessage{
+		Data:            msg.Data,
+		Attributes:      msg.Attributes,
+		DeliveryAttempt: attempt,
+	})
+	if err != nil {
+		return err
+	}
+	return dlTopic.Publish(ctx, body)
 }
 
 // BatchConsumerFunc is similar to ConsumerFunc, except it takes in a batch of
@@ -262,15 +653,23 @@ type BatchGroupFunc func(a, b *Message) bool
 // BatchConsume is like Consume, except it groups incoming messages together,
 // allowing them to be processed in batches instead of individually.
 //
-// BatchConsume first collects messages internally for half the
-// ConsumerOpts.Timeout value. Once that time has passed it will group all
-// messages based on the BatchGroupFunc (if nil then all collected messages form
-// one big group). The BatchConsumerFunc is called for each group, with the
-// context passed in having a timeout of ConsumerOpts.Timeout/2.
+// BatchConsume collects messages internally, grouping them based on the
+// BatchGroupFunc (if nil then all collected messages form one big group).
+// Unless ConsumerOpts.DisableBatchAutoTrigger is set, a group is flushed as
+// soon as it reaches ConsumerOpts.MaxBatchSize messages; every group is also
+// flushed, regardless of size, once half of ConsumerOpts.Timeout has passed.
+// The BatchConsumerFunc is called for each flushed group, with the context
+// passed in having a timeout of ConsumerOpts.Timeout/2.
 //
 // The ConsumerOpts.Concurrent value determines the maximum number of messages
 // collected during the first section of the process (before BatchConsumerFn is
 // called).
+//
+// ConsumerOpts.Retry applies per-message rather than per-group: since the
+// BatchConsumerFunc's returned bool applies to every message in the group,
+// every message in a failing group hits the same RetryPolicy decision on the
+// same pass, which in practice routes (or gives up on) the whole group
+// together.
 func (s *Subscription) BatchConsume(
 	ctx context.Context,
 	fn BatchConsumerFunc, gfn BatchGroupFunc,
@@ -286,16 +685,32 @@ func (s *Subscription) BatchConsume(
 	var groups [][]promise
 	var groupsL sync.Mutex
 
+	// buffered by one so a group crossing the threshold while processGroups
+	// is already running doesn't block groupProm's caller.
+	autoTriggerCh := make(chan struct{}, 1)
+
 	groupProm := func(prom promise) {
 		groupsL.Lock()
-		defer groupsL.Unlock()
+		groupLen := 0
 		for i := range groups {
 			if gfn == nil || gfn(groups[i][0].msg, prom.msg) {
 				groups[i] = append(groups[i], prom)
-				return
+				groupLen = len(groups[i])
+				break
+			}
+		}
+		if groupLen == 0 {
+			groups = append(groups, []promise{prom})
+			groupLen = 1
+		}
+		groupsL.Unlock()
+
+		if !opts.DisableBatchAutoTrigger && groupLen >= opts.MaxBatchSize {
+			select {
+			case autoTriggerCh <- struct{}{}:
+			default:
 			}
 		}
-		groups = append(groups, []promise{prom})
 	}
 
 	wg := new(sync.WaitGroup)
@@ -351,6 +766,8 @@ func (s *Subscription) BatchConsume(
 			select {
 			case <-tick.C:
 				processGroups()
+			case <-autoTriggerCh:
+				processGroups()
 			case <-s.batchTestTrigger:
 				processGroups()
 			case <-ctx.Done():
@@ -366,7 +783,7 @@ func (s *Subscription) BatchConsume(
 		case ret := <-retCh:
 			return ret, nil
 		case <-ctx.Done():
-			return false, merr.New("reading from batch grouping process timed out", s.ctx, ctx)
+			return false, merr.New(s.ctx, "reading from batch grouping process timed out")
 		}
 	}, opts)
 
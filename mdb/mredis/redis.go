@@ -3,6 +3,7 @@ package mredis
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/mediocregopher/mediocre-go-lib/mcfg"
 	"github.com/mediocregopher/mediocre-go-lib/mcmp"
@@ -19,6 +20,7 @@ type Redis struct {
 
 type redisOpts struct {
 	dialOpts []radix.DialOpt
+	client   radix.Client
 }
 
 // RedisOption is a value which adjusts the behavior of InstRedis.
@@ -32,10 +34,38 @@ func RedisDialOpts(dialOpts ...radix.DialOpt) RedisOption {
 	}
 }
 
+// RedisClient specifies that the given, already-constructed radix.Client
+// should be used instead of one being constructed based on configuration
+// params. This is primarily useful for tests.
+func RedisClient(client radix.Client) RedisOption {
+	return func(opts *redisOpts) {
+		opts.client = client
+	}
+}
+
+// redis mode strings, as used by the "mode" config param.
+const (
+	modeStandalone = "standalone"
+	modeSentinel   = "sentinel"
+	modeCluster    = "cluster"
+)
+
+func (opts redisOpts) poolFunc() radix.ClientFunc {
+	return func(network, addr string) (radix.Client, error) {
+		return radix.NewPool(network, addr, 1, radix.PoolConnFunc(func(network, addr string) (radix.Conn, error) {
+			return radix.Dial(network, addr, opts.dialOpts...)
+		}))
+	}
+}
+
 // InstRedis instantiates a Redis instance which will be initialized when the
 // Init event is triggered on the given Component. The redis client will have
 // Close called on it when the Shutdown event is triggered on the given
 // Component.
+//
+// By default a single standalone instance is connected to, but this can be
+// changed to a sentinel or cluster topology using the "mode" config param
+// (see RedisOption for injecting a pre-built client instead, e.g. for tests).
 func InstRedis(parent *mcmp.Component, options ...RedisOption) *Redis {
 	var opts redisOpts
 	for _, opt := range options {
@@ -47,20 +77,59 @@ func InstRedis(parent *mcmp.Component, options ...RedisOption) *Redis {
 
 	addr := mcfg.String(cmp, "addr",
 		mcfg.ParamDefault("127.0.0.1:6379"),
-		mcfg.ParamUsage("Address redis is listening on"))
+		mcfg.ParamUsage("Address redis is listening on, when mode is standalone"))
 	poolSize := mcfg.Int(cmp, "pool-size",
 		mcfg.ParamDefault(4),
-		mcfg.ParamUsage("Number of connections in pool"))
+		mcfg.ParamUsage("Number of connections in pool, when mode is standalone"))
+	mode := mcfg.String(cmp, "mode",
+		mcfg.ParamDefault(modeStandalone),
+		mcfg.ParamUsage("Topology to connect to redis with. One of 'standalone', 'sentinel', or 'cluster'"))
+
+	var sentinelAddrs []string
+	mcfg.JSON(cmp, "sentinel-addrs", &sentinelAddrs,
+		mcfg.ParamUsage("Addresses of the sentinel instances to use for discovery, when mode is sentinel"))
+	sentinelMasterName := mcfg.String(cmp, "sentinel-master-name",
+		mcfg.ParamUsage("Name of the master instance, as configured in sentinel, when mode is sentinel"))
+
+	var clusterAddrs []string
+	mcfg.JSON(cmp, "cluster-addrs", &clusterAddrs,
+		mcfg.ParamUsage("Seed addresses of cluster instances, when mode is cluster"))
+
 	mrun.InitHook(cmp, func(ctx context.Context) error {
-		cmp.Annotate("addr", *addr, "poolSize", *poolSize)
+		if opts.client != nil {
+			client.Client = opts.client
+			return nil
+		}
+
+		cmp.Annotate("mode", *mode)
 		mlog.From(cmp).Info("connecting to redis", ctx)
+
 		var err error
-		client.Client, err = radix.NewPool(
-			"tcp", *addr, *poolSize,
-			radix.PoolConnFunc(func(network, addr string) (radix.Conn, error) {
-				return radix.Dial(network, addr, opts.dialOpts...)
-			}),
-		)
+		switch *mode {
+		case modeStandalone:
+			cmp.Annotate("addr", *addr, "poolSize", *poolSize)
+			client.Client, err = radix.NewPool(
+				"tcp", *addr, *poolSize,
+				radix.PoolConnFunc(func(network, addr string) (radix.Conn, error) {
+					return radix.Dial(network, addr, opts.dialOpts...)
+				}),
+			)
+		case modeSentinel:
+			cmp.Annotate("sentinelAddrs", sentinelAddrs, "sentinelMasterName", *sentinelMasterName)
+			client.Client, err = radix.NewSentinel(*sentinelMasterName, sentinelAddrs,
+				radix.SentinelConnFunc(func(network, addr string) (radix.Conn, error) {
+					return radix.Dial(network, addr, opts.dialOpts...)
+				}),
+				radix.SentinelPoolFunc(opts.poolFunc()),
+			)
+		case modeCluster:
+			cmp.Annotate("clusterAddrs", clusterAddrs)
+			client.Client, err = radix.NewCluster(clusterAddrs,
+				radix.ClusterPoolFunc(opts.poolFunc()),
+			)
+		default:
+			err = fmt.Errorf("unknown redis mode %q", *mode)
+		}
 		return err
 	})
 	mrun.ShutdownHook(cmp, func(ctx context.Context) error {
@@ -0,0 +1,187 @@
+package mredis
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+
+	"github.com/mediocregopher/mediocre-go-lib/mcfg"
+	"github.com/mediocregopher/mediocre-go-lib/mcmp"
+	"github.com/mediocregopher/mediocre-go-lib/mlog"
+	"github.com/mediocregopher/mediocre-go-lib/mrun"
+	"github.com/mediocregopher/radix/v3"
+)
+
+// ErrCrossShard is returned by ShardedRedis.Do (or anything which calls it)
+// when an Action's keys hash to more than one shard, and so there is no
+// single node the Action can be sent to.
+var ErrCrossShard = errors.New("action's keys span more than one shard")
+
+// shardScore hashes nodeID and key together using FNV-1a (64-bit), producing
+// the value used by ShardedRedis to rendezvous-hash a key onto a node.
+//
+// FNV-1a is used, rather than xxhash or siphash, so that this doesn't require
+// pulling in a new dependency; it's not cryptographically secure but HRW
+// hashing doesn't need it to be, just well-distributed.
+func shardScore(nodeID, key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(nodeID))
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+type shardNode struct {
+	// id is the hash input identifying this node, and is generally its
+	// address. client is the radix.Client used to actually talk to it.
+	id     string
+	client radix.Client
+}
+
+// ShardedRedis routes commands across a fixed set of standalone Redis nodes
+// using rendezvous (highest random weight, aka HRW) hashing of the command's
+// first key. Unlike Redis Cluster's slot-based hashing, HRW hashing means
+// adding or removing a node only reshuffles the keys which were (or will be)
+// assigned to that node, rather than needing a hash ring or reshuffling
+// everything.
+//
+// ShardedRedis implements radix.Client, and so can be used anywhere a
+// radix.Client is expected, so long as every Action given to it only
+// operates on keys belonging to a single shard (see ErrCrossShard).
+type ShardedRedis struct {
+	cmp   *mcmp.Component
+	nodes []*shardNode
+}
+
+var _ radix.Client = (*ShardedRedis)(nil)
+
+// nodeFor returns the node which the given key hashes to.
+func (sr *ShardedRedis) nodeFor(key string) *shardNode {
+	var best *shardNode
+	var bestScore uint64
+	for _, n := range sr.nodes {
+		if s := shardScore(n.id, key); best == nil || s > bestScore {
+			best, bestScore = n, s
+		}
+	}
+	return best
+}
+
+func (sr *ShardedRedis) nodeForAction(a radix.Action) (*shardNode, error) {
+	keys := a.Keys()
+	if len(keys) == 0 {
+		return nil, mlog.ErrWithKV(
+			errors.New("action has no keys, can't determine which shard to use"),
+			mlog.KV{"keys": keys},
+		)
+	}
+
+	node := sr.nodeFor(keys[0])
+	for _, key := range keys[1:] {
+		if sr.nodeFor(key) != node {
+			return nil, ErrCrossShard
+		}
+	}
+	return node, nil
+}
+
+// Do implements the method for the radix.Client interface. The given Action's
+// keys (see radix.Action.Keys) must all hash to the same node, or
+// ErrCrossShard is returned without the Action being run anywhere.
+func (sr *ShardedRedis) Do(a radix.Action) error {
+	node, err := sr.nodeForAction(a)
+	if err != nil {
+		return err
+	}
+	return node.client.Do(a)
+}
+
+// Close implements the method for the radix.Client interface. It closes every
+// underlying node's client, returning the first encountered error (if any)
+// after attempting to close all of them.
+func (sr *ShardedRedis) Close() error {
+	var firstErr error
+	for _, n := range sr.nodes {
+		if err := n.client.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// ForEachShard calls fn once for every shard's underlying radix.Client,
+// for scatter-gather style operations (e.g. INFO, FLUSHDB, or per-shard
+// SCAN-based key iteration) which must be run against every node
+// individually rather than routed by key. It calls fn against every shard
+// regardless of errors, but returns the first one encountered (if any).
+func (sr *ShardedRedis) ForEachShard(fn func(client radix.Client) error) error {
+	var firstErr error
+	for _, n := range sr.nodes {
+		if err := fn(n.client); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+type shardedRedisOpts struct {
+	dialOpts []radix.DialOpt
+	poolSize int
+}
+
+// ShardedRedisOption is a value which adjusts the behavior of InstShardedRedis.
+type ShardedRedisOption func(*shardedRedisOpts)
+
+// ShardedRedisDialOpts specifies that the given set of DialOpts should be used
+// when creating any new connections to any of the shards.
+func ShardedRedisDialOpts(dialOpts ...radix.DialOpt) ShardedRedisOption {
+	return func(opts *shardedRedisOpts) {
+		opts.dialOpts = dialOpts
+	}
+}
+
+// InstShardedRedis instantiates a ShardedRedis instance which will be
+// initialized (i.e. connect a pool to every configured node address) when the
+// Init event is triggered on the given Component, and have all of its
+// shards' Close called when the Shutdown event is triggered.
+func InstShardedRedis(parent *mcmp.Component, options ...ShardedRedisOption) *ShardedRedis {
+	opts := shardedRedisOpts{poolSize: 4}
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	cmp := parent.Child("sharded-redis")
+
+	var addrs []string
+	mcfg.JSON(cmp, "addrs", &addrs,
+		mcfg.ParamUsage("Addresses of the standalone redis nodes to shard keys across"))
+	poolSize := mcfg.Int(cmp, "pool-size",
+		mcfg.ParamDefault(opts.poolSize),
+		mcfg.ParamUsage("Number of connections in the pool kept to each shard"))
+
+	sr := &ShardedRedis{cmp: cmp}
+
+	mrun.InitHook(cmp, func(ctx context.Context) error {
+		cmp.Annotate("addrs", addrs)
+		mlog.From(cmp).Info("connecting to redis shards", ctx)
+
+		sr.nodes = make([]*shardNode, len(addrs))
+		for i, addr := range addrs {
+			client, err := radix.NewPool("tcp", addr, *poolSize,
+				radix.PoolConnFunc(func(network, addr string) (radix.Conn, error) {
+					return radix.Dial(network, addr, opts.dialOpts...)
+				}),
+			)
+			if err != nil {
+				return err
+			}
+			sr.nodes[i] = &shardNode{id: addr, client: client}
+		}
+		return nil
+	})
+	mrun.ShutdownHook(cmp, func(ctx context.Context) error {
+		mlog.From(cmp).Info("shutting down redis shards", ctx)
+		return sr.Close()
+	})
+
+	return sr
+}
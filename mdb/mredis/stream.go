@@ -40,6 +40,47 @@ func (s *streamReaderEntry) UnmarshalRESP(br *bufio.Reader) error {
 	return (resp2.Any{I: &s.entries}).UnmarshalRESP(br)
 }
 
+// pendingEntry holds a single entry of the extended XPENDING response, i.e.
+// one <id, consumer, idle-ms, delivery-count> tuple.
+type pendingEntry struct {
+	ID            radix.StreamEntryID
+	Consumer      string
+	IdleMillis    int64
+	DeliveryCount int64
+}
+
+func (p *pendingEntry) UnmarshalRESP(br *bufio.Reader) error {
+	var ah resp2.ArrayHeader
+	if err := ah.UnmarshalRESP(br); err != nil {
+		return err
+	}
+	if ah.N != 4 {
+		return errors.New("invalid xpending entry")
+	}
+
+	if err := p.ID.UnmarshalRESP(br); err != nil {
+		return err
+	}
+
+	var consumer resp2.BulkString
+	if err := consumer.UnmarshalRESP(br); err != nil {
+		return err
+	}
+	p.Consumer = consumer.S
+
+	var idle, deliveryCount resp2.Int
+	if err := idle.UnmarshalRESP(br); err != nil {
+		return err
+	}
+	p.IdleMillis = idle.I
+	if err := deliveryCount.UnmarshalRESP(br); err != nil {
+		return err
+	}
+	p.DeliveryCount = deliveryCount.I
+
+	return nil
+}
+
 // StreamEntry wraps radix's StreamEntry type in order to provde some extra
 // functionality.
 type StreamEntry struct {
@@ -89,6 +130,30 @@ type StreamOpts struct {
 	//
 	// Defaults to 5 * time.Second
 	Block time.Duration
+
+	// (Optional) MinIdleTime indicates how long an entry may sit in another
+	// consumer's Pending Entries List, un-acked, before this Stream will
+	// claim it for itself via XCLAIM. A zero value disables claiming
+	// abandoned entries altogether.
+	MinIdleTime time.Duration
+
+	// (Optional) ClaimInterval indicates how often this Stream will check for
+	// (and claim) entries idle for longer than MinIdleTime. Only used if
+	// MinIdleTime is non-zero.
+	//
+	// Defaults to 10 * MinIdleTime.
+	ClaimInterval time.Duration
+
+	// (Optional) MaxDeliveryCount, if greater than zero, causes an abandoned
+	// entry whose delivery count (as reported by XPENDING, including its
+	// original delivery) exceeds it to be routed to DeadLetterKey (if set) and
+	// ack'd once claimed, rather than being re-delivered.
+	MaxDeliveryCount int
+
+	// (Optional) DeadLetterKey, if set, is the stream key which entries which
+	// have exceeded MaxDeliveryCount will be XADD'd to, prior to being ack'd
+	// off of Key. If not set those entries are simply ack'd and dropped.
+	DeadLetterKey string
 }
 
 func (opts *StreamOpts) fillDefaults() {
@@ -98,6 +163,9 @@ func (opts *StreamOpts) fillDefaults() {
 	if opts.Block == 0 {
 		opts.Block = 5 * time.Second
 	}
+	if opts.MinIdleTime > 0 && opts.ClaimInterval == 0 {
+		opts.ClaimInterval = 10 * opts.MinIdleTime
+	}
 }
 
 // Stream wraps a Redis instance in order to provide an abstraction over
@@ -116,6 +184,12 @@ type Stream struct {
 
 	hasInit    bool
 	numPending int64
+
+	// nextClaimAt is the next time claimAbandoned should be run, used to rate
+	// limit how often XPENDING/XCLAIM are called. Zero value means it hasn't
+	// been set yet, and so claimAbandoned should run the first time fillBuf
+	// is called (assuming MinIdleTime is set at all).
+	nextClaimAt time.Time
 }
 
 // NewStream initializes and returns a Stream instance using the given options.
@@ -180,6 +254,74 @@ func (s *Stream) fillBufFrom(id string) error {
 	return nil
 }
 
+// claimAbandoned looks for entries which have been idle in the Pending
+// Entries List for longer than MinIdleTime and claims them for this Stream's
+// Consumer, appending the results to s.buf. Entries which have already been
+// delivered more than MaxDeliveryCount times are instead dead-lettered (or
+// simply ack'd, if DeadLetterKey isn't set).
+func (s *Stream) claimAbandoned() error {
+	minIdleMillis := strconv.FormatInt(s.opts.MinIdleTime.Milliseconds(), 10)
+
+	count := s.opts.ReadCount
+	if count <= 0 {
+		count = 100
+	}
+
+	var pending []pendingEntry
+	err := s.client.Do(radix.Cmd(&pending, "XPENDING", s.opts.Key, s.opts.Group,
+		"IDLE", minIdleMillis, "-", "+", strconv.Itoa(count)))
+	if err != nil {
+		return merr.Wrap(err, s.client.cmp.Context())
+	} else if len(pending) == 0 {
+		return nil
+	}
+
+	deliveryCounts := make(map[radix.StreamEntryID]int64, len(pending))
+	args := []string{s.opts.Key, s.opts.Group, s.opts.Consumer, minIdleMillis}
+	for _, p := range pending {
+		deliveryCounts[p.ID] = p.DeliveryCount
+		args = append(args, p.ID.String())
+	}
+
+	// XCLAIM (without JUSTID) is used, rather than XCLAIM...JUSTID followed
+	// by a separate fetch, so that the claimed entries' fields come back in
+	// the same round-trip.
+	var claimed []radix.StreamEntry
+	if err := s.client.Do(radix.Cmd(&claimed, "XCLAIM", args...)); err != nil {
+		return merr.Wrap(err, s.client.cmp.Context())
+	}
+
+	for _, entry := range claimed {
+		if s.opts.MaxDeliveryCount > 0 && deliveryCounts[entry.ID] > int64(s.opts.MaxDeliveryCount) {
+			if err := s.deadLetter(entry); err != nil {
+				return err
+			}
+			continue
+		}
+		s.buf = append(s.buf, s.wrapEntry(entry))
+	}
+	return nil
+}
+
+// deadLetter routes entry to DeadLetterKey (if set) and acks it off of Key,
+// rather than letting it be re-delivered indefinitely.
+func (s *Stream) deadLetter(entry radix.StreamEntry) error {
+	if s.opts.DeadLetterKey != "" {
+		args := make([]string, 2, 2+len(entry.Fields)*2)
+		args[0], args[1] = s.opts.DeadLetterKey, "*"
+		for k, v := range entry.Fields {
+			args = append(args, k, v)
+		}
+		if err := s.client.Do(radix.Cmd(nil, "XADD", args...)); err != nil {
+			return merr.Wrap(err, s.client.cmp.Context())
+		}
+	}
+	return merr.Wrap(
+		s.client.Do(radix.Cmd(nil, "XACK", s.opts.Key, s.opts.Group, entry.ID.String())),
+		s.client.cmp.Context(),
+	)
+}
+
 func (s *Stream) fillBuf() error {
 	if len(s.buf) > 0 {
 		return nil
@@ -191,6 +333,15 @@ func (s *Stream) fillBuf() error {
 		}
 	}
 
+	if s.opts.MinIdleTime > 0 && !time.Now().Before(s.nextClaimAt) {
+		s.nextClaimAt = time.Now().Add(s.opts.ClaimInterval)
+		if err := s.claimAbandoned(); err != nil {
+			return err
+		} else if len(s.buf) > 0 {
+			return nil
+		}
+	}
+
 	numPending := atomic.LoadInt64(&s.numPending)
 	if numPending > 0 {
 		if err := s.fillBufFrom("0"); err != nil {
@@ -157,3 +157,90 @@ func TestStream(t *T) {
 		}
 	})
 }
+
+// TestStreamClaimAbandoned covers the scenario MinIdleTime/ClaimInterval are
+// meant to protect against: a consumer reads some entries and then
+// disappears (crashes, gets rescheduled, whatever) without ever Ack'ing or
+// Nack'ing them. Another Stream, consuming from the same group under a
+// different consumer name, should eventually claim and successfully process
+// those entries itself.
+func TestStreamClaimAbandoned(t *T) {
+	cmp := mtest.Component()
+	redis := InstRedis(cmp)
+
+	streamKey := "stream-" + mrand.Hex(8)
+	group := "group-" + mrand.Hex(8)
+
+	mtest.Run(cmp, t, func() {
+		abandoned := NewStream(redis, StreamOpts{
+			Key:           streamKey,
+			Group:         group,
+			Consumer:      "abandoned-consumer",
+			InitialCursor: "0",
+		})
+
+		const numEntries = 5
+		expEntries := map[radix.StreamEntryID]radix.StreamEntry{}
+		for i := 0; i < numEntries; i++ {
+			var id radix.StreamEntryID
+			key, val := mrand.Hex(8), mrand.Hex(8)
+			if err := redis.Do(radix.Cmd(&id, "XADD", streamKey, "*", key, val)); err != nil {
+				t.Fatalf("error XADDing: %v", err)
+			}
+			expEntries[id] = radix.StreamEntry{
+				ID:     id,
+				Fields: map[string]string{key: val},
+			}
+		}
+
+		// read every entry into abandoned's PEL, and then walk away: it never
+		// Acks or Nacks any of them, simulating a consumer which crashed
+		// mid-processing.
+		t.Log("reading all entries into the abandoned consumer's PEL")
+		for i := 0; i < numEntries; i++ {
+			if _, ok, err := abandoned.Next(); err != nil {
+				t.Fatalf("error calling Next on abandoned consumer: %v", err)
+			} else if !ok {
+				t.Fatalf("abandoned consumer didn't read an entry it should have")
+			}
+		}
+
+		rescuer := NewStream(redis, StreamOpts{
+			Key:           streamKey,
+			Group:         group,
+			Consumer:      "rescuer-consumer",
+			InitialCursor: "0",
+			MinIdleTime:   50 * time.Millisecond,
+			ClaimInterval: 50 * time.Millisecond,
+		})
+
+		t.Log("waiting for rescuer to claim the abandoned entries")
+		gotEntries := map[radix.StreamEntryID]radix.StreamEntry{}
+		waitTimer := time.After(5 * time.Second)
+	loop:
+		for len(gotEntries) < numEntries {
+			select {
+			case <-waitTimer:
+				t.Fatalf("timed out waiting for rescuer to claim all entries, got %d/%d", len(gotEntries), numEntries)
+				break loop
+			default:
+			}
+
+			entry, ok, err := rescuer.Next()
+			if err != nil {
+				t.Fatalf("error calling Next on rescuer: %v", err)
+			} else if !ok {
+				continue
+			}
+
+			if err := entry.Ack(); err != nil {
+				t.Fatalf("error calling Ack: %v", err)
+			}
+			gotEntries[entry.ID] = entry.StreamEntry
+		}
+
+		if !reflect.DeepEqual(expEntries, gotEntries) {
+			t.Errorf("expEntries:%#v != gotEntries:%#v", expEntries, gotEntries)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package mrepo
+
+import "fmt"
+
+// Dialect describes the SQL-flavor-specific syntax a Repo needs in order to
+// build queries for a particular database engine.
+type Dialect interface {
+	// Placeholder returns the bound-parameter placeholder for the i'th
+	// (0-indexed) value in a query.
+	Placeholder(i int) string
+
+	// Upsert returns the clause to append to an INSERT so that, on a
+	// conflict against pkCol, the given columns are updated instead.
+	Upsert(pkCol string, cols []string) string
+
+	// Returning, if this dialect needs a RETURNING clause in order to read
+	// an auto-generated primary key back out of an INSERT (e.g. postgres),
+	// returns that clause. Dialects which instead expose the generated key
+	// via sql.Result.LastInsertId (e.g. mysql) return "".
+	Returning(pkCol string) string
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Placeholder(int) string { return "?" }
+
+func (mysqlDialect) Upsert(pkCol string, cols []string) string {
+	set := ""
+	for i, col := range cols {
+		if i > 0 {
+			set += ", "
+		}
+		set += fmt.Sprintf("%s = VALUES(%s)", col, col)
+	}
+	return "ON DUPLICATE KEY UPDATE " + set
+}
+
+func (mysqlDialect) Returning(string) string { return "" }
+
+type postgresDialect struct{}
+
+func (postgresDialect) Placeholder(i int) string { return fmt.Sprintf("$%d", i+1) }
+
+func (postgresDialect) Upsert(pkCol string, cols []string) string {
+	set := ""
+	for i, col := range cols {
+		if i > 0 {
+			set += ", "
+		}
+		set += fmt.Sprintf("%s = EXCLUDED.%s", col, col)
+	}
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", pkCol, set)
+}
+
+func (postgresDialect) Returning(pkCol string) string { return "RETURNING " + pkCol }
+
+var (
+	// MySQL is the Dialect for MySQL, used by default if none is given to
+	// Inst.
+	MySQL Dialect = mysqlDialect{}
+
+	// MariaDB is the Dialect for MariaDB. MariaDB speaks the same wire
+	// protocol and SQL dialect as MySQL for the purposes of this package.
+	MariaDB = MySQL
+
+	// Postgres is the Dialect for PostgreSQL.
+	Postgres Dialect = postgresDialect{}
+)
@@ -0,0 +1,382 @@
+// Package mrepo implements a generic, reflection-driven repository layer on
+// top of msql, giving a Go struct typed Insert/Update/Delete/Get/Select/
+// Upsert methods without requiring hand-written SQL for each.
+//
+// A Repo is constructed for a single model type via Inst:
+//
+//	type User struct {
+//		ID        int64     `db:"id"`
+//		Name      string    `db:"name"`
+//		CreatedAt time.Time `db:"created_at"`
+//		UpdatedAt time.Time `db:"updated_at"`
+//		Version   int64     `db:"version"`
+//	}
+//
+//	users := mrepo.Inst(cmp, sql, &User{})
+//
+// created_at/updated_at columns, if present on the model, are managed
+// automatically: created_at is set on Insert, updated_at on both Insert and
+// Update. A version column, if present, is used for optimistic locking: every
+// Update is conditioned on the version it read, and bumps it by one, failing
+// with ErrVersionConflict if another writer got there first.
+package mrepo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/mediocregopher/mediocre-go-lib/mcmp"
+	"github.com/mediocregopher/mediocre-go-lib/mctx"
+	"github.com/mediocregopher/mediocre-go-lib/mdb/msql"
+	"github.com/mediocregopher/mediocre-go-lib/merr"
+	"github.com/mediocregopher/mediocre-go-lib/mlog"
+	"github.com/mediocregopher/mediocre-go-lib/mrun"
+)
+
+// ErrVersionConflict is returned by Update when the model's version column
+// no longer matches what's in the database, indicating another writer
+// updated the row first.
+var ErrVersionConflict = fmt.Errorf("version conflict")
+
+type field struct {
+	name  string // struct field name
+	index int    // field index within the struct
+	col   string // db column name
+}
+
+// Repo is a reflection-driven repository of a single table, providing typed
+// CRUD methods for the model type it was constructed with via Inst.
+//
+// All methods are safe for concurrent use.
+type Repo struct {
+	cmp     *mcmp.Component
+	sql     *msql.SQL
+	dialect Dialect
+
+	table     string
+	fields    []field
+	pk        field
+	createdAt *field
+	updatedAt *field
+	version   *field
+}
+
+type repoOpts struct {
+	dialect Dialect
+	table   string
+	pkCol   string
+}
+
+// Opt is used to configure a Repo constructed via Inst.
+type Opt func(*repoOpts)
+
+// WithDialect sets the SQL dialect the Repo generates queries for. The
+// default is MySQL.
+func WithDialect(d Dialect) Opt {
+	return func(o *repoOpts) { o.dialect = d }
+}
+
+// WithTable overrides the table name the Repo operates on. The default is
+// the lower-cased name of the model's type.
+func WithTable(table string) Opt {
+	return func(o *repoOpts) { o.table = table }
+}
+
+// WithPrimaryKey overrides the db column used as the model's primary key.
+// The default is "id".
+func WithPrimaryKey(col string) Opt {
+	return func(o *repoOpts) { o.pkCol = col }
+}
+
+func colName(f reflect.StructField) string {
+	tag := f.Tag.Get("db")
+	if tag == "" || tag == "-" {
+		if tag == "-" {
+			return ""
+		}
+		return strings.ToLower(f.Name)
+	}
+	return strings.SplitN(tag, ",", 2)[0]
+}
+
+// Inst returns a Repo for the given model, which must be a pointer to a
+// struct. The Repo is registered on cmp so that it participates in the
+// standard mcmp Init lifecycle: on Init, the model is validated (e.g. that
+// a primary key column was found) and the Repo's table name is annotated
+// onto cmp for logging purposes.
+func Inst(cmp *mcmp.Component, sql *msql.SQL, model interface{}, opts ...Opt) *Repo {
+	o := repoOpts{dialect: MySQL, pkCol: "id"}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	t := reflect.TypeOf(model)
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		panic("mrepo.Inst: model must be a pointer to a struct")
+	}
+	t = t.Elem()
+
+	if o.table == "" {
+		o.table = strings.ToLower(t.Name())
+	}
+
+	r := &Repo{
+		cmp:     cmp.Child("repo-" + o.table),
+		sql:     sql,
+		dialect: o.dialect,
+		table:   o.table,
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		col := colName(t.Field(i))
+		if col == "" {
+			continue
+		}
+		f := field{name: t.Field(i).Name, index: i, col: col}
+		r.fields = append(r.fields, f)
+
+		switch col {
+		case o.pkCol:
+			r.pk = f
+		case "created_at":
+			fCopy := f
+			r.createdAt = &fCopy
+		case "updated_at":
+			fCopy := f
+			r.updatedAt = &fCopy
+		case "version":
+			fCopy := f
+			r.version = &fCopy
+		}
+	}
+
+	mrun.InitHook(r.cmp, func(ctx context.Context) error {
+		if r.pk.col == "" {
+			return merr.Wrap(fmt.Errorf("no %q column found on %s", o.pkCol, t), r.cmp.Context(), ctx)
+		}
+		r.cmp.Annotate("table", r.table)
+		mlog.From(r.cmp).Info("repo initialized", ctx)
+		return nil
+	})
+
+	return r
+}
+
+// Context returns the annotated Context from this instance's initialization.
+func (r *Repo) Context() context.Context {
+	return r.cmp.Context()
+}
+
+func (r *Repo) logQuery(ctx context.Context, query string, args []interface{}) {
+	mlog.From(r.cmp).Debug("executing query", mctx.Annotate(ctx, "query", query, "args", args))
+}
+
+func (r *Repo) get(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	r.logQuery(ctx, query, args)
+	err := r.sql.GetContext(ctx, dest, r.sql.Rebind(query), args...)
+	if err != nil {
+		return merr.Wrap(err, r.cmp.Context(), ctx)
+	}
+	return nil
+}
+
+func (r *Repo) sel(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	r.logQuery(ctx, query, args)
+	err := r.sql.SelectContext(ctx, dest, r.sql.Rebind(query), args...)
+	if err != nil {
+		return merr.Wrap(err, r.cmp.Context(), ctx)
+	}
+	return nil
+}
+
+func (r *Repo) exec(ctx context.Context, query string, args ...interface{}) error {
+	r.logQuery(ctx, query, args)
+	_, err := r.sql.ExecContext(ctx, r.sql.Rebind(query), args...)
+	if err != nil {
+		return merr.Wrap(err, r.cmp.Context(), ctx)
+	}
+	return nil
+}
+
+// Where begins a Query against the Repo's table.
+func (r *Repo) Where(col, op string, val interface{}) *Query {
+	return (&Query{repo: r}).Where(col, op, val)
+}
+
+// Get fetches the row whose primary key matches model's (model must be a
+// pointer to the Repo's model type) and scans it into model.
+func (r *Repo) Get(ctx context.Context, model interface{}) error {
+	v := reflect.ValueOf(model).Elem()
+	pk := v.Field(r.pk.index).Interface()
+	return r.Where(r.pk.col, "=", pk).Get(ctx, model)
+}
+
+// Select fetches every row in the Repo's table into dest, which must be a
+// pointer to a slice of the Repo's model type.
+func (r *Repo) Select(ctx context.Context, dest interface{}) error {
+	sql := fmt.Sprintf("SELECT * FROM %s", r.table)
+	return r.sel(ctx, dest, sql)
+}
+
+// Insert inserts model (a pointer to the Repo's model type) as a new row.
+// created_at, updated_at, and version columns, if present, are populated
+// automatically. If the table has an auto-incrementing primary key, the
+// generated value is scanned back into model.
+func (r *Repo) Insert(ctx context.Context, model interface{}) error {
+	v := reflect.ValueOf(model).Elem()
+	now := time.Now()
+	if r.createdAt != nil {
+		v.Field(r.createdAt.index).Set(reflect.ValueOf(now).Convert(v.Field(r.createdAt.index).Type()))
+	}
+	if r.updatedAt != nil {
+		v.Field(r.updatedAt.index).Set(reflect.ValueOf(now).Convert(v.Field(r.updatedAt.index).Type()))
+	}
+	if r.version != nil {
+		v.Field(r.version.index).SetInt(1)
+	}
+
+	var cols, placeholders []string
+	var args []interface{}
+	for _, f := range r.fields {
+		// a zero primary key is left out, so the database can generate one
+		if f.col == r.pk.col && v.Field(f.index).IsZero() {
+			continue
+		}
+		cols = append(cols, f.col)
+		placeholders = append(placeholders, r.dialect.Placeholder(len(args)))
+		args = append(args, v.Field(f.index).Interface())
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		r.table, strings.Join(cols, ", "), strings.Join(placeholders, ", "))
+
+	if returning := r.dialect.Returning(r.pk.col); returning != "" {
+		query += " " + returning
+		r.logQuery(ctx, query, args)
+		pk := v.Field(r.pk.index).Addr().Interface()
+		if err := r.sql.GetContext(ctx, pk, r.sql.Rebind(query), args...); err != nil {
+			return merr.Wrap(err, r.cmp.Context(), ctx)
+		}
+		return nil
+	}
+
+	r.logQuery(ctx, query, args)
+	res, err := r.sql.ExecContext(ctx, r.sql.Rebind(query), args...)
+	if err != nil {
+		return merr.Wrap(err, r.cmp.Context(), ctx)
+	}
+	if id, err := res.LastInsertId(); err == nil && id > 0 {
+		v.Field(r.pk.index).Set(reflect.ValueOf(id).Convert(v.Field(r.pk.index).Type()))
+	}
+	return nil
+}
+
+// Update updates the row whose primary key matches model's (model must be a
+// pointer to the Repo's model type) to match model's other fields.
+// updated_at is refreshed automatically if present.
+//
+// If the model has a version column, the update is conditioned on the
+// version the model was last read with, and that version is incremented in
+// model on success. If no row matches (because the row was concurrently
+// updated elsewhere), ErrVersionConflict is returned.
+func (r *Repo) Update(ctx context.Context, model interface{}) error {
+	v := reflect.ValueOf(model).Elem()
+	if r.updatedAt != nil {
+		now := time.Now()
+		v.Field(r.updatedAt.index).Set(reflect.ValueOf(now).Convert(v.Field(r.updatedAt.index).Type()))
+	}
+
+	var sets []string
+	var args []interface{}
+	place := func() string { p := r.dialect.Placeholder(len(args)); return p }
+
+	oldVersion := int64(0)
+	for _, f := range r.fields {
+		if f.col == r.pk.col {
+			continue
+		}
+		if r.version != nil && f.col == r.version.col {
+			oldVersion = v.Field(f.index).Int()
+			sets = append(sets, fmt.Sprintf("%s = %s", f.col, place()))
+			args = append(args, oldVersion+1)
+			continue
+		}
+		sets = append(sets, fmt.Sprintf("%s = %s", f.col, place()))
+		args = append(args, v.Field(f.index).Interface())
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = %s", r.table, strings.Join(sets, ", "), r.pk.col, place())
+	args = append(args, v.Field(r.pk.index).Interface())
+
+	if r.version != nil {
+		query += fmt.Sprintf(" AND %s = %s", r.version.col, place())
+		args = append(args, oldVersion)
+	}
+
+	r.logQuery(ctx, query, args)
+	res, err := r.sql.ExecContext(ctx, r.sql.Rebind(query), args...)
+	if err != nil {
+		return merr.Wrap(err, r.cmp.Context(), ctx)
+	}
+
+	if r.version != nil {
+		n, err := res.RowsAffected()
+		if err != nil {
+			return merr.Wrap(err, r.cmp.Context(), ctx)
+		} else if n == 0 {
+			return merr.Wrap(ErrVersionConflict, r.cmp.Context(), ctx)
+		}
+		v.Field(r.version.index).SetInt(oldVersion + 1)
+	}
+	return nil
+}
+
+// Upsert inserts model as a new row, or, if a row with the same primary key
+// already exists, updates it in place instead.
+func (r *Repo) Upsert(ctx context.Context, model interface{}) error {
+	v := reflect.ValueOf(model).Elem()
+	now := time.Now()
+	if r.createdAt != nil {
+		v.Field(r.createdAt.index).Set(reflect.ValueOf(now).Convert(v.Field(r.createdAt.index).Type()))
+	}
+	if r.updatedAt != nil {
+		v.Field(r.updatedAt.index).Set(reflect.ValueOf(now).Convert(v.Field(r.updatedAt.index).Type()))
+	}
+	if r.version != nil {
+		v.Field(r.version.index).SetInt(1)
+	}
+
+	cols := make([]string, len(r.fields))
+	placeholders := make([]string, len(r.fields))
+	args := make([]interface{}, len(r.fields))
+	var updateCols []string
+	for i, f := range r.fields {
+		cols[i] = f.col
+		placeholders[i] = r.dialect.Placeholder(i)
+		args[i] = v.Field(f.index).Interface()
+		if f.col != r.pk.col {
+			updateCols = append(updateCols, f.col)
+		}
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) %s",
+		r.table, strings.Join(cols, ", "), strings.Join(placeholders, ", "),
+		r.dialect.Upsert(r.pk.col, updateCols))
+
+	r.logQuery(ctx, query, args)
+	if _, err := r.sql.ExecContext(ctx, r.sql.Rebind(query), args...); err != nil {
+		return merr.Wrap(err, r.cmp.Context(), ctx)
+	}
+	return nil
+}
+
+// Delete deletes the row whose primary key matches model's (model must be a
+// pointer to the Repo's model type).
+func (r *Repo) Delete(ctx context.Context, model interface{}) error {
+	v := reflect.ValueOf(model).Elem()
+	pk := v.Field(r.pk.index).Interface()
+	return r.Where(r.pk.col, "=", pk).Delete(ctx)
+}
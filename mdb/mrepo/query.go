@@ -0,0 +1,105 @@
+package mrepo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mediocregopher/mediocre-go-lib/merr"
+)
+
+type where struct {
+	col string
+	op  string
+	val interface{}
+}
+
+// Query is a fluent builder for the conditions of a Select, Get, or Delete
+// call. A Query is only valid for use with the Repo which created it.
+type Query struct {
+	repo    *Repo
+	wheres  []where
+	orderBy string
+	desc    bool
+	limit   int
+}
+
+// Where adds a "col op ?" condition to the Query, ANDed together with any
+// other conditions already added. op is used verbatim, e.g. "=", ">", "LIKE".
+func (q *Query) Where(col, op string, val interface{}) *Query {
+	q.wheres = append(q.wheres, where{col: col, op: op, val: val})
+	return q
+}
+
+// OrderBy sets the column results are ordered by. desc reverses the order.
+func (q *Query) OrderBy(col string, desc bool) *Query {
+	q.orderBy = col
+	q.desc = desc
+	return q
+}
+
+// Limit sets the maximum number of rows a Select may return. It has no
+// effect on Get, which always fetches at most one row.
+func (q *Query) Limit(n int) *Query {
+	q.limit = n
+	return q
+}
+
+// whereSQL returns the "WHERE ..." clause (or "" if there are no
+// conditions) along with the bound values for its placeholders, in order.
+func (q *Query) whereSQL() (string, []interface{}) {
+	if len(q.wheres) == 0 {
+		return "", nil
+	}
+	sql := " WHERE "
+	args := make([]interface{}, len(q.wheres))
+	for i, w := range q.wheres {
+		if i > 0 {
+			sql += " AND "
+		}
+		sql += fmt.Sprintf("%s %s %s", w.col, w.op, q.repo.dialect.Placeholder(i))
+		args[i] = w.val
+	}
+	return sql, args
+}
+
+func (q *Query) suffixSQL() string {
+	sql := ""
+	if q.orderBy != "" {
+		sql += " ORDER BY " + q.orderBy
+		if q.desc {
+			sql += " DESC"
+		}
+	}
+	if q.limit > 0 {
+		sql += fmt.Sprintf(" LIMIT %d", q.limit)
+	}
+	return sql
+}
+
+// Get fetches the first row matching the Query into dest, which must be a
+// pointer to a value of the Repo's model type.
+//
+// Returns sql.ErrNoRows (wrapped) if no row matches.
+func (q *Query) Get(ctx context.Context, dest interface{}) error {
+	whereSQL, args := q.whereSQL()
+	sql := fmt.Sprintf("SELECT * FROM %s%s%s", q.repo.table, whereSQL, q.suffixSQL())
+	return q.repo.get(ctx, dest, sql, args...)
+}
+
+// Select fetches all rows matching the Query into dest, which must be a
+// pointer to a slice of the Repo's model type.
+func (q *Query) Select(ctx context.Context, dest interface{}) error {
+	whereSQL, args := q.whereSQL()
+	sql := fmt.Sprintf("SELECT * FROM %s%s%s", q.repo.table, whereSQL, q.suffixSQL())
+	return q.repo.sel(ctx, dest, sql, args...)
+}
+
+// Delete deletes all rows matching the Query.
+func (q *Query) Delete(ctx context.Context) error {
+	whereSQL, args := q.whereSQL()
+	if whereSQL == "" {
+		return merr.Wrap(fmt.Errorf("Delete requires at least one Where condition"), q.repo.cmp.Context(), ctx)
+	}
+	sql := fmt.Sprintf("DELETE FROM %s%s", q.repo.table, whereSQL)
+	return q.repo.exec(ctx, sql, args...)
+}
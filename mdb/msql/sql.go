@@ -4,11 +4,14 @@ package msql
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
 
-	// If something is importing msql it must need mysql, because that's all
-	// that is implemented at the moment
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 	"github.com/mediocregopher/mediocre-go-lib/mcfg"
 	"github.com/mediocregopher/mediocre-go-lib/mcmp"
@@ -31,38 +34,161 @@ type SQL struct {
 // defaultDB indicates the name of the database in MySQL to use by default,
 // though it will be overwritable in the config.
 func InstMySQL(cmp *mcmp.Component, defaultDB string) *SQL {
-	sql := SQL{cmp: cmp.Child("mysql")}
+	return instSQL(cmp, "mysql", defaultDB)
+}
+
+// InstMariaDB is the same as InstMySQL, but the Component and log messages it
+// produces are named for MariaDB rather than MySQL. MariaDB speaks the same
+// wire protocol as MySQL, so the same driver and DSN options are used
+// underneath for both.
+func InstMariaDB(cmp *mcmp.Component, defaultDB string) *SQL {
+	return instSQL(cmp, "mariadb", defaultDB)
+}
+
+func instSQL(cmp *mcmp.Component, name, defaultDB string) *SQL {
+	sql := SQL{cmp: cmp.Child(name)}
 
 	addr := mcfg.String(sql.cmp, "addr",
 		mcfg.ParamDefault("[::1]:3306"),
-		mcfg.ParamUsage("Address where MySQL server can be found"))
+		mcfg.ParamUsage("Address where the server can be found"))
 	user := mcfg.String(sql.cmp, "user",
 		mcfg.ParamDefault("root"),
-		mcfg.ParamUsage("User to authenticate to MySQL server as"))
+		mcfg.ParamUsage("User to authenticate as"))
 	pass := mcfg.String(sql.cmp, "password",
-		mcfg.ParamUsage("Password to authenticate to MySQL server with"))
+		mcfg.ParamUsage("Password to authenticate with"))
 	db := mcfg.String(sql.cmp, "database",
 		mcfg.ParamDefault(defaultDB),
-		mcfg.ParamUsage("MySQL database to use"))
+		mcfg.ParamUsage("Database to use"))
+
+	tlsMode := mcfg.String(sql.cmp, "tls",
+		mcfg.ParamDefault("false"),
+		mcfg.ParamUsage("TLS mode to use for the connection. One of false, skip-verify, preferred, true, or custom"))
+	tlsCA := mcfg.String(sql.cmp, "tls-ca-file",
+		mcfg.ParamUsage("Path to a PEM encoded CA file, used when tls is set to custom"))
+	tlsCert := mcfg.String(sql.cmp, "tls-cert-file",
+		mcfg.ParamUsage("Path to a PEM encoded client certificate file, used when tls is set to custom"))
+	tlsKey := mcfg.String(sql.cmp, "tls-key-file",
+		mcfg.ParamUsage("Path to a PEM encoded client private key file, used when tls is set to custom"))
+
+	parseTime := mcfg.Bool(sql.cmp, "parse-time",
+		mcfg.ParamUsage("Parse DATE/DATETIME/TIMESTAMP columns into time.Time"))
+	loc := mcfg.String(sql.cmp, "loc",
+		mcfg.ParamUsage("Location name used to parse/format DATE/DATETIME/TIMESTAMP columns, e.g. UTC or Local"))
+	maxAllowedPacket := mcfg.Int(sql.cmp, "max-allowed-packet",
+		mcfg.ParamUsage("Max packet size, in bytes, allowed to be sent to or received from the server. 0 uses the driver's default"))
+	readTimeout := mcfg.Duration(sql.cmp, "read-timeout",
+		mcfg.ParamUsage("I/O read timeout. 0 means no timeout"))
+	writeTimeout := mcfg.Duration(sql.cmp, "write-timeout",
+		mcfg.ParamUsage("I/O write timeout. 0 means no timeout"))
+
+	maxOpenConns := mcfg.Int(sql.cmp, "max-open-conns",
+		mcfg.ParamUsage("Maximum number of open connections to the database. 0 means unlimited"))
+	maxIdleConns := mcfg.Int(sql.cmp, "max-idle-conns",
+		mcfg.ParamDefault(2),
+		mcfg.ParamUsage("Maximum number of idle connections to keep open"))
+	connMaxLifetime := mcfg.Duration(sql.cmp, "conn-max-lifetime",
+		mcfg.ParamUsage("Maximum amount of time a connection may be reused for. 0 means unlimited"))
 
 	mrun.InitHook(sql.cmp, func(ctx context.Context) error {
 		sql.cmp.Annotate("addr", *addr, "user", *user)
+
+		tlsConfigName, err := setupTLS(sql.cmp, *tlsMode, *tlsCA, *tlsCert, *tlsKey)
+		if err != nil {
+			return merr.Wrap(err, sql.cmp.Context(), ctx)
+		}
+
+		qs := url.Values{}
+		switch *tlsMode {
+		case "false", "skip-verify", "true":
+			qs.Set("tls", *tlsMode)
+		case "preferred":
+			// the underlying driver has no notion of opportunistic TLS, so
+			// the closest equivalent is to just always require it.
+			qs.Set("tls", "true")
+		case "custom":
+			qs.Set("tls", tlsConfigName)
+		}
+		if *parseTime {
+			qs.Set("parseTime", "true")
+		}
+		if *loc != "" {
+			qs.Set("loc", *loc)
+		}
+		if *maxAllowedPacket > 0 {
+			qs.Set("maxAllowedPacket", fmt.Sprint(*maxAllowedPacket))
+		}
+		if readTimeout.Duration > 0 {
+			qs.Set("readTimeout", readTimeout.Duration.String())
+		}
+		if writeTimeout.Duration > 0 {
+			qs.Set("writeTimeout", writeTimeout.Duration.String())
+		}
+
 		dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s", *user, *pass, *addr, *db)
+		if enc := qs.Encode(); enc != "" {
+			dsn += "?" + enc
+		}
+
 		mlog.From(sql.cmp).Debug("constructed dsn", mctx.Annotate(ctx, "dsn", dsn))
-		mlog.From(sql.cmp).Info("connecting to MySQL server", ctx)
-		var err error
+		mlog.From(sql.cmp).Info("connecting to server", ctx)
 		sql.DB, err = sqlx.ConnectContext(ctx, "mysql", dsn)
-		return merr.Wrap(err, sql.cmp.Context(), ctx)
+		if err != nil {
+			return merr.Wrap(err, sql.cmp.Context(), ctx)
+		}
+
+		sql.DB.SetMaxOpenConns(*maxOpenConns)
+		sql.DB.SetMaxIdleConns(*maxIdleConns)
+		sql.DB.SetConnMaxLifetime(connMaxLifetime.Duration)
+
+		return nil
 	})
 
 	mrun.ShutdownHook(sql.cmp, func(ctx context.Context) error {
-		mlog.From(sql.cmp).Info("closing connection to MySQL server", ctx)
+		mlog.From(sql.cmp).Info("closing connection to server", ctx)
 		return merr.Wrap(sql.Close(), sql.cmp.Context(), ctx)
 	})
 
 	return &sql
 }
 
+// setupTLS, when mode is "custom", loads the given CA (and, if given, client
+// certificate/key) files, registers a *tls.Config under a name derived from
+// cmp's Path, and returns that name for use as the tls DSN parameter. For any
+// other mode this is a no-op.
+func setupTLS(cmp *mcmp.Component, mode, caFile, certFile, keyFile string) (string, error) {
+	if mode != "custom" {
+		return "", nil
+	}
+
+	cfg := new(tls.Config)
+
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return "", merr.Wrap(err, cmp.Context())
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return "", merr.Wrap(fmt.Errorf("no valid certificates found in %q", caFile), cmp.Context())
+		}
+		cfg.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return "", merr.Wrap(err, cmp.Context())
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	name := strings.Join(cmp.Path(), ".") + ".tls"
+	if err := mysql.RegisterTLSConfig(name, cfg); err != nil {
+		return "", merr.Wrap(err, cmp.Context())
+	}
+	return name, nil
+}
+
 // Context returns the annotated Context from this instance's initialization.
 func (sql *SQL) Context() context.Context {
 	return sql.cmp.Context()
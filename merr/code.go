@@ -0,0 +1,94 @@
+package merr
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Code identifies a stable, cross-process category of error (e.g. so an RPC
+// client receiving an error can act on it programmatically: retry, map to an
+// HTTP status, etc...), as opposed to the error's message, which is intended
+// for human consumption only.
+//
+// A Code's zero value is not valid; Codes are obtained via RegisterCode.
+type Code struct {
+	id string
+}
+
+// String returns the stable identifier the Code was registered under.
+func (c Code) String() string {
+	return c.id
+}
+
+var (
+	codeRegistryL sync.RWMutex
+	codeRegistry  = map[string]Code{}
+)
+
+// RegisterCode returns a Code which serializes (via KV, Serialize, or any RPC
+// transport built on top of them) as the given id. The id is expected to be
+// stable across the lifetime of the process, and ideally across versions of
+// it as well, since it may be relied on by remote clients.
+//
+// RegisterCode is expected to be called from an init function, or as part of
+// a package-level var declaration. It panics if id has already been
+// registered, since ids need to be unique across the whole process in order
+// to be useful for cross-process classification.
+func RegisterCode(id string) Code {
+	codeRegistryL.Lock()
+	defer codeRegistryL.Unlock()
+	if _, ok := codeRegistry[id]; ok {
+		panic(fmt.Sprintf("merr: Code %q already registered", id))
+	}
+	c := Code{id: id}
+	codeRegistry[id] = c
+	return c
+}
+
+// CodeByID returns the Code previously returned by RegisterCode for the given
+// id, if any. This is primarily useful when deserializing a Code which
+// crossed a process boundary as a plain string (see Deserialize).
+func CodeByID(id string) (Code, bool) {
+	codeRegistryL.RLock()
+	defer codeRegistryL.RUnlock()
+	c, ok := codeRegistry[id]
+	return c, ok
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// codeErr attaches a Code to an error. It implements Unwrap, so that a Code
+// can be attached to an error without hiding it from errors.Is/errors.As.
+type codeErr struct {
+	error
+	code Code
+}
+
+// Unwrap implements the interface used by errors.Is/errors.As.
+func (ce codeErr) Unwrap() error {
+	return ce.error
+}
+
+// WithCode returns a copy of err which carries code, retrievable via CodeOf.
+// The returned error still wraps err (per errors.Unwrap), so errors.Is and
+// errors.As against it behave exactly as they would against err directly.
+//
+// Wrapping nil returns nil.
+func WithCode(err error, code Code) error {
+	if err == nil {
+		return nil
+	}
+	return codeErr{error: err, code: code}
+}
+
+// CodeOf returns the Code attached to err via WithCode, and whether err
+// carries one at all. If err was wrapped with WithCode multiple times, the
+// outermost (most recently attached) Code is returned.
+func CodeOf(err error) (Code, bool) {
+	var ce codeErr
+	if !errors.As(err, &ce) {
+		return Code{}, false
+	}
+	return ce.code, true
+}
@@ -0,0 +1,70 @@
+package merr
+
+import (
+	"errors"
+	. "testing"
+
+	"github.com/mediocregopher/mediocre-go-lib/mtest/massert"
+)
+
+func TestCode(t *T) {
+	codeFoo := RegisterCode("merr_test.foo")
+
+	base := errors.New("foo")
+	withCode := WithCode(base, codeFoo)
+
+	gotCode, ok := CodeOf(withCode)
+	massert.Fatal(t, massert.Comment(
+		massert.All(
+			massert.Equal(true, ok),
+			massert.Equal(codeFoo, gotCode),
+			massert.Equal(true, errors.Is(withCode, base)),
+		),
+		"withCode:%#v", withCode,
+	))
+
+	if _, ok := CodeOf(base); ok {
+		t.Fatal("base shouldn't have a Code attached")
+	}
+
+	gotByID, ok := CodeByID("merr_test.foo")
+	massert.Fatal(t, massert.All(
+		massert.Equal(true, ok),
+		massert.Equal(codeFoo, gotByID),
+	))
+}
+
+func TestSerializeDeserialize(t *T) {
+	codeBar := RegisterCode("merr_test.bar")
+
+	er := WithValue(errors.New("bar"), "baz", "buz", true)
+	er = WithValue(er, "invisible", "shh", false)
+	er = WithCode(er, codeBar)
+
+	se := Serialize(er)
+	massert.Fatal(t, massert.Comment(
+		massert.All(
+			massert.Equal("merr_test.bar", se.Code),
+			massert.Equal("bar", se.Message),
+			massert.Equal("buz", se.VisibleKV["baz"]),
+			massert.Equal(true, se.StackTop != ""),
+		),
+		"se:%#v", se,
+	))
+	if _, ok := se.VisibleKV["invisible"]; ok {
+		t.Fatal("invisible KV shouldn't have been serialized")
+	}
+
+	er2 := Deserialize(se)
+	gotCode, ok := CodeOf(er2)
+	kv2 := KV(er2).KV()
+	massert.Fatal(t, massert.Comment(
+		massert.All(
+			massert.Equal("bar", er2.Error()),
+			massert.Equal(true, ok),
+			massert.Equal(codeBar, gotCode),
+			massert.Equal("buz", kv2["baz"]),
+		),
+		"er2:%#v kv2:%#v", er2, kv2,
+	))
+}
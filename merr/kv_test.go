@@ -1,6 +1,7 @@
 package merr
 
 import (
+	"errors"
 	"strings"
 	. "testing"
 
@@ -15,7 +16,7 @@ func TestKV(t *T) {
 		massert.Len(KV(nil).KV(), 0),
 	))
 
-	er := New("foo", "bar", "baz")
+	er := WithValue(errors.New("foo"), "bar", "baz", true)
 	kv := KV(er).KV()
 	massert.Fatal(t, massert.Comment(
 		massert.All(
@@ -96,3 +97,28 @@ func TestKV(t *T) {
 		"kv: %#v", kv,
 	))
 }
+
+// TestWithValueStackDedup covers the case where e isn't itself a wrapped
+// (e.g. because WithCode sits between two WithValue calls), but already has
+// a Stack further down its Unwrap chain. Re-wrapping from the exact same
+// call site shouldn't recapture the Stack, while re-wrapping from a
+// different one should.
+func TestWithValueStackDedup(t *T) {
+	annotate := func(e error) error { return WithValue(e, "k", "v", true) }
+
+	er := annotate(errors.New("foo"))
+	er = WithCode(er, RegisterCode("merr_test.withValueStackDedup"))
+
+	sameSite := annotate(er)
+	diffSite := WithValue(er, "k", "v", true)
+
+	srcOrig := KV(er).KV()["errSrc"]
+	massert.Fatal(t, massert.Comment(
+		massert.All(
+			massert.Equal(srcOrig, KV(sameSite).KV()["errSrc"]),
+			massert.Equal(true, KV(diffSite).KV()["errSrc"] != srcOrig),
+		),
+		"srcOrig:%v sameSite:%v diffSite:%v",
+		srcOrig, KV(sameSite).KV()["errSrc"], KV(diffSite).KV()["errSrc"],
+	))
+}
@@ -0,0 +1,116 @@
+package merr
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// MultiError aggregates multiple non-nil errors into a single error value.
+// It's returned by Append once more than one error remains after flattening
+// and dropping nils, and is what Group.Wait returns when more than one of its
+// goroutines failed.
+type MultiError []error
+
+// Error implements the error interface, joining together the Error string of
+// every child error. Each child's message is indented beneath its own
+// bullet, using the pooled strings.Builder and the same continuation-line
+// indentation Error.Error uses for multi-line annotation values.
+func (me MultiError) Error() string {
+	sb := strBuilderPool.Get().(*strings.Builder)
+	defer putStrBuilder(sb)
+
+	fmt.Fprintf(sb, "%d errors occurred:", len(me))
+	for _, err := range me {
+		sb.WriteString("\n\t* ")
+		lines := strings.Split(strings.TrimSpace(err.Error()), "\n")
+		for i, line := range lines {
+			if i > 0 {
+				sb.WriteString("\n\t\t")
+			}
+			sb.WriteString(strings.TrimSpace(line))
+		}
+	}
+
+	return sb.String()
+}
+
+// Unwrap implements the interface used by Go 1.20+'s errors.Is and
+// errors.As, so that they traverse every error in me in turn.
+func (me MultiError) Unwrap() []error {
+	return []error(me)
+}
+
+// Append flattens existing (which may be nil) and errs together into a
+// single error, dropping nils and flattening any MultiErrors found amongst
+// them so that a MultiError is never nested within another.
+//
+// If every error is nil then nil is returned. If exactly one error remains
+// then it's returned as-is, unwrapped from any MultiError. Otherwise a
+// MultiError of all remaining errors, in the order given, is returned.
+func Append(existing error, errs ...error) error {
+	var all []error
+	collect := func(err error) {
+		if err == nil {
+			return
+		} else if me, ok := err.(MultiError); ok {
+			all = append(all, me...)
+			return
+		}
+		all = append(all, err)
+	}
+
+	collect(existing)
+	for _, err := range errs {
+		collect(err)
+	}
+
+	switch len(all) {
+	case 0:
+		return nil
+	case 1:
+		return all[0]
+	default:
+		return MultiError(all)
+	}
+}
+
+// Group runs a set of goroutines which are all part of the same overall
+// task, analogous to golang.org/x/sync/errgroup.Group. Unlike errgroup.Group,
+// Wait returns every error encountered, not just the first.
+//
+// Group's zero value is ready to use.
+type Group struct {
+	wg sync.WaitGroup
+	l  sync.Mutex
+	// accumulates every non-nil error returned by a Go'd function, merged
+	// together via Append once Wait is called.
+	err error
+}
+
+// Go calls fn in a new goroutine. If fn returns a non-nil error it's first
+// merr.Wrap'd with ctx, so that annotations and a stacktrace specific to that
+// goroutine are preserved, and then collected to be returned from Wait.
+func (g *Group) Go(ctx context.Context, fn func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := fn(); err != nil {
+			err = WrapSkip(ctx, err, 1)
+			g.l.Lock()
+			g.err = Append(g.err, err)
+			g.l.Unlock()
+		}
+	}()
+}
+
+// Wait blocks until every goroutine started via Go has returned, and then
+// returns the aggregate of their errors: nil if none failed, the single
+// error if only one did, or a MultiError if more than one did.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.l.Lock()
+	defer g.l.Unlock()
+	return g.err
+}
@@ -0,0 +1,76 @@
+package merr
+
+import (
+	"context"
+	"errors"
+	. "testing"
+
+	"github.com/mediocregopher/mediocre-go-lib/mctx"
+	"github.com/mediocregopher/mediocre-go-lib/mtest/massert"
+)
+
+func TestAppend(t *T) {
+	errFoo := errors.New("foo")
+	errBar := errors.New("bar")
+
+	massert.Fatal(t, massert.Nil(Append(nil)))
+	massert.Fatal(t, massert.Nil(Append(nil, nil, nil)))
+	massert.Fatal(t, massert.Equal(errFoo, Append(nil, errFoo)))
+	massert.Fatal(t, massert.Equal(errFoo, Append(errFoo, nil)))
+
+	me, ok := Append(nil, errFoo, errBar).(MultiError)
+	massert.Fatal(t, massert.Comment(
+		massert.All(massert.Equal(true, ok), massert.Equal(MultiError{errFoo, errBar}, me)),
+		"me:%#v ok:%v", me, ok,
+	))
+
+	// appending a MultiError onto more errors should flatten, never nest
+	flattened := Append(me, errors.New("baz"))
+	massert.Fatal(t, massert.Equal(
+		MultiError{errFoo, errBar, errors.New("baz")},
+		flattened,
+	))
+
+	massert.Fatal(t, massert.Equal(true, errors.Is(me, errFoo)))
+	massert.Fatal(t, massert.Equal(true, errors.Is(me, errBar)))
+}
+
+func TestMultiErrorError(t *T) {
+	me := MultiError{errors.New("foo"), errors.New("bar\nbaz")}
+	exp := `2 errors occurred:
+	* foo
+	* bar
+		baz`
+	massert.Fatal(t, massert.Equal(exp, me.Error()))
+}
+
+func TestGroup(t *T) {
+	errFoo := errors.New("foo")
+	errBar := errors.New("bar")
+
+	var g Group
+	ctxFoo := mctx.Annotate(context.Background(), "which", "foo")
+	ctxBar := mctx.Annotate(context.Background(), "which", "bar")
+
+	g.Go(ctxFoo, func() error { return errFoo })
+	g.Go(ctxBar, func() error { return errBar })
+	g.Go(context.Background(), func() error { return nil })
+
+	err := g.Wait()
+	me, ok := err.(MultiError)
+	massert.Fatal(t, massert.Comment(
+		massert.All(massert.Equal(true, ok), massert.Equal(2, len(me))),
+		"err:%v", err,
+	))
+	massert.Fatal(t, massert.Equal(true, errors.Is(err, errFoo)))
+	massert.Fatal(t, massert.Equal(true, errors.Is(err, errBar)))
+
+	var g2 Group
+	g2.Go(context.Background(), func() error { return errFoo })
+	g2.Go(context.Background(), func() error { return nil })
+	massert.Fatal(t, massert.Equal(true, errors.Is(g2.Wait(), errFoo)))
+
+	var g3 Group
+	g3.Go(context.Background(), func() error { return nil })
+	massert.Fatal(t, massert.Nil(g3.Wait()))
+}
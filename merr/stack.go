@@ -52,6 +52,70 @@ func (s Stacktrace) Frames() []runtime.Frame {
 	return out
 }
 
+// framePackage returns the package portion of a runtime.Frame.Function
+// value, e.g. "github.com/.../merr" for "github.com/.../merr.WrapSkip" or
+// "github.com/.../merr.(*Error).Error".
+func framePackage(function string) string {
+	slash := strings.LastIndexByte(function, '/')
+	dot := strings.IndexByte(function[slash+1:], '.')
+	if dot < 0 {
+		return function
+	}
+	return function[:slash+1+dot]
+}
+
+// Filter returns a copy of the Stacktrace containing only those frames for
+// which keep returns true.
+func (s Stacktrace) Filter(keep func(runtime.Frame) bool) Stacktrace {
+	frames := make([]uintptr, 0, len(s.frames))
+	for _, pc := range s.frames {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		if keep(frame) {
+			frames = append(frames, pc)
+		}
+	}
+	return Stacktrace{frames: frames}
+}
+
+// TrimBelow returns a copy of the Stacktrace with every frame below (i.e.
+// further from the point the Stacktrace was captured than) the deepest
+// frame belonging to the package at pkgPrefix removed. This is useful for
+// cutting off runtime/testing scaffolding (go test's tRunner, runtime.main,
+// etc...) once the trace has unwound back out of the caller's own package.
+//
+// If no frame belongs to pkgPrefix, the Stacktrace is returned unchanged.
+func (s Stacktrace) TrimBelow(pkgPrefix string) Stacktrace {
+	cut := len(s.frames)
+	for i, pc := range s.frames {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		if framePackage(frame.Function) == pkgPrefix {
+			cut = i + 1
+		}
+	}
+	frames := make([]uintptr, cut)
+	copy(frames, s.frames[:cut])
+	return Stacktrace{frames: frames}
+}
+
+// TrimRuntime returns a copy of the Stacktrace with frames belonging to the
+// runtime, reflect, and testing packages filtered out, so that FullString
+// isn't dominated by runtime.goexit and test-framework frames that aren't
+// useful when tracking down where an error actually originated.
+func (s Stacktrace) TrimRuntime() Stacktrace {
+	return s.Filter(func(frame runtime.Frame) bool {
+		switch pkg := framePackage(frame.Function); {
+		case pkg == "runtime" || strings.HasPrefix(pkg, "runtime/"):
+			return false
+		case pkg == "reflect":
+			return false
+		case pkg == "testing" || strings.HasPrefix(pkg, "testing/"):
+			return false
+		default:
+			return true
+		}
+	})
+}
+
 // String returns a string representing the top-most frame of the stack.
 func (s Stacktrace) String() string {
 	if len(s.frames) == 0 {
@@ -7,42 +7,71 @@ import (
 	"github.com/mediocregopher/mediocre-go-lib/mtest/massert"
 )
 
-func TestStack(t *T) {
-	foo := New("test")
-	fooStack, ok := Stack(foo)
-	massert.Require(t, massert.Equal(true, ok))
+func TestStacktrace(t *T) {
+	st := newStacktrace(0)
 
 	// test Frame
-	frame := fooStack.Frame()
-	massert.Require(t,
+	frame := st.Frame()
+	massert.Fatal(t, massert.All(
 		massert.Equal(true, strings.Contains(frame.File, "stack_test.go")),
-		massert.Equal(true, strings.Contains(frame.Function, "TestStack")),
-	)
+		massert.Equal(true, strings.Contains(frame.Function, "TestStacktrace")),
+	))
 
-	frames := fooStack.Frames()
-	massert.Require(t, massert.Comment(
+	frames := st.Frames()
+	massert.Fatal(t, massert.Comment(
 		massert.All(
 			massert.Equal(true, len(frames) >= 2),
 			massert.Equal(true, strings.Contains(frames[0].File, "stack_test.go")),
-			massert.Equal(true, strings.Contains(frames[0].Function, "TestStack")),
+			massert.Equal(true, strings.Contains(frames[0].Function, "TestStacktrace")),
 		),
-		"fooStack.FullString():\n%s", fooStack.FullString(),
+		"st.FullString():\n%s", st.FullString(),
 	))
 
-	// test that WithStack works and can be used to skip frames
+	// test that newStacktrace can be used to skip frames
 	inner := func() {
-		bar := WithStack(foo, 1)
-		barStack, _ := Stack(bar)
-		frames := barStack.Frames()
-		massert.Require(t, massert.Comment(
+		barSt := newStacktrace(1)
+		barFrames := barSt.Frames()
+		massert.Fatal(t, massert.Comment(
 			massert.All(
-				massert.Equal(true, len(frames) >= 2),
-				massert.Equal(true, strings.Contains(frames[0].File, "stack_test.go")),
-				massert.Equal(true, strings.Contains(frames[0].Function, "TestStack")),
+				massert.Equal(true, len(barFrames) >= 2),
+				massert.Equal(true, strings.Contains(barFrames[0].File, "stack_test.go")),
+				massert.Equal(true, strings.Contains(barFrames[0].Function, "TestStacktrace")),
 			),
-			"barStack.FullString():\n%s", barStack.FullString(),
+			"barSt.FullString():\n%s", barSt.FullString(),
 		))
 	}
 	inner()
+}
+
+func TestStacktraceTrimBelow(t *T) {
+	st := newStacktrace(0)
+	trimmed := st.TrimBelow("github.com/mediocregopher/mediocre-go-lib/merr")
+
+	massert.Fatal(t, massert.Comment(
+		massert.All(
+			massert.Equal(true, len(trimmed.Frames()) < len(st.Frames())),
+			massert.Equal(true, strings.Contains(trimmed.Frame().File, "stack_test.go")),
+		),
+		"st.FullString():\n%s\ntrimmed.FullString():\n%s", st.FullString(), trimmed.FullString(),
+	))
+
+	// a pkgPrefix which matches nothing leaves the Stacktrace unchanged
+	untouched := st.TrimBelow("no/such/package")
+	massert.Fatal(t, massert.Equal(len(st.Frames()), len(untouched.Frames())))
+}
+
+func TestStacktraceTrimRuntime(t *T) {
+	st := newStacktrace(0)
+	trimmed := st.TrimRuntime()
+
+	for _, frame := range trimmed.Frames() {
+		massert.Fatal(t, massert.Comment(
+			massert.Equal(false, strings.HasPrefix(frame.Function, "runtime.")),
+			"frame:%#v", frame,
+		))
+	}
 
+	// TestStacktraceTrimRuntime's own frame (this one) should survive the
+	// filter, since it isn't a runtime/reflect/testing frame.
+	massert.Fatal(t, massert.Equal(true, len(trimmed.Frames()) > 0))
 }
@@ -0,0 +1,82 @@
+package merr
+
+// SerializedError is a transport-neutral representation of an error, as
+// produced by Serialize, suitable for carrying across an RPC boundary (e.g.
+// set into a Response's mrpc.Debug) without losing the Code, the visible KVs,
+// or the top stack frame that KV already extracts.
+type SerializedError struct {
+	Code      string                 `json:"code,omitempty"`
+	Message   string                 `json:"message"`
+	VisibleKV map[string]interface{} `json:"visible_kv,omitempty"`
+	StackTop  string                 `json:"stack_top,omitempty"`
+}
+
+// Serialize converts err into a SerializedError. If err is nil the zero
+// SerializedError is returned.
+func Serialize(err error) SerializedError {
+	if err == nil {
+		return SerializedError{}
+	}
+
+	se := SerializedError{Message: err.Error()}
+	if code, ok := CodeOf(err); ok {
+		se.Code = code.String()
+	}
+
+	kv := KV(err).KV()
+	delete(kv, string(attrKeyErr))
+	if stackTop, ok := kv[string(attrKeyErrSrc)]; ok {
+		se.StackTop, _ = stackTop.(string)
+		delete(kv, string(attrKeyErrSrc))
+	}
+	if len(kv) > 0 {
+		se.VisibleKV = kv
+	}
+
+	return se
+}
+
+// attrKeyRemoteStackTop is the KV key Deserialize stores se.StackTop under.
+// It's deliberately distinct from attrKeyErrSrc, which KV always repopulates
+// from a live Stack captured at the point an error is wrapped (here, inside
+// Deserialize itself) rather than from an arbitrary stored string.
+const attrKeyRemoteStackTop attrKey = "remoteErrSrc"
+
+// Deserialize is the inverse of Serialize: it reconstructs an error carrying
+// se's Code (if any), its visible KVs, and se's StackTop (under the
+// "remoteErrSrc" KV key, to distinguish it from the errSrc of the
+// reconstructed error itself, which reflects where Deserialize was called).
+//
+// The returned error is not the same error which was originally Serialized
+// (e.g. its stacktrace is reduced to just the top frame, and errors.Is/As
+// against anything other than the returned error itself won't succeed); it's
+// meant to let a Client report a structured error it received, not to
+// reconstitute the exact error a Server encountered.
+func Deserialize(se SerializedError) error {
+	var err error = plainError(se.Message)
+
+	if se.StackTop != "" {
+		err = WithValue(err, attrKeyRemoteStackTop, se.StackTop, true)
+	}
+	for k, v := range se.VisibleKV {
+		err = WithKV(err, map[string]interface{}{k: v})
+	}
+	if se.Code != "" {
+		code, ok := CodeByID(se.Code)
+		if !ok {
+			code = Code{id: se.Code}
+		}
+		err = WithCode(err, code)
+	}
+
+	return err
+}
+
+// plainError is a bare string error, used as the base of a Deserialize'd
+// error (analogous to errors.New, but named distinctly since it's only ever
+// constructed internally).
+type plainError string
+
+func (e plainError) Error() string {
+	return string(e)
+}
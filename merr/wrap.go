@@ -0,0 +1,169 @@
+package merr
+
+import "runtime"
+
+// attrKey is the type used internally for the handful of attribute keys this
+// package itself reserves (as opposed to the arbitrary keys callers pass to
+// WithValue).
+type attrKey string
+
+// val is the value half of an attr entry, pairing the actual value with
+// whether it should be included in KV's output.
+type val struct {
+	val     interface{}
+	visible bool
+}
+
+// wrapped is the concrete error type returned by wrap. It implements Unwrap
+// so that errors.Is/errors.As continue to see through it to the original
+// error, even once attrs have been attached.
+type wrapped struct {
+	err  error
+	attr map[interface{}]val
+}
+
+// Error implements the error interface.
+func (w wrapped) Error() string {
+	return w.err.Error()
+}
+
+// Unwrap implements the interface used by errors.Is/errors.As, exposing the
+// original error wrap hides.
+func (w wrapped) Unwrap() error {
+	return w.err
+}
+
+// stackAttrKey is the attr key under which wrap embeds a Stack, so that KV
+// can find it and fold it into the errSrc field. It's an unexported, empty
+// struct type so it can never collide with a caller-supplied WithValue key.
+type stackAttrKey struct{}
+
+// wrap ensures e is a wrapped, embedding a Stack (captured skip frames above
+// the caller of wrap) as a reserved attr if one isn't already present.
+//
+// If create is false, e is never copied: if e is already a wrapped it's
+// returned as-is, and otherwise its attrs (if any are found further down e's
+// Unwrap chain, e.g. because e wraps a wrapped using some other error
+// wrapping mechanism, like WithCode) are merged read-only into a throwaway
+// wrapped, since the caller only intends to read attrs off of it, not set
+// any. If create is true and e is already a wrapped, a copy is returned with
+// its own attr map, so that setting a new attr doesn't affect the original
+// error; otherwise a new wrapped is created around e, seeded with whatever
+// attrs are found down e's Unwrap chain.
+func wrap(e error, create bool, skip int) wrapped {
+	if w, ok := e.(wrapped); ok {
+		if !create {
+			return w
+		}
+		attr := make(map[interface{}]val, len(w.attr)+1)
+		for k, v := range w.attr {
+			attr[k] = v
+		}
+		return wrapped{err: w.err, attr: attr}
+	}
+
+	if !create {
+		return wrapped{err: e, attr: findAttrs(e)}
+	}
+
+	found := findAttrs(e)
+	attr := make(map[interface{}]val, len(found)+1)
+	for k, v := range found {
+		attr[k] = v
+	}
+	setStack(attr, found, skip)
+	return wrapped{err: e, attr: attr}
+}
+
+// setStack records the caller's Stack into attr under stackAttrKey, unless
+// found already holds a Stack captured at the exact same PC (e.g. because e
+// was already wrapped, just not directly as a wrapped, so this is a
+// repeated WithValue/WithKV call from the same call site annotating the
+// same underlying error). In that case the existing entry is left in attr
+// untouched.
+//
+// Comparing PCs is done with a single cheap runtime.Caller lookup, so the
+// common repeated-annotation case never pays for capturing and storing a
+// redundant Stack.
+func setStack(attr map[interface{}]val, found map[interface{}]val, skip int) {
+	// +2: one frame for wrap (setStack's caller), one more so skip=0 lines
+	// up with wrap's own convention of 0 meaning "wrap's caller".
+	pc := callerPC(skip + 2)
+	if existing, ok := found[stackAttrKey{}]; ok {
+		if s, ok := existing.val.(Stack); ok && s.pc == pc {
+			return
+		}
+	}
+	attr[stackAttrKey{}] = val{val: Stack{pc: pc}, visible: true}
+}
+
+// findAttrs walks e's Unwrap chain (not including e itself) looking for any
+// wrapped values, merging all of their attrs together (with attrs found
+// earlier in the chain, i.e. closer to e, taking precedence on key
+// conflicts). This lets KV and GetValue see attrs attached via WithValue
+// even if the error has since been wrapped by some other mechanism which
+// isn't itself a wrapped, e.g. WithCode.
+func findAttrs(e error) map[interface{}]val {
+	var attr map[interface{}]val
+	for {
+		u, ok := e.(interface{ Unwrap() error })
+		if !ok {
+			return attr
+		}
+		e = u.Unwrap()
+
+		w, ok := e.(wrapped)
+		if !ok {
+			continue
+		}
+
+		if attr == nil {
+			attr = make(map[interface{}]val, len(w.attr))
+		}
+		for k, v := range w.attr {
+			if _, ok := attr[k]; !ok {
+				attr[k] = v
+			}
+		}
+	}
+}
+
+// Stack represents the top of a stack trace, captured at the point an error
+// was first wrapped via WithValue, or re-captured when an error wrapped by
+// some other mechanism (e.g. WithCode) is annotated again from a different
+// call site (see setStack). It's embedded into every wrapped error as a
+// reserved attr, and compressed down to the errSrc field by KV.
+type Stack struct {
+	pc uintptr
+}
+
+// callerPC returns the PC of the frame skip levels above callerPC's own
+// caller (skip=0 meaning callerPC's direct caller), without allocating a
+// frames slice the way runtime.Callers would.
+func callerPC(skip int) uintptr {
+	pc, _, _, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return 0
+	}
+	return pc
+}
+
+// Frame returns the captured stack frame.
+func (s Stack) Frame() runtime.Frame {
+	frame, _ := runtime.CallersFrames([]uintptr{s.pc}).Next()
+	return frame
+}
+
+// kvKey is the attr key type used by WithKV, kept distinct from plain string
+// keys so that bulk-set values don't silently collide with ones set
+// individually via WithValue(e, "someKey", ...).
+type kvKey string
+
+// WithKV is a shortcut for calling WithValue for every key/value pair in kv,
+// with visible set to true.
+func WithKV(e error, kv map[string]interface{}) error {
+	for k, v := range kv {
+		e = WithValue(e, kvKey(k), v, true)
+	}
+	return e
+}
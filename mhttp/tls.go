@@ -0,0 +1,238 @@
+package mhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mediocregopher/mediocre-go-lib/mcfg"
+	"github.com/mediocregopher/mediocre-go-lib/mcmp"
+	"github.com/mediocregopher/mediocre-go-lib/merr"
+	"github.com/mediocregopher/mediocre-go-lib/mlog"
+	"github.com/mediocregopher/mediocre-go-lib/mnet"
+	"github.com/mediocregopher/mediocre-go-lib/mrun"
+	"github.com/mediocregopher/mediocre-go-lib/mtime"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// tlsOpts holds the options configurable via TLSOpt.
+type tlsOpts struct {
+	acmeCache autocert.Cache
+}
+
+// TLSOpt is a value which adjusts the behavior of InstListeningServerTLS.
+type TLSOpt func(*tlsOpts)
+
+// TLSACMECache sets the autocert.Cache used to persist ACME account keys and
+// certificates, used when tls-mode is acme. autocert.Cache is a minimal
+// key/value interface (Get/Put/Delete of a []byte by string key), so it's
+// straightforward to back with Consul, etcd, Redis, or any other KV store,
+// for deployments which run multiple instances behind the same hostnames and
+// want to share a single ACME account/certificate between them (similar to
+// how Traefik supports clustered ACME).
+//
+// If this isn't given, an autocert.DirCache rooted at acme-cache-dir is used.
+func TLSACMECache(cache autocert.Cache) TLSOpt {
+	return func(opts *tlsOpts) {
+		opts.acmeCache = cache
+	}
+}
+
+// InstListeningServerTLS is like InstListeningServer, but the returned
+// Server may additionally be configured to serve over TLS, either using a
+// static certificate/key pair loaded from disk or a certificate
+// automatically obtained (and renewed) via ACME, e.g. Let's Encrypt.
+//
+// The tls-mode config param selects between these:
+//
+//	off (default): identical to InstListeningServer, no TLS is used.
+//	file: TLS is served using the certificate/key pair given via the
+//	tls-cert/tls-key params.
+//	acme: TLS is served using a certificate obtained via ACME, configured
+//	by the acme-* params. A companion listener (see acme-http-addr) is
+//	started to serve the HTTP-01 challenge needed to obtain it, and a
+//	background renewal check (see acme-renew-check-interval) keeps the
+//	certificate renewed and annotates the Component with its expiry (see
+//	the "acmeCertExpiry" annotation) for logging.
+func InstListeningServerTLS(cmp *mcmp.Component, h http.Handler, tlsOptArgs ...TLSOpt) *Server {
+	var tOpts tlsOpts
+	for _, opt := range tlsOptArgs {
+		opt(&tOpts)
+	}
+
+	srv := &Server{
+		Server: &http.Server{Handler: h},
+		cmp:    cmp.Child("http"),
+	}
+
+	listener := mnet.InstListener(srv.cmp,
+		// http.Server.Shutdown will handle this
+		mnet.ListenerCloseOnShutdown(false),
+	)
+
+	tlsMode := mcfg.String(srv.cmp, "tls-mode",
+		mcfg.ParamDefault("off"),
+		mcfg.ParamUsage("TLS mode to serve with. One of off, file, or acme"))
+	tlsCert := mcfg.String(srv.cmp, "tls-cert",
+		mcfg.ParamUsage("Path to a PEM encoded certificate file, used when tls-mode is file"))
+	tlsKey := mcfg.String(srv.cmp, "tls-key",
+		mcfg.ParamUsage("Path to a PEM encoded private key file, used when tls-mode is file"))
+
+	acmeHosts := mcfg.String(srv.cmp, "acme-hosts",
+		mcfg.ParamUsage("Comma separated list of hostnames ACME is allowed to obtain certificates for, used when tls-mode is acme"))
+	acmeEmail := mcfg.String(srv.cmp, "acme-email",
+		mcfg.ParamUsage("Contact email address given to the ACME provider, used when tls-mode is acme"))
+	acmeCacheDir := mcfg.String(srv.cmp, "acme-cache-dir",
+		mcfg.ParamDefault("acme-cache"),
+		mcfg.ParamUsage("Directory certificates obtained via ACME are cached in between restarts, used when tls-mode is acme"))
+	acmeDirectoryURL := mcfg.String(srv.cmp, "acme-directory-url",
+		mcfg.ParamUsage("ACME directory endpoint to use. Defaults to the production Let's Encrypt endpoint; point this at a staging endpoint for testing, used when tls-mode is acme"))
+	acmeHTTPAddr := mcfg.String(srv.cmp, "acme-http-addr",
+		mcfg.ParamDefault(":80"),
+		mcfg.ParamUsage("Address the companion HTTP-01 challenge listener listens on, used when tls-mode is acme"))
+	acmeRenewCheckInterval := mcfg.Duration(srv.cmp, "acme-renew-check-interval",
+		mcfg.ParamDefault(mtime.Duration{12 * time.Hour}),
+		mcfg.ParamUsage("How often to check the ACME certificate's expiry and trigger autocert's renewal if it's due, used when tls-mode is acme"))
+
+	var acmeListener net.Listener
+	acmeRenewStop := make(chan struct{})
+	threadCtx := context.Background()
+	acmeThreadCtx := context.Background()
+	acmeRenewThreadCtx := context.Background()
+
+	mrun.InitHook(srv.cmp, func(ctx context.Context) error {
+		srv.Addr = listener.Addr().String()
+		srv.cmp.Annotate("tlsMode", *tlsMode)
+
+		switch *tlsMode {
+		case "off":
+
+		case "file":
+			cert, err := tls.LoadX509KeyPair(*tlsCert, *tlsKey)
+			if err != nil {
+				return merr.Wrap(err, srv.cmp.Context(), ctx)
+			}
+			srv.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+
+		case "acme":
+			acmeHostnames := strings.Split(*acmeHosts, ",")
+
+			cache := tOpts.acmeCache
+			if cache == nil {
+				cache = autocert.DirCache(*acmeCacheDir)
+			}
+
+			manager := &autocert.Manager{
+				Prompt:     autocert.AcceptTOS,
+				Cache:      cache,
+				Email:      *acmeEmail,
+				HostPolicy: autocert.HostWhitelist(acmeHostnames...),
+			}
+			if *acmeDirectoryURL != "" {
+				manager.Client = &acme.Client{DirectoryURL: *acmeDirectoryURL}
+			}
+			srv.TLSConfig = manager.TLSConfig()
+
+			var err error
+			acmeListener, err = net.Listen("tcp", *acmeHTTPAddr)
+			if err != nil {
+				return merr.Wrap(err, srv.cmp.Context(), ctx)
+			}
+
+			acmeThreadCtx = mrun.WithThreads(acmeThreadCtx, 1, func() error {
+				mlog.From(srv.cmp).Info("serving acme http-01 challenges", ctx)
+				err := http.Serve(acmeListener, manager.HTTPHandler(nil))
+				if !merr.Equal(err, http.ErrServerClosed) {
+					return merr.Wrap(err, srv.cmp.Context(), ctx)
+				}
+				return nil
+			})
+
+			acmeRenewThreadCtx = mrun.WithThreads(acmeRenewThreadCtx, 1, func() error {
+				annotateCertExpiry(ctx, srv.cmp, manager, acmeHostnames[0])
+
+				t := time.NewTicker((*acmeRenewCheckInterval).Duration)
+				defer t.Stop()
+				for {
+					select {
+					case <-t.C:
+						annotateCertExpiry(ctx, srv.cmp, manager, acmeHostnames[0])
+					case <-acmeRenewStop:
+						return nil
+					}
+				}
+			})
+
+		default:
+			return merr.Wrap(fmt.Errorf("unknown tls-mode %q", *tlsMode), srv.cmp.Context(), ctx)
+		}
+
+		threadCtx = mrun.WithThreads(threadCtx, 1, func() error {
+			mlog.From(srv.cmp).Info("serving requests", ctx)
+			var err error
+			if srv.TLSConfig != nil {
+				err = srv.ServeTLS(listener, "", "")
+			} else {
+				err = srv.Serve(listener)
+			}
+			if !merr.Equal(err, http.ErrServerClosed) {
+				mlog.From(srv.cmp).Error("error serving listener", ctx, merr.Context(err))
+				return merr.Wrap(err, srv.cmp.Context(), ctx)
+			}
+			return nil
+		})
+		return nil
+	})
+
+	mrun.ShutdownHook(srv.cmp, func(ctx context.Context) error {
+		mlog.From(srv.cmp).Info("shutting down server", ctx)
+		if err := srv.Shutdown(ctx); err != nil {
+			return merr.Wrap(err, srv.cmp.Context(), ctx)
+		}
+		if acmeListener != nil {
+			if err := acmeListener.Close(); err != nil {
+				return merr.Wrap(err, srv.cmp.Context(), ctx)
+			}
+			close(acmeRenewStop)
+		}
+		if err := mrun.Wait(threadCtx, ctx.Done()); err != nil {
+			return merr.Wrap(err, srv.cmp.Context(), ctx)
+		}
+		if err := mrun.Wait(acmeThreadCtx, ctx.Done()); err != nil {
+			return merr.Wrap(err, srv.cmp.Context(), ctx)
+		}
+		return merr.Wrap(mrun.Wait(acmeRenewThreadCtx, ctx.Done()), srv.cmp.Context(), ctx)
+	})
+
+	return srv
+}
+
+// annotateCertExpiry fetches the current ACME certificate for host from
+// manager (obtaining or renewing it if necessary, the same as a real TLS
+// handshake would), and annotates cmp with its expiry for logging. Calling
+// this periodically, rather than relying solely on the renewal check
+// autocert performs during a handshake, ensures certs are renewed even for a
+// host which isn't receiving regular traffic.
+func annotateCertExpiry(ctx context.Context, cmp *mcmp.Component, manager *autocert.Manager, host string) {
+	cert, err := manager.GetCertificate(&tls.ClientHelloInfo{ServerName: host})
+	if err != nil {
+		mlog.From(cmp).Warn("error fetching acme certificate", ctx, err)
+		return
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		mlog.From(cmp).Warn("error parsing acme certificate", ctx, err)
+		return
+	}
+
+	cmp.Annotate("acmeCertExpiry", leaf.NotAfter)
+	mlog.From(cmp).Info("acme certificate expiry", ctx)
+}
@@ -0,0 +1,250 @@
+package mlog
+
+import (
+	"errors"
+	"time"
+)
+
+// OverflowPolicy describes what an async MessageHandler (see
+// NewAsyncMessageHandler) should do when its internal buffer is full and a
+// new Message needs to be enqueued.
+type OverflowPolicy int
+
+const (
+	// Block causes the caller to block until space is available in the
+	// buffer.
+	Block OverflowPolicy = iota
+
+	// DropOldest causes the oldest buffered Message to be discarded in order
+	// to make room for the new one.
+	DropOldest
+
+	// DropNewest causes the new Message to be discarded if the buffer is
+	// full.
+	DropNewest
+
+	// BlockWithTimeout is like Block, but gives up and discards the new
+	// Message if AsyncOpts.BlockTimeout elapses first.
+	BlockWithTimeout
+)
+
+// AsyncOpts are parameters used to configure the behavior of a MessageHandler
+// returned by NewAsyncMessageHandler. All fields are optional.
+type AsyncOpts struct {
+	// BufferSize is the number of FullMessages which may be queued up,
+	// waiting to be handled by the inner MessageHandler, before Overflow
+	// takes effect.
+	//
+	// Defaults to 1024.
+	BufferSize int
+
+	// FlushInterval is the maximum amount of time which may pass before
+	// queued FullMessages are flushed to the inner MessageHandler, even if
+	// MaxBatchSize hasn't been reached yet.
+	//
+	// Defaults to 1 second.
+	FlushInterval time.Duration
+
+	// MaxBatchSize is the maximum number of FullMessages which will be
+	// handled by the inner MessageHandler in a single call.
+	//
+	// Defaults to 100.
+	MaxBatchSize int
+
+	// Overflow determines what happens when a FullMessage is enqueued but
+	// BufferSize has already been reached.
+	//
+	// Defaults to Block.
+	Overflow OverflowPolicy
+
+	// BlockTimeout is used when Overflow is BlockWithTimeout, and determines
+	// how long to wait for buffer space before giving up on enqueuing a
+	// FullMessage.
+	//
+	// Defaults to 1 second.
+	BlockTimeout time.Duration
+}
+
+func (o AsyncOpts) withDefaults() AsyncOpts {
+	if o.BufferSize == 0 {
+		o.BufferSize = 1024
+	}
+	if o.FlushInterval == 0 {
+		o.FlushInterval = time.Second
+	}
+	if o.MaxBatchSize == 0 {
+		o.MaxBatchSize = 100
+	}
+	if o.BlockTimeout == 0 {
+		o.BlockTimeout = time.Second
+	}
+	return o
+}
+
+type batchMessageHandler interface {
+	MessageHandler
+	HandleBatch([]FullMessage) error
+}
+
+// batchAdapter lets any MessageHandler be driven with batches of
+// FullMessages, by calling Handle on each one in turn.
+type batchAdapter struct {
+	MessageHandler
+}
+
+func (b batchAdapter) HandleBatch(msgs []FullMessage) error {
+	for _, msg := range msgs {
+		if err := b.Handle(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type asyncMessageHandler struct {
+	inner  batchMessageHandler
+	opts   AsyncOpts
+	msgCh  chan FullMessage
+	syncCh chan chan error
+}
+
+// NewAsyncMessageHandler wraps inner such that Handle enqueues the
+// FullMessage onto an internal buffer, rather than blocking on inner.Handle
+// directly, and a background goroutine drains that buffer into inner in
+// batches (bounded by AsyncOpts.MaxBatchSize and flushed at least every
+// AsyncOpts.FlushInterval).
+//
+// What happens when the buffer is full is controlled by AsyncOpts.Overflow.
+//
+// Fatal Messages (Level.Int() < 0) bypass the buffer entirely: they, and
+// everything enqueued ahead of them, are flushed to inner synchronously
+// before Handle returns, so that a subsequent os.Exit(1) doesn't race with
+// the background goroutine.
+//
+// Sync flushes all pending Messages to inner and waits for the background
+// goroutine to finish doing so before returning.
+func NewAsyncMessageHandler(inner MessageHandler, opts AsyncOpts) MessageHandler {
+	opts = opts.withDefaults()
+
+	bInner, ok := inner.(batchMessageHandler)
+	if !ok {
+		bInner = batchAdapter{inner}
+	}
+
+	h := &asyncMessageHandler{
+		inner:  bInner,
+		opts:   opts,
+		msgCh:  make(chan FullMessage, opts.BufferSize),
+		syncCh: make(chan chan error),
+	}
+	go h.loop()
+	return h
+}
+
+func (h *asyncMessageHandler) loop() {
+	batch := make([]FullMessage, 0, h.opts.MaxBatchSize)
+	ticker := time.NewTicker(h.opts.FlushInterval)
+	defer ticker.Stop()
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := h.inner.HandleBatch(batch)
+		batch = batch[:0]
+		return err
+	}
+
+	for {
+		select {
+		case msg, ok := <-h.msgCh:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, msg)
+			if len(batch) >= h.opts.MaxBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case replyCh := <-h.syncCh:
+			// drain whatever's currently queued without blocking, flushing
+			// every MaxBatchSize messages, then flush the remainder
+			var err error
+			for {
+				select {
+				case msg := <-h.msgCh:
+					batch = append(batch, msg)
+					if len(batch) >= h.opts.MaxBatchSize {
+						if fErr := flush(); err == nil {
+							err = fErr
+						}
+					}
+					continue
+				default:
+				}
+				break
+			}
+			if fErr := flush(); err == nil {
+				err = fErr
+			}
+			replyCh <- err
+		}
+	}
+}
+
+func (h *asyncMessageHandler) Handle(msg FullMessage) error {
+	if msg.Level.Int() < 0 {
+		// Fatal: flush everything queued so far, then this message,
+		// synchronously.
+		if err := h.Sync(); err != nil {
+			return err
+		}
+		return h.inner.HandleBatch([]FullMessage{msg})
+	}
+
+	switch h.opts.Overflow {
+	case DropNewest:
+		select {
+		case h.msgCh <- msg:
+		default:
+		}
+		return nil
+	case DropOldest:
+		select {
+		case h.msgCh <- msg:
+		default:
+			select {
+			case <-h.msgCh:
+			default:
+			}
+			select {
+			case h.msgCh <- msg:
+			default:
+			}
+		}
+		return nil
+	case BlockWithTimeout:
+		t := time.NewTimer(h.opts.BlockTimeout)
+		defer t.Stop()
+		select {
+		case h.msgCh <- msg:
+			return nil
+		case <-t.C:
+			return errors.New("mlog: async handler buffer full, message dropped")
+		}
+	default: // Block
+		h.msgCh <- msg
+		return nil
+	}
+}
+
+func (h *asyncMessageHandler) Sync() error {
+	replyCh := make(chan error, 1)
+	h.syncCh <- replyCh
+	if err := <-replyCh; err != nil {
+		return err
+	}
+	return h.inner.Sync()
+}
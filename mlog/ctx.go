@@ -2,57 +2,107 @@ package mlog
 
 import (
 	"context"
+	"strings"
+
+	"github.com/mediocregopher/mediocre-go-lib/merr"
+	"github.com/mediocregopher/mediocre-go-lib/v2/mctx"
 )
 
 type ctxKey int
 
-// WithLogger returns the Context with the Logger carried by it.
+// WithLogger returns a Context carrying l, for later retrieval via FromCtx.
 func WithLogger(ctx context.Context, l *Logger) context.Context {
 	return context.WithValue(ctx, ctxKey(0), l)
 }
 
-// DefaultLogger is an instance of Logger which is returned by From when a
-// Logger hasn't been previously WithLogger on the Contexts passed in.
-var DefaultLogger = NewLogger()
-
-// From looks at each context and returns the Logger from the first Context
-// which carries one via a WithLogger call. If none carry a Logger than
-// DefaultLogger is returned.
-func From(ctxs ...context.Context) *Logger {
-	for _, ctx := range ctxs {
-		if l, _ := ctx.Value(ctxKey(0)).(*Logger); l != nil {
-			return l
-		}
+// FromCtx returns the Logger previously stored on ctx (or one of its
+// ancestors) via WithLogger, or DefaultLogger if none was stored.
+func FromCtx(ctx context.Context) *Logger {
+	if l, _ := ctx.Value(ctxKey(0)).(*Logger); l != nil {
+		return l
 	}
 	return DefaultLogger
 }
 
-// Debug is a shortcut for
-//	mlog.From(ctxs...).Debug(desc, ctxs...)
-func Debug(descr string, ctxs ...context.Context) {
-	From(ctxs...).Debug(descr, ctxs...)
+// withPath annotates ctx with its mctx.Path, if it has one, so that the path
+// is included as a KV alongside any other annotations on every Message
+// logged using ctx.
+func withPath(ctx context.Context) context.Context {
+	if path := mctx.Path(ctx); len(path) > 0 {
+		ctx = mctx.Annotate(ctx, "path", strings.Join(path, "/"))
+	}
+	return ctx
+}
+
+// WithKV returns a Context with the given key/value pairs annotated onto it
+// (see mctx.Annotate), so that any subsequent logging call made using ctx
+// (directly or via a descendant Context) will include them without the
+// caller having to re-annotate them itself.
+func WithKV(ctx context.Context, kv KV) context.Context {
+	kvs := make([]interface{}, 0, len(kv)*2)
+	for k, v := range kv {
+		kvs = append(kvs, k, v)
+	}
+	return mctx.Annotate(ctx, kvs...)
+}
+
+// CtxWithErr returns a Context with err's KV (see merr.KV) annotated onto it,
+// so that any subsequent logging call made using ctx (directly or via a
+// descendant Context) includes err's visible key/values and top stack frame,
+// the same way Warn and Error do for an err passed to them directly. This is
+// primarily useful ahead of Debug, Info, or WarnString/ErrorString, none of
+// which take an error argument to begin with.
+//
+// A nil err is a no-op. An err which isn't from the merr package
+// (github.com/mediocregopher/mediocre-go-lib/merr) carries no key/values or
+// stack frame, so only its message is annotated, under the "err" key.
+func CtxWithErr(ctx context.Context, err error) context.Context {
+	if err == nil {
+		return ctx
+	}
+	kv := merr.KV(err).KV()
+	if len(kv) == 0 {
+		return ctx
+	}
+	return WithKV(ctx, KV(kv))
+}
+
+// Debug is a shortcut for FromCtx(ctx).Debug(withPath(ctx), descr).
+func Debug(ctx context.Context, descr string) {
+	FromCtx(ctx).Debug(withPath(ctx), descr)
+}
+
+// Info is a shortcut for FromCtx(ctx).Info(withPath(ctx), descr).
+func Info(ctx context.Context, descr string) {
+	FromCtx(ctx).Info(withPath(ctx), descr)
+}
+
+// WarnString is a shortcut for FromCtx(ctx).WarnString(withPath(ctx), descr).
+func WarnString(ctx context.Context, descr string) {
+	FromCtx(ctx).WarnString(withPath(ctx), descr)
+}
+
+// Warn is a shortcut for FromCtx(ctx).Warn(withPath(ctx), descr, err).
+func Warn(ctx context.Context, descr string, err error) {
+	FromCtx(ctx).Warn(withPath(ctx), descr, err)
 }
 
-// Info is a shortcut for
-//	mlog.From(ctxs...).Info(desc, ctxs...)
-func Info(descr string, ctxs ...context.Context) {
-	From(ctxs...).Info(descr, ctxs...)
+// ErrorString is a shortcut for FromCtx(ctx).ErrorString(withPath(ctx), descr).
+func ErrorString(ctx context.Context, descr string) {
+	FromCtx(ctx).ErrorString(withPath(ctx), descr)
 }
 
-// Warn is a shortcut for
-//	mlog.From(ctxs...).Warn(desc, ctxs...)
-func Warn(descr string, ctxs ...context.Context) {
-	From(ctxs...).Warn(descr, ctxs...)
+// Error is a shortcut for FromCtx(ctx).Error(withPath(ctx), descr, err).
+func Error(ctx context.Context, descr string, err error) {
+	FromCtx(ctx).Error(withPath(ctx), descr, err)
 }
 
-// Error is a shortcut for
-//	mlog.From(ctxs...).Error(desc, ctxs...)
-func Error(descr string, ctxs ...context.Context) {
-	From(ctxs...).Error(descr, ctxs...)
+// FatalString is a shortcut for FromCtx(ctx).FatalString(withPath(ctx), descr).
+func FatalString(ctx context.Context, descr string) {
+	FromCtx(ctx).FatalString(withPath(ctx), descr)
 }
 
-// Fatal is a shortcut for
-//	mlog.From(ctxs...).Fatal(desc, ctxs...)
-func Fatal(descr string, ctxs ...context.Context) {
-	From(ctxs...).Fatal(descr, ctxs...)
+// Fatal is a shortcut for FromCtx(ctx).Fatal(withPath(ctx), descr, err).
+func Fatal(ctx context.Context, descr string, err error) {
+	FromCtx(ctx).Fatal(withPath(ctx), descr, err)
 }
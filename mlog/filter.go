@@ -0,0 +1,191 @@
+package mlog
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mediocregopher/mediocre-go-lib/v2/mctx"
+)
+
+// FilterLogger wraps a *Logger, replacing its single global MaxLevel with a
+// hierarchical policy: each Message is allowed through based on whichever
+// configured path's rule is the longest matching prefix of the Message's
+// Context path (see mctx.Path), falling back to a default Level if no rule
+// matches.
+//
+// This is analogous to tendermint's log/filter.go, adapted to use
+// mediocre-go-lib's Context-driven Component paths rather than key/value
+// pairs. It's useful for services with many Components, where some
+// Components' logs need a different verbosity than the rest, e.g.
+// "foo/bar=DEBUG" while everything else stays at the default of INFO.
+//
+// The wrapped Logger's own MaxLevel should be set permissively (at least as
+// permissive as the most verbose Level any rule allows), since FilterLogger
+// applies its policy in addition to, not instead of, the wrapped Logger's
+// own MaxLevel check.
+type FilterLogger struct {
+	logger *Logger
+	def    Level
+	rules  []filterRule
+}
+
+type filterRule struct {
+	path []string
+	lvl  Level
+}
+
+// NewFilterLogger initializes a FilterLogger which wraps logger, using def
+// as the Level for any Component path not covered more specifically by
+// rules. rules maps a "/"-joined Component path (as given by mctx.Path) to
+// the Level which should apply to it and (unless overridden) its
+// descendants.
+func NewFilterLogger(logger *Logger, def Level, rules map[string]Level) *FilterLogger {
+	fl := &FilterLogger{logger: logger, def: def}
+
+	for pathStr, lvl := range rules {
+		var path []string
+		if pathStr != "" {
+			path = strings.Split(pathStr, "/")
+		}
+		fl.rules = append(fl.rules, filterRule{path: path, lvl: lvl})
+	}
+
+	// longest (most specific) paths are checked first.
+	sort.Slice(fl.rules, func(i, j int) bool {
+		return len(fl.rules[i].path) > len(fl.rules[j].path)
+	})
+
+	return fl
+}
+
+func pathHasPrefix(path, prefix []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (fl *FilterLogger) levelFor(path []string) Level {
+	for _, rule := range fl.rules {
+		if pathHasPrefix(path, rule.path) {
+			return rule.lvl
+		}
+	}
+	return fl.def
+}
+
+// V returns true if a Message of the given Level, with the given Context,
+// would actually be emitted by this FilterLogger (see Logger.V).
+func (fl *FilterLogger) V(ctx context.Context, lvl Level) bool {
+	return fl.levelFor(mctx.Path(ctx)).Int() >= lvl.Int()
+}
+
+// Log is the FilterLogger equivalent of Logger.Log: it gates msg against the
+// hierarchical policy (using mctx.Path(msg.Context)), forwarding it to the
+// wrapped Logger only if it passes.
+func (fl *FilterLogger) Log(msg Message) {
+	if !fl.V(msg.Context, msg.Level) {
+		return
+	}
+	fl.logger.Log(msg)
+}
+
+// Debug logs a LevelDebug message, subject to the hierarchical policy.
+func (fl *FilterLogger) Debug(ctx context.Context, descr string) {
+	fl.Log(mkMsg(ctx, LevelDebug, descr))
+}
+
+// Info logs a LevelInfo message, subject to the hierarchical policy.
+func (fl *FilterLogger) Info(ctx context.Context, descr string) {
+	fl.Log(mkMsg(ctx, LevelInfo, descr))
+}
+
+// WarnString logs a LevelWarn message which is only a string, subject to the
+// hierarchical policy.
+func (fl *FilterLogger) WarnString(ctx context.Context, descr string) {
+	fl.Log(mkMsg(ctx, LevelWarn, descr))
+}
+
+// Warn logs a LevelWarn message, including information from the given
+// error, subject to the hierarchical policy.
+func (fl *FilterLogger) Warn(ctx context.Context, descr string, err error) {
+	fl.Log(mkErrMsg(ctx, LevelWarn, descr, err))
+}
+
+// ErrorString logs a LevelError message which is only a string, subject to
+// the hierarchical policy.
+func (fl *FilterLogger) ErrorString(ctx context.Context, descr string) {
+	fl.Log(mkMsg(ctx, LevelError, descr))
+}
+
+// Error logs a LevelError message, including information from the given
+// error, subject to the hierarchical policy.
+func (fl *FilterLogger) Error(ctx context.Context, descr string, err error) {
+	fl.Log(mkErrMsg(ctx, LevelError, descr, err))
+}
+
+// FatalString logs a LevelFatal message which is only a string, subject to
+// the hierarchical policy. A Fatal message automatically stops the process
+// with an os.Exit(1) if the default MessageHandler is used.
+func (fl *FilterLogger) FatalString(ctx context.Context, descr string) {
+	fl.Log(mkMsg(ctx, LevelFatal, descr))
+}
+
+// Fatal logs a LevelFatal message, subject to the hierarchical policy. A
+// Fatal message automatically stops the process with an os.Exit(1) if the
+// default MessageHandler is used.
+func (fl *FilterLogger) Fatal(ctx context.Context, descr string, err error) {
+	fl.Log(mkErrMsg(ctx, LevelFatal, descr, err))
+}
+
+// ParseFilterSpec parses a comma-separated hierarchical level policy, as
+// might be given via a config parameter, into the def and rules arguments
+// expected by NewFilterLogger. Each element is either a bare Level, giving
+// the default (there must be exactly one of these), or a "path=LEVEL" pair,
+// e.g.:
+//
+//	INFO,foo/bar=DEBUG,foo/baz=WARN
+func ParseFilterSpec(spec string) (Level, map[string]Level, error) {
+	var def Level
+	rules := map[string]Level{}
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		path, lvlStr, isRule := strings.Cut(part, "=")
+
+		lvl := LevelFromString(lvlStr)
+		if !isRule {
+			lvl = LevelFromString(path)
+		}
+		if lvl == nil {
+			return nil, nil, fmt.Errorf("invalid level in filter spec %q", part)
+		}
+
+		if !isRule {
+			if def != nil {
+				return nil, nil, fmt.Errorf("filter spec %q has more than one default level", spec)
+			}
+			def = lvl
+			continue
+		}
+
+		rules[path] = lvl
+	}
+
+	if def == nil {
+		return nil, nil, fmt.Errorf("filter spec %q is missing a default level", spec)
+	}
+
+	return def, rules, nil
+}
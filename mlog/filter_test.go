@@ -0,0 +1,59 @@
+package mlog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	. "testing"
+
+	"github.com/mediocregopher/mediocre-go-lib/v2/mctx"
+	"github.com/mediocregopher/mediocre-go-lib/v2/mtest/massert"
+)
+
+func TestFilterLogger(t *T) {
+	buf := new(bytes.Buffer)
+	l := NewLogger(&LoggerOpts{
+		MessageHandler: NewMessageHandler(buf),
+		MaxLevel:       LevelDebug.Int(),
+	})
+
+	fl := NewFilterLogger(l, LevelInfo, map[string]Level{
+		"foo/bar": LevelDebug,
+		"foo/baz": LevelWarn,
+	})
+
+	ctx := context.Background()
+	fooCtx := mctx.NewChild(ctx, "foo")
+	barCtx := mctx.NewChild(fooCtx, "bar")
+	bazCtx := mctx.NewChild(fooCtx, "baz")
+
+	fl.Debug(ctx, "root debug")       // suppressed, default is INFO
+	fl.Info(ctx, "root info")         // allowed
+	fl.Debug(barCtx, "bar debug")     // allowed, foo/bar is DEBUG
+	fl.Info(bazCtx, "baz info")       // suppressed, foo/baz is WARN
+	fl.WarnString(bazCtx, "baz warn") // allowed
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	massert.Require(t,
+		massert.Len(lines, 3),
+		massert.Equal(true, strings.Contains(lines[0], "root info")),
+		massert.Equal(true, strings.Contains(lines[1], "bar debug")),
+		massert.Equal(true, strings.Contains(lines[2], "baz warn")),
+	)
+}
+
+func TestParseFilterSpec(t *T) {
+	def, rules, err := ParseFilterSpec("INFO,foo/bar=DEBUG,foo/baz=WARN")
+	massert.Require(t,
+		massert.Nil(err),
+		massert.Equal(LevelInfo, def),
+		massert.Equal(LevelDebug, rules["foo/bar"]),
+		massert.Equal(LevelWarn, rules["foo/baz"]),
+	)
+
+	_, _, err = ParseFilterSpec("foo/bar=DEBUG")
+	massert.Require(t, massert.Equal(true, err != nil))
+
+	_, _, err = ParseFilterSpec("INFO,foo/bar=NOTALEVEL")
+	massert.Require(t, massert.Equal(true, err != nil))
+}
@@ -0,0 +1,243 @@
+package mlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/mediocregopher/mediocre-go-lib/v2/mctx"
+)
+
+// HumanFormatterName, JSONFormatterName, and LogfmtFormatterName are the
+// names under which this package's built-in Formatters are registered (see
+// RegisterFormatter and NewMessageHandlerByName).
+const (
+	HumanFormatterName  = "human"
+	JSONFormatterName   = "json"
+	LogfmtFormatterName = "logfmt"
+)
+
+// Formatter serializes a FullMessage into a byte sequence suitable for writing
+// to a MessageHandler's output, e.g. as a line of JSON or logfmt.
+//
+// Every Formatter is expected to produce a stable set of top-level fields:
+// time, level, namespace, and msg, followed by every annotation which was
+// attached to the Message's Context (see mctx). If the Message was
+// constructed via mkErrMsg then the errMsg, errCtx, and errLine annotations
+// are broken out into their own nested fields rather than being flattened
+// into strings, so that downstream log aggregators (Loki, ELK, etc...) can
+// index them directly.
+type Formatter interface {
+	Format(FullMessage) ([]byte, error)
+}
+
+// fullMessageFields walks the FullMessage's Context for annotations (via
+// mctx.EvaluateAnnotations) and returns the stable field set described by
+// Formatter, with errMsg/errCtx/errLine (if present) nested under "err".
+func fullMessageFields(msg FullMessage) map[string]interface{} {
+	fields := map[string]interface{}{
+		"time":  msg.Time,
+		"level": msg.Level.String(),
+		"msg":   msg.Description,
+	}
+
+	if len(msg.Namespace) > 0 {
+		fields["namespace"] = msg.Namespace
+	}
+
+	aa := mctx.EvaluateAnnotations(msg.Context, nil)
+
+	var errFields map[string]interface{}
+	for k, v := range aa {
+		mk, ok := k.(mlogAnnotation)
+		if !ok {
+			fields[fmt.Sprint(k)] = v
+			continue
+		}
+
+		switch mk {
+		case "errMsg", "errCtx", "errLine":
+			if errFields == nil {
+				errFields = map[string]interface{}{}
+			}
+			name := string(mk)[len("err"):]
+			if name == "Msg" {
+				name = "msg"
+			} else if name == "Ctx" {
+				name = "ctx"
+			} else {
+				name = "line"
+			}
+			switch a := v.(type) {
+			case mctx.Annotator:
+				ctxFields := map[string]interface{}{}
+				a.Annotate(mctx.Annotations(ctxFields))
+				errFields[name] = ctxFields
+			default:
+				errFields[name] = v
+			}
+		default:
+			fields[string(mk)] = v
+		}
+	}
+
+	if errFields != nil {
+		fields["err"] = errFields
+	}
+
+	return fields
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// JSONFormatter is a Formatter which writes each FullMessage as a single JSON
+// object.
+type JSONFormatter struct{}
+
+// Format implements the Formatter interface.
+func (JSONFormatter) Format(msg FullMessage) ([]byte, error) {
+	b, err := json.Marshal(fullMessageFields(msg))
+	if err != nil {
+		return nil, fmt.Errorf("marshaling FullMessage as JSON: %w", err)
+	}
+	return append(b, '\n'), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// LogfmtFormatter is a Formatter which writes each FullMessage as a single
+// line of logfmt (key=value pairs separated by spaces).
+type LogfmtFormatter struct{}
+
+func logfmtValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		if v == "" || bytes.ContainsAny([]byte(v), " =\"\t\n") {
+			return fmt.Sprintf("%q", v)
+		}
+		return v
+	default:
+		s := fmt.Sprint(v)
+		return logfmtValue(s)
+	}
+}
+
+// Format implements the Formatter interface.
+func (f LogfmtFormatter) Format(msg FullMessage) ([]byte, error) {
+	fields := fullMessageFields(msg)
+
+	// time, level, and msg always come first and in that order, for
+	// readability; everything else follows in Go's map iteration order,
+	// which can differ from one line to the next.
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "time=%s level=%s msg=%s",
+		logfmtValue(fields["time"]), logfmtValue(fields["level"]), logfmtValue(fields["msg"]))
+	delete(fields, "time")
+	delete(fields, "level")
+	delete(fields, "msg")
+
+	for k, v := range fields {
+		fmt.Fprintf(buf, " %s=%s", logfmtValue(k), logfmtValue(v))
+	}
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+var (
+	formatterRegistryL sync.RWMutex
+	formatterRegistry  = map[string]Formatter{
+		JSONFormatterName:   JSONFormatter{},
+		LogfmtFormatterName: LogfmtFormatter{},
+	}
+)
+
+// RegisterFormatter makes a Formatter available under the given name, for
+// use with NewMessageHandlerByName, so that third-party Formatters can be
+// selected by name (e.g. via a config parameter) without the caller needing
+// to import them directly.
+//
+// RegisterFormatter is expected to be called from an init function. It
+// panics if name has already been registered, or if name is
+// HumanFormatterName, which is reserved for the non-Formatter handler
+// constructed by NewMessageHandler.
+func RegisterFormatter(name string, f Formatter) {
+	if name == HumanFormatterName {
+		panic(fmt.Sprintf("mlog: formatter name %q is reserved", name))
+	}
+
+	formatterRegistryL.Lock()
+	defer formatterRegistryL.Unlock()
+	if _, ok := formatterRegistry[name]; ok {
+		panic(fmt.Sprintf("mlog: Formatter %q already registered", name))
+	}
+	formatterRegistry[name] = f
+}
+
+// NewMessageHandlerByName constructs a MessageHandler which writes to out
+// using the Formatter registered, via RegisterFormatter, under the given
+// name. If name is HumanFormatterName ("human") then NewMessageHandler is
+// used instead, since the human-readable output isn't produced via the
+// Formatter interface.
+//
+// This is useful for letting operators select the output format (e.g.
+// "json", for piping into a log aggregator) via a config parameter, rather
+// than requiring the MessageHandler to be constructed in code.
+func NewMessageHandlerByName(name string, out io.Writer) (MessageHandler, error) {
+	if name == HumanFormatterName {
+		return NewMessageHandler(out), nil
+	}
+
+	formatterRegistryL.RLock()
+	f, ok := formatterRegistry[name]
+	formatterRegistryL.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no mlog Formatter registered under name %q", name)
+	}
+
+	return NewMessageHandlerFormat(out, f), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type formatMsgHandler struct {
+	l   sync.Mutex
+	out io.Writer
+	f   Formatter
+}
+
+// NewMessageHandlerFormat initializes and returns a MessageHandler which
+// writes all messages to the given io.Writer, using the given Formatter to
+// serialize each one.
+//
+// If the io.Writer also implements a Sync or Flush method then that will be
+// called when Sync is called on the returned MessageHandler.
+func NewMessageHandlerFormat(w io.Writer, f Formatter) MessageHandler {
+	return &formatMsgHandler{
+		out: w,
+		f:   f,
+	}
+}
+
+func (h *formatMsgHandler) Handle(msg FullMessage) error {
+	h.l.Lock()
+	defer h.l.Unlock()
+
+	b, err := h.f.Format(msg)
+	if err != nil {
+		return fmt.Errorf("formatting message: %w", err)
+	}
+
+	_, err = h.out.Write(b)
+	return err
+}
+
+func (h *formatMsgHandler) Sync() error {
+	h.l.Lock()
+	defer h.l.Unlock()
+	return maybeSyncWriter(h.out)
+}
@@ -0,0 +1,81 @@
+package mlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	. "testing"
+	"time"
+
+	"github.com/mediocregopher/mediocre-go-lib/v2/mctx"
+	"github.com/mediocregopher/mediocre-go-lib/v2/mtest/massert"
+)
+
+func TestJSONFormatter(t *T) {
+	buf := new(bytes.Buffer)
+	now := time.Now().UTC()
+
+	l := NewLogger(&LoggerOpts{
+		MessageHandler: NewMessageHandlerFormat(buf, JSONFormatter{}),
+		Now:            func() time.Time { return now },
+	})
+
+	ctx := mctx.Annotate(context.Background(), "foo", "bar")
+	l.Info(ctx, "hello")
+
+	var fields map[string]interface{}
+	line, err := buf.ReadString('\n')
+	massert.Require(t, massert.Nil(err))
+
+	massert.Require(t,
+		massert.Nil(json.Unmarshal([]byte(strings.TrimSpace(line)), &fields)),
+		massert.Equal("INFO", fields["level"]),
+		massert.Equal("hello", fields["msg"]),
+		massert.Equal("bar", fields["foo"]),
+	)
+}
+
+func TestNewMessageHandlerByName(t *T) {
+	buf := new(bytes.Buffer)
+	now := time.Now().UTC()
+
+	h, err := NewMessageHandlerByName(JSONFormatterName, buf)
+	massert.Require(t, massert.Nil(err))
+
+	l := NewLogger(&LoggerOpts{
+		MessageHandler: h,
+		Now:            func() time.Time { return now },
+	})
+	l.Info(context.Background(), "hello")
+
+	var fields map[string]interface{}
+	line, err := buf.ReadString('\n')
+	massert.Require(t,
+		massert.Nil(err),
+		massert.Nil(json.Unmarshal([]byte(strings.TrimSpace(line)), &fields)),
+		massert.Equal("hello", fields["msg"]),
+	)
+
+	_, err = NewMessageHandlerByName("nonexistent", buf)
+	massert.Require(t, massert.Equal(true, err != nil))
+}
+
+func TestLogfmtFormatter(t *T) {
+	buf := new(bytes.Buffer)
+	now := time.Now().UTC()
+
+	l := NewLogger(&LoggerOpts{
+		MessageHandler: NewMessageHandlerFormat(buf, LogfmtFormatter{}),
+		Now:            func() time.Time { return now },
+	})
+
+	l.Info(context.Background(), "hello world")
+
+	line, err := buf.ReadString('\n')
+	massert.Require(t,
+		massert.Nil(err),
+		massert.Equal(true, strings.HasPrefix(line, "time=")),
+		massert.Equal(true, strings.Contains(line, `msg="hello world"`)),
+	)
+}
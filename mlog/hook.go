@@ -0,0 +1,92 @@
+package mlog
+
+import (
+	"math/rand"
+	"sync/atomic"
+)
+
+// Hook is a type which can observe Messages as they are logged, in addition
+// to (and independently of) the Logger's MessageHandler. Hooks are useful for
+// bolting on side-effects like metrics, sampling/rate-limiting, or forwarding
+// to an external system (e.g. Sentry, syslog) without having to wrap the
+// MessageHandler itself.
+//
+// Fire is called synchronously from within Logger.Log, after the Message has
+// passed the Logger's MaxLevel filter but before it's passed to the
+// MessageHandler. If Fire returns an error it will be reported via the
+// Logger's own Error method, asynchronously, so that a broken Hook can't take
+// down the Logger.
+type Hook interface {
+	Fire(FullMessage) error
+
+	// Levels indicates which Levels this Hook wishes to receive Messages for.
+	// If Levels returns nil then the Hook receives Messages of every Level.
+	Levels() []Level
+}
+
+func hookAppliesToLevel(h Hook, lvl Level) bool {
+	levels := h.Levels()
+	if levels == nil {
+		return true
+	}
+	for _, l := range levels {
+		if l == lvl {
+			return true
+		}
+	}
+	return false
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type samplingHook struct {
+	rate   float64
+	levels []Level
+	fire   func(FullMessage) error
+}
+
+// Levels implements the Hook interface.
+func (h samplingHook) Levels() []Level { return h.levels }
+
+// Fire implements the Hook interface.
+func (h samplingHook) Fire(msg FullMessage) error {
+	if rand.Float64() >= h.rate {
+		return nil
+	}
+	return h.fire(msg)
+}
+
+// SamplingHook returns a Hook which wraps fire such that it is only called
+// for the given rate of Messages, a number between 0 (none) and 1 (all). If
+// levels is given then only Messages of those Levels are considered at all;
+// otherwise every Level is considered.
+func SamplingHook(rate float64, fire func(FullMessage) error, levels ...Level) Hook {
+	return samplingHook{rate: rate, levels: levels, fire: fire}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// CounterHook is a Hook implementation which keeps a running count of how
+// many Messages have been fired to it, e.g. for exposing as a Prometheus
+// counter. It is safe for concurrent use.
+type CounterHook struct {
+	count uint64
+}
+
+// Fire implements the Hook interface.
+func (c *CounterHook) Fire(FullMessage) error {
+	atomic.AddUint64(&c.count, 1)
+	return nil
+}
+
+// Levels implements the Hook interface, returning nil so that every Message
+// is counted.
+func (c *CounterHook) Levels() []Level {
+	return nil
+}
+
+// Count returns the current count of Messages which have been fired to this
+// CounterHook.
+func (c *CounterHook) Count() uint64 {
+	return atomic.LoadUint64(&c.count)
+}
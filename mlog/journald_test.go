@@ -0,0 +1,26 @@
+package mlog
+
+import (
+	. "testing"
+
+	"github.com/mediocregopher/mediocre-go-lib/v2/mtest/massert"
+)
+
+func TestJournaldFieldName(t *T) {
+	massert.Require(t,
+		massert.Equal("FOO_BAR", journaldFieldName("foo-bar")),
+		massert.Equal("FOO", journaldFieldName("foo")),
+		massert.Equal("", journaldFieldName("123foo")),
+		massert.Equal("", journaldFieldName("!!!")),
+	)
+}
+
+func TestJournaldPriority(t *T) {
+	massert.Require(t,
+		massert.Equal(2, journaldPriority(LevelFatal)),
+		massert.Equal(3, journaldPriority(LevelError)),
+		massert.Equal(4, journaldPriority(LevelWarn)),
+		massert.Equal(6, journaldPriority(LevelInfo)),
+		massert.Equal(7, journaldPriority(LevelDebug)),
+	)
+}
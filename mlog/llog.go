@@ -232,6 +232,13 @@ func (l *Logger) SetMaxLevel(lvl Level) {
 	l.SetMaxLevelUint(lvl.Uint())
 }
 
+// MaxLevelUint returns the maximum (up-to-and-including) level priority which
+// the Logger is currently configured to output a log for (see
+// SetMaxLevelUint).
+func (l *Logger) MaxLevelUint() uint {
+	return l.getMaxLevel()
+}
+
 func (l *Logger) spin() {
 	for msg := range l.msgCh {
 		if err := l.WriteFn(l.WriteCloser, msg); err != nil {
@@ -121,8 +121,11 @@ type messageJSON struct {
 	Description string   `json:"descr"`
 	LevelInt    int      `json:"level_int"`
 
-	// key -> value
-	Annotations map[string]string `json:"annotations,omitempty"`
+	// key -> value. Values keep their original type (string, number, bool,
+	// ...) rather than being stringified, so that downstream log
+	// aggregators can index/query them as such; encoding/json sorts map
+	// keys when marshaling, so this field's key order is always stable.
+	Annotations map[string]interface{} `json:"annotations,omitempty"`
 }
 
 const msgTimeFormat = "06/01/02 15:04:05.000000"
@@ -131,6 +134,15 @@ func (h *jsonMsgHandler) Handle(msg FullMessage) error {
 	h.l.Lock()
 	defer h.l.Unlock()
 
+	aa := mctx.EvaluateAnnotations(msg.Context, h.aa)
+	var annotations map[string]interface{}
+	if len(aa) > 0 {
+		annotations = make(map[string]interface{}, len(aa))
+		for k, v := range aa {
+			annotations[fmt.Sprint(k)] = v
+		}
+	}
+
 	msgJSON := messageJSON{
 		TimeDate:    msg.Time.UTC().Format(msgTimeFormat),
 		Timestamp:   msg.Time.UnixNano(),
@@ -138,7 +150,7 @@ func (h *jsonMsgHandler) Handle(msg FullMessage) error {
 		LevelInt:    msg.Level.Int(),
 		Namespace:   msg.Namespace,
 		Description: msg.Description,
-		Annotations: mctx.EvaluateAnnotations(msg.Context, h.aa).StringMap(),
+		Annotations: annotations,
 	}
 
 	for k := range h.aa {
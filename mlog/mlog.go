@@ -128,6 +128,22 @@ type LoggerOpts struct {
 	//
 	// Defaults to time.Now.
 	Now func() time.Time
+
+	// Hooks are called, in order, for every Message which passes the
+	// MaxLevel filter, before the Message is passed to MessageHandler. See
+	// the Hook type for more.
+	//
+	// Defaults to no Hooks.
+	Hooks []Hook
+
+	// Sampler, if set, is consulted for every Message which passes the
+	// MaxLevel filter, before Hooks are fired or the Message is passed to
+	// MessageHandler. If it decides a Message shouldn't be emitted then
+	// neither Hooks nor MessageHandler are invoked for it at all. See the
+	// Sampler type for more.
+	//
+	// Defaults to nil, meaning no sampling is performed.
+	Sampler *Sampler
 }
 
 func (o *LoggerOpts) withDefaults() *LoggerOpts {
@@ -182,6 +198,8 @@ func (l *Logger) clone() *Logger {
 			MessageHandler: l.opts.MessageHandler,
 			MaxLevel:       l.opts.MaxLevel,
 			Now:            l.opts.Now,
+			Hooks:          append([]Hook{}, l.opts.Hooks...),
+			Sampler:        l.opts.Sampler,
 		},
 		l:  new(sync.RWMutex),
 		ns: make([]string, len(l.ns), len(l.ns)+1),
@@ -215,6 +233,35 @@ func (l *Logger) MaxLevel() int {
 	return l.opts.MaxLevel
 }
 
+// V returns true if a Message of the given Level would actually be emitted by
+// this Logger, i.e. if its severity is not coarser than MaxLevel. This can be
+// used to skip constructing an expensive log message entirely:
+//
+//	if l.V(mlog.LevelDebug) {
+//		l.Debug(ctx, expensive())
+//	}
+func (l *Logger) V(lvl Level) bool {
+	l.l.RLock()
+	defer l.l.RUnlock()
+	return l.opts.MaxLevel >= lvl.Int()
+}
+
+// WithHook returns a clone of the Logger with the given Hook appended to its
+// set of Hooks (see Hook and LoggerOpts.Hooks).
+func (l *Logger) WithHook(h Hook) *Logger {
+	l = l.clone()
+	l.opts.Hooks = append(l.opts.Hooks, h)
+	return l
+}
+
+// WithSampler returns a clone of the Logger with the given Sampler set (see
+// Sampler and LoggerOpts.Sampler). A nil Sampler disables sampling.
+func (l *Logger) WithSampler(s *Sampler) *Logger {
+	l = l.clone()
+	l.opts.Sampler = s
+	return l
+}
+
 // Log can be used to manually log a message of some custom defined Level.
 //
 // If the Level is a fatal (Int() < 0) then calling this will never return,
@@ -227,12 +274,31 @@ func (l *Logger) Log(msg Message) {
 		return
 	}
 
+	if l.opts.Sampler != nil {
+		ok, suppressed := l.opts.Sampler.allow(msg.Context, msg.Level, msg.Description, l.ns)
+		if !ok {
+			return
+		}
+		if suppressed > 0 {
+			msg.Context = mctx.Annotate(msg.Context, mlogAnnotation("sampled"), suppressed)
+		}
+	}
+
 	fullMsg := FullMessage{
 		Message:   msg,
 		Time:      l.opts.Now(),
 		Namespace: l.ns,
 	}
 
+	for _, h := range l.opts.Hooks {
+		if !hookAppliesToLevel(h, msg.Level) {
+			continue
+		}
+		if err := h.Fire(fullMsg); err != nil {
+			go l.Error(context.Background(), "Hook.Fire returned error", err)
+		}
+	}
+
 	if err := l.opts.MessageHandler.Handle(fullMsg); err != nil {
 		go l.Error(context.Background(), "MessageHandler.Handle returned error", err)
 		return
@@ -310,3 +376,25 @@ func (l *Logger) FatalString(ctx context.Context, descr string) {
 func (l *Logger) Fatal(ctx context.Context, descr string, err error) {
 	l.Log(mkErrMsg(ctx, LevelFatal, descr, err))
 }
+
+// DebugFunc logs a LevelDebug message, calling descr to produce it only if
+// LevelDebug would actually be emitted (see V). This avoids the cost of
+// constructing expensive debug messages which would just be discarded.
+func (l *Logger) DebugFunc(ctx context.Context, descr func() string) {
+	if !l.V(LevelDebug) {
+		return
+	}
+	l.Debug(ctx, descr())
+}
+
+// ErrorFunc logs a LevelError message, calling descr to produce the message
+// and error only if LevelError would actually be emitted (see V). This avoids
+// the cost of constructing expensive error messages which would just be
+// discarded.
+func (l *Logger) ErrorFunc(ctx context.Context, descr func() (string, error)) {
+	if !l.V(LevelError) {
+		return
+	}
+	str, err := descr()
+	l.Error(ctx, str, err)
+}
@@ -68,3 +68,57 @@ func TestLogger(t *T) {
 		assertOut(`{"td":"<TD>","ts":<TS>,"level":"INFO","ns":["ns"],"descr":"bar","level_int":30,"annotations":{"foo":"bar"}}`),
 	)
 }
+
+// TestLoggerSampler extends TestLogger to cover LoggerOpts.Sampler: it fires
+// 10k identical Info calls through a tail-sampling Sampler and asserts that
+// only the configured subset (the first N, then 1 of every M) made it
+// through, with each of the 1-of-M lines (and none of the first N) annotated
+// with how many siblings were suppressed.
+func TestLoggerSampler(t *T) {
+	const (
+		first = 5
+		every = 1000
+		n     = 10000
+	)
+
+	buf := new(bytes.Buffer)
+	l := NewLogger(&LoggerOpts{
+		MessageHandler: NewJSONMessageHandler(buf),
+		Sampler: NewSampler(&SamplerOpts{
+			TailSampleFirst:    first,
+			TailSampleEvery:    every,
+			TailSampleInterval: time.Hour,
+		}),
+	})
+
+	ctx := context.Background()
+	for i := 0; i < n; i++ {
+		l.Info(ctx, "spammy")
+	}
+
+	var lines []string
+	for {
+		line, err := buf.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	wantLines := first + (n-first)/every
+	if len(lines) != wantLines {
+		t.Fatalf("expected %d emitted lines, got %d: %v", wantLines, len(lines), lines)
+	}
+
+	for i, line := range lines {
+		wantAnnotation := `"annotations":{"sampled":` + fmt.Sprint(every-1) + `}`
+		gotSampled := strings.Contains(line, wantAnnotation)
+		wantSampled := i >= first // every tail-sampled line suppressed every-1 siblings
+		if gotSampled != wantSampled {
+			t.Fatalf("line %d (%s) contains %q = %v, want %v", i, line, wantAnnotation, gotSampled, wantSampled)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Package mslog bridges mlog with the standard library's log/slog package,
+// allowing an mlog.Logger to be used as a slog.Handler, and an arbitrary
+// slog.Handler to be used as the backing MessageHandler of an mlog.Logger.
+package mslog
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/mediocregopher/mediocre-go-lib/v2/mctx"
+	"github.com/mediocregopher/mediocre-go-lib/v2/mlog"
+)
+
+func slogLevelToLevel(lvl slog.Level) mlog.Level {
+	switch {
+	case lvl >= slog.LevelError:
+		return mlog.LevelError
+	case lvl >= slog.LevelWarn:
+		return mlog.LevelWarn
+	case lvl >= slog.LevelInfo:
+		return mlog.LevelInfo
+	default:
+		return mlog.LevelDebug
+	}
+}
+
+func levelToSlogLevel(lvl mlog.Level) slog.Level {
+	switch lvl {
+	case mlog.LevelError, mlog.LevelFatal:
+		return slog.LevelError
+	case mlog.LevelWarn:
+		return slog.LevelWarn
+	case mlog.LevelDebug:
+		return slog.LevelDebug
+	default:
+		return slog.LevelInfo
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type slogHandler struct {
+	l      *mlog.Logger
+	prefix string // dotted group namespace prefix for attr keys
+	attrs  []slog.Attr
+}
+
+// NewSlogHandler returns a slog.Handler which writes all log/slog Records
+// through the given Logger. slog levels are mapped onto the nearest mlog
+// Level (anything below slog.LevelInfo becomes LevelDebug), slog groups are
+// flattened into dotted key prefixes (e.g. "group.key"), and each Record's
+// Attrs are added to the Logger's Message via mctx.Annotate.
+func NewSlogHandler(l *mlog.Logger) slog.Handler {
+	return &slogHandler{l: l}
+}
+
+func (h *slogHandler) Enabled(_ context.Context, lvl slog.Level) bool {
+	return slogLevelToLevel(lvl).Int() <= h.l.MaxLevel()
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	kvs := make([]interface{}, 0, 2*(r.NumAttrs()+len(h.attrs)))
+
+	addAttr := func(a slog.Attr) {
+		if a.Equal(slog.Attr{}) {
+			return
+		}
+		key := a.Key
+		if h.prefix != "" {
+			key = h.prefix + "." + key
+		}
+		kvs = append(kvs, key, a.Value.Any())
+	}
+
+	for _, a := range h.attrs {
+		addAttr(a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(a)
+		return true
+	})
+
+	if len(kvs) > 0 {
+		ctx = mctx.Annotate(ctx, kvs...)
+	}
+
+	h.l.Log(mlog.Message{
+		Context:     ctx,
+		Level:       slogLevelToLevel(r.Level),
+		Description: r.Message,
+	})
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &h2
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	if h.prefix == "" {
+		h2.prefix = name
+	} else {
+		h2.prefix = h.prefix + "." + name
+	}
+	h2.l = h.l.WithNamespace(name)
+	return &h2
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type slogMessageHandler struct {
+	l sync.Mutex
+	h slog.Handler
+}
+
+// NewLoggerFromSlog returns an mlog.Logger whose MessageHandler forwards
+// every FullMessage to the given slog.Handler as a slog.Record, translating
+// mlog's annotations (see mctx) into slog.Attrs.
+func NewLoggerFromSlog(h slog.Handler) *mlog.Logger {
+	return mlog.NewLogger(&mlog.LoggerOpts{
+		MessageHandler: &slogMessageHandler{h: h},
+	})
+}
+
+func (h *slogMessageHandler) Handle(msg mlog.FullMessage) error {
+	h.l.Lock()
+	defer h.l.Unlock()
+
+	r := slog.NewRecord(msg.Time, levelToSlogLevel(msg.Level), msg.Description, 0)
+
+	if len(msg.Namespace) > 0 {
+		r.AddAttrs(slog.String("namespace", strings.Join(msg.Namespace, ".")))
+	}
+
+	aa := mctx.EvaluateAnnotations(msg.Context, nil)
+	for k, v := range aa {
+		r.AddAttrs(slog.Any(toKeyString(k), v))
+	}
+
+	return h.h.Handle(msg.Context, r)
+}
+
+func (h *slogMessageHandler) Sync() error {
+	h.l.Lock()
+	defer h.l.Unlock()
+	if s, ok := h.h.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+func toKeyString(k interface{}) string {
+	if s, ok := k.(string); ok {
+		return s
+	}
+	return slog.AnyValue(k).String()
+}
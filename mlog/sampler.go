@@ -0,0 +1,407 @@
+package mlog
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mediocregopher/mediocre-go-lib/v2/mctx"
+)
+
+// SamplerKeyFunc derives an additional key component, used to group Messages
+// for the purposes of sampling/rate-limiting, from a Message's Level,
+// Description, and Context. A Sampler always also groups by a Message's
+// Logger namespace and Description, so a SamplerKeyFunc is only needed when
+// messages sharing those should nonetheless be split into further groups
+// (e.g. by a request id pulled off of ctx).
+//
+// The returned string becomes part of a map key on every logged Message, so
+// it should be cheap to compute and bounded in cardinality.
+type SamplerKeyFunc func(ctx context.Context, lvl Level, descr string) string
+
+// DefaultSamplerKeyFunc returns an empty string, meaning Messages are grouped
+// solely by their Logger namespace, Level, and Description.
+func DefaultSamplerKeyFunc(context.Context, Level, string) string {
+	return ""
+}
+
+// SamplerOpts are optional parameters used to construct a Sampler via
+// NewSampler. All fields are optional; a nil value is equivalent to an empty
+// one.
+type SamplerOpts struct {
+	// KeyFunc derives an additional, user-defined component of the key
+	// Messages are grouped by (see SamplerKeyFunc).
+	//
+	// Defaults to DefaultSamplerKeyFunc.
+	KeyFunc SamplerKeyFunc
+
+	// RateLimit, if greater than zero, caps each key to at most RateLimit
+	// Messages per RateLimitInterval. It's implemented as a token bucket, so
+	// bursts up to RateLimit are allowed even if they arrive faster than
+	// RateLimitInterval would otherwise allow, so long as the key has been
+	// idle long enough to accrue them.
+	//
+	// Defaults to 0, meaning no rate limit is applied.
+	RateLimit int
+
+	// RateLimitInterval is the period over which RateLimit Messages are
+	// allowed for a given key. Only meaningful if RateLimit is set.
+	//
+	// Defaults to time.Second.
+	RateLimitInterval time.Duration
+
+	// TailSampleFirst, if greater than zero, causes the first
+	// TailSampleFirst Messages for a given key, within a given
+	// TailSampleInterval, to always be emitted.
+	//
+	// Defaults to 0.
+	TailSampleFirst int
+
+	// TailSampleEvery, if greater than zero, causes only 1 in every
+	// TailSampleEvery Messages for a given key to be emitted once
+	// TailSampleFirst has been exceeded, within a given TailSampleInterval.
+	// If zero, no further Messages for that key are emitted until the
+	// TailSampleInterval elapses.
+	//
+	// Defaults to 0.
+	TailSampleEvery int
+
+	// TailSampleInterval is the period after which a key's TailSampleFirst/
+	// TailSampleEvery counters reset, allowing its next TailSampleFirst
+	// Messages through again.
+	//
+	// Defaults to 0, meaning the counters never reset.
+	TailSampleInterval time.Duration
+}
+
+func (o *SamplerOpts) withDefaults() *SamplerOpts {
+	out := new(SamplerOpts)
+	if o != nil {
+		*out = *o
+	}
+
+	if out.KeyFunc == nil {
+		out.KeyFunc = DefaultSamplerKeyFunc
+	}
+
+	if out.RateLimit > 0 && out.RateLimitInterval <= 0 {
+		out.RateLimitInterval = time.Second
+	}
+
+	return out
+}
+
+// Sampler implements sampling/rate-limiting for a Logger (see
+// LoggerOpts.Sampler), so that high-volume log sites can't swamp whatever is
+// consuming the Logger's output.
+//
+// A Sampler combines two independent strategies, either or both of which may
+// be configured via SamplerOpts: a per-key token-bucket rate limit, and a
+// per-key tail-sample (always emit the first N of a key, then 1 of every M
+// thereafter, as popularized by zerolog/zap). If both are configured, a
+// Message must pass both to be emitted.
+//
+// Whenever a Message survives the tail-sample but not every Message since
+// the last survivor was emitted, the Logger adds a "sampled" annotation to
+// the emitted Message (visible as "annotations.sampled" in JSON output)
+// giving the number of Messages of that key which were suppressed in
+// between, so downstream consumers know suppression is taking place.
+//
+// Per-key state is kept in a sharded map, so that a Message whose key has
+// already been seen never needs the map's lock: its bucket is found under a
+// brief read-lock and the bucket's own state is updated with sync/atomic.
+// The Sampler-wide counters reported by Stats are updated under a mutex, and
+// only when a Message is dropped or carries a "sampled" annotation. A Sampler
+// is safe for concurrent use.
+type Sampler struct {
+	opts          *SamplerOpts
+	refillPerNano float64
+	shards        [samplerShardCount]samplerShard
+
+	statsL  sync.Mutex
+	dropped map[string]uint64
+	sampled map[string]uint64
+}
+
+// NewSampler initializes and returns a new Sampler around the given
+// SamplerOpts.
+func NewSampler(opts *SamplerOpts) *Sampler {
+	opts = opts.withDefaults()
+	s := &Sampler{
+		opts:    opts,
+		dropped: map[string]uint64{},
+		sampled: map[string]uint64{},
+	}
+	if opts.RateLimit > 0 {
+		s.refillPerNano = float64(opts.RateLimit) * 1000 / float64(opts.RateLimitInterval.Nanoseconds())
+	}
+	for i := range s.shards {
+		s.shards[i].buckets = map[string]*samplerBucket{}
+	}
+	return s
+}
+
+// SamplerStats is a snapshot of the counters a Sampler maintains about its
+// own behavior, returned by Sampler.Stats.
+type SamplerStats struct {
+	// Dropped gives, for each Level's String() which has had at least one
+	// Message dropped (i.e. failed the rate limit or tail-sample entirely,
+	// rather than merely being counted towards a "sampled" annotation), the
+	// total number of Messages of that Level which were dropped.
+	Dropped map[string]uint64
+
+	// Sampled gives, for each key produced by samplerKey (Level, Namespace,
+	// Description, and SamplerKeyFunc output) which has had at least one
+	// Message survive its tail-sample with siblings suppressed, the total
+	// number of sibling Messages suppressed on that key's behalf across the
+	// Sampler's lifetime.
+	Sampled map[string]uint64
+}
+
+// Stats returns a snapshot of the Sampler's current counters, for tests or
+// monitoring to assert on how much suppression is actually taking place.
+func (s *Sampler) Stats() SamplerStats {
+	s.statsL.Lock()
+	defer s.statsL.Unlock()
+
+	stats := SamplerStats{
+		Dropped: make(map[string]uint64, len(s.dropped)),
+		Sampled: make(map[string]uint64, len(s.sampled)),
+	}
+	for k, v := range s.dropped {
+		stats.Dropped[k] = v
+	}
+	for k, v := range s.sampled {
+		stats.Sampled[k] = v
+	}
+	return stats
+}
+
+func (s *Sampler) incrDropped(lvl Level) {
+	s.statsL.Lock()
+	defer s.statsL.Unlock()
+	s.dropped[lvl.String()]++
+}
+
+func (s *Sampler) incrSampled(key string, suppressed int64) {
+	s.statsL.Lock()
+	defer s.statsL.Unlock()
+	s.sampled[key] += uint64(suppressed)
+}
+
+const samplerShardCount = 32
+
+type samplerShard struct {
+	l       sync.RWMutex
+	buckets map[string]*samplerBucket
+}
+
+// samplerBucket holds all per-key state for a Sampler. Every field is only
+// ever touched via sync/atomic, so a *samplerBucket can be read out of its
+// shard's map once and then used lock-free from then on.
+type samplerBucket struct {
+	// token bucket, fixed-point with 3 decimal digits (i.e. in units of
+	// 1/1000th of a token)
+	tokensMilli    int64
+	lastRefillNano int64
+
+	// tail sample
+	windowStartNano int64
+	countInWindow   int64
+}
+
+func samplerKey(namespace []string, lvl Level, descr, extra string) string {
+	var b strings.Builder
+	for _, ns := range namespace {
+		b.WriteString(ns)
+		b.WriteByte('/')
+	}
+	b.WriteString(lvl.String())
+	b.WriteByte('|')
+	b.WriteString(descr)
+	if extra != "" {
+		b.WriteByte('|')
+		b.WriteString(extra)
+	}
+	return b.String()
+}
+
+func (s *Sampler) bucket(key string) *samplerBucket {
+	shard := &s.shards[fnv32(key)%samplerShardCount]
+
+	shard.l.RLock()
+	b, ok := shard.buckets[key]
+	shard.l.RUnlock()
+	if ok {
+		return b
+	}
+
+	shard.l.Lock()
+	defer shard.l.Unlock()
+	if b, ok := shard.buckets[key]; ok {
+		return b
+	}
+	b = new(samplerBucket)
+	shard.buckets[key] = b
+	return b
+}
+
+// allow decides whether a Message with the given Level/Description/namespace
+// should be emitted, folding in the extra key component produced by ctx and
+// the Sampler's KeyFunc. It returns whether the Message should be emitted
+// and, if so, how many prior Messages of the same key were suppressed since
+// the last one which was emitted.
+func (s *Sampler) allow(ctx context.Context, lvl Level, descr string, namespace []string) (bool, int64) {
+	tailConfigured := s.opts.TailSampleFirst > 0 || s.opts.TailSampleEvery > 0
+	rateConfigured := s.opts.RateLimit > 0
+	if !tailConfigured && !rateConfigured {
+		return true, 0
+	}
+
+	key := samplerKey(namespace, lvl, descr, s.opts.KeyFunc(ctx, lvl, descr))
+	b := s.bucket(key)
+
+	var suppressed int64
+	if tailConfigured {
+		var ok bool
+		if ok, suppressed = b.tailSample(s.opts.TailSampleFirst, s.opts.TailSampleEvery, s.opts.TailSampleInterval); !ok {
+			s.incrDropped(lvl)
+			return false, 0
+		}
+	}
+
+	if rateConfigured && !b.takeToken(int64(s.opts.RateLimit)*1000, s.refillPerNano) {
+		s.incrDropped(lvl)
+		return false, 0
+	}
+
+	if suppressed > 0 {
+		s.incrSampled(key, suppressed)
+	}
+
+	return true, suppressed
+}
+
+// tailSample implements the "first N, then 1 of every M" strategy described
+// on SamplerOpts, resetting its counters once interval has elapsed since the
+// current window started.
+func (b *samplerBucket) tailSample(first, every int, interval time.Duration) (bool, int64) {
+	now := time.Now().UnixNano()
+	intervalNano := interval.Nanoseconds()
+
+	for {
+		windowStart := atomic.LoadInt64(&b.windowStartNano)
+		if intervalNano > 0 && now-windowStart >= intervalNano {
+			if atomic.CompareAndSwapInt64(&b.windowStartNano, windowStart, now) {
+				atomic.StoreInt64(&b.countInWindow, 0)
+			}
+			continue
+		}
+		break
+	}
+
+	count := atomic.AddInt64(&b.countInWindow, 1)
+	if count <= int64(first) {
+		return true, 0
+	}
+
+	if every <= 0 {
+		return false, 0
+	}
+
+	if (count-int64(first))%int64(every) == 0 {
+		return true, int64(every) - 1
+	}
+	return false, 0
+}
+
+// takeToken implements the token-bucket rate limit described on SamplerOpts,
+// lazily refilling based on elapsed time since the last call.
+func (b *samplerBucket) takeToken(capacityMilli int64, refillPerNano float64) bool {
+	now := time.Now().UnixNano()
+	for {
+		last := atomic.LoadInt64(&b.lastRefillNano)
+		cur := atomic.LoadInt64(&b.tokensMilli)
+
+		var elapsed int64
+		if last == 0 {
+			cur = capacityMilli // bucket starts full
+		} else if elapsed = now - last; elapsed < 0 {
+			elapsed = 0
+		}
+
+		refilled := cur + int64(float64(elapsed)*refillPerNano)
+		if refilled > capacityMilli {
+			refilled = capacityMilli
+		}
+
+		if refilled < 1000 {
+			if atomic.CompareAndSwapInt64(&b.lastRefillNano, last, now) {
+				atomic.StoreInt64(&b.tokensMilli, refilled)
+			}
+			return false
+		}
+
+		if atomic.CompareAndSwapInt64(&b.lastRefillNano, last, now) {
+			atomic.StoreInt64(&b.tokensMilli, refilled-1000)
+			return true
+		}
+	}
+}
+
+// fnv32 is the FNV-1a hash, used to pick a key's shard. It's implemented by
+// hand, rather than via hash/fnv, so that picking a shard never allocates.
+func fnv32(s string) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	h := uint32(offset32)
+	for i := 0; i < len(s); i++ {
+		h ^= uint32(s[i])
+		h *= prime32
+	}
+	return h
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// SampledHandler wraps inner with s (see Sampler), so that Messages are
+// sampled/rate-limited on their way into inner rather than (or possibly in
+// addition to, though this would be redundant) via LoggerOpts.Sampler.
+//
+// This is useful when a MessageHandler is shared across multiple Loggers
+// (e.g. Loggers produced by different calls to WithNamespace) and sampling
+// should therefore be applied once, against their combined output, rather
+// than independently per Logger with its own counters.
+//
+// Messages at LevelError or more severe (i.e. LevelFatal) always pass
+// through to inner unconditionally, regardless of s, since those are the
+// messages an operator can least afford to lose to a log storm elsewhere.
+func SampledHandler(inner MessageHandler, s *Sampler) MessageHandler {
+	return &sampledHandler{inner: inner, s: s}
+}
+
+type sampledHandler struct {
+	inner MessageHandler
+	s     *Sampler
+}
+
+func (h *sampledHandler) Handle(msg FullMessage) error {
+	if msg.Level.Int() > LevelError.Int() {
+		ok, suppressed := h.s.allow(msg.Context, msg.Level, msg.Description, msg.Namespace)
+		if !ok {
+			return nil
+		}
+		if suppressed > 0 {
+			msg.Context = mctx.Annotate(msg.Context, mlogAnnotation("sampled"), suppressed)
+		}
+	}
+	return h.inner.Handle(msg)
+}
+
+func (h *sampledHandler) Sync() error {
+	return h.inner.Sync()
+}
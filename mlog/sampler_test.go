@@ -0,0 +1,99 @@
+package mlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	. "testing"
+	"time"
+)
+
+func TestSamplerStats(t *T) {
+	const (
+		first = 2
+		every = 10
+		n     = 100
+	)
+
+	s := NewSampler(&SamplerOpts{
+		TailSampleFirst:    first,
+		TailSampleEvery:    every,
+		TailSampleInterval: time.Hour,
+	})
+
+	ctx := context.Background()
+	for i := 0; i < n; i++ {
+		s.allow(ctx, LevelInfo, "spammy", nil)
+	}
+
+	stats := s.Stats()
+
+	wantDropped := uint64(n - first - (n-first)/every)
+	if got := stats.Dropped["INFO"]; got != wantDropped {
+		t.Fatalf("Dropped[INFO] = %d, want %d", got, wantDropped)
+	}
+
+	key := samplerKey(nil, LevelInfo, "spammy", "")
+	wantSampled := uint64((every - 1) * (n - first) / every)
+	if got := stats.Sampled[key]; got != wantSampled {
+		t.Fatalf("Sampled[%q] = %d, want %d", key, got, wantSampled)
+	}
+}
+
+func TestSampledHandler(t *T) {
+	const (
+		first = 1
+		every = 5
+		n     = 20
+	)
+
+	buf := new(bytes.Buffer)
+	s := NewSampler(&SamplerOpts{
+		TailSampleFirst:    first,
+		TailSampleEvery:    every,
+		TailSampleInterval: time.Hour,
+	})
+	h := SampledHandler(NewJSONMessageHandler(buf), s)
+
+	l := NewLogger(&LoggerOpts{MessageHandler: h})
+
+	ctx := context.Background()
+	for i := 0; i < n; i++ {
+		l.Info(ctx, "spammy")
+	}
+	// errors always pass through, regardless of the Sampler
+	for i := 0; i < n; i++ {
+		l.Error(ctx, "uh oh", errors.New("ERR"))
+	}
+
+	var infoLines, errorLines int
+	for {
+		line, err := buf.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if strings.Contains(line, `"level":"INFO"`) {
+			infoLines++
+		} else if strings.Contains(line, `"level":"ERROR"`) {
+			errorLines++
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	wantInfoLines := first + (n-first)/every
+	if infoLines != wantInfoLines {
+		t.Fatalf("expected %d INFO lines, got %d", wantInfoLines, infoLines)
+	}
+	if errorLines != n {
+		t.Fatalf("expected all %d ERROR lines to pass through, got %d", n, errorLines)
+	}
+
+	stats := s.Stats()
+	if stats.Dropped["INFO"] == 0 {
+		t.Fatal("expected some INFO Messages to have been dropped")
+	}
+	if _, ok := stats.Dropped["ERROR"]; ok {
+		t.Fatal("expected no ERROR Messages to have been dropped")
+	}
+}
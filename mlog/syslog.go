@@ -0,0 +1,67 @@
+package mlog
+
+import (
+	"fmt"
+	"log/syslog"
+	"sync"
+)
+
+// syslogMessageHandler is a MessageHandler which forwards every Message to a
+// syslog daemon. Formatting of everything but the priority (time, namespace,
+// description, and annotations) is delegated to a LogfmtFormatter, since
+// syslog already attaches its own timestamp and facility/severity.
+type syslogMessageHandler struct {
+	l sync.Mutex
+	w *syslog.Writer
+	f Formatter
+}
+
+// Priority is an alias of log/syslog's Priority, the facility a
+// NewSyslogMessageHandler's messages are tagged with (e.g. syslog.LOG_USER
+// or syslog.LOG_LOCAL0). Its severity half is ignored; NewSyslogMessageHandler
+// picks the severity of each message itself, from its Level.
+type Priority = syslog.Priority
+
+// NewSyslogMessageHandler dials the syslog daemon at addr over the given
+// network (see log/syslog.Dial; network and addr may both be left empty to
+// use the local syslog daemon) and returns a MessageHandler which writes
+// every Message to it, tagged with tag and facility priority.
+//
+// Each Level is mapped to the syslog severity closest to it: LevelDebug to
+// LOG_DEBUG, LevelInfo to LOG_INFO, LevelWarn to LOG_WARNING, LevelError to
+// LOG_ERR, and LevelFatal to LOG_CRIT.
+func NewSyslogMessageHandler(tag string, priority Priority, network, addr string) (MessageHandler, error) {
+	w, err := syslog.Dial(network, addr, priority, tag)
+	if err != nil {
+		return nil, fmt.Errorf("dialing syslog: %w", err)
+	}
+	return &syslogMessageHandler{w: w, f: LogfmtFormatter{}}, nil
+}
+
+func (h *syslogMessageHandler) Handle(msg FullMessage) error {
+	h.l.Lock()
+	defer h.l.Unlock()
+
+	b, err := h.f.Format(msg)
+	if err != nil {
+		return fmt.Errorf("formatting message: %w", err)
+	}
+	s := string(b)
+
+	switch msg.Level {
+	case LevelFatal:
+		return h.w.Crit(s)
+	case LevelError:
+		return h.w.Err(s)
+	case LevelWarn:
+		return h.w.Warning(s)
+	case LevelInfo:
+		return h.w.Info(s)
+	default:
+		return h.w.Debug(s)
+	}
+}
+
+func (h *syslogMessageHandler) Sync() error {
+	return nil
+}
@@ -4,8 +4,13 @@ package mnet
 
 import (
 	"context"
+	"crypto/tls"
 	"net"
+	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/mediocregopher/mediocre-go-lib/mcfg"
 	"github.com/mediocregopher/mediocre-go-lib/mcmp"
@@ -13,8 +18,40 @@ import (
 	"github.com/mediocregopher/mediocre-go-lib/merr"
 	"github.com/mediocregopher/mediocre-go-lib/mlog"
 	"github.com/mediocregopher/mediocre-go-lib/mrun"
+	"github.com/mediocregopher/mediocre-go-lib/mtime"
+
+	"golang.org/x/crypto/acme/autocert"
 )
 
+// ListenerMetrics, if set on a Listener, is called to record standard
+// connection-level activity as the Listener is used. See
+// mstat.InstrumentListener for an implementation which reports these as
+// statsd metrics.
+type ListenerMetrics interface {
+	// Accepted is called every time Accept successfully returns a new
+	// connection.
+	Accepted()
+	// ConnClosed is called when a connection previously returned by Accept
+	// is Closed.
+	ConnClosed()
+	// BytesIn and BytesOut are called after every Read/Write on a connection
+	// previously returned by Accept, with the number of bytes
+	// read/written.
+	BytesIn(n int)
+	BytesOut(n int)
+}
+
+// ListenerStats holds a snapshot of a Listener's connection counts, as
+// returned by (*Listener).Stats.
+type ListenerStats struct {
+	// Current is the number of connections currently accepted and not yet
+	// closed.
+	Current int
+	// Total is the number of connections which have been accepted over the
+	// Listener's lifetime, including Current.
+	Total int64
+}
+
 // Listener is returned by WithListen and simply wraps a net.Listener.
 type Listener struct {
 	// One of these will be populated during the start hook, depending on the
@@ -22,13 +59,34 @@ type Listener struct {
 	net.Listener
 	net.PacketConn
 
+	// Metrics, if set, is called to record Accept/Close/Read/Write activity
+	// on every connection this Listener accepts.
+	Metrics ListenerMetrics
+
 	cmp *mcmp.Component
+
+	sem       chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	connWG     sync.WaitGroup
+	connsL     sync.Mutex
+	conns      map[net.Conn]*trackedConn
+	totalConns int64
 }
 
 type listenerOpts struct {
-	proto           string
-	defaultAddr     string
-	closeOnShutdown bool
+	proto               string
+	defaultAddr         string
+	closeOnShutdown     bool
+	maxConns            int
+	defaultDrainTimeout time.Duration
+
+	tlsConfig *tls.Config
+
+	acmeHostPolicy autocert.HostPolicy
+	acmeCacheDir   string
+	acmeEmail      string
 }
 
 func (lOpts listenerOpts) isPacketConn() bool {
@@ -69,21 +127,52 @@ func ListenerDefaultAddr(defaultAddr string) ListenerOpt {
 	}
 }
 
+// ListenerMaxConns limits the Listener to having at most n connections
+// accepted and not yet closed at a time. Once the limit is reached, Accept
+// blocks (logging a debug message) until a connection is closed or the
+// Listener itself is.
+//
+// The default is 0, indicating no limit.
+func ListenerMaxConns(n int) ListenerOpt {
+	return func(opts *listenerOpts) {
+		opts.maxConns = n
+	}
+}
+
+// ListenerDrainTimeout adjusts the default value of the "drain-timeout"
+// config param: how long the Shutdown hook waits for in-flight connections
+// to close on their own before force-closing them. The timeout will still be
+// configurable via mcfg regardless of what this is set to. The default is 30
+// seconds.
+func ListenerDrainTimeout(d time.Duration) ListenerOpt {
+	return func(opts *listenerOpts) {
+		opts.defaultDrainTimeout = d
+	}
+}
+
 // InstListener instantiates a Listener which will be initialized when the Init
 // event is triggered on the given Component, and closed when the Shutdown event
 // is triggered on the returned Component.
 func InstListener(cmp *mcmp.Component, opts ...ListenerOpt) *Listener {
 	lOpts := listenerOpts{
-		proto:           "tcp",
-		defaultAddr:     ":0",
-		closeOnShutdown: true,
+		proto:               "tcp",
+		defaultAddr:         ":0",
+		closeOnShutdown:     true,
+		defaultDrainTimeout: 30 * time.Second,
 	}
 	for _, opt := range opts {
 		opt(&lOpts)
 	}
 
 	cmp = cmp.Child("net")
-	l := &Listener{cmp: cmp}
+	l := &Listener{
+		cmp:    cmp,
+		closed: make(chan struct{}),
+		conns:  map[net.Conn]*trackedConn{},
+	}
+	if lOpts.maxConns > 0 {
+		l.sem = make(chan struct{}, lOpts.maxConns)
+	}
 
 	addr := mcfg.String(cmp, "listen-addr",
 		mcfg.ParamDefault(lOpts.defaultAddr),
@@ -92,8 +181,28 @@ func InstListener(cmp *mcmp.Component, opts ...ListenerOpt) *Listener {
 				"[host]:port. If port is 0 then a random one will be chosen",
 		),
 	)
+	drainTimeout := mcfg.Duration(cmp, "drain-timeout",
+		mcfg.ParamDefault(mtime.Duration{Duration: lOpts.defaultDrainTimeout}),
+		mcfg.ParamUsage(
+			"How long to wait for in-flight connections to close on their "+
+				"own during shutdown, before force-closing them.",
+		),
+	)
 
-	mrun.InitHook(cmp, func(context.Context) error {
+	var acmeCacheDir, acmeHTTPAddr *string
+	if lOpts.acmeHostPolicy != nil {
+		acmeCacheDir = mcfg.String(cmp, "acme-cache-dir",
+			mcfg.ParamDefault(lOpts.acmeCacheDir),
+			mcfg.ParamUsage("Directory certificates obtained via ACME are cached in between restarts."))
+		acmeHTTPAddr = mcfg.String(cmp, "acme-http-addr",
+			mcfg.ParamDefault(":80"),
+			mcfg.ParamUsage("Address the companion HTTP-01 challenge listener listens on."))
+	}
+
+	var acmeHTTPListener net.Listener
+	acmeThreadCtx := context.Background()
+
+	mrun.InitHook(cmp, func(ctx context.Context) error {
 		var err error
 
 		cmp.Annotate("proto", lOpts.proto, "addr", *addr)
@@ -109,39 +218,193 @@ func InstListener(cmp *mcmp.Component, opts ...ListenerOpt) *Listener {
 			return merr.Wrap(err, cmp.Context())
 		}
 
+		tlsConfig := lOpts.tlsConfig
+		if lOpts.acmeHostPolicy != nil {
+			manager := &autocert.Manager{
+				Prompt:     autocert.AcceptTOS,
+				Cache:      autocert.DirCache(*acmeCacheDir),
+				Email:      lOpts.acmeEmail,
+				HostPolicy: lOpts.acmeHostPolicy,
+			}
+			tlsConfig = manager.TLSConfig()
+
+			if acmeHTTPListener, err = net.Listen("tcp", *acmeHTTPAddr); err != nil {
+				return merr.Wrap(err, cmp.Context())
+			}
+
+			acmeThreadCtx = mrun.WithThreads(acmeThreadCtx, 1, func() error {
+				mlog.From(cmp).Info("serving acme http-01 challenges", ctx)
+				err := http.Serve(acmeHTTPListener, manager.HTTPHandler(nil))
+				if !merr.Equal(err, net.ErrClosed) {
+					return merr.Wrap(err, cmp.Context(), ctx)
+				}
+				return nil
+			})
+		}
+		if tlsConfig != nil && !lOpts.isPacketConn() {
+			l.Listener = tls.NewListener(l.Listener, tlsConfig)
+		}
+
 		mlog.From(cmp).Info("listening")
 		return nil
 	})
 
-	// TODO track connections and wait for them to complete before shutting
-	// down?
-	mrun.ShutdownHook(cmp, func(context.Context) error {
+	mrun.ShutdownHook(cmp, func(ctx context.Context) error {
 		if !lOpts.closeOnShutdown {
 			return nil
 		}
-		mlog.From(cmp).Info("shutting down listener")
-		return l.Close()
+		mlog.From(cmp).Info("shutting down listener, draining connections",
+			mctx.Annotated("drainTimeout", drainTimeout.Duration))
+		closeErr := l.Close()
+
+		drained := make(chan struct{})
+		go func() {
+			l.connWG.Wait()
+			close(drained)
+		}()
+
+		select {
+		case <-drained:
+		case <-time.After(drainTimeout.Duration):
+			mlog.From(cmp).Warn("drain timeout exceeded, force-closing remaining connections")
+			l.closeTrackedConns()
+			<-drained
+		}
+
+		if acmeHTTPListener != nil {
+			if err := acmeHTTPListener.Close(); err != nil {
+				return merr.Wrap(err, cmp.Context(), ctx)
+			}
+			if err := mrun.Wait(acmeThreadCtx, ctx.Done()); err != nil {
+				return merr.Wrap(err, cmp.Context(), ctx)
+			}
+		}
+		return closeErr
 	})
 
 	return l
 }
 
-// Accept wraps a call to Accept on the underlying net.Listener, providing debug
-// logging.
+// Accept wraps a call to Accept on the underlying net.Listener, providing
+// debug logging, connection tracking for graceful shutdown, and (if
+// ListenerMaxConns was given) a cap on the number of connections open at
+// once.
 func (l *Listener) Accept() (net.Conn, error) {
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		default:
+			mlog.From(l.cmp).Debug("max connections reached, waiting for a slot to open")
+			select {
+			case l.sem <- struct{}{}:
+			case <-l.closed:
+				return nil, net.ErrClosed
+			}
+		}
+	}
+
 	conn, err := l.Listener.Accept()
 	if err != nil {
+		if l.sem != nil {
+			<-l.sem
+		}
 		return conn, err
 	}
 	mlog.From(l.cmp).Debug("connection accepted",
 		mctx.Annotated("remoteAddr", conn.RemoteAddr().String()))
-	return conn, nil
+
+	atomic.AddInt64(&l.totalConns, 1)
+	l.connWG.Add(1)
+
+	tc := &trackedConn{Conn: conn, l: l}
+	l.connsL.Lock()
+	l.conns[conn] = tc
+	l.connsL.Unlock()
+
+	if l.Metrics != nil {
+		l.Metrics.Accepted()
+	}
+	return tc, nil
+}
+
+// Stats returns the Listener's current connection counts.
+func (l *Listener) Stats() ListenerStats {
+	l.connsL.Lock()
+	current := len(l.conns)
+	l.connsL.Unlock()
+	return ListenerStats{
+		Current: current,
+		Total:   atomic.LoadInt64(&l.totalConns),
+	}
+}
+
+// closeTrackedConns force-closes every connection still tracked as open.
+func (l *Listener) closeTrackedConns() {
+	l.connsL.Lock()
+	conns := make([]*trackedConn, 0, len(l.conns))
+	for _, tc := range l.conns {
+		conns = append(conns, tc)
+	}
+	l.connsL.Unlock()
+
+	for _, tc := range conns {
+		tc.Close()
+	}
+}
+
+// trackedConn wraps a net.Conn accepted by a Listener, reporting its
+// activity to the Listener's Metrics (if set) and, on Close, removing
+// itself from the Listener's bookkeeping so Stats and the drain on shutdown
+// both see it as gone.
+type trackedConn struct {
+	net.Conn
+	l *Listener
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func (c *trackedConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 && c.l.Metrics != nil {
+		c.l.Metrics.BytesIn(n)
+	}
+	return n, err
+}
+
+func (c *trackedConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 && c.l.Metrics != nil {
+		c.l.Metrics.BytesOut(n)
+	}
+	return n, err
+}
+
+func (c *trackedConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.closeErr = c.Conn.Close()
+
+		c.l.connsL.Lock()
+		delete(c.l.conns, c.Conn)
+		c.l.connsL.Unlock()
+
+		if c.l.Metrics != nil {
+			c.l.Metrics.ConnClosed()
+		}
+		if c.l.sem != nil {
+			<-c.l.sem
+		}
+		c.l.connWG.Done()
+	})
+	return c.closeErr
 }
 
-// Close wraps a call to Close on the underlying net.Listener, providing debug
-// logging.
+// Close wraps a call to Close on the underlying net.Listener, providing
+// debug logging. It doesn't wait for, or close, connections already
+// accepted; see InstListener's Shutdown hook for that.
 func (l *Listener) Close() error {
 	mlog.From(l.cmp).Info("listener closing")
+	l.closeOnce.Do(func() { close(l.closed) })
 	if l.Listener != nil {
 		return l.Listener.Close()
 	}
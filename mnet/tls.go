@@ -0,0 +1,36 @@
+package mnet
+
+import (
+	"crypto/tls"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// ListenerTLS configures the Listener to wrap every accepted connection in
+// TLS, using cfg directly. If ListenerAutocert is also given, cfg is ignored
+// and the ACME-provided config is used instead. Only applies to stream-based
+// listeners (i.e. not one using ListenerProtocol("udp") or similar).
+func ListenerTLS(cfg *tls.Config) ListenerOpt {
+	return func(opts *listenerOpts) {
+		opts.tlsConfig = cfg
+	}
+}
+
+// ListenerAutocert configures the Listener to serve TLS using a certificate
+// obtained, and kept renewed, via ACME (e.g. Let's Encrypt), instead of a
+// static tls.Config. hostPolicy restricts which hostnames a certificate may
+// be requested for (see autocert.HostWhitelist); certificates and the ACME
+// account key are cached under cacheDir, which is also registered as the
+// "acme-cache-dir" config param so it can be overridden; email is given to
+// the ACME provider as a contact address.
+//
+// A companion HTTP-01 challenge listener is started as part of the same
+// Component, on the address given by the "acme-http-addr" config param
+// (default ":80").
+func ListenerAutocert(hostPolicy autocert.HostPolicy, cacheDir, email string) ListenerOpt {
+	return func(opts *listenerOpts) {
+		opts.acmeHostPolicy = hostPolicy
+		opts.acmeCacheDir = cacheDir
+		opts.acmeEmail = email
+	}
+}
@@ -0,0 +1,23 @@
+package mprom
+
+import (
+	"time"
+
+	"github.com/mediocregopher/mediocre-go-lib/mdb/mbigtable"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// InstrumentBigtable registers an "mbigtable_rpc_duration_seconds" histogram
+// onto reg and sets bt.Metrics so that every Get, Put, and ScanPrefix call
+// made through bt records its latency there, labeled by table and operation.
+func InstrumentBigtable(bt *mbigtable.Bigtable, reg prometheus.Registerer) {
+	hist := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "mbigtable_rpc_duration_seconds",
+		Help: "Latency of mbigtable RPCs, labeled by table and operation.",
+	}, []string{"table", "operation"})
+	reg.MustRegister(hist)
+
+	bt.Metrics = func(table, op string, dur time.Duration) {
+		hist.WithLabelValues(table, op).Observe(dur.Seconds())
+	}
+}
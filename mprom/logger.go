@@ -0,0 +1,35 @@
+package mprom
+
+import (
+	"github.com/mediocregopher/mediocre-go-lib/mlog"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// NewLoggerHook returns an mlog.Hook, for use with Logger.WithHook, which
+// registers a "log_messages_total" counter onto reg and increments it, per
+// Level, for every Message fired to it.
+func NewLoggerHook(reg prometheus.Registerer) mlog.Hook {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "log_messages_total",
+		Help: "Number of log messages emitted, labeled by level.",
+	}, []string{"level"})
+	reg.MustRegister(counter)
+
+	return loggerHook{counter: counter}
+}
+
+type loggerHook struct {
+	counter *prometheus.CounterVec
+}
+
+// Fire implements the mlog.Hook interface.
+func (h loggerHook) Fire(msg mlog.FullMessage) error {
+	h.counter.WithLabelValues(msg.Level.String()).Inc()
+	return nil
+}
+
+// Levels implements the mlog.Hook interface, returning nil so that every
+// Message is counted.
+func (h loggerHook) Levels() []mlog.Level {
+	return nil
+}
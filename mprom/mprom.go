@@ -0,0 +1,38 @@
+// Package mprom wires this module's components up to Prometheus, so that
+// services built on mrun/mlog/mdb get standard metrics without having to
+// hand-instrument anything themselves.
+package mprom
+
+import (
+	"github.com/mediocregopher/mediocre-go-lib/mcmp"
+	"github.com/mediocregopher/mediocre-go-lib/mhttp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// InstPrometheus creates a new prometheus.Registry and serves it as a
+// "/metrics" endpoint via mhttp.InstListeningServer, initialized and torn
+// down along with the rest of the Component's lifecycle.
+//
+// The returned Registry has the standard process and Go runtime Collectors
+// already registered on it (see prometheus.NewRegistry and
+// prometheus.Registry.MustRegister). Callers can register additional
+// Collectors of their own onto it, e.g. via NewLoggerHook, NewThreadMetrics,
+// or InstrumentBigtable.
+//
+// This function automatically handles setting up configuration parameters
+// via mcfg, by virtue of calling mhttp.InstListeningServer. The default
+// listen address is ":0".
+func InstPrometheus(cmp *mcmp.Component) *prometheus.Registry {
+	cmp = cmp.Child("prometheus")
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		prometheus.NewGoCollector(),
+	)
+
+	mhttp.InstListeningServer(cmp, promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+
+	return reg
+}
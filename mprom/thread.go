@@ -0,0 +1,59 @@
+package mprom
+
+import (
+	"context"
+	"time"
+
+	"github.com/mediocregopher/mediocre-go-lib/mrun"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ThreadMetrics instruments go-routines spawned via mrun.WithThreads,
+// exposing how many are currently live, how long they ran for, and how many
+// of them returned an error, as Prometheus metrics.
+type ThreadMetrics struct {
+	live     prometheus.Gauge
+	lifetime prometheus.Histogram
+	errors   prometheus.Counter
+}
+
+// NewThreadMetrics initializes a ThreadMetrics, registering its Collectors
+// onto reg.
+func NewThreadMetrics(reg prometheus.Registerer) *ThreadMetrics {
+	tm := &ThreadMetrics{
+		live: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "mrun_threads_live",
+			Help: "Number of go-routines currently running, as spawned via mrun.WithThreads.",
+		}),
+		lifetime: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name: "mrun_thread_lifetime_seconds",
+			Help: "How long each go-routine spawned via mrun.WithThreads ran for.",
+		}),
+		errors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "mrun_thread_errors_total",
+			Help: "Number of go-routines spawned via mrun.WithThreads which returned a non-nil error.",
+		}),
+	}
+	reg.MustRegister(tm.live, tm.lifetime, tm.errors)
+	return tm
+}
+
+// WithThreads is a drop-in replacement for mrun.WithThreads which
+// additionally tracks the number of currently live go-routines, their
+// lifetimes, and how many of them return an error, using tm's metrics.
+func (tm *ThreadMetrics) WithThreads(ctx context.Context, n uint, fn func() error) context.Context {
+	return mrun.WithThreads(ctx, n, func() error {
+		tm.live.Inc()
+		start := time.Now()
+		defer func() {
+			tm.lifetime.Observe(time.Since(start).Seconds())
+			tm.live.Dec()
+		}()
+
+		err := fn()
+		if err != nil {
+			tm.errors.Inc()
+		}
+		return err
+	})
+}
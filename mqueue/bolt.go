@@ -0,0 +1,192 @@
+package mqueue
+
+import (
+	"context"
+	"encoding/binary"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltQueue is a disk-backed Queue using a single embedded bolt file,
+// providing single-process durability across restarts without requiring any
+// external service.
+//
+// Entries live in one of two buckets: "pending" (enqueued, not yet
+// delivered) and "inflight" (delivered via Dequeue, not yet Ack'd/Nack'd).
+// On open, any entries left in "inflight" from a previous run (e.g. because
+// the process crashed before Ack/Nack was called) are moved back into
+// "pending", so they get redelivered.
+type boltQueue struct {
+	db           *bolt.DB
+	pendingBkt   []byte
+	inflightBkt  []byte
+	pollInterval time.Duration
+	length       int64 // atomic
+}
+
+func newBoltQueue(path, key string, pollInterval time.Duration) (*boltQueue, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	q := &boltQueue{
+		db:           db,
+		pendingBkt:   []byte(key + ".pending"),
+		inflightBkt:  []byte(key + ".inflight"),
+		pollInterval: pollInterval,
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		pending, err := tx.CreateBucketIfNotExists(q.pendingBkt)
+		if err != nil {
+			return err
+		}
+		inflight, err := tx.CreateBucketIfNotExists(q.inflightBkt)
+		if err != nil {
+			return err
+		}
+
+		// recover any entries abandoned by a previous, crashed run. Keys are
+		// collected up-front since bolt doesn't allow mutating a bucket
+		// while a Cursor over it is in use.
+		var abandoned [][]byte
+		c := inflight.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if err := pending.Put(k, v); err != nil {
+				return err
+			}
+			abandoned = append(abandoned, append([]byte{}, k...))
+		}
+		for _, k := range abandoned {
+			if err := inflight.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if err := q.countPending(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return q, nil
+}
+
+func (q *boltQueue) countPending() error {
+	return q.db.View(func(tx *bolt.Tx) error {
+		stats := tx.Bucket(q.pendingBkt).Stats()
+		atomic.StoreInt64(&q.length, int64(stats.KeyN))
+		return nil
+	})
+}
+
+func (q *boltQueue) Enqueue(ctx context.Context, data []byte) error {
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket(q.pendingBkt)
+		seq, err := bkt.NextSequence()
+		if err != nil {
+			return err
+		}
+		return bkt.Put(encodeBoltKey(seq), data)
+	})
+	if err != nil {
+		return err
+	}
+	atomic.AddInt64(&q.length, 1)
+	return nil
+}
+
+// Dequeue polls the bolt file every pollInterval until an entry is found in
+// the pending bucket or ctx is canceled.
+func (q *boltQueue) Dequeue(ctx context.Context) (Entry, bool, error) {
+	t := time.NewTicker(q.pollInterval)
+	defer t.Stop()
+
+	for {
+		entry, ok, err := q.tryDequeue()
+		if err != nil || ok {
+			return entry, ok, err
+		}
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return Entry{}, false, nil
+		}
+	}
+}
+
+func (q *boltQueue) tryDequeue() (Entry, bool, error) {
+	var key, data []byte
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		pending := tx.Bucket(q.pendingBkt)
+		inflight := tx.Bucket(q.inflightBkt)
+
+		c := pending.Cursor()
+		k, v := c.First()
+		if k == nil {
+			return nil
+		}
+		key, data = append([]byte{}, k...), append([]byte{}, v...)
+
+		if err := inflight.Put(key, data); err != nil {
+			return err
+		}
+		return pending.Delete(key)
+	})
+	if err != nil || key == nil {
+		return Entry{}, false, err
+	}
+
+	id := decodeBoltKey(key)
+	return Entry{
+		ID:   id,
+		Data: data,
+		Ack: func() error {
+			err := q.db.Update(func(tx *bolt.Tx) error {
+				return tx.Bucket(q.inflightBkt).Delete(key)
+			})
+			if err == nil {
+				atomic.AddInt64(&q.length, -1)
+			}
+			return err
+		},
+		Nack: func() {
+			_ = q.db.Update(func(tx *bolt.Tx) error {
+				inflight := tx.Bucket(q.inflightBkt)
+				pending := tx.Bucket(q.pendingBkt)
+				if err := pending.Put(key, inflight.Get(key)); err != nil {
+					return err
+				}
+				return inflight.Delete(key)
+			})
+		},
+	}, true, nil
+}
+
+func (q *boltQueue) Len() (int64, error) {
+	return atomic.LoadInt64(&q.length), nil
+}
+
+func (q *boltQueue) Close() error {
+	return q.db.Close()
+}
+
+func encodeBoltKey(seq uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, seq)
+	return b
+}
+
+func decodeBoltKey(b []byte) string {
+	return strconv.FormatUint(binary.BigEndian.Uint64(b), 10)
+}
@@ -0,0 +1,58 @@
+package mqueue
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+)
+
+// memQueue is an in-memory, channel-backed Queue. It is not durable across
+// process restarts, and is intended primarily for use in tests.
+type memQueue struct {
+	ch     chan Entry
+	nextID uint64 // atomic
+	length int64  // atomic; entries enqueued but not yet Ack'd
+}
+
+func newMemQueue(bufSize int) *memQueue {
+	return &memQueue{ch: make(chan Entry, bufSize)}
+}
+
+func (q *memQueue) Enqueue(ctx context.Context, data []byte) error {
+	var entry Entry
+	entry.ID = strconv.FormatUint(atomic.AddUint64(&q.nextID, 1), 10)
+	entry.Data = data
+	entry.Ack = func() error {
+		atomic.AddInt64(&q.length, -1)
+		return nil
+	}
+	entry.Nack = func() {
+		q.ch <- entry
+	}
+
+	select {
+	case q.ch <- entry:
+		atomic.AddInt64(&q.length, 1)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *memQueue) Dequeue(ctx context.Context) (Entry, bool, error) {
+	select {
+	case entry, ok := <-q.ch:
+		return entry, ok, nil
+	case <-ctx.Done():
+		return Entry{}, false, nil
+	}
+}
+
+func (q *memQueue) Len() (int64, error) {
+	return atomic.LoadInt64(&q.length), nil
+}
+
+func (q *memQueue) Close() error {
+	close(q.ch)
+	return nil
+}
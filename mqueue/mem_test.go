@@ -0,0 +1,80 @@
+package mqueue
+
+import (
+	"context"
+	"sync/atomic"
+	. "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemQueue(t *T) {
+	q := newMemQueue(16)
+	defer q.Close()
+
+	ctx := context.Background()
+
+	assert.NoError(t, q.Enqueue(ctx, []byte("foo")))
+	assert.NoError(t, q.Enqueue(ctx, []byte("bar")))
+
+	n, err := q.Len()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	entry, ok, err := q.Dequeue(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("foo"), entry.Data)
+
+	// not yet Ack'd, so Len still counts it
+	n, err = q.Len()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	assert.NoError(t, entry.Ack())
+	n, err = q.Len()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	// Nack'd entries are redelivered
+	entry, ok, err = q.Dequeue(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("bar"), entry.Data)
+	entry.Nack()
+
+	entry, ok, err = q.Dequeue(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("bar"), entry.Data)
+	assert.NoError(t, entry.Ack())
+}
+
+func TestConsume(t *T) {
+	q := newMemQueue(16)
+	defer q.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, q.Enqueue(ctx, []byte("x")))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	var processed int64
+	done := make(chan struct{})
+	go func() {
+		Consume(ctx, q, 2, func(Entry) error {
+			if atomic.AddInt64(&processed, 1) == 5 {
+				cancel()
+			}
+			return nil
+		})
+		close(done)
+	}()
+
+	<-done
+	assert.Equal(t, int64(5), atomic.LoadInt64(&processed))
+}
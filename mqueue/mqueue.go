@@ -0,0 +1,215 @@
+// Package mqueue implements a generic, durable work queue abstraction with
+// interchangeable backends, so that services which need a background queue
+// aren't forced to stand up infrastructure (e.g. redis) they don't otherwise
+// need.
+package mqueue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mediocregopher/mediocre-go-lib/mcfg"
+	"github.com/mediocregopher/mediocre-go-lib/mcmp"
+	"github.com/mediocregopher/mediocre-go-lib/mdb/mredis"
+	"github.com/mediocregopher/mediocre-go-lib/mlog"
+	"github.com/mediocregopher/mediocre-go-lib/mrun"
+	"github.com/mediocregopher/mediocre-go-lib/mtime"
+)
+
+// Entry is a single piece of work read from a Queue via Dequeue.
+type Entry struct {
+	// ID uniquely identifies this Entry within its Queue. Its format is
+	// backend-specific.
+	ID string
+
+	// Data is the payload which was originally passed to Enqueue.
+	Data []byte
+
+	// Ack is used to declare that this Entry has been successfully
+	// processed, and should not be redelivered.
+	Ack func() error
+
+	// Nack is used to declare that this Entry was not successfully
+	// processed, and needs to be redelivered to a future Dequeue call.
+	Nack func()
+}
+
+// Queue is a generic, durable work queue. Entries are not removed from the
+// queue's underlying store until they're Ack'd, so an Entry which is
+// Dequeue'd but never Ack'd or Nack'd (e.g. because the process crashed) is
+// redelivered once the Queue (or another instance backed by the same
+// underlying store) is started back up.
+type Queue interface {
+	// Enqueue adds a new piece of work to the queue.
+	Enqueue(ctx context.Context, data []byte) error
+
+	// Dequeue returns the next piece of work needing processing. It blocks,
+	// up to some backend-specific timeout, until work is available or ctx is
+	// canceled; in the latter case false is returned rather than an error.
+	Dequeue(ctx context.Context) (Entry, bool, error)
+
+	// Len returns the approximate number of entries which have been
+	// enqueued but not yet Ack'd.
+	Len() (int64, error)
+
+	// Close releases any resources held by the Queue.
+	Close() error
+}
+
+// backend name strings, as used by the "backend" config param.
+const (
+	backendMem    = "mem"
+	backendBolt   = "bolt"
+	backendStream = "stream"
+)
+
+type queueOpts struct {
+	redis *mredis.Redis
+}
+
+// QueueOption is a value which adjusts the behavior of WithQueue.
+type QueueOption func(*queueOpts)
+
+// QueueRedis specifies that the given, already-initialized Redis instance
+// should be used when the "stream" backend is selected, rather than one being
+// instantiated via mredis.InstRedis.
+func QueueRedis(r *mredis.Redis) QueueOption {
+	return func(opts *queueOpts) {
+		opts.redis = r
+	}
+}
+
+// WithQueue instantiates a Queue whose backend is selected via config (see
+// the "backend" param), and which will be initialized when the Init event is
+// triggered on the given Component, and Closed when the Shutdown event is
+// triggered.
+//
+// The returned Queue is a thin wrapper which proxies to whichever backend was
+// configured; it's usable as soon as Init has completed.
+func WithQueue(parent *mcmp.Component, options ...QueueOption) Queue {
+	var opts queueOpts
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	cmp := parent.Child("queue")
+	q := new(lazyQueue)
+
+	backend := mcfg.String(cmp, "backend",
+		mcfg.ParamDefault(backendMem),
+		mcfg.ParamUsage("Which Queue backend to use. One of 'mem', 'bolt', or 'stream'"))
+	key := mcfg.String(cmp, "key",
+		mcfg.ParamDefault("mqueue"),
+		mcfg.ParamUsage("Name of the queue, used as the bolt bucket name or redis stream key"))
+	memBufSize := mcfg.Int(cmp, "mem-buffer-size",
+		mcfg.ParamDefault(1024),
+		mcfg.ParamUsage("Max number of un-ack'd entries buffered in memory, when backend is mem"))
+	boltPath := mcfg.String(cmp, "bolt-path",
+		mcfg.ParamDefault("mqueue.bolt"),
+		mcfg.ParamUsage("Path to the bolt file to use, when backend is bolt"))
+	boltPollInterval := mcfg.Duration(cmp, "bolt-poll-interval",
+		mcfg.ParamDefault(mtime.Duration{Duration: 250 * time.Millisecond}),
+		mcfg.ParamUsage("How often Dequeue polls the bolt file for new entries, when backend is bolt"))
+
+	mrun.InitHook(cmp, func(ctx context.Context) error {
+		cmp.Annotate("backend", *backend, "key", *key)
+		mlog.From(cmp).Info("initializing queue", ctx)
+
+		var inner Queue
+		var err error
+		switch *backend {
+		case backendMem:
+			inner = newMemQueue(*memBufSize)
+		case backendBolt:
+			inner, err = newBoltQueue(*boltPath, *key, boltPollInterval.Duration)
+		case backendStream:
+			r := opts.redis
+			if r == nil {
+				r = mredis.InstRedis(cmp)
+			}
+			inner = newStreamQueue(r, *key)
+		default:
+			err = fmt.Errorf("unknown queue backend %q", *backend)
+		}
+		if err != nil {
+			return err
+		}
+
+		q.setInner(inner)
+		return nil
+	})
+	mrun.ShutdownHook(cmp, func(ctx context.Context) error {
+		mlog.From(cmp).Info("shutting down queue", ctx)
+		return q.Close()
+	})
+
+	return q
+}
+
+// lazyQueue proxies to an inner Queue which isn't available until Init has
+// run (since the inner Queue's backend, e.g. a bolt file or redis
+// connection, isn't ready until then either).
+type lazyQueue struct {
+	mu    sync.RWMutex
+	inner Queue
+}
+
+func (q *lazyQueue) setInner(inner Queue) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.inner = inner
+}
+
+func (q *lazyQueue) get() Queue {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.inner
+}
+
+func (q *lazyQueue) Enqueue(ctx context.Context, data []byte) error {
+	return q.get().Enqueue(ctx, data)
+}
+
+func (q *lazyQueue) Dequeue(ctx context.Context) (Entry, bool, error) {
+	return q.get().Dequeue(ctx)
+}
+
+func (q *lazyQueue) Len() (int64, error) {
+	return q.get().Len()
+}
+
+func (q *lazyQueue) Close() error {
+	if inner := q.get(); inner != nil {
+		return inner.Close()
+	}
+	return nil
+}
+
+// Consume runs n worker goroutines, each of which repeatedly Dequeues an
+// Entry from q and passes it to fn, Ack'ing the Entry if fn returns nil and
+// Nack'ing it otherwise. Consume blocks until ctx is canceled, at which point
+// it waits for all in-flight fn calls to finish before returning.
+func Consume(ctx context.Context, q Queue, n int, fn func(Entry) error) {
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				entry, ok, err := q.Dequeue(ctx)
+				if err != nil || !ok {
+					continue
+				}
+
+				if err := fn(entry); err != nil {
+					entry.Nack()
+				} else if err := entry.Ack(); err != nil {
+					entry.Nack()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
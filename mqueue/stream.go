@@ -0,0 +1,61 @@
+package mqueue
+
+import (
+	"context"
+
+	"github.com/mediocregopher/mediocre-go-lib/mdb/mredis"
+	"github.com/mediocregopher/radix/v3"
+)
+
+// streamQueue wraps an mredis.Stream, giving it durability via Redis Streams
+// and consumer groups rather than a single process's local disk.
+type streamQueue struct {
+	redis  *mredis.Redis
+	key    string
+	stream *mredis.Stream
+}
+
+func newStreamQueue(r *mredis.Redis, key string) *streamQueue {
+	return &streamQueue{
+		redis: r,
+		key:   key,
+		stream: mredis.NewStream(r, mredis.StreamOpts{
+			Key:      key,
+			Group:    "mqueue",
+			Consumer: "mqueue-consumer",
+		}),
+	}
+}
+
+const streamDataField = "data"
+
+func (q *streamQueue) Enqueue(ctx context.Context, data []byte) error {
+	return q.redis.Do(radix.FlatCmd(nil, "XADD", q.key, "*", streamDataField, data))
+}
+
+func (q *streamQueue) Dequeue(ctx context.Context) (Entry, bool, error) {
+	se, ok, err := q.stream.Next()
+	if err != nil || !ok {
+		return Entry{}, ok, err
+	}
+
+	return Entry{
+		ID:   se.ID.String(),
+		Data: []byte(se.Fields[streamDataField]),
+		Ack:  se.Ack,
+		Nack: se.Nack,
+	}, true, nil
+}
+
+// Len returns the stream's length as given by XLEN. Ack only XACKs an entry
+// and leaves it in the stream, so entries which have been Ack'd are still
+// counted.
+func (q *streamQueue) Len() (int64, error) {
+	var n int64
+	err := q.redis.Do(radix.Cmd(&n, "XLEN", q.key))
+	return n, err
+}
+
+func (q *streamQueue) Close() error {
+	return nil
+}
@@ -0,0 +1,93 @@
+package mrand
+
+// WeightedSampler draws random indices from a fixed set of weights in O(1)
+// time per draw, after an O(n) preprocessing step performed by
+// NewWeightedSampler. It implements Walker's alias method, and is intended
+// for callers which draw many samples from the same distribution (e.g.
+// load-balancing across a fixed set of backends, or weighted fuzz/test-case
+// generation), where Rand.Element's O(n)-per-draw weighting function would
+// otherwise dominate.
+type WeightedSampler struct {
+	prob  []float64
+	alias []int
+}
+
+// NewWeightedSampler builds a WeightedSampler over len(weights) indices,
+// where index i is drawn with probability proportional to weights[i].
+// weights must be non-empty and sum to greater than zero.
+func NewWeightedSampler(weights []uint64) *WeightedSampler {
+	n := len(weights)
+	if n == 0 {
+		panic("mrand: NewWeightedSampler called with no weights")
+	}
+
+	var total uint64
+	for _, w := range weights {
+		total += w
+	}
+	if total == 0 {
+		panic("mrand: NewWeightedSampler called with weights summing to zero")
+	}
+
+	// scaled[i] is weights[i] normalized such that the average bucket has a
+	// scaled weight of exactly 1; a bucket is "under-full" if its scaled
+	// weight is less than 1, and "over-full" otherwise.
+	scaled := make([]float64, n)
+	for i, w := range weights {
+		scaled[i] = float64(w) * float64(n) / float64(total)
+	}
+
+	var underfull, overfull []int
+	for i, s := range scaled {
+		if s < 1 {
+			underfull = append(underfull, i)
+		} else {
+			overfull = append(overfull, i)
+		}
+	}
+
+	prob := make([]float64, n)
+	alias := make([]int, n)
+
+	for len(underfull) > 0 && len(overfull) > 0 {
+		u := underfull[len(underfull)-1]
+		underfull = underfull[:len(underfull)-1]
+		o := overfull[len(overfull)-1]
+		overfull = overfull[:len(overfull)-1]
+
+		// bucket u is filled entirely by its own weight (prob[u]) plus,
+		// the rest of the time, a redirect to bucket o (alias[u]).
+		prob[u] = scaled[u]
+		alias[u] = o
+
+		// o gives up whatever it lent to u; if that leaves it under-full
+		// it moves to the other pile to be paired up itself.
+		scaled[o] = scaled[o] - (1 - scaled[u])
+		if scaled[o] < 1 {
+			underfull = append(underfull, o)
+		} else {
+			overfull = append(overfull, o)
+		}
+	}
+
+	// whatever's left over (in either pile, due to floating point error) is
+	// full on its own weight alone.
+	for _, i := range overfull {
+		prob[i] = 1
+	}
+	for _, i := range underfull {
+		prob[i] = 1
+	}
+
+	return &WeightedSampler{prob: prob, alias: alias}
+}
+
+// Sample draws a random index, using r as the source of randomness, such
+// that index i is returned with the probability given to NewWeightedSampler.
+func (ws *WeightedSampler) Sample(r Rand) int {
+	i := r.Intn(len(ws.prob))
+	if r.Float64() < ws.prob[i] {
+		return i
+	}
+	return ws.alias[i]
+}
@@ -0,0 +1,40 @@
+package mrand
+
+import (
+	. "testing"
+)
+
+func TestWeightedSampler(t *T) {
+	weights := []uint64{1, 2, 7}
+	ws := NewWeightedSampler(weights)
+
+	r := Rand{Rand: DefaultRand.Rand}
+	var total uint64
+	for _, w := range weights {
+		total += w
+	}
+
+	counts := make([]int, len(weights))
+	const n = 1_000_000
+	for i := 0; i < n; i++ {
+		counts[ws.Sample(r)]++
+	}
+
+	for i, w := range weights {
+		got := float64(counts[i]) / n
+		want := float64(w) / float64(total)
+		if diff := got - want; diff < -0.01 || diff > 0.01 {
+			t.Fatalf("weight %d: got frequency %f, want ~%f", i, got, want)
+		}
+	}
+}
+
+func TestWeightedSamplerSingleton(t *T) {
+	ws := NewWeightedSampler([]uint64{5})
+	r := Rand{Rand: DefaultRand.Rand}
+	for i := 0; i < 100; i++ {
+		if got := ws.Sample(r); got != 0 {
+			t.Fatalf("expected index 0, got %d", got)
+		}
+	}
+}
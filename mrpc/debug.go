@@ -1,5 +1,7 @@
 package mrpc
 
+import "context"
+
 // Debug data is arbitrary data embedded in a Request by the Client or in its
 // Response by the Server. Debug data is organized into namespaces to help avoid
 // conflicts while still preserving serializability.
@@ -46,3 +48,24 @@ func (d Debug) Get(ns, key string) (interface{}, bool) {
 	val, ok := d[ns][key]
 	return val, ok
 }
+
+type debugCtxKey struct{}
+
+// Inject returns a copy of ctx with d attached to it, for later retrieval via
+// Extract.
+//
+// This is distinct from passing Debug through a Request or ResponseWriter
+// directly; Inject/Extract are meant for code which only has a
+// context.Context to work with, e.g. gRPC/HTTP middleware sitting below
+// mrpc's own Handler/Client types (see mrpc/mrpcdebug, which uses these to
+// carry Debug over the wire for such middleware).
+func (d Debug) Inject(ctx context.Context) context.Context {
+	return context.WithValue(ctx, debugCtxKey{}, d)
+}
+
+// Extract returns the Debug previously attached to ctx via Inject, and
+// whether any was found.
+func Extract(ctx context.Context) (Debug, bool) {
+	d, ok := ctx.Value(debugCtxKey{}).(Debug)
+	return d, ok
+}
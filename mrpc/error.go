@@ -0,0 +1,87 @@
+package mrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrCode identifies a stable category of error that a Client can act on
+// programmatically (e.g. retrying, or mapping to an HTTP status), as opposed
+// to Error's Message field, which is intended for human consumption only.
+type ErrCode string
+
+// The built-in ErrCodes, loosely modeled on JSON-RPC 2.0's standard error
+// codes. RPC implementations and Handlers are free to define and use their
+// own ErrCodes as well; these are only the ones mrpc itself knows how to
+// produce automatically (see NewError).
+const (
+	CodeMethodNotFound   ErrCode = "method_not_found"
+	CodeInvalidRequest   ErrCode = "invalid_request"
+	CodeInternal         ErrCode = "internal"
+	CodeCanceled         ErrCode = "canceled"
+	CodeUnauthorized     ErrCode = "unauthorized"
+	CodeDeadlineExceeded ErrCode = "deadline_exceeded"
+)
+
+// Error is an error which can be returned from a Handler's ServeRPC (as the
+// ResponseWriter's Response) in order to give a Client structured,
+// actionable information about what went wrong. Unlike a plain Go error,
+// whose concrete type and any unexported state is lost once it crosses an
+// RPC implementation's wire format, an Error's fields are defined to survive
+// round-tripping intact.
+type Error struct {
+	Code    ErrCode         `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Coder may be implemented by an error to give its ErrCode, for errors which
+// an RPC implementation wants to carry a code for but which can't, or
+// shouldn't have to, be constructed as an *Error directly (see NewError).
+type Coder interface {
+	Code() ErrCode
+}
+
+// NewError converts err into an *Error, for use as a Handler's
+// ResponseWriter.Response. If err is nil, nil is returned.
+//
+// If err is already an *Error it's returned as-is. Otherwise, if err wraps
+// context.Canceled or context.DeadlineExceeded (per errors.Is) the
+// corresponding Code is used; otherwise, if err implements Coder, that
+// Code is used. If none of the above apply the error is considered
+// unexpected and is wrapped as CodeInternal.
+func NewError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	var asErr *Error
+	if errors.As(err, &asErr) {
+		return asErr
+	}
+
+	code := CodeInternal
+	switch {
+	case errors.Is(err, context.Canceled):
+		code = CodeCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		code = CodeDeadlineExceeded
+	default:
+		var coder Coder
+		if errors.As(err, &coder) {
+			code = coder.Code()
+		}
+	}
+
+	return &Error{Code: code, Message: err.Error()}
+}
@@ -0,0 +1,49 @@
+package mrpc
+
+import (
+	"context"
+	"errors"
+	. "testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewError(t *T) {
+	assert.Nil(t, NewError(nil))
+
+	t.Run("passthrough", func(t *T) {
+		orig := &Error{Code: CodeUnauthorized, Message: "nope"}
+		assert.Same(t, orig, NewError(orig))
+	})
+
+	t.Run("canceled", func(t *T) {
+		err := NewError(context.Canceled)
+		assert.Equal(t, CodeCanceled, err.Code)
+	})
+
+	t.Run("deadlineExceeded", func(t *T) {
+		err := NewError(context.DeadlineExceeded)
+		assert.Equal(t, CodeDeadlineExceeded, err.Code)
+	})
+
+	t.Run("coder", func(t *T) {
+		err := NewError(coderErr{})
+		assert.Equal(t, CodeUnauthorized, err.Code)
+	})
+
+	t.Run("unknown", func(t *T) {
+		err := NewError(errors.New("whatever"))
+		assert.Equal(t, CodeInternal, err.Code)
+		assert.Equal(t, "whatever", err.Message)
+	})
+
+	t.Run("asError", func(t *T) {
+		var target *Error
+		assert.True(t, errors.As(NewError(context.Canceled), &target))
+	})
+}
+
+type coderErr struct{}
+
+func (coderErr) Error() string { return "coder err" }
+func (coderErr) Code() ErrCode { return CodeUnauthorized }
@@ -0,0 +1,227 @@
+package jsonrpc2
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mediocregopher/mediocre-go-lib/mrpc"
+)
+
+// Client is an mrpc.Client which drives JSON-RPC 2.0 calls over a single
+// io.ReadWriteCloser. Unlike jstreamrpc.Client, concurrent CallRPCs aren't
+// serialized behind a mutex: they're multiplexed over the one connection by
+// request id, via a background goroutine (started by NewClient) which reads
+// responses (and batches of responses) off of the connection and dispatches
+// each to the CallRPC call which is waiting on it.
+type Client struct {
+	rwc io.ReadWriteCloser
+
+	writeL sync.Mutex
+	enc    *json.Encoder
+
+	nextID int64
+
+	l       sync.Mutex
+	pending map[string]chan response // nil once the connection is dead
+	readErr error
+}
+
+var _ mrpc.Client = (*Client)(nil)
+
+// NewClient returns a Client which makes calls over rwc.
+func NewClient(rwc io.ReadWriteCloser) *Client {
+	c := &Client{
+		rwc:     rwc,
+		enc:     json.NewEncoder(rwc),
+		pending: map[string]chan response{},
+	}
+	go c.readLoop()
+	return c
+}
+
+// Dial establishes a connection using net.Dial and wraps it in a Client via
+// NewClient.
+func Dial(network, addr string) (*Client, error) {
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// DialTLS is like Dial, but establishes the connection via tls.Dial using
+// the given tls.Config.
+func DialTLS(network, addr string, tlsConfig *tls.Config) (*Client, error) {
+	conn, err := tls.Dial(network, addr, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// Close closes the Client's underlying connection, causing any CallRPCs
+// still pending on it to fail. The Client may not be used afterwards.
+func (c *Client) Close() error {
+	return c.rwc.Close()
+}
+
+// readLoop continuously decodes responses (and batches of responses) off of
+// c.rwc and dispatches each to the pending CallRPC waiting on it, until a
+// read fails (including on a clean close, via io.EOF), at which point every
+// still-pending call is failed with that error.
+func (c *Client) readLoop() {
+	dec := json.NewDecoder(c.rwc)
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			c.shutdown(err)
+			return
+		}
+
+		if isBatch(raw) {
+			var resps []response
+			if err := json.Unmarshal(raw, &resps); err != nil {
+				continue
+			}
+			for _, resp := range resps {
+				c.dispatch(resp)
+			}
+			continue
+		}
+
+		var resp response
+		if err := json.Unmarshal(raw, &resp); err != nil {
+			continue
+		}
+		c.dispatch(resp)
+	}
+}
+
+// dispatch hands resp off to the pending CallRPC with the matching id, if
+// any (the call may have already been abandoned locally, e.g. due to ctx
+// cancellation, in which case resp is simply dropped).
+func (c *Client) dispatch(resp response) {
+	key := string(resp.ID)
+
+	c.l.Lock()
+	ch, ok := c.pending[key]
+	if ok {
+		delete(c.pending, key)
+	}
+	c.l.Unlock()
+
+	if ok {
+		ch <- resp
+	}
+}
+
+// shutdown marks the connection dead, failing every currently pending call
+// with err and causing all future CallRPCs to fail immediately.
+func (c *Client) shutdown(err error) {
+	c.l.Lock()
+	defer c.l.Unlock()
+	if c.pending == nil {
+		return
+	}
+
+	c.readErr = err
+	pending := c.pending
+	c.pending = nil
+	for _, ch := range pending {
+		close(ch)
+	}
+}
+
+func errResponse(err error) mrpc.Response {
+	return mrpc.Response{Unmarshal: func(interface{}) error { return err }}
+}
+
+// CallRPC implements the mrpc.Client interface.
+//
+// If ctx is canceled before a response is read, CallRPC abandons the call
+// locally (so a late response, if one ever arrives, is simply dropped) and
+// returns ctx.Err(); no cancellation is ever sent over the wire, since
+// JSON-RPC 2.0 has no such frame.
+func (c *Client) CallRPC(ctx context.Context, method string, args interface{}, dbg mrpc.Debug) mrpc.Response {
+	params, err := json.Marshal(args)
+	if err != nil {
+		return errResponse(fmt.Errorf("jsonrpc2: marshaling args: %w", err))
+	}
+
+	id := atomic.AddInt64(&c.nextID, 1)
+	idRaw := json.RawMessage(strconv.FormatInt(id, 10))
+	ch := make(chan response, 1)
+
+	c.l.Lock()
+	if c.pending == nil {
+		readErr := c.readErr
+		c.l.Unlock()
+		return errResponse(fmt.Errorf("jsonrpc2: client is no longer usable: %w", readErr))
+	}
+	c.pending[string(idRaw)] = ch
+	c.l.Unlock()
+
+	req := request{JSONRPC: "2.0", Method: method, Params: params, ID: &idRaw}
+	c.writeL.Lock()
+	err = c.enc.Encode(req)
+	c.writeL.Unlock()
+	if err != nil {
+		c.abandon(string(idRaw))
+		return errResponse(fmt.Errorf("jsonrpc2: writing request: %w", err))
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return errResponse(fmt.Errorf("jsonrpc2: connection closed: %w", c.readErr))
+		}
+		return toMRPCResponse(resp)
+	case <-ctx.Done():
+		c.abandon(string(idRaw))
+		return errResponse(ctx.Err())
+	}
+}
+
+// abandon removes a pending call's entry, so that a response which arrives
+// for it later (if ever) is silently dropped by dispatch instead of being
+// delivered to a CallRPC which has already returned.
+func (c *Client) abandon(key string) {
+	c.l.Lock()
+	if c.pending != nil {
+		delete(c.pending, key)
+	}
+	c.l.Unlock()
+}
+
+// toMRPCResponse converts a successfully-received response into an
+// mrpc.Response, reconstructing an *mrpc.Error (and any Debug smuggled
+// alongside it, see toJSONRPCError) from an error response.
+func toMRPCResponse(resp response) mrpc.Response {
+	if resp.Error != nil {
+		var ed errorData
+		// best-effort: if Data isn't in the errorData shape (e.g. the peer
+		// isn't using this package), ed is just left zero-valued.
+		json.Unmarshal(resp.Error.Data, &ed)
+
+		mErr := &mrpc.Error{
+			Code:    errCodeFromJSONRPC(resp.Error.Code),
+			Message: resp.Error.Message,
+			Data:    ed.Data,
+		}
+		return mrpc.Response{
+			Unmarshal: func(interface{}) error { return mErr },
+			Debug:     ed.Debug,
+		}
+	}
+
+	return mrpc.Response{
+		Unmarshal: func(i interface{}) error { return json.Unmarshal(resp.Result, i) },
+	}
+}
@@ -0,0 +1,111 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/mediocregopher/mediocre-go-lib/mrpc"
+)
+
+// HTTPHandler adapts h into an http.Handler which accepts JSON-RPC 2.0
+// requests (or batches thereof) as a POST body and writes the corresponding
+// response (or batch) as the HTTP response body. There's no official spec
+// for JSON-RPC 2.0 over HTTP; this follows the common convention of one
+// request/response pair per POST.
+//
+// Notifications (requests with no id) have no response to give, but HTTP
+// still requires some reply; an empty 204 is written for these.
+func HTTPHandler(h mrpc.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+			return
+		}
+
+		raw, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ctx := r.Context()
+		if isBatch(raw) {
+			var raws []json.RawMessage
+			if err := json.Unmarshal(raw, &raws); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			resps := handleBatch(ctx, raws, h)
+			if len(resps) == 0 {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			json.NewEncoder(w).Encode(resps)
+			return
+		}
+
+		resp, ok := handleOne(ctx, raw, h)
+		if !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+// HTTPClient is an mrpc.Client which makes JSON-RPC 2.0 calls by POSTing to
+// a single HTTP endpoint, one call per request. Unlike Client, no
+// request-id-based multiplexing is needed, since HTTP itself pairs each
+// request with its response.
+type HTTPClient struct {
+	url string
+	hc  *http.Client
+}
+
+var _ mrpc.Client = (*HTTPClient)(nil)
+
+// NewHTTPClient returns an HTTPClient which POSTs calls to url using hc. If
+// hc is nil, http.DefaultClient is used.
+func NewHTTPClient(url string, hc *http.Client) *HTTPClient {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return &HTTPClient{url: url, hc: hc}
+}
+
+// CallRPC implements the mrpc.Client interface.
+func (c *HTTPClient) CallRPC(ctx context.Context, method string, args interface{}, dbg mrpc.Debug) mrpc.Response {
+	params, err := json.Marshal(args)
+	if err != nil {
+		return errResponse(fmt.Errorf("jsonrpc2: marshaling args: %w", err))
+	}
+
+	idRaw := json.RawMessage("1")
+	reqBody, err := json.Marshal(request{JSONRPC: "2.0", Method: method, Params: params, ID: &idRaw})
+	if err != nil {
+		return errResponse(fmt.Errorf("jsonrpc2: marshaling request: %w", err))
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(reqBody))
+	if err != nil {
+		return errResponse(err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := c.hc.Do(httpReq)
+	if err != nil {
+		return errResponse(err)
+	}
+	defer httpResp.Body.Close()
+
+	var resp response
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return errResponse(fmt.Errorf("jsonrpc2: decoding response: %w", err))
+	}
+	return toMRPCResponse(resp)
+}
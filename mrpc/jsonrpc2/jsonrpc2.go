@@ -0,0 +1,191 @@
+// Package jsonrpc2 implements mrpc's Handler and Client interfaces on top of
+// the JSON-RPC 2.0 wire protocol (https://www.jsonrpc.org/specification), as
+// a concrete, interoperable alternative to jstreamrpc for talking to peers
+// which aren't necessarily using mediocre-go-lib themselves.
+//
+// Request/response correlation, notifications (requests with no id, which
+// get no response), and batching (a JSON array of requests, dispatched
+// concurrently and responded to with a single aggregated array) are all
+// implemented per the spec.
+//
+// mrpc.Debug is only conveyed on error responses, smuggled into the error
+// object's data field alongside whatever data the Handler's own error
+// carried (see toJSONRPCError); the spec has no comparable extension point
+// for successful responses, so Debug set by a Handler on a successful call
+// is silently dropped.
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/mediocregopher/mediocre-go-lib/mrpc"
+)
+
+// The standard JSON-RPC 2.0 error codes. Handlers don't need to know about
+// these directly; see toJSONRPCError for how mrpc.ErrCodes (and unmarshal
+// errors, and unknown methods) get mapped onto them.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+type request struct {
+	JSONRPC string           `json:"jsonrpc"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+}
+
+// isNotification returns true if this request is missing an id, and so
+// should receive no response.
+func (r request) isNotification() bool {
+	return r.ID == nil
+}
+
+type rpcError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// errorData is the shape marshaled into an rpcError's Data field, carrying
+// both whatever Data the Handler's own mrpc.Error set (if any) and the
+// ResponseWriter's Debug (if any). See toJSONRPCError/errCodeFromJSONRPC.
+type errorData struct {
+	Data  json.RawMessage `json:"data,omitempty"`
+	Debug mrpc.Debug      `json:"debug,omitempty"`
+}
+
+// toJSONRPCError converts a Handler's error response (as produced by
+// mrpc.NewError) and Debug into an rpcError, mapping mrpc's ErrCodes onto
+// the standard JSON-RPC error codes where a reasonable equivalent exists,
+// and CodeInternalError otherwise.
+func toJSONRPCError(err error, dbg mrpc.Debug) *rpcError {
+	mErr := mrpc.NewError(err)
+
+	code := CodeInternalError
+	switch mErr.Code {
+	case mrpc.CodeMethodNotFound:
+		code = CodeMethodNotFound
+	case mrpc.CodeInvalidRequest:
+		code = CodeInvalidParams
+	}
+
+	var data json.RawMessage
+	if len(mErr.Data) > 0 || len(dbg) > 0 {
+		if b, err := json.Marshal(errorData{Data: mErr.Data, Debug: dbg}); err == nil {
+			data = b
+		}
+	}
+
+	return &rpcError{Code: code, Message: mErr.Message, Data: data}
+}
+
+// errCodeFromJSONRPC is toJSONRPCError's inverse, used by the Client to
+// reconstruct an mrpc.Error's Code from a response's rpcError.
+func errCodeFromJSONRPC(code int) mrpc.ErrCode {
+	switch code {
+	case CodeMethodNotFound:
+		return mrpc.CodeMethodNotFound
+	case CodeInvalidParams:
+		return mrpc.CodeInvalidRequest
+	default:
+		return mrpc.CodeInternal
+	}
+}
+
+// isBatch returns true if raw's first non-whitespace byte is '[', i.e. it's
+// a batch of requests/responses rather than a single one.
+func isBatch(raw json.RawMessage) bool {
+	for _, b := range raw {
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b == '['
+	}
+	return false
+}
+
+// handleOne dispatches a single (non-batch) request to h, returning the
+// response to write back and true, or a zero response and false if raw was
+// a notification (no response should be written at all).
+func handleOne(ctx context.Context, raw json.RawMessage, h mrpc.Handler) (response, bool) {
+	var req request
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return response{
+			JSONRPC: "2.0",
+			Error:   &rpcError{Code: CodeInvalidRequest, Message: err.Error()},
+			ID:      json.RawMessage("null"),
+		}, true
+	}
+
+	rw := new(mrpc.ResponseWriter)
+	h.ServeRPC(mrpc.Request{
+		Context: ctx,
+		Method:  req.Method,
+		Unmarshal: func(i interface{}) error {
+			if len(req.Params) == 0 {
+				return nil
+			}
+			if err := json.Unmarshal(req.Params, i); err != nil {
+				return &mrpc.Error{Code: mrpc.CodeInvalidRequest, Message: err.Error()}
+			}
+			return nil
+		},
+	}, rw)
+
+	if req.isNotification() {
+		return response{}, false
+	}
+
+	resp := response{JSONRPC: "2.0", ID: *req.ID}
+	if errVal, ok := rw.Response.(error); ok {
+		resp.Error = toJSONRPCError(errVal, rw.Debug)
+	} else if b, err := json.Marshal(rw.Response); err != nil {
+		resp.Error = toJSONRPCError(err, rw.Debug)
+	} else {
+		resp.Result = b
+	}
+	return resp, true
+}
+
+// handleBatch dispatches every request in raws to h concurrently, per the
+// spec, and returns the responses which should be included in the
+// aggregated response array (in raws' original order, with notifications
+// omitted).
+func handleBatch(ctx context.Context, raws []json.RawMessage, h mrpc.Handler) []response {
+	resps := make([]*response, len(raws))
+
+	var wg sync.WaitGroup
+	for i := range raws {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if resp, ok := handleOne(ctx, raws[i], h); ok {
+				resps[i] = &resp
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	out := make([]response, 0, len(resps))
+	for _, resp := range resps {
+		if resp != nil {
+			out = append(out, *resp)
+		}
+	}
+	return out
+}
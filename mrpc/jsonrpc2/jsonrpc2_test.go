@@ -0,0 +1,126 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"net"
+	. "testing"
+
+	"github.com/mediocregopher/mediocre-go-lib/mrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func echoHandler() mrpc.Handler {
+	return mrpc.HandlerFunc(func(r mrpc.Request, rw *mrpc.ResponseWriter) {
+		switch r.Method {
+		case "echo":
+			var args string
+			if err := r.Unmarshal(&args); err != nil {
+				rw.Response = err
+				return
+			}
+			rw.Response = args
+		case "fail":
+			rw.Response = errors.New("oh no")
+		case "notFound":
+			rw.Response = &mrpc.Error{Code: mrpc.CodeMethodNotFound, Message: "no such method"}
+		default:
+			rw.Response = &mrpc.Error{Code: mrpc.CodeMethodNotFound, Message: "unknown method: " + r.Method}
+		}
+	})
+}
+
+func newTestClient(t *T) *Client {
+	srvConn, cliConn := net.Pipe()
+	go ServeConn(context.Background(), srvConn, echoHandler())
+	return NewClient(cliConn)
+}
+
+func TestClientCallRPC(t *T) {
+	c := newTestClient(t)
+	defer c.Close()
+
+	t.Run("success", func(t *T) {
+		var res string
+		err := c.CallRPC(context.Background(), "echo", "hello", nil).Unmarshal(&res)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", res)
+	})
+
+	t.Run("error", func(t *T) {
+		var res string
+		err := c.CallRPC(context.Background(), "fail", nil, nil).Unmarshal(&res)
+		var mErr *mrpc.Error
+		assert.True(t, errors.As(err, &mErr))
+		assert.Equal(t, mrpc.CodeInternal, mErr.Code)
+	})
+
+	t.Run("methodNotFound", func(t *T) {
+		var res string
+		err := c.CallRPC(context.Background(), "nope", nil, nil).Unmarshal(&res)
+		var mErr *mrpc.Error
+		assert.True(t, errors.As(err, &mErr))
+		assert.Equal(t, mrpc.CodeMethodNotFound, mErr.Code)
+	})
+}
+
+func TestClientConcurrentCalls(t *T) {
+	c := newTestClient(t)
+	defer c.Close()
+
+	const n = 20
+	errCh := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			var res string
+			err := c.CallRPC(context.Background(), "echo", "hello", nil).Unmarshal(&res)
+			if err == nil && res != "hello" {
+				err = errors.New("unexpected result: " + res)
+			}
+			errCh <- err
+		}(i)
+	}
+	for i := 0; i < n; i++ {
+		assert.NoError(t, <-errCh)
+	}
+}
+
+func TestClientDebugPropagation(t *T) {
+	h := mrpc.HandlerFunc(func(r mrpc.Request, rw *mrpc.ResponseWriter) {
+		rw.Response = errors.New("broken")
+		rw.Debug = mrpc.Debug{}.Set("ns", "key", "val")
+	})
+
+	srvConn, cliConn := net.Pipe()
+	go ServeConn(context.Background(), srvConn, h)
+	c := NewClient(cliConn)
+	defer c.Close()
+
+	resp := c.CallRPC(context.Background(), "whatever", nil, nil)
+	var res string
+	assert.Error(t, resp.Unmarshal(&res))
+	val, ok := resp.Debug.Get("ns", "key")
+	assert.True(t, ok)
+	assert.Equal(t, "val", val)
+}
+
+func TestClientCanceledContext(t *T) {
+	blockCh := make(chan struct{})
+	h := mrpc.HandlerFunc(func(r mrpc.Request, rw *mrpc.ResponseWriter) {
+		<-blockCh
+		rw.Response = "too late"
+	})
+	defer close(blockCh)
+
+	srvConn, cliConn := net.Pipe()
+	go ServeConn(context.Background(), srvConn, h)
+	c := NewClient(cliConn)
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var res string
+	err := c.CallRPC(ctx, "slow", nil, nil).Unmarshal(&res)
+	assert.Equal(t, context.Canceled, err)
+}
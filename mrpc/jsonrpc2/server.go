@@ -0,0 +1,76 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+
+	"github.com/mediocregopher/mediocre-go-lib/mrpc"
+)
+
+// ServeConn reads a stream of JSON-RPC 2.0 requests (and/or batches thereof)
+// off of rwc, dispatches each to h, and writes the corresponding
+// response/batch back to rwc, until a read off of rwc returns io.EOF (in
+// which case nil is returned) or some other error (which is returned as-is).
+//
+// Requests within a single batch are dispatched to h concurrently, per the
+// spec; separate (non-batched) requests read off of rwc are otherwise
+// handled one after another, in the order they're read.
+func ServeConn(ctx context.Context, rwc io.ReadWriteCloser, h mrpc.Handler) error {
+	dec := json.NewDecoder(rwc)
+	enc := json.NewEncoder(rwc)
+
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if isBatch(raw) {
+			var raws []json.RawMessage
+			if err := json.Unmarshal(raw, &raws); err != nil {
+				// malformed batch; nothing sensible to correlate an error
+				// response with, so just drop it and keep reading.
+				continue
+			}
+			if resps := handleBatch(ctx, raws, h); len(resps) > 0 {
+				if err := enc.Encode(resps); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if resp, ok := handleOne(ctx, raw, h); ok {
+			if err := enc.Encode(resp); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ListenAndServe listens for connections on network/addr (see net.Listen)
+// and calls ServeConn on each one (in its own goroutine, with a background
+// context), closing each connection once ServeConn returns. It blocks until
+// the net.Listener itself returns an error (e.g. because it was closed),
+// which it then returns.
+func ListenAndServe(network, addr string, h mrpc.Handler) error {
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		return err
+	}
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			ServeConn(context.Background(), conn, h)
+		}()
+	}
+}
@@ -0,0 +1,265 @@
+package jstreamrpc
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/mediocregopher/mediocre-go-lib/jstream"
+	"github.com/mediocregopher/mediocre-go-lib/mrpc"
+)
+
+// Client is an mrpc.Client which drives RPC calls over a single
+// io.ReadWriteCloser using jstreamrpc's wire format (the same format served
+// by HandleCall, one call at a time per connection, as in ServeReattach's
+// connection loop).
+//
+// Since jstream's wire format is inherently sequential, concurrent Calls on
+// the same Client are safely serialized behind a mutex rather than actually
+// running concurrently on the wire; use a ClientPool to spread concurrent
+// calls across multiple connections instead.
+type Client struct {
+	rwc io.ReadWriteCloser
+	r   *jstream.StreamReader
+	w   *jstream.StreamWriter
+
+	l      sync.Mutex
+	broken bool
+}
+
+var _ mrpc.Client = (*Client)(nil)
+
+// NewClient returns a Client which makes calls over rwc.
+func NewClient(rwc io.ReadWriteCloser) *Client {
+	return &Client{
+		rwc: rwc,
+		r:   jstream.NewStreamReader(rwc),
+		w:   jstream.NewStreamWriter(rwc),
+	}
+}
+
+// Dial establishes a connection using net.Dial and wraps it in a Client via
+// NewClient.
+func Dial(network, addr string) (*Client, error) {
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// DialTLS is like Dial, but establishes the connection via tls.Dial using
+// the given tls.Config.
+func DialTLS(network, addr string, tlsConfig *tls.Config) (*Client, error) {
+	conn, err := tls.Dial(network, addr, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// Close closes the Client's underlying connection. The Client may not be
+// used afterwards.
+func (c *Client) Close() error {
+	return c.rwc.Close()
+}
+
+func (c *Client) isBroken() bool {
+	c.l.Lock()
+	defer c.l.Unlock()
+	return c.broken
+}
+
+var errClientBroken = errors.New("jstreamrpc: client is no longer usable after a previous call on it was canceled")
+
+// Call performs an RPC call over the Client's connection, writing req as the
+// request body and unmarshaling the response into res; req and res are
+// understood the same way marshalBody/unmarshalBody understand HandleCall's
+// request/response bodies, so either may be a plain value, an io.Reader (for
+// req) or *io.Reader (for res), or a func(*jstream.StreamWriter) error /
+// func(*jstream.StreamReader) error for streaming bodies.
+//
+// If ctx is canceled before the call completes, Call writes a cancel
+// delimiter (see jstream.StreamWriter.Cancel) onto the connection, so that
+// the peer's HandleCall observes jstream.ErrCanceled, and returns ctx.Err().
+// Afterwards the Client is left broken: that and every subsequent Call on it
+// will fail immediately, since the cancellation leaves the connection desynced
+// from the peer's perspective. Discard the Client (or, when using a
+// ClientPool, let it transparently redial) once this happens.
+func (c *Client) Call(ctx context.Context, method string, req, res interface{}, dbg mrpc.Debug) error {
+	tail, err := c.callCtx(ctx, method, req, res, dbg)
+	if err != nil {
+		return err
+	} else if tail.Error != nil {
+		return tail.Error
+	}
+	return nil
+}
+
+// CallRPC implements the mrpc.Client interface, so that Client can be used
+// anywhere generic mrpc.Client code is expected (e.g. as a replacement for
+// the interop mrpc.ReflectClient's doc string alludes to wanting).
+//
+// Unlike Call, the response is always read as a plain JSON value into a
+// buffered json.RawMessage, since the returned mrpc.Response's Unmarshal may
+// be called after CallRPC itself has returned (and the connection's lock
+// released); ByteBlob and Stream response bodies aren't supported through
+// this method.
+func (c *Client) CallRPC(ctx context.Context, method string, args interface{}, dbg mrpc.Debug) mrpc.Response {
+	var resRaw json.RawMessage
+	tail, err := c.callCtx(ctx, method, args, &resRaw, dbg)
+	return mrpc.Response{
+		Unmarshal: func(i interface{}) error {
+			if err != nil {
+				return err
+			} else if tail.Error != nil {
+				return tail.Error
+			}
+			return json.Unmarshal(resRaw, i)
+		},
+		Debug: tail.Debug,
+	}
+}
+
+// callCtx runs roundTrip in a goroutine, racing it against ctx being
+// canceled, and handles marking the Client broken (either because the
+// roundTrip itself failed, or because it had to be abandoned via Cancel).
+func (c *Client) callCtx(ctx context.Context, method string, req, res interface{}, dbg mrpc.Debug) (resTail, error) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	if c.broken {
+		return resTail{}, errClientBroken
+	}
+
+	type result struct {
+		tail resTail
+		err  error
+	}
+	doneCh := make(chan result, 1)
+	go func() {
+		tail, err := c.roundTrip(method, req, res, dbg)
+		doneCh <- result{tail: tail, err: err}
+	}()
+
+	select {
+	case r := <-doneCh:
+		if r.err != nil {
+			c.broken = true
+		}
+		return r.tail, r.err
+	case <-ctx.Done():
+		c.broken = true
+		c.w.Cancel()
+		return resTail{}, ctx.Err()
+	}
+}
+
+// roundTrip performs the actual request/response wire exchange, mirroring
+// HandleCall's read-then-write protocol from the other direction.
+func (c *Client) roundTrip(method string, req, res interface{}, dbg mrpc.Debug) (resTail, error) {
+	if err := c.w.EncodeValue(reqHead{debug: debug{Debug: dbg}, Method: method}); err != nil {
+		return resTail{}, fmt.Errorf("writing request head: %w", err)
+	} else if err := marshalBody(c.w, req); err != nil {
+		return resTail{}, fmt.Errorf("writing request body: %w", err)
+	}
+
+	if err := unmarshalBody(res, c.r.Next()); err != nil {
+		return resTail{}, fmt.Errorf("reading response body: %w", err)
+	}
+
+	var tail resTail
+	if err := c.r.Next().Value(&tail); err != nil {
+		return resTail{}, fmt.Errorf("reading response tail: %w", err)
+	}
+	return tail, nil
+}
+
+// ClientPool maintains up to size connections to a single RPC endpoint,
+// establishing each one lazily (via dial, as they're first needed, and again
+// whenever a Call leaves one broken), and round-robins Calls across them.
+type ClientPool struct {
+	dial func() (*Client, error)
+
+	l       sync.Mutex
+	clients []*Client
+	next    int
+}
+
+var _ mrpc.Client = (*ClientPool)(nil)
+
+// NewClientPool returns a ClientPool which keeps up to size connections
+// open, established via dial.
+func NewClientPool(size int, dial func() (*Client, error)) *ClientPool {
+	return &ClientPool{dial: dial, clients: make([]*Client, size)}
+}
+
+// get returns the next connection in the pool (round-robin), (re)dialing it
+// first if it hasn't been established yet or was left broken by a previous
+// Call's cancellation.
+func (p *ClientPool) get() (*Client, error) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	i := p.next
+	p.next = (p.next + 1) % len(p.clients)
+
+	if c := p.clients[i]; c != nil && !c.isBroken() {
+		return c, nil
+	}
+
+	c, err := p.dial()
+	if err != nil {
+		return nil, err
+	}
+	p.clients[i] = c
+	return c, nil
+}
+
+// Call picks the pool's next connection (see get) and performs Call against
+// it.
+func (p *ClientPool) Call(ctx context.Context, method string, req, res interface{}, dbg mrpc.Debug) error {
+	c, err := p.get()
+	if err != nil {
+		return fmt.Errorf("dialing client pool connection: %w", err)
+	}
+	return c.Call(ctx, method, req, res, dbg)
+}
+
+// CallRPC implements the mrpc.Client interface, picking the pool's next
+// connection (see get) and performing CallRPC against it. See Client.CallRPC
+// for its limitations.
+func (p *ClientPool) CallRPC(ctx context.Context, method string, args interface{}, dbg mrpc.Debug) mrpc.Response {
+	c, err := p.get()
+	if err != nil {
+		return mrpc.Response{
+			Unmarshal: func(interface{}) error {
+				return fmt.Errorf("dialing client pool connection: %w", err)
+			},
+		}
+	}
+	return c.CallRPC(ctx, method, args, dbg)
+}
+
+// Close closes every connection which has been established in the pool so
+// far.
+func (p *ClientPool) Close() error {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	var firstErr error
+	for _, c := range p.clients {
+		if c == nil {
+			continue
+		}
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
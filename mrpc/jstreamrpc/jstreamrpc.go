@@ -12,8 +12,6 @@ import (
 	"github.com/mediocregopher/mediocre-go-lib/mrpc"
 )
 
-// TODO Error?
-// TODO SizeHints
 // TODO it'd be nice if the types here played nice with mrpc.ReflectClient
 
 type debug struct {
@@ -27,7 +25,7 @@ type reqHead struct {
 
 type resTail struct {
 	debug
-	Error error `json:"err,omitempty"`
+	Error *mrpc.Error `json:"err,omitempty"`
 }
 
 type ctxVal int
@@ -40,35 +38,66 @@ const (
 func unmarshalBody(i interface{}, el jstream.Element) error {
 	switch iT := i.(type) {
 	case func(*jstream.StreamReader) error:
-		stream, err := el.DecodeStream()
+		stream, err := el.Stream()
 		if err != nil {
 			return err
 		}
 		return iT(stream)
 	case *io.Reader:
-		ioR, err := el.DecodeBytes()
+		ioR, err := el.Bytes()
 		if err != nil {
 			return err
 		}
 		*iT = ioR
 		return nil
 	default:
-		return el.DecodeValue(i)
+		return el.Value(i)
 	}
 }
 
+// SizeHinter may be implemented by an io.Reader passed as a request or
+// response body (see marshalBody) to give its exact size, letting the body
+// be written via jstream.StreamWriter.EncodeBytesSized instead of
+// EncodeBytes. *bytes.Reader implements this directly, via its own Size
+// method; other io.Readers (e.g. *os.File, via Stat) can be wrapped to
+// implement it once their size is known.
+type SizeHinter interface {
+	Size() int64
+}
+
 func marshalBody(w *jstream.StreamWriter, i interface{}) error {
 	switch iT := i.(type) {
 	case func(*jstream.StreamWriter) error:
 		return w.EncodeStream(0, iT)
 	case io.Reader:
+		if sh, ok := iT.(SizeHinter); ok {
+			return w.EncodeBytesSized(sh.Size(), iT)
+		}
 		return w.EncodeBytes(0, iT)
 	default:
 		return w.EncodeValue(iT)
 	}
 }
 
-// HandleCall TODO
+// HandleCall reads a single RPC call off of r, dispatches it to h, and
+// writes the response (and its tail) to w.
+//
+// h.ServeRPC is run in its own goroutine, so that handlers which stream
+// their request and/or response bodies (see marshalBody/unmarshalBody's
+// func(*jstream.StreamWriter) error / func(*jstream.StreamReader) error
+// cases) may read from r and write to w concurrently, rather than the
+// latter only ever starting once ServeRPC has fully returned.
+//
+// If ctx is canceled while ServeRPC is still running, a cancel delimiter
+// (see jstream.StreamWriter.Cancel) is written to w, so the peer's read of
+// the response observes jstream.ErrCanceled, and HandleCall waits for
+// ServeRPC to return (it's expected to do so promptly, since its ctx is
+// also canceled) before touching w again. Similarly, if the request body
+// itself turns out to have been canceled by the peer (an inbound
+// jstream.ErrCanceled, as surfaced on the Element read by
+// Request.Unmarshal), ctx is canceled, so the handler can react
+// immediately instead of only discovering this the next time it happens to
+// call Request.Unmarshal.
 //
 // If this returns an error then both r and w should be discarded and no longer
 // used.
@@ -82,30 +111,46 @@ func HandleCall(
 	defer cancel()
 
 	var head reqHead
-	if err := r.Next().DecodeValue(&head); err != nil {
+	if err := r.Next().Value(&head); err != nil {
 		return err
 	} else if head.Method == "" {
 		return errors.New("request head missing 'method' field")
 	}
 
-	var didReadBody bool
 	ctx = context.WithValue(ctx, ctxValR, r)
 	ctx = context.WithValue(ctx, ctxValW, w)
 
+	var didReadBody bool
 	rw := new(mrpc.ResponseWriter)
-	h.ServeRPC(mrpc.Request{
-		Context: ctx,
-		Method:  head.Method,
-		Unmarshal: func(i interface{}) error {
-			didReadBody = true
-			return unmarshalBody(i, r.Next())
-		},
-		Debug: head.debug.Debug,
-	}, rw)
-
-	// TODO unmarshaling request and marshaling response should be in
-	// their own go-routines, just in case they are streams/bytes which depend
-	// on each other
+
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		h.ServeRPC(mrpc.Request{
+			Context: ctx,
+			Method:  head.Method,
+			Unmarshal: func(i interface{}) error {
+				didReadBody = true
+				el := r.Next()
+				if el.Err == jstream.ErrCanceled {
+					cancel()
+				}
+				return unmarshalBody(i, el)
+			},
+			Debug: head.debug.Debug,
+		}, rw)
+	}()
+
+	select {
+	case <-doneCh:
+	case <-ctx.Done():
+		// best-effort, same as EncodeBytes's own cancellation: if the peer
+		// isn't currently reading this could block indefinitely, so it's
+		// done in its own goroutine rather than holding up waiting for
+		// ServeRPC to notice ctx and return on its own.
+		go w.Cancel()
+		<-doneCh
+	}
 
 	resErr, resErrOk := rw.Response.(error)
 	if resErrOk {
@@ -128,7 +173,7 @@ func HandleCall(
 
 	if err := w.EncodeValue(resTail{
 		debug: debug{Debug: rw.Debug},
-		Error: resErr,
+		Error: mrpc.NewError(resErr),
 	}); err != nil {
 		return err
 	}
@@ -0,0 +1,174 @@
+package jstreamrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/mediocregopher/mediocre-go-lib/jstream"
+	"github.com/mediocregopher/mediocre-go-lib/mrpc"
+)
+
+// ReattachEnvVar is the environment variable which ReattachClient reads from,
+// and which the output of ServeReattach is meant to end up in (e.g. via
+// `export MRPC_REATTACH=$(...)` in a shell, or by a test harness capturing
+// the server's stderr).
+const ReattachEnvVar = "MRPC_REATTACH"
+
+// ReattachConfig describes how to reach a Handler which was put into
+// reattach mode via ServeReattach.
+type ReattachConfig struct {
+	Network string
+	Addr    string
+	Pid     int
+}
+
+// ServeReattach listens on a freely chosen local TCP address and serves h
+// over it (using HandleCall, one call at a time per connection) until ctx is
+// canceled. Before blocking it writes a JSON object of the form
+// {name: ReattachConfig}, describing the listener it chose, to stderr.
+//
+// ServeReattach is meant to be used in place of a normal mrun-managed
+// listener when a service binary is being run under a debugger (e.g. `dlv
+// exec`): since the debugger, not mrun, owns the process's lifecycle, the
+// process picks its own listen address here and prints it out, so that a
+// separate process (e.g. an integration test driving the service via
+// ReattachClient) can discover and connect to it without needing to have
+// started it.
+func ServeReattach(ctx context.Context, name string, h mrpc.Handler) error {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("listening for reattach: %w", err)
+	}
+	defer l.Close()
+
+	cfg := ReattachConfig{
+		Network: l.Addr().Network(),
+		Addr:    l.Addr().String(),
+		Pid:     os.Getpid(),
+	}
+	if err := json.NewEncoder(os.Stderr).Encode(map[string]ReattachConfig{name: cfg}); err != nil {
+		return fmt.Errorf("writing reattach config: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go serveReattachConn(ctx, conn, h)
+	}
+}
+
+// serveReattachConn repeatedly calls HandleCall on conn until it errors out
+// (e.g. because the client hung up), since a reattached client is expected to
+// make many calls over the same long-lived connection rather than dialing
+// fresh for each one.
+func serveReattachConn(ctx context.Context, conn net.Conn, h mrpc.Handler) {
+	defer conn.Close()
+
+	r := jstream.NewStreamReader(conn)
+	w := jstream.NewStreamWriter(conn)
+	for ctx.Err() == nil {
+		if err := HandleCall(ctx, r, w, h); err != nil {
+			return
+		}
+	}
+}
+
+// reattachClient is an mrpc.Client which drives calls over a single
+// connection to a Handler being served via ServeReattach.
+//
+// Only calls whose response is a plain JSON value are supported; ByteBlob and
+// Stream responses return an error on Unmarshal, since buffering the
+// connection for reattach purposes (debugging/integration tests) is simpler
+// and sufficient for that use-case.
+type reattachClient struct {
+	l sync.Mutex
+	r *jstream.StreamReader
+	w *jstream.StreamWriter
+}
+
+// ReattachClient reads ReattachEnvVar for a config describing how to reach
+// the service of the given name (as set up by that service's call to
+// ServeReattach), dials it, and returns an mrpc.Client for making calls
+// against it.
+//
+// Unlike Clients normally instantiated via mrun/mcfg, the Client returned by
+// ReattachClient doesn't go through any Component lifecycle at all; it's
+// usable as soon as this function returns.
+func ReattachClient(name string) (mrpc.Client, error) {
+	var cfgs map[string]ReattachConfig
+	if err := json.Unmarshal([]byte(os.Getenv(ReattachEnvVar)), &cfgs); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", ReattachEnvVar, err)
+	}
+
+	cfg, ok := cfgs[name]
+	if !ok {
+		return nil, fmt.Errorf("no reattach config for service %q in %s", name, ReattachEnvVar)
+	}
+
+	conn, err := net.Dial(cfg.Network, cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("dialing reattached service %q: %w", name, err)
+	}
+
+	return &reattachClient{
+		r: jstream.NewStreamReader(conn),
+		w: jstream.NewStreamWriter(conn),
+	}, nil
+}
+
+func errResponse(err error) mrpc.Response {
+	return mrpc.Response{Unmarshal: func(interface{}) error { return err }}
+}
+
+// CallRPC implements the mrpc.Client interface.
+func (c *reattachClient) CallRPC(
+	ctx context.Context,
+	method string,
+	args interface{},
+	dbg mrpc.Debug,
+) mrpc.Response {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	if err := c.w.EncodeValue(reqHead{debug: debug{Debug: dbg}, Method: method}); err != nil {
+		return errResponse(fmt.Errorf("writing request head: %w", err))
+	} else if err := marshalBody(c.w, args); err != nil {
+		return errResponse(fmt.Errorf("writing request body: %w", err))
+	}
+
+	// buffered as a json.RawMessage so Unmarshal can be called by the caller
+	// after CallRPC has already returned and released the connection lock.
+	var resRaw json.RawMessage
+	if err := c.r.Next().Value(&resRaw); err != nil {
+		return errResponse(fmt.Errorf("reading response body: %w", err))
+	}
+
+	var tail resTail
+	if err := c.r.Next().Value(&tail); err != nil {
+		return errResponse(fmt.Errorf("reading response tail: %w", err))
+	}
+
+	return mrpc.Response{
+		Unmarshal: func(i interface{}) error {
+			if tail.Error != nil {
+				return tail.Error
+			}
+			return json.Unmarshal(resRaw, i)
+		},
+		Debug: tail.debug.Debug,
+	}
+}
@@ -0,0 +1,253 @@
+// Package mrpcdebug carries mrpc.Debug over the wire for transports which
+// don't go through mrpc's Handler/Client types directly, by serializing it
+// into gRPC metadata or HTTP headers (alongside mrpc.Debug.Inject/Extract on
+// the receiving side's context.Context).
+//
+// Every string-valued entry in a Debug gets its own header/metadata key of
+// the form "<prefix><ns>-<key>". Any other value isn't representable as a
+// single header value, so all of them are instead grouped into one Debug,
+// JSON encoded, and carried length-prefixed (so a receiver can tell exactly
+// where the JSON ends) under a single fallback key.
+package mrpcdebug
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mediocregopher/mediocre-go-lib/mrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	grpcKeyPrefix = "mrpc-dbg-"
+	grpcJSONKey   = "mrpc-dbg-json"
+
+	httpHeaderPrefix = "X-Mrpc-Debug-"
+	httpJSONHeader   = "X-Mrpc-Debug-Json"
+)
+
+func encode(d mrpc.Debug, prefix, jsonKey string) map[string][]string {
+	out := map[string][]string{}
+	var fallback mrpc.Debug
+	for ns, kv := range d {
+		for key, val := range kv {
+			if s, ok := val.(string); ok {
+				name := prefix + ns + "-" + key
+				out[name] = append(out[name], s)
+				continue
+			}
+			fallback = fallback.Set(ns, key, val)
+		}
+	}
+	if len(fallback) > 0 {
+		if b, err := json.Marshal(fallback); err == nil {
+			out[jsonKey] = []string{fmt.Sprintf("%d:%s", len(b), b)}
+		}
+	}
+	return out
+}
+
+func decode(headers map[string][]string, prefix, jsonKey string) mrpc.Debug {
+	var d mrpc.Debug
+	loPrefix, loJSONKey := strings.ToLower(prefix), strings.ToLower(jsonKey)
+	for name, vals := range headers {
+		loName := strings.ToLower(name)
+		if loName == loJSONKey {
+			for _, v := range vals {
+				fallback, ok := decodeJSONFallback(v)
+				if !ok {
+					continue
+				}
+				for ns, kv := range fallback {
+					for key, val := range kv {
+						d = d.Set(ns, key, val)
+					}
+				}
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(loName, loPrefix) || len(vals) == 0 {
+			continue
+		}
+		rest := loName[len(loPrefix):]
+		i := strings.IndexByte(rest, '-')
+		if i < 0 {
+			continue
+		}
+		d = d.Set(rest[:i], rest[i+1:], vals[0])
+	}
+	return d
+}
+
+func decodeJSONFallback(s string) (mrpc.Debug, bool) {
+	i := strings.IndexByte(s, ':')
+	if i < 0 {
+		return nil, false
+	}
+	n, err := strconv.Atoi(s[:i])
+	if err != nil {
+		return nil, false
+	}
+	body := s[i+1:]
+	if len(body) < n {
+		return nil, false
+	}
+	var d mrpc.Debug
+	if err := json.Unmarshal([]byte(body[:n]), &d); err != nil {
+		return nil, false
+	}
+	return d, true
+}
+
+// ToMetadata serializes d into gRPC metadata, using keys of the form
+// "mrpc-dbg-<ns>-<key>".
+func ToMetadata(d mrpc.Debug) metadata.MD {
+	return metadata.MD(encode(d, grpcKeyPrefix, grpcJSONKey))
+}
+
+// FromMetadata deserializes a Debug out of gRPC metadata previously populated
+// by ToMetadata.
+func FromMetadata(md metadata.MD) mrpc.Debug {
+	return decode(map[string][]string(md), grpcKeyPrefix, grpcJSONKey)
+}
+
+// ToHeader serializes d into HTTP headers on h, using headers of the form
+// "X-Mrpc-Debug-<ns>-<key>".
+func ToHeader(d mrpc.Debug, h http.Header) {
+	for name, vals := range encode(d, httpHeaderPrefix, httpJSONHeader) {
+		for _, v := range vals {
+			h.Add(name, v)
+		}
+	}
+}
+
+// FromHeader deserializes a Debug out of HTTP headers previously populated by
+// ToHeader.
+func FromHeader(h http.Header) mrpc.Debug {
+	return decode(map[string][]string(h), httpHeaderPrefix, httpJSONHeader)
+}
+
+func appendToOutgoingContext(ctx context.Context, d mrpc.Debug) context.Context {
+	for name, vals := range ToMetadata(d) {
+		for _, v := range vals {
+			ctx = metadata.AppendToOutgoingContext(ctx, name, v)
+		}
+	}
+	return ctx
+}
+
+// UnaryServerInterceptor extracts a Debug out of the incoming gRPC call's
+// metadata and injects it (via mrpc.Debug.Inject) into the context passed to
+// the handler, so code further down the stack can retrieve it via
+// mrpc.Extract.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			ctx = FromMetadata(md).Inject(ctx)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// UnaryClientInterceptor injects whatever Debug is attached to ctx (via
+// mrpc.Debug.Inject) into the outgoing gRPC call's metadata, so the server's
+// UnaryServerInterceptor can pick it back up.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		if d, ok := mrpc.Extract(ctx); ok {
+			ctx = appendToOutgoingContext(ctx, d)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// serverStream wraps a grpc.ServerStream, overriding its Context method.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (ss serverStream) Context() context.Context { return ss.ctx }
+
+// StreamServerInterceptor is the streaming-call counterpart to
+// UnaryServerInterceptor.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx := ss.Context()
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			ctx = FromMetadata(md).Inject(ctx)
+		}
+		return handler(srv, serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// StreamClientInterceptor is the streaming-call counterpart to
+// UnaryClientInterceptor.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		if d, ok := mrpc.Extract(ctx); ok {
+			ctx = appendToOutgoingContext(ctx, d)
+		}
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+// Middleware wraps next, extracting a Debug out of each request's headers
+// and injecting it (via mrpc.Debug.Inject) into the request's context before
+// calling next, so handlers further down the stack can retrieve it via
+// mrpc.Extract.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		d := FromHeader(r.Header)
+		next.ServeHTTP(rw, r.WithContext(d.Inject(r.Context())))
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// RoundTripper wraps next, injecting whatever Debug is attached to each
+// outgoing request's context (via mrpc.Debug.Inject) into its headers before
+// sending it, so the receiving side's Middleware can pick it back up.
+func RoundTripper(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if d, ok := mrpc.Extract(r.Context()); ok {
+			r = r.Clone(r.Context())
+			ToHeader(d, r.Header)
+		}
+		return next.RoundTrip(r)
+	})
+}
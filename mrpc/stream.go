@@ -0,0 +1,162 @@
+package mrpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// Stream represents a bidirectional sequence of messages exchanged as part
+// of a streaming RPC call (see StreamHandler/StreamClient), as opposed to
+// the single Request/Response pair used by Handler/Client.
+//
+// Concurrent calls to Send are not safe, nor are concurrent calls to Recv;
+// a single goroutine should own each direction.
+type Stream interface {
+	// Send marshals and writes the given message onto the Stream.
+	Send(interface{}) error
+
+	// Recv reads and unmarshals the next message on the Stream into the
+	// given pointer. It returns io.EOF once the peer has called CloseSend (or
+	// the call has otherwise ended) and no further messages will arrive.
+	Recv(interface{}) error
+
+	// CloseSend indicates that no further messages will be Sent on this
+	// Stream. It's safe to keep calling Recv afterwards, to drain any
+	// messages still coming from the peer.
+	CloseSend() error
+
+	// Context returns the Context associated with the Stream. It's canceled
+	// once the Stream is no longer usable, e.g. because the underlying
+	// connection was closed.
+	Context() context.Context
+}
+
+// StreamHandler is like Handler, but for RPC methods which deal in a Stream
+// of messages (in either or both directions) rather than a single
+// Request/Response pair.
+//
+// ServeRPCStream should keep calling Recv and/or Send on the given Stream
+// until there's nothing further to do (e.g. Recv returns io.EOF and this
+// side has no more to Send) or the Request's Context is done, and then
+// return; returning ends the call.
+type StreamHandler interface {
+	ServeRPCStream(Request, Stream)
+}
+
+// StreamHandlerFunc can be used to wrap an individual function which fits
+// the ServeRPCStream signature, and use that function as a StreamHandler.
+type StreamHandlerFunc func(Request, Stream)
+
+// ServeRPCStream implements the StreamHandler interface by calling the
+// underlying function.
+func (shf StreamHandlerFunc) ServeRPCStream(r Request, s Stream) {
+	shf(r, s)
+}
+
+// StreamClient is an entity which can perform streaming RPC calls against a
+// remote endpoint, opening a Stream which the caller Sends/Recvs messages
+// on.
+type StreamClient interface {
+	CallRPCStream(ctx context.Context, method string, debug Debug) (Stream, error)
+}
+
+// StreamClientFunc can be used to wrap an individual function which fits the
+// CallRPCStream signature, and use that function as a StreamClient.
+type StreamClientFunc func(context.Context, string, Debug) (Stream, error)
+
+// CallRPCStream implements the StreamClient interface by calling the
+// underlying function.
+func (scf StreamClientFunc) CallRPCStream(
+	ctx context.Context,
+	method string,
+	debug Debug,
+) (Stream, error) {
+	return scf(ctx, method, debug)
+}
+
+// chanStream implements Stream on top of a pair of Go channels, one used to
+// Send and the other to Recv, with sendClosed/peerClosed channels used to
+// implement CloseSend/io.EOF between the two ends. See
+// ReflectStreamClient.
+type chanStream struct {
+	ctx context.Context
+
+	send chan interface{}
+	recv chan interface{}
+
+	sendClosed     chan struct{}
+	sendClosedOnce sync.Once
+	peerClosed     chan struct{}
+}
+
+// newChanStreamPair returns two chanStreams wired up so that one's Send
+// feeds the other's Recv, and vice versa.
+func newChanStreamPair(ctx context.Context) (a, b *chanStream) {
+	ch1, ch2 := make(chan interface{}), make(chan interface{})
+	closed1, closed2 := make(chan struct{}), make(chan struct{})
+	a = &chanStream{ctx: ctx, send: ch1, recv: ch2, sendClosed: closed1, peerClosed: closed2}
+	b = &chanStream{ctx: ctx, send: ch2, recv: ch1, sendClosed: closed2, peerClosed: closed1}
+	return a, b
+}
+
+func (cs *chanStream) Send(i interface{}) error {
+	select {
+	case cs.send <- i:
+		return nil
+	case <-cs.ctx.Done():
+		return cs.ctx.Err()
+	}
+}
+
+func (cs *chanStream) Recv(into interface{}) error {
+	select {
+	case v := <-cs.recv:
+		dstV, srcV := reflect.Indirect(reflect.ValueOf(into)), reflect.Indirect(reflect.ValueOf(v))
+		if !dstV.CanSet() || dstV.Type() != srcV.Type() {
+			return fmt.Errorf("can't set value of type %v into type %v", srcV.Type(), dstV.Type())
+		}
+		dstV.Set(srcV)
+		return nil
+	case <-cs.peerClosed:
+		return io.EOF
+	case <-cs.ctx.Done():
+		return cs.ctx.Err()
+	}
+}
+
+func (cs *chanStream) CloseSend() error {
+	cs.sendClosedOnce.Do(func() { close(cs.sendClosed) })
+	return nil
+}
+
+func (cs *chanStream) Context() context.Context { return cs.ctx }
+
+var _ Stream = (*chanStream)(nil)
+
+// ReflectStreamClient returns a StreamClient whose CallRPCStream method
+// invokes the given StreamHandler's ServeRPCStream directly, in its own
+// goroutine, pairing two chanStreams together so that messages Sent on one
+// side are delivered to Recv on the other with no marshaling involved (via
+// reflect.Value's Set method, the same approach ReflectClient uses for
+// single-shot calls).
+func ReflectStreamClient(h StreamHandler) StreamClient {
+	return StreamClientFunc(func(ctx context.Context, method string, debug Debug) (Stream, error) {
+		serverSide, clientSide := newChanStreamPair(ctx)
+		go func() {
+			// CloseSend is called unconditionally once ServeRPCStream
+			// returns (same as the wire transports automatically signal a
+			// stream's end once its handler returns), so the client side's
+			// Recv isn't left blocked forever if the handler forgot to.
+			defer serverSide.CloseSend()
+			h.ServeRPCStream(Request{
+				Context: ctx,
+				Method:  method,
+				Debug:   debug,
+			}, serverSide)
+		}()
+		return clientSide, nil
+	})
+}
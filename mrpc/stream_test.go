@@ -0,0 +1,37 @@
+package mrpc
+
+import (
+	"context"
+	"io"
+	. "testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReflectStreamClient(t *T) {
+	echo := StreamHandlerFunc(func(r Request, s Stream) {
+		for {
+			var v string
+			if err := s.Recv(&v); err == io.EOF {
+				return
+			} else if err != nil {
+				return
+			}
+			if err := s.Send(v + v); err != nil {
+				return
+			}
+		}
+	})
+
+	client := ReflectStreamClient(echo)
+	stream, err := client.CallRPCStream(context.Background(), "echo", nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, stream.Send("ab"))
+	var res string
+	assert.NoError(t, stream.Recv(&res))
+	assert.Equal(t, "abab", res)
+
+	assert.NoError(t, stream.CloseSend())
+	assert.Equal(t, io.EOF, stream.Recv(&res))
+}
@@ -0,0 +1,80 @@
+package streamrpc
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+
+	"github.com/mediocregopher/mediocre-go-lib/mrpc"
+)
+
+// Client is an mrpc.StreamClient which opens streaming RPC calls over a
+// single io.ReadWriteCloser. Concurrent CallRPCStream calls are multiplexed
+// over the one connection, each identified by its own id, via a background
+// goroutine (started by NewClient) which reads frames off of the connection
+// and dispatches each to the Stream it belongs to.
+type Client struct {
+	conn   *conn
+	nextID uint64
+}
+
+var _ mrpc.StreamClient = (*Client)(nil)
+
+// NewClient returns a Client which opens streaming calls over rwc.
+func NewClient(rwc io.ReadWriteCloser) *Client {
+	c := &Client{conn: newConn(rwc)}
+	go c.conn.readLoop(nil)
+	return c
+}
+
+// Dial establishes a connection using net.Dial and wraps it in a Client via
+// NewClient.
+func Dial(network, addr string) (*Client, error) {
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// DialTLS is like Dial, but establishes the connection via tls.Dial using
+// the given tls.Config.
+func DialTLS(network, addr string, tlsConfig *tls.Config) (*Client, error) {
+	conn, err := tls.Dial(network, addr, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// Close closes the Client's underlying connection, causing any Streams still
+// open on it to fail. The Client may not be used afterwards.
+func (c *Client) Close() error {
+	return c.conn.rwc.Close()
+}
+
+// CallRPCStream implements the mrpc.StreamClient interface.
+//
+// If ctx is canceled, the returned Stream's Context is canceled alongside
+// it; no frame is sent to the peer to indicate this (there being no ctx on
+// the wire to begin with), so callers which want the peer to know the call
+// is being abandoned should CloseSend (and/or communicate this some other
+// way within the stream's own message protocol) themselves.
+func (c *Client) CallRPCStream(ctx context.Context, method string, debug mrpc.Debug) (mrpc.Stream, error) {
+	id := atomic.AddUint64(&c.nextID, 1)
+	ws := newWireStream(ctx, c.conn, id)
+
+	if !c.conn.register(id, ws) {
+		return nil, fmt.Errorf("streamrpc: client is no longer usable: %w", c.conn.err)
+	}
+
+	if err := c.conn.writeFrame(frame{Type: frameOpen, ID: id, Method: method, Debug: debug}); err != nil {
+		c.conn.unregister(id)
+		return nil, fmt.Errorf("streamrpc: opening stream: %w", err)
+	}
+
+	return ws, nil
+}
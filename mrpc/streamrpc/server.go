@@ -0,0 +1,72 @@
+package streamrpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/mediocregopher/mediocre-go-lib/mrpc"
+)
+
+// ServeConn reads frameOpen frames off of rwc, dispatching each to its own
+// call to h.ServeRPCStream (in its own goroutine, so multiple streaming
+// calls may be in flight concurrently over the one connection), until a read
+// off of rwc fails (including on a clean close, via io.EOF, which is not
+// treated as an error).
+//
+// ServeConn blocks until every in-flight ServeRPCStream call has returned,
+// which should happen promptly once rwc is no longer readable, since each
+// call's Request.Context is canceled alongside ctx.
+func ServeConn(ctx context.Context, rwc io.ReadWriteCloser, h mrpc.StreamHandler) error {
+	c := newConn(rwc)
+	var wg sync.WaitGroup
+
+	c.readLoop(func(f frame) {
+		ws := newWireStream(ctx, c, f.ID)
+		if !c.register(f.ID, ws) {
+			return
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.ServeRPCStream(mrpc.Request{
+				Context: ws.ctx,
+				Method:  f.Method,
+				Debug:   f.Debug,
+			}, ws)
+			c.writeFrame(frame{Type: frameClose, ID: f.ID})
+			c.unregister(f.ID)
+		}()
+	})
+
+	wg.Wait()
+	if c.err == io.EOF {
+		return nil
+	}
+	return c.err
+}
+
+// ListenAndServe listens for connections on network/addr (see net.Listen)
+// and calls ServeConn on each one (in its own goroutine, with a background
+// context), closing each connection once ServeConn returns. It blocks until
+// the net.Listener itself returns an error (e.g. because it was closed),
+// which it then returns.
+func ListenAndServe(network, addr string, h mrpc.StreamHandler) error {
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		return err
+	}
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			ServeConn(context.Background(), conn, h)
+		}()
+	}
+}
@@ -0,0 +1,285 @@
+// Package streamrpc implements mrpc's StreamHandler and StreamClient
+// interfaces over a single io.ReadWriteCloser, using a simple
+// length-prefixed JSON framing to carry stream open/message/closeSend/close
+// events. Any number of streaming calls may be multiplexed concurrently over
+// one connection, each identified by a unique id chosen by the Client.
+//
+// This complements jstreamrpc/jsonrpc2, neither of which support a call
+// lasting more than one request/response exchange.
+package streamrpc
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/mediocregopher/mediocre-go-lib/mrpc"
+)
+
+type frameType string
+
+const (
+	frameOpen      frameType = "open"
+	frameMessage   frameType = "message"
+	frameCloseSend frameType = "closeSend"
+	frameClose     frameType = "close"
+)
+
+type frame struct {
+	Type    frameType       `json:"type"`
+	ID      uint64          `json:"id"`
+	Method  string          `json:"method,omitempty"`
+	Debug   mrpc.Debug      `json:"debug,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Err     string          `json:"err,omitempty"`
+}
+
+// conn multiplexes any number of concurrent wireStreams over a single
+// underlying io.ReadWriteCloser. It's the shared machinery behind both
+// ServeConn (server side) and Client (client side).
+type conn struct {
+	rwc io.ReadWriteCloser
+
+	writeL sync.Mutex
+
+	l       sync.Mutex
+	streams map[uint64]*wireStream
+	err     error // set once the connection is known to be dead
+}
+
+func newConn(rwc io.ReadWriteCloser) *conn {
+	return &conn{rwc: rwc, streams: map[uint64]*wireStream{}}
+}
+
+func (c *conn) writeFrame(f frame) error {
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	var lenBuf [4]byte
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(b)))
+
+	c.writeL.Lock()
+	defer c.writeL.Unlock()
+	if _, err := c.rwc.Write(lenBuf[:]); err != nil {
+		return err
+	}
+	_, err = c.rwc.Write(b)
+	return err
+}
+
+func (c *conn) readFrame() (frame, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(c.rwc, lenBuf[:]); err != nil {
+		return frame{}, err
+	}
+
+	b := make([]byte, binary.BigEndian.Uint32(lenBuf[:]))
+	if _, err := io.ReadFull(c.rwc, b); err != nil {
+		return frame{}, err
+	}
+
+	var f frame
+	err := json.Unmarshal(b, &f)
+	return f, err
+}
+
+// register adds ws to the set of streams this conn will dispatch frames to,
+// returning false (without adding it) if the conn is already dead.
+func (c *conn) register(id uint64, ws *wireStream) bool {
+	c.l.Lock()
+	defer c.l.Unlock()
+	if c.err != nil {
+		return false
+	}
+	c.streams[id] = ws
+	return true
+}
+
+func (c *conn) unregister(id uint64) {
+	c.l.Lock()
+	delete(c.streams, id)
+	c.l.Unlock()
+}
+
+// readLoop reads frames off of c.rwc, dispatching each to its wireStream (as
+// found by frame.ID) until a read fails, at which point every still-open
+// wireStream is shut down with that error. onOpen is called for every
+// frameOpen frame seen; the client side, which never expects to receive one,
+// passes nil.
+func (c *conn) readLoop(onOpen func(frame)) {
+	for {
+		f, err := c.readFrame()
+		if err != nil {
+			c.shutdown(err)
+			return
+		}
+
+		if f.Type == frameOpen {
+			if onOpen != nil {
+				onOpen(f)
+			}
+			continue
+		}
+
+		c.l.Lock()
+		ws := c.streams[f.ID]
+		c.l.Unlock()
+		if ws != nil {
+			ws.handleFrame(f)
+		}
+	}
+}
+
+func (c *conn) shutdown(err error) {
+	c.l.Lock()
+	if c.err != nil {
+		c.l.Unlock()
+		return
+	}
+	c.err = err
+	streams := c.streams
+	c.streams = map[uint64]*wireStream{}
+	c.l.Unlock()
+
+	for _, ws := range streams {
+		ws.shutdown(err)
+	}
+}
+
+// wireStream implements mrpc.Stream over a conn, identified by an id shared
+// with its peer's wireStream on the other end of the connection.
+//
+// Incoming messages are queued (rather than delivered via a bare channel
+// send) so that a slow consumer on one stream can never block conn's single
+// readLoop goroutine from dispatching frames for the connection's other
+// streams.
+type wireStream struct {
+	ctx    context.Context
+	cancel context.CancelFunc // called once the underlying conn dies
+	conn   *conn
+	id     uint64
+
+	mu       sync.Mutex
+	queue    []json.RawMessage
+	peerDone bool
+	peerErr  error
+	notifyCh chan struct{}
+
+	sendClosedOnce sync.Once
+}
+
+func newWireStream(ctx context.Context, c *conn, id uint64) *wireStream {
+	ctx, cancel := context.WithCancel(ctx)
+	return &wireStream{
+		ctx:      ctx,
+		cancel:   cancel,
+		conn:     c,
+		id:       id,
+		notifyCh: make(chan struct{}, 1),
+	}
+}
+
+func (ws *wireStream) notify() {
+	select {
+	case ws.notifyCh <- struct{}{}:
+	default:
+	}
+}
+
+// handleFrame is called by conn's readLoop for every frame belonging to this
+// stream.
+func (ws *wireStream) handleFrame(f frame) {
+	switch f.Type {
+	case frameMessage:
+		ws.mu.Lock()
+		ws.queue = append(ws.queue, f.Payload)
+		ws.mu.Unlock()
+		ws.notify()
+
+	case frameCloseSend, frameClose:
+		ws.mu.Lock()
+		if !ws.peerDone {
+			ws.peerDone = true
+			if f.Err != "" {
+				ws.peerErr = errors.New(f.Err)
+			}
+		}
+		ws.mu.Unlock()
+		ws.notify()
+
+		if f.Type == frameClose {
+			ws.conn.unregister(f.ID)
+		}
+	}
+}
+
+// shutdown is called once the underlying conn has died, failing any
+// outstanding/future Recv the same way a frameClose with that error would.
+func (ws *wireStream) shutdown(err error) {
+	ws.mu.Lock()
+	if !ws.peerDone {
+		ws.peerDone = true
+		ws.peerErr = err
+	}
+	ws.mu.Unlock()
+	ws.notify()
+	ws.cancel()
+}
+
+// Send implements the mrpc.Stream interface.
+func (ws *wireStream) Send(i interface{}) error {
+	b, err := json.Marshal(i)
+	if err != nil {
+		return err
+	}
+	return ws.conn.writeFrame(frame{Type: frameMessage, ID: ws.id, Payload: b})
+}
+
+// Recv implements the mrpc.Stream interface.
+func (ws *wireStream) Recv(into interface{}) error {
+	for {
+		ws.mu.Lock()
+		if len(ws.queue) > 0 {
+			payload := ws.queue[0]
+			ws.queue = ws.queue[1:]
+			ws.mu.Unlock()
+			return json.Unmarshal(payload, into)
+		}
+		peerDone, peerErr := ws.peerDone, ws.peerErr
+		ws.mu.Unlock()
+
+		if peerDone {
+			if peerErr != nil {
+				return peerErr
+			}
+			return io.EOF
+		}
+
+		select {
+		case <-ws.notifyCh:
+		case <-ws.ctx.Done():
+			return ws.ctx.Err()
+		}
+	}
+}
+
+// CloseSend implements the mrpc.Stream interface.
+func (ws *wireStream) CloseSend() error {
+	var err error
+	ws.sendClosedOnce.Do(func() {
+		err = ws.conn.writeFrame(frame{Type: frameCloseSend, ID: ws.id})
+	})
+	return err
+}
+
+// Context implements the mrpc.Stream interface.
+func (ws *wireStream) Context() context.Context {
+	return ws.ctx
+}
+
+var _ mrpc.Stream = (*wireStream)(nil)
@@ -0,0 +1,81 @@
+package streamrpc
+
+import (
+	"context"
+	"io"
+	"net"
+	. "testing"
+
+	"github.com/mediocregopher/mediocre-go-lib/mrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func echoHandler(r mrpc.Request, s mrpc.Stream) {
+	for {
+		var v string
+		if err := s.Recv(&v); err == io.EOF {
+			return
+		} else if err != nil {
+			return
+		}
+		if err := s.Send(v + v); err != nil {
+			return
+		}
+	}
+}
+
+func TestClientServerRoundTrip(t *T) {
+	srvConn, cliConn := net.Pipe()
+	go ServeConn(context.Background(), srvConn, mrpc.StreamHandlerFunc(echoHandler))
+
+	c := NewClient(cliConn)
+	defer c.Close()
+
+	stream, err := c.CallRPCStream(context.Background(), "echo", nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, stream.Send("ab"))
+	var res string
+	assert.NoError(t, stream.Recv(&res))
+	assert.Equal(t, "abab", res)
+
+	assert.NoError(t, stream.CloseSend())
+	assert.Equal(t, io.EOF, stream.Recv(&res))
+}
+
+func TestConcurrentStreams(t *T) {
+	srvConn, cliConn := net.Pipe()
+	go ServeConn(context.Background(), srvConn, mrpc.StreamHandlerFunc(echoHandler))
+
+	c := NewClient(cliConn)
+	defer c.Close()
+
+	const n = 10
+	errCh := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			stream, err := c.CallRPCStream(context.Background(), "echo", nil)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			if err := stream.Send("xy"); err != nil {
+				errCh <- err
+				return
+			}
+			var res string
+			if err := stream.Recv(&res); err != nil {
+				errCh <- err
+				return
+			}
+			if res != "xyxy" {
+				errCh <- io.ErrUnexpectedEOF
+				return
+			}
+			errCh <- stream.CloseSend()
+		}()
+	}
+	for i := 0; i < n; i++ {
+		assert.NoError(t, <-errCh)
+	}
+}
@@ -2,6 +2,11 @@ package mrun
 
 import (
 	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/mediocregopher/mediocre-go-lib/mcmp"
 )
@@ -14,13 +19,25 @@ type hookKey struct {
 	key interface{}
 }
 
+// hookEntry is the value actually stored in a Component's series for a given
+// hookKey; it carries along the hook's registration site (file:line of the
+// AddHook call) so that TriggerHooksWith can annotate errors with it.
+type hookEntry struct {
+	hook Hook
+	site string
+}
+
 // AddHook registers a Hook under a typed key. The Hook will be called when
 // TriggerHooks is called with that same key. Multiple Hooks can be registered
 // for the same key, and will be called sequentially when triggered.
 //
 // Hooks will be called with whatever Context is passed into TriggerHooks.
 func AddHook(cmp *mcmp.Component, key interface{}, hook Hook) {
-	mcmp.AddSeriesValue(cmp, hookKey{key}, hook)
+	_, file, line, _ := runtime.Caller(1)
+	mcmp.AddSeriesValue(cmp, hookKey{key}, hookEntry{
+		hook: hook,
+		site: fmt.Sprintf("%s:%d", file, line),
+	})
 }
 
 func triggerHooks(
@@ -44,8 +61,8 @@ func triggerHooks(
 				return err
 			}
 		} else {
-			hook := el.Value.(Hook)
-			if err := hook(ctx); err != nil {
+			he := el.Value.(hookEntry)
+			if err := he.hook(ctx); err != nil {
 				return err
 			}
 		}
@@ -85,6 +102,230 @@ func TriggerHooksReverse(ctx context.Context, cmp *mcmp.Component, key interface
 	return triggerHooks(ctx, cmp, key, start, next)
 }
 
+////////////////////////////////////////////////////////////////////////////////
+
+// TriggerHooksOpts are optional parameters used to modify the behavior of
+// TriggerHooksWith. The zero value runs Hooks in the same order as
+// TriggerHooks and likewise stops at the first error, which is returned
+// wrapped with the Hook's Component path and registration site.
+type TriggerHooksOpts struct {
+	// Parallel, if true, causes Hooks registered directly on a single
+	// Component to be run concurrently with each other, rather than
+	// sequentially. Child Components (see the mcmp package) are still
+	// visited in the order they were registered, relative to their
+	// siblings' Hooks, so dependency ordering between different Components
+	// is always preserved; only Hooks registered on the *same* Component may
+	// run concurrently with each other.
+	Parallel bool
+
+	// MaxConcurrency caps how many of a single Component's Hooks may run at
+	// once when Parallel is true. Zero (the default) means unbounded.
+	MaxConcurrency int
+
+	// PerHookTimeout, if greater than zero, wraps the Context passed into
+	// each Hook with context.WithTimeout, so a single hung Hook can't block
+	// TriggerHooksWith forever.
+	PerHookTimeout time.Duration
+
+	// ContinueOnError causes a Hook's error to not prevent any other Hook
+	// (whether a sibling, a cousin in another Component, or one running
+	// concurrently with it) from also being run. Every error encountered is
+	// collected and returned together as a *HookError.
+	//
+	// If false (the default), the first Hook observed to error stops all
+	// further Hooks from running, and that error is returned directly, as
+	// TriggerHooks has always done.
+	ContinueOnError bool
+}
+
+// HookError is returned by TriggerHooksWith when TriggerHooksOpts.
+// ContinueOnError is true and one or more Hooks returned an error. It
+// implements Unwrap() []error, so that errors.Is and errors.As (as of Go
+// 1.20) can traverse into any of the underlying Hook errors.
+type HookError struct {
+	// Errs holds every error returned by a Hook triggered during the
+	// TriggerHooksWith call, in no particular order (Hooks run concurrently
+	// under TriggerHooksOpts.Parallel may complete out of order). Each is
+	// wrapped with the mcmp.Component path and AddHook call site of the Hook
+	// that produced it.
+	Errs []error
+}
+
+// Error implements the error interface.
+func (he *HookError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d hook(s) returned an error:", len(he.Errs))
+	for _, err := range he.Errs {
+		fmt.Fprintf(&sb, "\n\t* %s", err.Error())
+	}
+	return sb.String()
+}
+
+// Unwrap implements the interface expected by errors.Is/errors.As as of Go
+// 1.20, allowing them to traverse into any of HookError's underlying Errs.
+func (he *HookError) Unwrap() []error {
+	return he.Errs
+}
+
+// componentPathStr returns a human-readable rendering of cmp's Path, for use
+// in error messages; the root Component's path renders as "<root>".
+func componentPathStr(cmp *mcmp.Component) string {
+	path := cmp.Path()
+	if len(path) == 0 {
+		return "<root>"
+	}
+	return strings.Join(path, "/")
+}
+
+// runHook runs a single Hook, applying opts.PerHookTimeout, and annotates any
+// returned error with he's registration site and cmp's path.
+func runHook(ctx context.Context, cmp *mcmp.Component, he hookEntry, opts TriggerHooksOpts) error {
+	if opts.PerHookTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.PerHookTimeout)
+		defer cancel()
+	}
+
+	if err := he.hook(ctx); err != nil {
+		return fmt.Errorf("hook on component %q registered at %s: %w", componentPathStr(cmp), he.site, err)
+	}
+	return nil
+}
+
+// runHooks runs a batch of Hooks which were all registered directly on cmp,
+// honoring opts.Parallel/MaxConcurrency/ContinueOnError, and returns every
+// error encountered.
+func runHooks(ctx context.Context, cmp *mcmp.Component, hes []hookEntry, opts TriggerHooksOpts) []error {
+	if !opts.Parallel || len(hes) < 2 {
+		var errs []error
+		for _, he := range hes {
+			if err := runHook(ctx, cmp, he, opts); err != nil {
+				errs = append(errs, err)
+				if !opts.ContinueOnError {
+					break
+				}
+			}
+		}
+		return errs
+	}
+
+	maxConcurrency := opts.MaxConcurrency
+	if maxConcurrency <= 0 || maxConcurrency > len(hes) {
+		maxConcurrency = len(hes)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		sem  = make(chan struct{}, maxConcurrency)
+		errL sync.Mutex
+		errs []error
+	)
+
+	for _, he := range hes {
+		he := he
+		sem <- struct{}{}
+		errL.Lock()
+		stop := len(errs) > 0 && !opts.ContinueOnError
+		errL.Unlock()
+		if stop {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := runHook(ctx, cmp, he, opts); err != nil {
+				errL.Lock()
+				errs = append(errs, err)
+				errL.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	return errs
+}
+
+// triggerHooksWith walks cmp's series for key (in reverse if reverse is
+// true, recursing into children in the same order), running batches of
+// consecutive Hook entries via runHooks, and stops early if a batch or child
+// errors and opts.ContinueOnError is false. It returns every error
+// encountered.
+func triggerHooksWith(
+	ctx context.Context,
+	cmp *mcmp.Component,
+	key interface{},
+	opts TriggerHooksOpts,
+	reverse bool,
+) []error {
+	els := mcmp.SeriesElements(cmp, hookKey{key})
+	if reverse {
+		reversed := make([]mcmp.SeriesElement, len(els))
+		for i, el := range els {
+			reversed[len(els)-1-i] = el
+		}
+		els = reversed
+	}
+
+	var errs []error
+	var batch []hookEntry
+
+	// flush runs any accumulated batch and reports whether the walk should
+	// continue.
+	flush := func() bool {
+		if len(batch) == 0 {
+			return true
+		}
+		berrs := runHooks(ctx, cmp, batch, opts)
+		batch = batch[:0]
+		errs = append(errs, berrs...)
+		return len(berrs) == 0 || opts.ContinueOnError
+	}
+
+	for _, el := range els {
+		if el.Child != nil {
+			if !flush() {
+				return errs
+			}
+			errs = append(errs, triggerHooksWith(ctx, el.Child, key, opts, reverse)...)
+			if !opts.ContinueOnError && len(errs) > 0 {
+				return errs
+			}
+		} else {
+			batch = append(batch, el.Value.(hookEntry))
+		}
+	}
+
+	flush()
+	return errs
+}
+
+// TriggerHooksWith is like TriggerHooks, but takes in TriggerHooksOpts to
+// control concurrency, per-Hook timeouts, and whether to keep running other
+// Hooks after one errors.
+//
+// If opts.ContinueOnError is false (the default) the first error
+// encountered, possibly from a concurrently-running Hook, is returned
+// directly, as with TriggerHooks. If opts.ContinueOnError is true then every
+// error encountered is returned together as a *HookError, or nil if there
+// were none.
+func TriggerHooksWith(
+	ctx context.Context,
+	cmp *mcmp.Component,
+	key interface{},
+	opts TriggerHooksOpts,
+) error {
+	errs := triggerHooksWith(ctx, cmp, key, opts, false)
+	if len(errs) == 0 {
+		return nil
+	} else if !opts.ContinueOnError {
+		return errs[0]
+	}
+	return &HookError{Errs: errs}
+}
+
 type builtinEvent int
 
 const (
@@ -120,8 +361,15 @@ func ShutdownHook(cmp *mcmp.Component, hook Hook) {
 	AddHook(cmp, shutdownEvent, hook)
 }
 
-// Shutdown runs all Hooks registered using ShutdownHook in the reverse order in
-// which they were registered. This is a special case of TriggerHooks.
+// Shutdown runs all Hooks registered using ShutdownHook in the reverse order
+// in which they were registered. This is a special case of
+// TriggerHooksWith, with ContinueOnError set to true, so that one subsystem
+// failing to shut down cleanly can't prevent the rest of the Component tree
+// from also getting a chance to shut down.
 func Shutdown(ctx context.Context, cmp *mcmp.Component) error {
-	return TriggerHooksReverse(ctx, cmp, shutdownEvent)
+	errs := triggerHooksWith(ctx, cmp, shutdownEvent, TriggerHooksOpts{ContinueOnError: true}, true)
+	if len(errs) == 0 {
+		return nil
+	}
+	return &HookError{Errs: errs}
 }
@@ -2,7 +2,10 @@ package mrun
 
 import (
 	"context"
+	"errors"
+	"sync"
 	. "testing"
+	"time"
 
 	"github.com/mediocregopher/mediocre-go-lib/mcmp"
 	"github.com/mediocregopher/mediocre-go-lib/mtest/massert"
@@ -45,3 +48,122 @@ func TestHooks(t *T) {
 		massert.Equal([]int{7, 6, 5, 4, 3, 2, 1}, out),
 	)
 }
+
+func TestTriggerHooksWithContinueOnError(t *T) {
+	testErrA := errors.New("hook a failed")
+	testErrB := errors.New("hook b failed")
+
+	cmp := new(mcmp.Component)
+	var ran []string
+	var mu sync.Mutex
+	record := func(name string) {
+		mu.Lock()
+		ran = append(ran, name)
+		mu.Unlock()
+	}
+
+	AddHook(cmp, 0, func(context.Context) error {
+		record("a")
+		return testErrA
+	})
+
+	cmpChild := cmp.Child("child")
+	AddHook(cmpChild, 0, func(context.Context) error {
+		record("b")
+		return testErrB
+	})
+
+	AddHook(cmp, 0, func(context.Context) error {
+		record("c")
+		return nil
+	})
+
+	// Without ContinueOnError, the first error stops everything else.
+	ran = nil
+	err := TriggerHooksWith(context.Background(), cmp, 0, TriggerHooksOpts{})
+	massert.Require(t,
+		massert.Equal(true, errors.Is(err, testErrA)),
+		massert.Equal([]string{"a"}, ran),
+	)
+
+	// With ContinueOnError, every Hook still runs and every error is
+	// collected into a *HookError that errors.Is/As can traverse.
+	ran = nil
+	err = TriggerHooksWith(context.Background(), cmp, 0, TriggerHooksOpts{ContinueOnError: true})
+
+	var hookErr *HookError
+	massert.Require(t,
+		massert.Equal(true, errors.As(err, &hookErr)),
+		massert.Equal(2, len(hookErr.Errs)),
+		massert.Equal(true, errors.Is(err, testErrA)),
+		massert.Equal(true, errors.Is(err, testErrB)),
+		massert.Equal([]string{"a", "b", "c"}, ran),
+	)
+}
+
+func TestTriggerHooksWithParallel(t *T) {
+	const n = 8
+
+	cmp := new(mcmp.Component)
+	releaseCh := make(chan struct{})
+	var running int32
+	var maxRunning int32
+	var mu sync.Mutex
+
+	for i := 0; i < n; i++ {
+		AddHook(cmp, 0, func(context.Context) error {
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+
+			<-releaseCh
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return nil
+		})
+	}
+
+	doneCh := make(chan error, 1)
+	go func() {
+		doneCh <- TriggerHooksWith(context.Background(), cmp, 0, TriggerHooksOpts{
+			Parallel:       true,
+			MaxConcurrency: 3,
+		})
+	}()
+
+	// give the hooks a chance to all start up, then let them finish
+	time.Sleep(100 * time.Millisecond)
+	close(releaseCh)
+
+	if err := <-doneCh; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if maxRunning > 3 {
+		t.Fatalf("expected at most 3 hooks running concurrently, got %d", maxRunning)
+	} else if maxRunning < 2 {
+		t.Fatalf("expected hooks to actually run concurrently, max was %d", maxRunning)
+	}
+}
+
+func TestTriggerHooksWithPerHookTimeout(t *T) {
+	cmp := new(mcmp.Component)
+	AddHook(cmp, 0, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	err := TriggerHooksWith(context.Background(), cmp, 0, TriggerHooksOpts{
+		PerHookTimeout: 10 * time.Millisecond,
+	})
+	massert.Require(t,
+		massert.Equal(true, errors.Is(err, context.DeadlineExceeded)),
+	)
+}
@@ -30,6 +30,7 @@ package mrun
 import (
 	"context"
 	"errors"
+	"fmt"
 )
 
 type futureErr struct {
@@ -88,14 +89,69 @@ func WithThreads(ctx context.Context, n uint, fn func() error) context.Context {
 // returned.
 var ErrDone = errors.New("Wait is done waiting")
 
+// MultiError aggregates every non-nil error returned by the go-routines
+// tracked by a Wait or WaitAll call, in the order those go-routines were
+// spawned via WithThreads.
+type MultiError struct {
+	errs []error
+}
+
+// Errors returns every error aggregated by MultiError.
+func (me *MultiError) Errors() []error {
+	return me.errs
+}
+
+// Error implements the error interface.
+func (me *MultiError) Error() string {
+	if len(me.errs) == 1 {
+		return me.errs[0].Error()
+	}
+	str := fmt.Sprintf("%d errors occurred:", len(me.errs))
+	for _, err := range me.errs {
+		str += "\n\t* " + err.Error()
+	}
+	return str
+}
+
+// Unwrap returns every error aggregated by MultiError, for use by
+// errors.Is and errors.As (see the errors package docs on Unwrap() []error
+// for details).
+func (me *MultiError) Unwrap() []error {
+	return me.errs
+}
+
+// waitAll blocks until all go-routines spawned using WithThreads on the
+// passed in Context (and its predecessors) have returned, same as Wait, but
+// always returns the aggregate MultiError (nil if no go-routine errored),
+// rather than collapsing it down to a bare error in the single-error case.
+func waitAll(ctx context.Context, cancelCh <-chan struct{}) (*MultiError, error) {
+	futErrs, _ := ctx.Value(threadCtxKey(0)).([]*futureErr)
+
+	var me MultiError
+	for _, futErr := range futErrs {
+		err, ok := futErr.get(cancelCh)
+		if !ok {
+			return nil, ErrDone
+		} else if err != nil {
+			me.errs = append(me.errs, err)
+		}
+	}
+
+	if len(me.errs) == 0 {
+		return nil, nil
+	}
+	return &me, nil
+}
+
 // Wait blocks until all go-routines spawned using WithThreads on the passed in
 // Context (and its predecessors) have returned. Any number of the go-routines
 // may have returned already when Wait is called, and not all go-routines need
 // to be from the same WithThreads call.
 //
-// If any of the thread functions returned an error during its runtime Wait will
-// return that error. If multiple returned an error only one of those will be
-// returned. TODO: Handle multi-errors better.
+// If exactly one of the thread functions returned an error during its
+// runtime, that bare error is returned. If more than one did, a *MultiError
+// aggregating all of them is returned instead; see WaitAll for a variant
+// which always returns the aggregate, even when there was only one error.
 //
 // If cancelCh is not nil and is closed before all threads have returned then
 // this function stops waiting and returns ErrDone.
@@ -103,15 +159,22 @@ var ErrDone = errors.New("Wait is done waiting")
 // Wait is safe to call in parallel, and will return the same result if called
 // multiple times.
 func Wait(ctx context.Context, cancelCh <-chan struct{}) error {
-	futErrs, _ := ctx.Value(threadCtxKey(0)).([]*futureErr)
-	for _, futErr := range futErrs {
-		err, ok := futErr.get(cancelCh)
-		if !ok {
-			return ErrDone
-		} else if err != nil {
-			return err
-		}
+	me, err := waitAll(ctx, cancelCh)
+	if err != nil {
+		return err
+	} else if me == nil {
+		return nil
+	} else if len(me.errs) == 1 {
+		return me.errs[0]
 	}
+	return me
+}
 
-	return nil
+// WaitAll is like Wait, but always returns the aggregate *MultiError (nil if
+// no go-routine errored) rather than collapsing it down to a bare error when
+// only one did. This is useful for callers which want deterministic
+// behavior, e.g. always doing a type assertion to *MultiError rather than
+// having to handle both cases.
+func WaitAll(ctx context.Context, cancelCh <-chan struct{}) (*MultiError, error) {
+	return waitAll(ctx, cancelCh)
 }
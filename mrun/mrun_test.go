@@ -77,3 +77,61 @@ func TestThreadWait(t *T) {
 		})
 	})
 }
+
+func TestWaitMultiError(t *T) {
+	testErr1 := errors.New("test error 1")
+	testErr2 := errors.New("test error 2")
+
+	t.Run("singleErrIsBare", func(t *T) {
+		ctx := context.Background()
+		ctx = WithThreads(ctx, 1, func() error { return testErr1 })
+		if err := Wait(ctx, nil); err != testErr1 {
+			t.Fatalf("should have got bare testErr1, got: %v", err)
+		}
+	})
+
+	t.Run("multiErrIsMultiError", func(t *T) {
+		ctx := context.Background()
+		ctx = WithThreads(ctx, 1, func() error { return testErr1 })
+		ctx = WithThreads(ctx, 1, func() error { return testErr2 })
+		ctx = WithThreads(ctx, 1, func() error { return nil })
+
+		err := Wait(ctx, nil)
+		var me *MultiError
+		if !errors.As(err, &me) {
+			t.Fatalf("expected *MultiError, got: %v (%T)", err, err)
+		}
+		if len(me.Errors()) != 2 {
+			t.Fatalf("expected 2 errors, got: %v", me.Errors())
+		}
+		if !errors.Is(err, testErr1) || !errors.Is(err, testErr2) {
+			t.Fatalf("errors.Is should find both aggregated errors, got: %v", err)
+		}
+	})
+
+	t.Run("waitAllAlwaysAggregates", func(t *T) {
+		ctx := context.Background()
+		ctx = WithThreads(ctx, 1, func() error { return testErr1 })
+
+		me, err := WaitAll(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(me.Errors()) != 1 || me.Errors()[0] != testErr1 {
+			t.Fatalf("expected [testErr1], got: %v", me.Errors())
+		}
+	})
+
+	t.Run("waitAllNoErr", func(t *T) {
+		ctx := context.Background()
+		ctx = WithThreads(ctx, 1, func() error { return nil })
+
+		me, err := WaitAll(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if me != nil {
+			t.Fatalf("expected nil MultiError, got: %v", me)
+		}
+	})
+}
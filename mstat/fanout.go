@@ -0,0 +1,25 @@
+package mstat
+
+// FanOut returns a Backend which records every metric given to it to each of
+// backends in turn, and which Closes all of them when it's Closed.
+func FanOut(backends ...Backend) Backend {
+	return fanOutBackend(backends)
+}
+
+type fanOutBackend []Backend
+
+func (f fanOutBackend) record(m metric) {
+	for _, b := range f {
+		b.record(m)
+	}
+}
+
+func (f fanOutBackend) Close() error {
+	var err error
+	for _, b := range f {
+		if closeErr := b.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
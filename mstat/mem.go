@@ -0,0 +1,52 @@
+package mstat
+
+import "sync"
+
+// Metric is a single data point recorded against a MemBackend, exposed for
+// tests to assert against.
+type Metric struct {
+	// Type is one of "c" (Counter), "g" (Gauge), "h" (Histogram), or "ms"
+	// (Timer).
+	Type  string
+	Name  string
+	Value float64
+	Tags  []string
+}
+
+// MemBackend is a Backend which records every metric given to it in memory,
+// for use in tests which want to assert on what was recorded without
+// running a statsd daemon.
+type MemBackend struct {
+	l       sync.Mutex
+	metrics []Metric
+}
+
+// NewMemBackend initializes and returns an empty MemBackend.
+func NewMemBackend() *MemBackend {
+	return &MemBackend{}
+}
+
+func (b *MemBackend) record(m metric) {
+	b.l.Lock()
+	defer b.l.Unlock()
+	b.metrics = append(b.metrics, Metric{
+		Type:  string(m.typ),
+		Name:  m.name,
+		Value: m.value,
+		Tags:  m.tags,
+	})
+}
+
+// Close implements the Backend interface. It's a no-op.
+func (b *MemBackend) Close() error {
+	return nil
+}
+
+// Metrics returns every Metric recorded against b so far.
+func (b *MemBackend) Metrics() []Metric {
+	b.l.Lock()
+	defer b.l.Unlock()
+	metrics := make([]Metric, len(b.metrics))
+	copy(metrics, b.metrics)
+	return metrics
+}
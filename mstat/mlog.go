@@ -0,0 +1,26 @@
+package mstat
+
+import "github.com/mediocregopher/mediocre-go-lib/mlog"
+
+// NewLoggerHook returns an mlog.Hook, for use with Logger.WithHook, which
+// increments a "log_messages" counter on sink, tagged with the Message's
+// Level, for every Message fired to it.
+func NewLoggerHook(sink *Sink) mlog.Hook {
+	return loggerHook{sink: sink}
+}
+
+type loggerHook struct {
+	sink *Sink
+}
+
+// Fire implements the mlog.Hook interface.
+func (h loggerHook) Fire(msg mlog.FullMessage) error {
+	h.sink.Counter("log_messages", "level:"+msg.Level.String())
+	return nil
+}
+
+// Levels implements the mlog.Hook interface, returning nil so that every
+// Message is counted.
+func (h loggerHook) Levels() []mlog.Level {
+	return nil
+}
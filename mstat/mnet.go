@@ -0,0 +1,37 @@
+package mstat
+
+import (
+	"sync/atomic"
+
+	"github.com/mediocregopher/mediocre-go-lib/mnet"
+)
+
+// InstrumentListener sets l.Metrics so that every connection it accepts is
+// recorded to sink: an "accept" counter, an "active_conns" gauge tracking
+// currently-open connections, and "bytes_in"/"bytes_out" histograms of the
+// size of each Read/Write.
+func InstrumentListener(l *mnet.Listener, sink *Sink) {
+	l.Metrics = &listenerMetrics{sink: sink}
+}
+
+type listenerMetrics struct {
+	sink   *Sink
+	active int64
+}
+
+func (m *listenerMetrics) Accepted() {
+	m.sink.Counter("accept")
+	m.sink.Gauge("active_conns", float64(atomic.AddInt64(&m.active, 1)))
+}
+
+func (m *listenerMetrics) ConnClosed() {
+	m.sink.Gauge("active_conns", float64(atomic.AddInt64(&m.active, -1)))
+}
+
+func (m *listenerMetrics) BytesIn(n int) {
+	m.sink.Histogram("bytes_in", float64(n))
+}
+
+func (m *listenerMetrics) BytesOut(n int) {
+	m.sink.Histogram("bytes_out", float64(n))
+}
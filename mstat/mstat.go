@@ -0,0 +1,230 @@
+// Package mstat provides a statsd-compatible metric sink, tied to a
+// Component's lifecycle in the same way as mnet's Listener and mlog's
+// Logger, so that services built on this module get metrics shipped to a
+// statsd daemon (e.g. for consumption by Datadog, Telegraf, or
+// statsd_exporter) without pulling in a heavyweight metrics library.
+package mstat
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mediocregopher/mediocre-go-lib/mcfg"
+	"github.com/mediocregopher/mediocre-go-lib/mcmp"
+	"github.com/mediocregopher/mediocre-go-lib/merr"
+	"github.com/mediocregopher/mediocre-go-lib/mlog"
+	"github.com/mediocregopher/mediocre-go-lib/mrun"
+	"github.com/mediocregopher/mediocre-go-lib/mtime"
+)
+
+// metricType is the statsd "type" suffix of a metric line, e.g. "c" for a
+// counter.
+type metricType string
+
+const (
+	typeCounter   metricType = "c"
+	typeGauge     metricType = "g"
+	typeHistogram metricType = "h"
+	typeTimer     metricType = "ms"
+)
+
+// metric is a single data point recorded against a Sink.
+type metric struct {
+	typ   metricType
+	name  string
+	value float64
+	tags  []string
+}
+
+// line renders m in the statsd wire format: name:value|type|#tag,tag,...
+func (m metric) line(prefix string) string {
+	b := strings.Builder{}
+	b.WriteString(prefix)
+	b.WriteString(m.name)
+	b.WriteByte(':')
+	fmt.Fprintf(&b, "%v", m.value)
+	b.WriteByte('|')
+	b.WriteString(string(m.typ))
+	if len(m.tags) > 0 {
+		b.WriteString("|#")
+		b.WriteString(strings.Join(m.tags, ","))
+	}
+	return b.String()
+}
+
+// Backend is implemented by each way a Sink can actually record metrics:
+// over UDP to a real statsd daemon, in-memory for tests (see MemBackend), or
+// fanned out to multiple other Backends (see FanOut).
+type Backend interface {
+	record(metric)
+	Close() error
+}
+
+// Sink batches and records Counter/Gauge/Histogram/Timer calls against a
+// Backend.
+type Sink struct {
+	backend Backend
+}
+
+// NewSink returns a Sink which records every metric given to it to b.
+func NewSink(b Backend) *Sink {
+	return &Sink{backend: b}
+}
+
+// Counter increments, by 1, the counter with the given name, tagged with
+// tags (each of the form "key:value").
+func (s *Sink) Counter(name string, tags ...string) {
+	s.backend.record(metric{typ: typeCounter, name: name, value: 1, tags: tags})
+}
+
+// Gauge sets the gauge with the given name to value, tagged with tags (each
+// of the form "key:value").
+func (s *Sink) Gauge(name string, value float64, tags ...string) {
+	s.backend.record(metric{typ: typeGauge, name: name, value: value, tags: tags})
+}
+
+// Histogram records a sample of value against the histogram with the given
+// name, tagged with tags (each of the form "key:value").
+func (s *Sink) Histogram(name string, value float64, tags ...string) {
+	s.backend.record(metric{typ: typeHistogram, name: name, value: value, tags: tags})
+}
+
+// Timer records d, in milliseconds, against the timer with the given name,
+// tagged with tags (each of the form "key:value").
+func (s *Sink) Timer(name string, d time.Duration, tags ...string) {
+	s.backend.record(metric{typ: typeTimer, name: name, value: float64(d.Milliseconds()), tags: tags})
+}
+
+// Close closes the underlying Backend.
+func (s *Sink) Close() error {
+	return s.backend.Close()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// udpBackend implements Backend by batching metric lines and periodically
+// flushing them, newline-separated, to a statsd daemon over UDP.
+type udpBackend struct {
+	conn   net.Conn
+	prefix string
+
+	l     sync.Mutex
+	lines []string
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newUDPBackend(conn net.Conn, prefix string, flushInterval time.Duration) *udpBackend {
+	b := &udpBackend{
+		conn:   conn,
+		prefix: prefix,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go b.flushEvery(flushInterval)
+	return b
+}
+
+func (b *udpBackend) record(m metric) {
+	line := m.line(b.prefix)
+	b.l.Lock()
+	b.lines = append(b.lines, line)
+	b.l.Unlock()
+}
+
+func (b *udpBackend) flushEvery(interval time.Duration) {
+	defer close(b.done)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			b.flush()
+		case <-b.stop:
+			b.flush()
+			return
+		}
+	}
+}
+
+// flush writes every buffered line out as a single UDP packet, newline
+// separated, which is the batching format statsd daemons expect.
+func (b *udpBackend) flush() {
+	b.l.Lock()
+	lines := b.lines
+	b.lines = nil
+	b.l.Unlock()
+
+	if len(lines) == 0 {
+		return
+	}
+	_, _ = b.conn.Write([]byte(strings.Join(lines, "\n")))
+}
+
+func (b *udpBackend) Close() error {
+	close(b.stop)
+	<-b.done
+	return b.conn.Close()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type statsdOpts struct {
+	defaultAddr string
+}
+
+// StatsdOpt is a value which adjusts the behavior of InstStatsd.
+type StatsdOpt func(*statsdOpts)
+
+// StatsdDefaultAddr adjusts the default value of the "statsd-addr" config
+// param. The default is "127.0.0.1:8125".
+func StatsdDefaultAddr(addr string) StatsdOpt {
+	return func(opts *statsdOpts) {
+		opts.defaultAddr = addr
+	}
+}
+
+// InstStatsd instantiates a Sink which will be initialized, by dialing the
+// configured statsd daemon over UDP, when the Init event is triggered on the
+// given Component, and closed (flushing any buffered metrics) when the
+// Shutdown event is triggered.
+func InstStatsd(cmp *mcmp.Component, options ...StatsdOpt) *Sink {
+	opts := statsdOpts{defaultAddr: "127.0.0.1:8125"}
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	cmp = cmp.Child("stat")
+	s := &Sink{}
+
+	addr := mcfg.String(cmp, "statsd-addr",
+		mcfg.ParamDefault(opts.defaultAddr),
+		mcfg.ParamUsage("Address of the statsd daemon to send metrics to, over UDP."))
+	prefix := mcfg.String(cmp, "statsd-prefix",
+		mcfg.ParamUsage("Prefix prepended to every metric name sent to statsd."))
+	flushInterval := mcfg.Duration(cmp, "statsd-flush-interval",
+		mcfg.ParamDefault(mtime.Duration{Duration: time.Second}),
+		mcfg.ParamUsage("How often to flush batched metrics to the statsd daemon."))
+
+	mrun.InitHook(cmp, func(ctx context.Context) error {
+		mlog.From(cmp).Info("connecting to statsd", ctx)
+		conn, err := net.Dial("udp", *addr)
+		if err != nil {
+			return merr.Wrap(ctx, err)
+		}
+		s.backend = newUDPBackend(conn, *prefix, flushInterval.Duration)
+		return nil
+	})
+
+	mrun.ShutdownHook(cmp, func(ctx context.Context) error {
+		mlog.From(cmp).Info("closing statsd sink", ctx)
+		return s.backend.Close()
+	})
+
+	return s
+}
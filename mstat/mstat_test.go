@@ -0,0 +1,41 @@
+package mstat
+
+import (
+	. "testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSink(t *T) {
+	mem := NewMemBackend()
+	s := NewSink(mem)
+
+	s.Counter("foo", "tag:a")
+	s.Gauge("bar", 42)
+	s.Histogram("baz", 1.5)
+	s.Timer("qux", 2*time.Second)
+
+	metrics := mem.Metrics()
+	assert.Equal(t, []Metric{
+		{Type: "c", Name: "foo", Value: 1, Tags: []string{"tag:a"}},
+		{Type: "g", Name: "bar", Value: 42},
+		{Type: "h", Name: "baz", Value: 1.5},
+		{Type: "ms", Name: "qux", Value: 2000},
+	}, metrics)
+}
+
+func TestFanOut(t *T) {
+	memA, memB := NewMemBackend(), NewMemBackend()
+	s := NewSink(FanOut(memA, memB))
+
+	s.Counter("foo")
+
+	assert.Len(t, memA.Metrics(), 1)
+	assert.Len(t, memB.Metrics(), 1)
+}
+
+func TestMetricLine(t *T) {
+	m := metric{typ: typeCounter, name: "foo", value: 1, tags: []string{"a:b", "c:d"}}
+	assert.Equal(t, "prefix.foo:1|c|#a:b,c:d", m.line("prefix."))
+}
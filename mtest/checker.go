@@ -17,6 +17,14 @@ type CheckerErr struct {
 	// The sequence of applied actions which generated the error
 	Applied []Applyer
 
+	// MinimalApplied is a subsequence of Applied which Checker's shrinking
+	// pass found to be the smallest it could still reproduce a failure
+	// with, via RunCase. It's only populated by Run/RunOnce, not RunCase. If
+	// shrinking wasn't able to reduce Applied any further (or wasn't able to
+	// run at all, e.g. ShrinkTimeout elapsed immediately) it holds the same
+	// Applyers as Applied.
+	MinimalApplied []Applyer
+
 	// The error returned by the final Action
 	Err error
 }
@@ -80,6 +88,18 @@ type Checker struct {
 	// State. This is called after Init and after every subsequent Action is
 	// applied.
 	Actions func(State) []Action
+
+	// ShrinkTimeout bounds how long Run/RunOnce's shrinking pass (which
+	// populates CheckerErr.MinimalApplied once a failure is found) is
+	// allowed to run for. If zero there is no time limit, only whatever
+	// MaxShrinkAttempts imposes.
+	ShrinkTimeout time.Duration
+
+	// MaxShrinkAttempts bounds how many times Run/RunOnce's shrinking pass
+	// may call RunCase while trying to find a smaller failing subsequence.
+	// If zero there is no limit, only whatever ShrinkTimeout imposes. If
+	// both are zero, shrinking runs to completion.
+	MaxShrinkAttempts int
 }
 
 // Run performs RunOnce in a loop until maxDuration has elapsed.
@@ -119,8 +139,9 @@ func (c Checker) RunOnce(maxDepth int) error {
 
 		if err != nil {
 			return CheckerErr{
-				Applied: applied,
-				Err:     err,
+				Applied:        applied,
+				MinimalApplied: c.shrink(applied),
+				Err:            err,
 			}
 		} else if action.Incomplete {
 			continue
@@ -145,3 +166,82 @@ func (c Checker) RunCase(aa ...Applyer) error {
 	}
 	return nil
 }
+
+// shrink runs the ddmin delta-debugging algorithm over applied, bounded by
+// c's ShrinkTimeout/MaxShrinkAttempts, returning the smallest subsequence it
+// found which still reproduces a failure via RunCase.
+func (c Checker) shrink(applied []Applyer) []Applyer {
+	var deadline time.Time
+	if c.ShrinkTimeout > 0 {
+		deadline = time.Now().Add(c.ShrinkTimeout)
+	}
+	return c.ddmin(applied, deadline, c.MaxShrinkAttempts)
+}
+
+// ddmin implements the delta-debugging minimization algorithm (Zeller &
+// Hildebrandt): starting with n=2, seq is split into n roughly-equal
+// chunks, and each chunk's complement (seq with that chunk removed) is
+// tried via RunCase. If any complement still fails, seq is replaced by it
+// and n is reduced back towards 2 (since a smaller seq may now need fewer
+// chunks to make further progress); otherwise n is doubled, up to len(seq),
+// to try smaller, more numerous chunks. It terminates once n exceeds
+// len(seq) — meaning every individual Applyer has been tried for removal
+// and none could be removed — or once deadline/maxAttempts is hit.
+func (c Checker) ddmin(seq []Applyer, deadline time.Time, maxAttempts int) []Applyer {
+	attempts := 0
+	fails := func(s []Applyer) bool {
+		if len(s) == 0 {
+			return false
+		}
+		if maxAttempts > 0 && attempts >= maxAttempts {
+			return false
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return false
+		}
+		attempts++
+		return c.RunCase(s...) != nil
+	}
+
+	n := 2
+	for n <= len(seq) {
+		chunkSize := (len(seq) + n - 1) / n // ceiling division
+		reduced := false
+
+		for lo := 0; lo < len(seq); lo += chunkSize {
+			hi := lo + chunkSize
+			if hi > len(seq) {
+				hi = len(seq)
+			}
+
+			complement := make([]Applyer, 0, len(seq)-(hi-lo))
+			complement = append(complement, seq[:lo]...)
+			complement = append(complement, seq[hi:]...)
+
+			if fails(complement) {
+				seq = complement
+				if n > 2 {
+					n--
+				}
+				reduced = true
+				break
+			}
+		}
+
+		if (maxAttempts > 0 && attempts >= maxAttempts) ||
+			(!deadline.IsZero() && time.Now().After(deadline)) {
+			break
+		}
+
+		if !reduced {
+			if n == len(seq) {
+				break
+			}
+			n *= 2
+			if n > len(seq) {
+				n = len(seq)
+			}
+		}
+	}
+	return seq
+}
@@ -51,3 +51,87 @@ func TestCheckerRun(t *T) {
 		t.Fatalf("unexpected caseErr: %v", caseErr)
 	}
 }
+
+// checkerShrinkPush appends its own int value onto the State (a []int),
+// failing once both poison values below have been pushed at any point
+// (not necessarily adjacently), regardless of what else has been pushed.
+type checkerShrinkPush int
+
+const (
+	checkerShrinkPoisonA = 1000
+	checkerShrinkPoisonB = 2000
+)
+
+func (p checkerShrinkPush) Apply(s State) (State, error) {
+	seq := append(s.([]int), int(p))
+	var haveA, haveB bool
+	for _, v := range seq {
+		haveA = haveA || v == checkerShrinkPoisonA
+		haveB = haveB || v == checkerShrinkPoisonB
+	}
+	if haveA && haveB {
+		return nil, errors.New("poison pair present")
+	}
+	return seq, nil
+}
+
+func checkerShrinkTestChecker(seq []int) Checker {
+	return Checker{
+		Init: func() State { return []int{} },
+		Actions: func(s State) []Action {
+			i := len(s.([]int))
+			return []Action{{Applyer: checkerShrinkPush(seq[i])}}
+		},
+	}
+}
+
+// TestCheckerShrink constructs a sequence with a known "poison" pair of
+// actions buried amongst unrelated noise, and asserts that Run/RunOnce's
+// shrinking pass isolates MinimalApplied down to exactly that pair.
+func TestCheckerShrink(t *T) {
+	seq := []int{1, 2, 3, checkerShrinkPoisonA, 4, 5, checkerShrinkPoisonB, 6, 7, 8}
+	c := checkerShrinkTestChecker(seq)
+
+	err := c.RunOnce(len(seq))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	ce := err.(CheckerErr)
+
+	if len(ce.MinimalApplied) != 2 {
+		t.Fatalf("expected MinimalApplied to have 2 elements, got %d: %v", len(ce.MinimalApplied), ce.MinimalApplied)
+	}
+
+	got := [2]int{
+		int(ce.MinimalApplied[0].(checkerShrinkPush)),
+		int(ce.MinimalApplied[1].(checkerShrinkPush)),
+	}
+	want := [2]int{checkerShrinkPoisonA, checkerShrinkPoisonB}
+	if got != want {
+		t.Fatalf("expected minimized case %v, got %v", want, got)
+	}
+
+	// the minimized case should reproduce the same kind of failure on its
+	// own.
+	if caseErr := c.RunCase(ce.MinimalApplied...); caseErr == nil {
+		t.Fatal("expected MinimalApplied to reproduce a failure via RunCase")
+	}
+}
+
+// TestCheckerShrinkMaxAttempts sanity-checks that MaxShrinkAttempts bounds
+// the shrinking pass without breaking it.
+func TestCheckerShrinkMaxAttempts(t *T) {
+	seq := []int{1, 2, 3, checkerShrinkPoisonA, 4, 5, checkerShrinkPoisonB, 6, 7, 8}
+	c := checkerShrinkTestChecker(seq)
+	c.MaxShrinkAttempts = 1
+
+	err := c.RunOnce(len(seq))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	ce := err.(CheckerErr)
+
+	if len(ce.MinimalApplied) == 0 || len(ce.MinimalApplied) > len(ce.Applied) {
+		t.Fatalf("unexpected MinimalApplied with MaxShrinkAttempts=1: %v", ce.MinimalApplied)
+	}
+}
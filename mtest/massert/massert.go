@@ -12,6 +12,7 @@ import (
 	"strings"
 	"testing"
 	"text/tabwriter"
+	"time"
 )
 
 // AssertErr is an error returned by Assertions which have failed, containing
@@ -442,6 +443,80 @@ func Len(set interface{}, length int) Assertion {
 	}, toStr(set)+" has length "+strconv.Itoa(length), 0)
 }
 
-// TODO ChanRead(ch interface{}, within time.Duration, callback func(interface{}) error)
-// TODO ChanBlock(ch interface{}, for time.Duration)
-// TODO ChanClosed(ch interface{})
+func reflectChanValue(ch interface{}) reflect.Value {
+	v := reflect.ValueOf(ch)
+	if v.Kind() != reflect.Chan {
+		panic(fmt.Errorf("value of type %s is not a channel", v.Type()))
+	}
+	return v
+}
+
+// selectRecv does a single receive off of chV, giving up and setting
+// timedOut to true if timeout passes first. ok follows the same meaning as
+// the second return value of a `v, ok := <-ch` receive.
+func selectRecv(chV reflect.Value, timeout time.Duration) (val reflect.Value, ok, timedOut bool) {
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: chV},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(time.After(timeout))},
+	}
+	chosen, recv, recvOK := reflect.Select(cases)
+	if chosen == 1 {
+		return reflect.Value{}, false, true
+	}
+	return recv, recvOK, false
+}
+
+// ChanRead asserts that a value can be read off of ch within the given
+// duration, and passes that value (as an interface{}) into callback, whose
+// returned error (if any) becomes the Assertion's own error. ch may be a
+// channel of any element type.
+func ChanRead(ch interface{}, within time.Duration, callback func(interface{}) error) Assertion {
+	chV := reflectChanValue(ch)
+	descr := fmt.Sprintf("read from %s within %s", chV.Type(), within)
+	return newAssertion(func() error {
+		val, ok, timedOut := selectRecv(chV, within)
+		if timedOut {
+			return fmt.Errorf("timed out after %s waiting to read from channel", within)
+		} else if !ok {
+			return errors.New("channel was closed before a value could be read")
+		}
+		return callback(val.Interface())
+	}, descr, 0)
+}
+
+// ChanBlock asserts that no value can be read off of ch (nor is ch closed)
+// within the given duration. ch may be a channel of any element type.
+func ChanBlock(ch interface{}, dur time.Duration) Assertion {
+	chV := reflectChanValue(ch)
+	descr := fmt.Sprintf("%s blocks for %s", chV.Type(), dur)
+	return newAssertion(func() error {
+		_, ok, timedOut := selectRecv(chV, dur)
+		if timedOut {
+			return nil
+		} else if ok {
+			return errors.New("value was received from channel, expected it to block")
+		}
+		return errors.New("channel was closed, expected it to block")
+	}, descr, 0)
+}
+
+// chanClosedTimeout bounds how long ChanClosed will wait for ch to close
+// before giving up; an open channel which never produces a value would
+// otherwise hang the assertion forever.
+const chanClosedTimeout = 100 * time.Millisecond
+
+// ChanClosed asserts that ch is closed, or becomes closed within a small
+// internal timeout. ch may be a channel of any element type.
+func ChanClosed(ch interface{}) Assertion {
+	chV := reflectChanValue(ch)
+	descr := fmt.Sprintf("%s is closed", chV.Type())
+	return newAssertion(func() error {
+		_, ok, timedOut := selectRecv(chV, chanClosedTimeout)
+		if timedOut {
+			return fmt.Errorf("timed out after %s waiting for channel to close", chanClosedTimeout)
+		} else if ok {
+			return errors.New("a value was received from the channel, expected it to be closed")
+		}
+		return nil
+	}, descr, 0)
+}
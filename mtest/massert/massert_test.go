@@ -3,6 +3,7 @@ package massert
 import (
 	"errors"
 	. "testing"
+	"time"
 )
 
 func succeed() Assertion {
@@ -218,6 +219,79 @@ func TestHasKey(t *T) {
 
 }
 
+func TestChanRead(t *T) {
+	ch := make(chan int, 1)
+	ch <- 1
+
+	var got int
+	Require(t, ChanRead(ch, time.Second, func(v interface{}) error {
+		got = v.(int)
+		return nil
+	}))
+	if got != 1 {
+		t.Fatalf("expected 1, got %d", got)
+	}
+
+	// the callback's returned error becomes the Assertion's error
+	ch <- 2
+	if err := ChanRead(ch, time.Second, func(interface{}) error {
+		return errors.New("callback failure")
+	}).Assert(); err == nil {
+		t.Fatal("ChanRead should have failed due to callback's error")
+	}
+
+	// nothing to read, should time out
+	empty := make(chan int)
+	if err := ChanRead(empty, 10*time.Millisecond, func(interface{}) error {
+		return nil
+	}).Assert(); err == nil {
+		t.Fatal("ChanRead on an empty channel should have timed out")
+	}
+
+	// closed before anything could be read
+	closed := make(chan int)
+	close(closed)
+	if err := ChanRead(closed, time.Second, func(interface{}) error {
+		return nil
+	}).Assert(); err == nil {
+		t.Fatal("ChanRead on a closed channel should have failed")
+	}
+}
+
+func TestChanBlock(t *T) {
+	ch := make(chan int)
+	Require(t, ChanBlock(ch, 10*time.Millisecond))
+
+	withValue := make(chan int, 1)
+	withValue <- 1
+	if err := ChanBlock(withValue, 10*time.Millisecond).Assert(); err == nil {
+		t.Fatal("ChanBlock should have failed, a value was available to read")
+	}
+
+	closed := make(chan int)
+	close(closed)
+	if err := ChanBlock(closed, 10*time.Millisecond).Assert(); err == nil {
+		t.Fatal("ChanBlock should have failed, the channel was closed")
+	}
+}
+
+func TestChanClosed(t *T) {
+	closed := make(chan int)
+	close(closed)
+	Require(t, ChanClosed(closed))
+
+	empty := make(chan int)
+	if err := ChanClosed(empty).Assert(); err == nil {
+		t.Fatal("ChanClosed should have timed out on a channel which never closes")
+	}
+
+	withValue := make(chan int, 1)
+	withValue <- 1
+	if err := ChanClosed(withValue).Assert(); err == nil {
+		t.Fatal("ChanClosed should have failed, a value was received instead of a close")
+	}
+}
+
 func TestLength(t *T) {
 	Require(t,
 		Length([]int(nil), 0),
@@ -7,11 +7,17 @@
 // The Action is applied to the State to obtain a new State, and a new Action is
 // generated from there, and so on. If any Action fails it is output along with
 // all of the Actions leading up to it.
+//
+// RunFor additionally shrinks a failing sequence of Actions, via repeated
+// RunCase calls, down to the smallest sequence which still reproduces an
+// equivalent failure, making the resulting RunErr easier to reason about.
 package mchk
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"sync"
 	"time"
 )
 
@@ -23,8 +29,24 @@ type RunErr struct {
 	// The sequence of Action Params which generated the error
 	Params []Params
 
+	// Required parallels Params, indicating which of them came from an
+	// Action with Required set (see Action.Required). It's populated on a
+	// RunErr returned by Run or RunFor, but nil on one returned by RunCase,
+	// since RunCase doesn't track Required itself.
+	Required []bool
+
 	// The error returned by the final Action
 	Err error
+
+	// OrigLen is the length of the originally failing Params sequence, before
+	// any shrinking took place. It's always 0 on a RunErr returned directly
+	// by Run or RunCase.
+	OrigLen int
+
+	// Shrinks is the number of times the original failing sequence of Params
+	// was successfully reduced by RunFor's shrinking process. It's always 0
+	// on a RunErr returned directly by Run or RunCase.
+	Shrinks int
 }
 
 func (ce RunErr) Error() string {
@@ -34,6 +56,9 @@ func (ce RunErr) Error() string {
 		fmt.Fprintf(buf, "\t%#v,\n", p)
 	}
 	fmt.Fprintf(buf, "}\n")
+	if ce.Shrinks > 0 {
+		fmt.Fprintf(buf, "Shrunk from %d actions to %d in %d shrink steps\n", ce.OrigLen, len(ce.Params), ce.Shrinks)
+	}
 	fmt.Fprintf(buf, "Generated error: %s\n", ce.Err)
 	return buf.String()
 }
@@ -60,6 +85,11 @@ type Action struct {
 	// be the last Action applied, even if the Run's length hasn't reached
 	// MaxLength yet.
 	Terminate bool
+
+	// Required can be set to true to indicate that this Action must not be
+	// removed by RunFor's shrinking process, e.g. because it performs setup
+	// which later Actions in the sequence depend on.
+	Required bool
 }
 
 // Checker implements a very basic property checker. It generates random test
@@ -85,6 +115,34 @@ type Checker struct {
 	// MaxLength indicates the maximum number of Actions which can be strung
 	// together in a single Run. Defaults to 10 if not set.
 	MaxLength int
+
+	// Shrink is an optional function used by RunFor to simplify a failing
+	// Action's Params once no more Actions can be removed from a failing
+	// sequence. It should return candidate Params which are "smaller" than p
+	// in whatever sense is meaningful for the test, ordered from most to
+	// least reduced. If nil, individual Params are never simplified, and
+	// shrinking is limited to removing whole Actions from the sequence.
+	Shrink func(p Params) []Params
+
+	// ErrEqual is an optional function used by RunFor to determine whether
+	// two errors returned from RunCase represent the same underlying
+	// failure, which is necessary to know whether a shrunk sequence of
+	// Params still reproduces the original bug. Defaults to comparing
+	// err.Error() strings.
+	ErrEqual func(a, b error) bool
+
+	// MaxShrinkAttempts bounds the number of candidate sequences RunFor's
+	// shrinking process will try (i.e. the number of RunCase calls it'll
+	// make) before giving up and returning the smallest sequence found so
+	// far. Defaults to unlimited (0) if not set.
+	MaxShrinkAttempts int
+
+	// Parallelism, if greater than 1, causes RunFor to run that many Runs
+	// concurrently, each in its own goroutine, rather than looping over them
+	// one at a time. The first one to fail cancels the rest. This is only
+	// safe to set if Init/Next/Apply/Cleanup are themselves goroutine-safe;
+	// leave it at its default of 0 (equivalent to 1) otherwise.
+	Parallelism int
 }
 
 func (c Checker) withDefaults() Checker {
@@ -94,8 +152,22 @@ func (c Checker) withDefaults() Checker {
 	return c
 }
 
-// RunFor performs Runs in a loop until maxDuration has elapsed.
+// RunFor performs Runs in a loop until maxDuration has elapsed. If a Run
+// fails the resulting RunErr is shrunk, via repeated calls to RunCase, into
+// the smallest Params sequence which still reproduces an equivalent error
+// before being returned.
+//
+// If Parallelism is greater than 1, Runs are instead performed across that
+// many concurrent goroutines (see Parallelism's doc string for the
+// goroutine-safety this requires of the Checker's fields) until the first
+// failure, which cancels the rest, or maxDuration elapses, whichever comes
+// first.
 func (c Checker) RunFor(maxDuration time.Duration) error {
+	c = c.withDefaults()
+	if c.Parallelism > 1 {
+		return c.runForParallel(maxDuration)
+	}
+
 	doneTimer := time.After(maxDuration)
 	for {
 		select {
@@ -105,28 +177,194 @@ func (c Checker) RunFor(maxDuration time.Duration) error {
 		}
 
 		if err := c.Run(); err != nil {
-			return err
+			return c.shrink(err.(RunErr))
 		}
 	}
 }
 
+// runForParallel implements RunFor's Parallelism > 1 case.
+func (c Checker) runForParallel(maxDuration time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), maxDuration)
+	defer cancel()
+
+	// buffered so that a worker which finds a failure right as another one
+	// does never blocks trying to report it.
+	errCh := make(chan RunErr, c.Parallelism)
+
+	var wg sync.WaitGroup
+	wg.Add(c.Parallelism)
+	for i := 0; i < c.Parallelism; i++ {
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				if err := c.runCtx(ctx); err != nil {
+					select {
+					case errCh <- err.(RunErr):
+						cancel()
+					default:
+					}
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case runErr := <-errCh:
+		return c.shrink(runErr)
+	default:
+		return nil
+	}
+}
+
+// errEqual returns the Checker's ErrEqual function, or a default which
+// compares the Error strings of a and b.
+func (c Checker) errEqual() func(a, b error) bool {
+	if c.ErrEqual != nil {
+		return c.ErrEqual
+	}
+	return func(a, b error) bool { return a.Error() == b.Error() }
+}
+
+// shrink repeatedly reduces orig.Params, via RunCase, into a smaller sequence
+// which still produces an equivalent error. It implements a delta-debugging
+// style search: contiguous sub-ranges of decreasing size are removed first
+// (skipping any range which would remove a Required Action), and once no
+// sub-range can be removed any Shrink candidates for the individual Params
+// are tried. This continues until a full pass over both produces no further
+// reduction, or MaxShrinkAttempts candidates have been tried.
+func (c Checker) shrink(orig RunErr) RunErr {
+	origLen := len(orig.Params)
+	errEqual := c.errEqual()
+
+	attempts := 0
+	maxAttemptsHit := func() bool {
+		attempts++
+		return c.MaxShrinkAttempts > 0 && attempts > c.MaxShrinkAttempts
+	}
+
+	isEquivalent := func(params []Params) (RunErr, bool) {
+		err := c.RunCase(params...)
+		if err == nil {
+			return RunErr{}, false
+		}
+		re := err.(RunErr)
+		return re, errEqual(re.Err, orig.Err)
+	}
+
+	rangeRequired := func(required []bool, start, size int) bool {
+		for _, r := range required[start : start+size] {
+			if r {
+				return true
+			}
+		}
+		return false
+	}
+
+	best := orig
+	bestRequired := append([]bool(nil), orig.Required...)
+	if bestRequired == nil {
+		bestRequired = make([]bool, len(orig.Params))
+	}
+
+done:
+	for {
+		reduced := false
+
+		for size := len(best.Params) - 1; size >= 1 && !reduced; size-- {
+			for start := 0; start+size <= len(best.Params); start++ {
+				if rangeRequired(bestRequired, start, size) {
+					continue
+				}
+
+				candidate := make([]Params, 0, len(best.Params)-size)
+				candidate = append(candidate, best.Params[:start]...)
+				candidate = append(candidate, best.Params[start+size:]...)
+
+				if maxAttemptsHit() {
+					break done
+				}
+
+				if re, ok := isEquivalent(candidate); ok {
+					best = RunErr{Params: re.Params, Err: re.Err, Shrinks: best.Shrinks + 1}
+					bestRequired = append(append([]bool(nil), bestRequired[:start]...), bestRequired[start+size:]...)
+					bestRequired = bestRequired[:len(best.Params)]
+					reduced = true
+					break
+				}
+			}
+		}
+
+		if !reduced && c.Shrink != nil {
+			for i := 0; i < len(best.Params) && !reduced; i++ {
+				for _, p := range c.Shrink(best.Params[i]) {
+					candidate := append([]Params(nil), best.Params...)
+					candidate[i] = p
+
+					if maxAttemptsHit() {
+						break done
+					}
+
+					if re, ok := isEquivalent(candidate); ok {
+						best = RunErr{Params: re.Params, Err: re.Err, Shrinks: best.Shrinks + 1}
+						bestRequired = bestRequired[:len(best.Params)]
+						reduced = true
+						break
+					}
+				}
+			}
+		}
+
+		if !reduced {
+			break
+		}
+	}
+
+	best.Required = bestRequired
+	best.OrigLen = origLen
+	return best
+}
+
 // Run generates a single sequence of Actions and applies them in order,
 // returning nil once the number of Actions performed has reached MaxLength or a
 // CheckErr if an error is returned.
 func (c Checker) Run() error {
+	return c.runCtx(context.Background())
+}
+
+// runCtx is Run's implementation, with the addition that it returns (with no
+// error) as soon as ctx is canceled, rather than always running to
+// completion. This is used by runForParallel so that a worker can be
+// interrupted mid-sequence, rather than only in between Runs.
+func (c Checker) runCtx(ctx context.Context) error {
 	c = c.withDefaults()
 	s := c.Init()
+	if c.Cleanup != nil {
+		// wrap in a function so we don't capture the value of s right here
+		defer func() {
+			c.Cleanup(s)
+		}()
+	}
+
 	params := make([]Params, 0, c.MaxLength)
+	required := make([]bool, 0, c.MaxLength)
 	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
 		action := c.Next(s)
 		var err error
 		s, err = c.Apply(s, action)
 		params = append(params, action.Params)
+		required = append(required, action.Required)
 
 		if err != nil {
 			return RunErr{
-				Params: params,
-				Err:    err,
+				Params:   params,
+				Required: required,
+				Err:      err,
 			}
 		} else if action.Incomplete {
 			continue
@@ -2,6 +2,7 @@ package mchk
 
 import (
 	"errors"
+	"sync/atomic"
 	. "testing"
 	"time"
 
@@ -32,18 +33,152 @@ func TestCheckerRun(t *T) {
 		t.Fatal(err)
 	}
 
-	// 20 should always go over 5 eventually
+	// 20 should always go over 5 eventually, and shrinking should reduce the
+	// failing sequence down to the minimal one: 6 consecutive +1 Actions,
+	// since that's the shortest sequence which can push the sum over 5.
 	c.MaxLength = 20
 	err := c.RunFor(time.Second)
 	if err == nil {
 		t.Fatal("expected error when maxDepth is 20")
-	} else if len(err.(RunErr).Params) < 6 {
-		t.Fatalf("strange RunErr when maxDepth is 20: %s", err)
+	}
+	runErr := err.(RunErr)
+	if len(runErr.Params) != 6 {
+		t.Fatalf("expected shrinking to minimize RunErr to 6 Params, got: %s", err)
+	}
+	for _, p := range runErr.Params {
+		if p.(int) != 1 {
+			t.Fatalf("expected all Params in minimized RunErr to be 1, got: %s", err)
+		}
 	}
 
-	t.Logf("got expected error with large maxDepth:\n%s", err)
-	caseErr := c.RunCase(err.(RunErr).Params...)
-	if caseErr == nil || err.Error() != caseErr.Error() {
+	t.Logf("got expected minimized error with large maxDepth:\n%s", err)
+	caseErr := c.RunCase(runErr.Params...)
+	if caseErr == nil || caseErr.(RunErr).Err.Error() != runErr.Err.Error() {
 		t.Fatalf("unexpected caseErr: %v", caseErr)
 	}
 }
+
+func TestCheckerRunShrinkParams(t *T) {
+	// A single Action whose Params always fail on their own, and so can't be
+	// removed by RunFor's removal-based shrinking (removing it would leave an
+	// empty, non-failing sequence). Shrink should still be able to reduce its
+	// Params down to the smallest value which still reproduces the failure.
+	c := Checker{
+		Init: func() State { return 0 },
+		Next: func(State) Action { return Action{Params: 7 + mrand.Intn(94)} },
+		Apply: func(s State, a Action) (State, error) {
+			si := s.(int) + a.Params.(int)
+			if si > 5 {
+				return nil, errors.New("went over 5")
+			}
+			return si, nil
+		},
+		Shrink: func(p Params) []Params {
+			n := p.(int)
+			if n <= 6 {
+				return nil
+			}
+			cand := n / 2
+			if cand < 6 {
+				cand = 6
+			}
+			return []Params{cand}
+		},
+		MaxLength: 1,
+	}
+
+	err := c.RunFor(time.Second)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	runErr := err.(RunErr)
+	if len(runErr.Params) != 1 || runErr.Params[0].(int) != 6 {
+		t.Fatalf("expected Shrink to minimize Params to [6], got: %s", err)
+	}
+	if runErr.Shrinks == 0 {
+		t.Fatalf("expected Shrinks to be non-zero, got: %s", err)
+	}
+}
+
+func TestCheckerRunForParallel(t *T) {
+	var cleanedUp int64
+
+	c := Checker{
+		Init: func() State { return 0 },
+		Next: func(State) Action {
+			if mrand.Intn(3) == 0 {
+				return Action{Params: -1}
+			}
+			return Action{Params: 1}
+		},
+		Apply: func(s State, a Action) (State, error) {
+			si := s.(int) + a.Params.(int)
+			if si > 5 {
+				return nil, errors.New("went over 5")
+			}
+			return si, nil
+		},
+		Cleanup: func(State) {
+			atomic.AddInt64(&cleanedUp, 1)
+		},
+		MaxLength:   20,
+		Parallelism: 4,
+	}
+
+	err := c.RunFor(time.Second)
+	if err == nil {
+		t.Fatal("expected error when maxDepth is 20")
+	}
+	runErr := err.(RunErr)
+	if len(runErr.Params) != 6 {
+		t.Fatalf("expected shrinking to minimize RunErr to 6 Params, got: %s", err)
+	}
+	for _, p := range runErr.Params {
+		if p.(int) != 1 {
+			t.Fatalf("expected all Params in minimized RunErr to be 1, got: %s", err)
+		}
+	}
+
+	if atomic.LoadInt64(&cleanedUp) == 0 {
+		t.Fatal("expected Cleanup to have been called at least once")
+	}
+}
+
+func TestCheckerRunRequired(t *T) {
+	// Same as TestCheckerRun, except the very first Action (a no-op +0,
+	// marked Required) must survive shrinking even though it's not needed to
+	// reproduce the failure.
+	var first bool
+	c := Checker{
+		Init: func() State { first = true; return 0 },
+		Next: func(State) Action {
+			if first {
+				first = false
+				return Action{Params: 0, Required: true}
+			}
+			return Action{Params: 1}
+		},
+		Apply: func(s State, a Action) (State, error) {
+			si := s.(int) + a.Params.(int)
+			if si > 5 {
+				return nil, errors.New("went over 5")
+			}
+			return si, nil
+		},
+		MaxLength: 20,
+	}
+
+	err := c.RunFor(time.Second)
+	if err == nil {
+		t.Fatal("expected error when maxDepth is 20")
+	}
+	runErr := err.(RunErr)
+	if runErr.Params[0].(int) != 0 {
+		t.Fatalf("expected Required Action to survive shrinking, got: %s", err)
+	}
+	for _, p := range runErr.Params[1:] {
+		if p.(int) != 1 {
+			t.Fatalf("expected all non-Required Params in minimized RunErr to be 1, got: %s", err)
+		}
+	}
+}
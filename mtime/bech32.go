@@ -0,0 +1,164 @@
+package mtime
+
+import (
+	"errors"
+	"strings"
+)
+
+// bech32Charset is the set of 32 characters Bech32 maps 5-bit groups onto. It
+// deliberately excludes "1", "b", "i", and "o" to avoid visual ambiguity.
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+var (
+	// ErrBech32InvalidChecksum is returned by Bech32Decode when a string's
+	// trailing checksum doesn't match its hrp/data, as will be the case for
+	// almost any typo or copy/paste error.
+	ErrBech32InvalidChecksum = errors.New("invalid bech32 checksum")
+
+	// ErrBech32MixedCase is returned by Bech32Decode when a string contains
+	// both upper and lower case characters, which the Bech32 spec disallows
+	// (mixed case isn't itself caught by the checksum, so it's rejected
+	// up-front instead).
+	ErrBech32MixedCase = errors.New("bech32 string contains mixed case")
+
+	errBech32NoSeparator = errors.New("bech32 string is missing its '1' separator")
+	errBech32InvalidChar = errors.New("bech32 string contains a character outside its charset")
+)
+
+// bech32Polymod computes the Bech32 checksum function described in BIP-173,
+// over the given sequence of 5-bit values. The generator polynomial's first
+// term, 0x3b6a57b2, is this implementation's most significant constant; the
+// other four are fixed alongside it by the spec.
+func bech32Polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		b := byte(chk >> 25)
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i, g := range gen {
+			if (b>>uint(i))&1 == 1 {
+				chk ^= g
+			}
+		}
+	}
+	return chk
+}
+
+// bech32HRPExpand spreads hrp's bits out for use in the checksum, per BIP-173:
+// the high 3 bits of each character, then a zero, then the low 5 bits of each
+// character.
+func bech32HRPExpand(hrp string) []byte {
+	out := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]>>5)
+	}
+	out = append(out, 0)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]&31)
+	}
+	return out
+}
+
+func bech32CreateChecksum(hrp string, data []byte) []byte {
+	values := append(bech32HRPExpand(hrp), data...)
+	values = append(values, 0, 0, 0, 0, 0, 0)
+	mod := bech32Polymod(values) ^ 1
+	out := make([]byte, 6)
+	for i := range out {
+		out[i] = byte((mod >> uint(5*(5-i))) & 31)
+	}
+	return out
+}
+
+func bech32VerifyChecksum(hrp string, data []byte) bool {
+	return bech32Polymod(append(bech32HRPExpand(hrp), data...)) == 1
+}
+
+// convertBits regroups data, a sequence of fromBits-wide values, into a
+// sequence of toBits-wide values, padding the final group with zeros if pad
+// is true (used when going from 8 bits to 5) or requiring it to already be
+// aligned if not (used when going from 5 bits back to 8).
+func convertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte, error) {
+	var acc uint32
+	var bits uint
+	var out []byte
+	maxV := uint32(1)<<toBits - 1
+	for _, v := range data {
+		if uint32(v)>>fromBits != 0 {
+			return nil, errors.New("bech32: invalid data for bit conversion")
+		}
+		acc = acc<<fromBits | uint32(v)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			out = append(out, byte(acc>>bits)&byte(maxV))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(toBits-bits))&byte(maxV))
+		}
+	} else if bits >= fromBits || (byte(acc<<(toBits-bits))&byte(maxV)) != 0 {
+		return nil, errors.New("bech32: invalid padding in bit conversion")
+	}
+	return out, nil
+}
+
+// Bech32Encode encodes data using the Bech32 format (BIP-173, the same
+// scheme used for Tendermint/Cosmos addresses): the given hrp (human
+// readable part), a "1" separator, data regrouped into 5-bit words and
+// mapped through bech32Charset, and a trailing 6-character checksum covering
+// both hrp and data. The checksum lets Bech32Decode immediately detect
+// almost any single (or small number of) mistyped character(s), which
+// matters for strings meant to be read, copied, or retyped by a human.
+func Bech32Encode(hrp string, data []byte) (string, error) {
+	values, err := convertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	combined := append(values, bech32CreateChecksum(hrp, values)...)
+
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, v := range combined {
+		sb.WriteByte(bech32Charset[v])
+	}
+	return sb.String(), nil
+}
+
+// Bech32Decode reverses Bech32Encode, verifying the trailing checksum before
+// returning. It returns ErrBech32MixedCase if s contains both upper and
+// lower case characters, or ErrBech32InvalidChecksum if the checksum doesn't
+// match hrp and data (the case for almost any corrupted s).
+func Bech32Decode(s string) (hrp string, data []byte, err error) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, ErrBech32MixedCase
+	}
+	s = strings.ToLower(s)
+
+	i := strings.LastIndexByte(s, '1')
+	if i < 1 || i+7 > len(s) {
+		return "", nil, errBech32NoSeparator
+	}
+	hrp, dataStr := s[:i], s[i+1:]
+
+	values := make([]byte, len(dataStr))
+	for j := 0; j < len(dataStr); j++ {
+		v := strings.IndexByte(bech32Charset, dataStr[j])
+		if v < 0 {
+			return "", nil, errBech32InvalidChar
+		}
+		values[j] = byte(v)
+	}
+
+	if !bech32VerifyChecksum(hrp, values) {
+		return "", nil, ErrBech32InvalidChecksum
+	}
+
+	data, err = convertBits(values[:len(values)-6], 5, 8, false)
+	if err != nil {
+		return "", nil, err
+	}
+	return hrp, data, nil
+}
@@ -0,0 +1,51 @@
+package mtime
+
+import (
+	"strings"
+	. "testing"
+
+	"github.com/mediocregopher/mediocre-go-lib/mrand"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBech32RoundTrip(t *T) {
+	for i := 0; i < 1000; i++ {
+		data := mrand.Bytes(1 + mrand.Intn(64))
+		str, err := Bech32Encode("ts", data)
+		require.NoError(t, err)
+
+		hrp, got, err := Bech32Decode(str)
+		require.NoError(t, err)
+		assert.Equal(t, "ts", hrp)
+		assert.Equal(t, data, got)
+	}
+}
+
+func TestBech32Corruption(t *T) {
+	str, err := Bech32Encode("ts", mrand.Bytes(16))
+	require.NoError(t, err)
+
+	for i := len("ts1"); i < len(str); i++ {
+		orig := str[i]
+		for _, c := range []byte(bech32Charset) {
+			if c == orig {
+				continue
+			}
+			corrupted := []byte(str)
+			corrupted[i] = c
+			_, _, err := Bech32Decode(string(corrupted))
+			assert.Error(t, err, "corrupting char %d (%q -> %q) of %q should have been caught", i, orig, c, str)
+			break
+		}
+	}
+}
+
+func TestBech32MixedCase(t *T) {
+	str, err := Bech32Encode("ts", mrand.Bytes(16))
+	require.NoError(t, err)
+
+	mixed := strings.ToUpper(str[:1]) + str[1:]
+	_, _, err = Bech32Decode(mixed)
+	assert.Equal(t, ErrBech32MixedCase, err)
+}
@@ -0,0 +1,130 @@
+package mtime
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// monoEpoch is recorded once, the first time this package is used, as the
+// reference point MonoTS's monotonic offsets are measured against.
+var monoEpoch = time.Now()
+
+// monoTSHRP is the Bech32 human-readable-part prefix used by MonoTS's text
+// encoding.
+const monoTSHRP = "ts"
+
+// MonoTS is, like TS, a wrapper around a point in time, but it additionally
+// carries a monotonic offset (akin to what you'd get out of time.Since) and
+// marshals to a compact, checksummed Bech32 string rather than a
+// floating-point unix timestamp. The checksum means a single mistyped or
+// mis-copied character is caught on unmarshal before it could ever be
+// mistaken for a different, valid timestamp, which matters for values a
+// human might read, copy, or retype (e.g. out of a log and into a support
+// ticket), and the resulting string is also safe to use unescaped in URLs
+// and DNS labels, unlike TS's punctuation-heavy float format.
+type MonoTS struct {
+	Wall time.Time
+	Mono time.Duration
+}
+
+// NewMonoTS returns a MonoTS wrapping the given time.Time, paired with the
+// monotonic offset (time.Since(monoEpoch)) measured at the moment this is
+// called.
+func NewMonoTS(t time.Time) MonoTS {
+	return MonoTS{Wall: t, Mono: time.Since(monoEpoch)}
+}
+
+// NowMonoTS is a wrapper around time.Now which returns a MonoTS.
+func NowMonoTS() MonoTS {
+	return NewMonoTS(time.Now())
+}
+
+func (t MonoTS) bytes() []byte {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b[:8], uint64(t.Wall.UnixNano()))
+	binary.BigEndian.PutUint64(b[8:], uint64(t.Mono))
+	return b
+}
+
+func monoTSFromBytes(b []byte) (MonoTS, error) {
+	if len(b) != 16 {
+		return MonoTS{}, fmt.Errorf("malformed MonoTS payload (%d bytes)", len(b))
+	}
+	return MonoTS{
+		Wall: time.Unix(0, int64(binary.BigEndian.Uint64(b[:8]))),
+		Mono: time.Duration(binary.BigEndian.Uint64(b[8:])),
+	}, nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface. Unlike
+// MarshalText/String, this returns t's raw 16-byte encoding rather than a
+// self-contained Bech32 string, for use by callers which need to embed a
+// MonoTS within some larger encoded structure of their own (see
+// mcrypto.Signature's TimeFormatBech32, for example).
+func (t MonoTS) MarshalBinary() ([]byte, error) {
+	return t.bytes(), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (t *MonoTS) UnmarshalBinary(b []byte) error {
+	parsed, err := monoTSFromBytes(b)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// String returns the Bech32 encoding of t, prefixed with the "ts" HRP.
+func (t MonoTS) String() string {
+	s, err := Bech32Encode(monoTSHRP, t.bytes())
+	if err != nil {
+		// t.bytes() always produces a fixed 16 bytes, so encoding can't fail.
+		panic(err)
+	}
+	return s
+}
+
+// MonoTSFromString parses a string produced by MonoTS's String/MarshalText
+// methods back into a MonoTS. It returns an error if s's Bech32 checksum
+// doesn't validate (e.g. due to a typo) or its HRP isn't "ts".
+func MonoTSFromString(s string) (MonoTS, error) {
+	hrp, data, err := Bech32Decode(s)
+	if err != nil {
+		return MonoTS{}, err
+	} else if hrp != monoTSHRP {
+		return MonoTS{}, fmt.Errorf("unexpected MonoTS hrp %q", hrp)
+	}
+	return monoTSFromBytes(data)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (t MonoTS) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (t *MonoTS) UnmarshalText(b []byte) error {
+	parsed, err := MonoTSFromString(string(b))
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (t MonoTS) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (t *MonoTS) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return t.UnmarshalText([]byte(s))
+}
@@ -0,0 +1,57 @@
+package mtime
+
+import (
+	"encoding/json"
+	. "testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonoTS(t *T) {
+	now := NowMonoTS()
+
+	b, err := now.MarshalText()
+	require.NoError(t, err)
+
+	var got MonoTS
+	require.NoError(t, got.UnmarshalText(b))
+	assert.True(t, got.Wall.Equal(now.Wall))
+	assert.Equal(t, now.Mono, got.Mono)
+
+	got2, err := MonoTSFromString(string(b))
+	require.NoError(t, err)
+	assert.Equal(t, got, got2)
+}
+
+func TestMonoTSJSON(t *T) {
+	now := NowMonoTS()
+	b, err := now.MarshalJSON()
+	require.NoError(t, err)
+
+	var got MonoTS
+	require.NoError(t, json.Unmarshal(b, &got))
+	assert.True(t, got.Wall.Equal(now.Wall))
+	assert.Equal(t, now.Mono, got.Mono)
+}
+
+// TestMonoTSCorruption verifies that corrupting any single character of a
+// marshaled MonoTS is always caught (via its Bech32 checksum) rather than
+// silently parsing into a different, wrong, timestamp.
+func TestMonoTSCorruption(t *T) {
+	str := NowMonoTS().String()
+
+	for i := len(monoTSHRP) + 1; i < len(str); i++ {
+		orig := str[i]
+		for _, c := range []byte(bech32Charset) {
+			if c == orig {
+				continue
+			}
+			corrupted := []byte(str)
+			corrupted[i] = c
+			_, err := MonoTSFromString(string(corrupted))
+			assert.Error(t, err, "corrupting char %d (%q -> %q) of %q should have been caught", i, orig, c, str)
+			break
+		}
+	}
+}